@@ -0,0 +1,234 @@
+package auditor
+
+import (
+	"errors"
+	"fmt"
+	"hide-pay/builder"
+	"hide-pay/wallet"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// AuditedNote is an output opened from its audit memo.
+type AuditedNote struct {
+	Index      uint64
+	Commitment fr.Element
+	Opening    builder.Commitment
+	Nullifier  fr.Element
+	Spent      bool
+}
+
+// AssetSummary totals the audited flows of one asset. Inflow counts every
+// created note, Outflow every spent one.
+type AssetSummary struct {
+	Asset   fr.Element
+	Inflow  *big.Int
+	Outflow *big.Int
+}
+
+func (summary *AssetSummary) Outstanding() *big.Int {
+	return new(big.Int).Sub(summary.Inflow, summary.Outflow)
+}
+
+type AnomalyKind string
+
+const (
+	// AnomalyMalformedMemo is an audit memo that does not parse.
+	AnomalyMalformedMemo AnomalyKind = "malformed_memo"
+	// AnomalyDecryptionFailed is an audit memo the audit key cannot open.
+	AnomalyDecryptionFailed AnomalyKind = "decryption_failed"
+	// AnomalyCommitmentMismatch is an audit memo whose opening does not
+	// recompute the commitment it was published with.
+	AnomalyCommitmentMismatch AnomalyKind = "commitment_mismatch"
+	// AnomalyUnknownNullifier is a nullifier matching no audited note.
+	AnomalyUnknownNullifier AnomalyKind = "unknown_nullifier"
+)
+
+// Anomaly flags an event the auditor could not account for. Index and
+// Commitment are unset for nullifier anomalies.
+type Anomaly struct {
+	Kind       AnomalyKind
+	Index      uint64
+	Commitment fr.Element
+	Nullifier  fr.Element
+	Reason     string
+}
+
+type Report struct {
+	Notes     []AuditedNote
+	Assets    []AssetSummary
+	Anomalies []Anomaly
+}
+
+// Auditor opens audit memos with the audit secret key and derives the flows
+// of every note it can see.
+type Auditor struct {
+	auditSecretKey big.Int
+
+	notes       []*AuditedNote
+	commitments map[fr.Element]*AuditedNote
+	nullifiers  map[fr.Element]*AuditedNote
+	// pending holds nullifiers not yet matched to a note, in arrival order
+	pending   []fr.Element
+	anomalies []Anomaly
+}
+
+func NewAuditor(auditSecretKey big.Int) *Auditor {
+	return &Auditor{
+		auditSecretKey: auditSecretKey,
+		commitments:    make(map[fr.Element]*AuditedNote),
+		nullifiers:     make(map[fr.Element]*AuditedNote),
+	}
+}
+
+// Audit processes every event of source until it is drained.
+func (auditor *Auditor) Audit(source wallet.EventSource) error {
+	for {
+		event, err := source.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := auditor.Process(event); err != nil {
+			return err
+		}
+	}
+}
+
+func (auditor *Auditor) Process(event *wallet.Event) error {
+	switch {
+	case event.CommitmentAdded != nil:
+		auditor.processCommitment(event.CommitmentAdded)
+	case event.Nullifier != nil:
+		auditor.processNullifier(*event.Nullifier)
+	default:
+		return fmt.Errorf("empty event")
+	}
+
+	return nil
+}
+
+func (auditor *Auditor) processCommitment(added *wallet.CommitmentAdded) {
+	if _, ok := auditor.commitments[added.Commitment]; ok {
+		return
+	}
+
+	// Each event carries the audit memo of its own commitment only
+	var envelope builder.EncryptedMemo
+	if err := envelope.UnmarshalWords(added.AuditMemo); err != nil {
+		auditor.flag(AnomalyMalformedMemo, added, err)
+		return
+	}
+
+	opening, spentKey, err := envelope.Decrypt(auditor.auditSecretKey)
+	if err != nil {
+		auditor.flag(AnomalyDecryptionFailed, added, err)
+		return
+	}
+
+	if opening.Compute() != added.Commitment {
+		auditor.flag(AnomalyCommitmentMismatch, added, fmt.Errorf("opening does not recompute the commitment"))
+		return
+	}
+
+	// The spent key in the memo gives the auditor the nullifier, hence the
+	// spent status, without any help from the owner
+	nullifier := builder.Nullifier{
+		Commitment:      *opening,
+		SpentPrivateKey: *spentKey,
+	}
+
+	note := &AuditedNote{
+		Index:      added.Index,
+		Commitment: added.Commitment,
+		Opening:    *opening,
+		Nullifier:  nullifier.Compute(),
+	}
+
+	auditor.notes = append(auditor.notes, note)
+	auditor.commitments[note.Commitment] = note
+	auditor.nullifiers[note.Nullifier] = note
+}
+
+func (auditor *Auditor) processNullifier(nullifier fr.Element) {
+	if note, ok := auditor.nullifiers[nullifier]; ok {
+		note.Spent = true
+		return
+	}
+
+	auditor.pending = append(auditor.pending, nullifier)
+}
+
+func (auditor *Auditor) flag(kind AnomalyKind, added *wallet.CommitmentAdded, err error) {
+	auditor.anomalies = append(auditor.anomalies, Anomaly{
+		Kind:       kind,
+		Index:      added.Index,
+		Commitment: added.Commitment,
+		Reason:     err.Error(),
+	})
+}
+
+// Report summarizes everything processed so far. Nullifiers that arrived
+// before their note are resolved here; those still unmatched are anomalies.
+func (auditor *Auditor) Report() *Report {
+	report := &Report{
+		Anomalies: append([]Anomaly{}, auditor.anomalies...),
+	}
+
+	spent := make(map[fr.Element]bool)
+	for _, nullifier := range auditor.pending {
+		if _, ok := auditor.nullifiers[nullifier]; ok {
+			spent[nullifier] = true
+			continue
+		}
+
+		report.Anomalies = append(report.Anomalies, Anomaly{
+			Kind:      AnomalyUnknownNullifier,
+			Nullifier: nullifier,
+			Reason:    "nullifier does not match any audited note",
+		})
+	}
+
+	assets := make(map[fr.Element]*AssetSummary)
+	for _, note := range auditor.notes {
+		audited := *note
+		audited.Spent = audited.Spent || spent[audited.Nullifier]
+		report.Notes = append(report.Notes, audited)
+
+		summary, ok := assets[audited.Opening.Asset]
+		if !ok {
+			summary = &AssetSummary{
+				Asset:   audited.Opening.Asset,
+				Inflow:  new(big.Int),
+				Outflow: new(big.Int),
+			}
+			assets[audited.Opening.Asset] = summary
+		}
+
+		amount := audited.Opening.Amount.BigInt(new(big.Int))
+		summary.Inflow.Add(summary.Inflow, amount)
+		if audited.Spent {
+			summary.Outflow.Add(summary.Outflow, amount)
+		}
+	}
+
+	sort.Slice(report.Notes, func(i, j int) bool {
+		return report.Notes[i].Index < report.Notes[j].Index
+	})
+
+	for _, summary := range assets {
+		report.Assets = append(report.Assets, *summary)
+	}
+
+	sort.Slice(report.Assets, func(i, j int) bool {
+		return report.Assets[i].Asset.Cmp(&report.Assets[j].Asset) < 0
+	})
+
+	return report
+}
@@ -0,0 +1,201 @@
+package auditor_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"hide-pay/auditor"
+	"hide-pay/builder"
+	"hide-pay/utils"
+	"hide-pay/wallet"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAuditedEvent builds a CommitmentAdded event whose audit memo is
+// encrypted to auditPublicKey, and returns the note's nullifier.
+func newAuditedEvent(t *testing.T, index uint64, asset uint64, amount uint64, auditPublicKey twistededwardbn254.PointAffine) (wallet.Event, fr.Element) {
+	commitment, spentKey := builder.GenerateCommitment(int64(index) + 1)
+	commitment.Asset = fr.NewElement(asset)
+	commitment.Amount = fr.NewElement(amount)
+	commitment.AuditPubKey = auditPublicKey
+
+	memo := &builder.Memo{
+		SecretKey: *big.NewInt(int64(index) + 1000),
+		PublicKey: auditPublicKey,
+	}
+
+	envelope, err := memo.Encrypt(*commitment, *spentKey)
+	require.NoError(t, err)
+
+	nullifier := builder.Nullifier{
+		Commitment:      *commitment,
+		SpentPrivateKey: *spentKey,
+	}
+
+	return wallet.Event{
+		CommitmentAdded: &wallet.CommitmentAdded{
+			Index:      index,
+			Commitment: commitment.Compute(),
+			OwnerMemo:  envelope.MarshalWords(),
+			AuditMemo:  envelope.MarshalWords(),
+		},
+	}, nullifier.Compute()
+}
+
+func newAuditedReport(t *testing.T) *auditor.Report {
+	auditSecretKey := big.NewInt(33333)
+	auditPublicKey := utils.BuildPublicKey(*auditSecretKey)
+
+	note0, nullifier0 := newAuditedEvent(t, 0, 1, 100, auditPublicKey)
+	note1, _ := newAuditedEvent(t, 1, 1, 40, auditPublicKey)
+	note2, nullifier2 := newAuditedEvent(t, 2, 2, 9, auditPublicKey)
+
+	// Encrypted to another auditor
+	foreign, _ := newAuditedEvent(t, 3, 1, 1, utils.BuildPublicKey(*big.NewInt(44444)))
+
+	// Valid memo republished next to another commitment
+	mismatched, _ := newAuditedEvent(t, 4, 1, 5, auditPublicKey)
+	mismatched.CommitmentAdded.Commitment.SetOne()
+
+	malformed, _ := newAuditedEvent(t, 5, 1, 5, auditPublicKey)
+	malformed.CommitmentAdded.AuditMemo = malformed.CommitmentAdded.AuditMemo[:2]
+
+	unknown := fr.NewElement(777)
+
+	events := []wallet.Event{
+		note0,
+		note1,
+		{Nullifier: &nullifier0},
+		foreign,
+		mismatched,
+		malformed,
+		{Nullifier: &unknown},
+		note2,
+		{Nullifier: &nullifier2},
+	}
+
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	for _, event := range events {
+		require.NoError(t, encoder.Encode(event))
+	}
+
+	audit := auditor.NewAuditor(*auditSecretKey)
+	require.NoError(t, audit.Audit(wallet.NewFileSource(buf)))
+
+	return audit.Report()
+}
+
+func TestAuditor_Report(t *testing.T) {
+	report := newAuditedReport(t)
+
+	require.Len(t, report.Notes, 3)
+	assert.True(t, report.Notes[0].Spent)
+	assert.False(t, report.Notes[1].Spent)
+	assert.True(t, report.Notes[2].Spent)
+	assert.Equal(t, fr.NewElement(40), report.Notes[1].Opening.Amount)
+
+	require.Len(t, report.Assets, 2)
+	assert.Equal(t, fr.NewElement(1), report.Assets[0].Asset)
+	assert.Equal(t, big.NewInt(140), report.Assets[0].Inflow)
+	assert.Equal(t, big.NewInt(100), report.Assets[0].Outflow)
+	assert.Equal(t, big.NewInt(40), report.Assets[0].Outstanding())
+	assert.Equal(t, big.NewInt(9), report.Assets[1].Outflow)
+
+	kinds := make([]auditor.AnomalyKind, len(report.Anomalies))
+	for i := range report.Anomalies {
+		kinds[i] = report.Anomalies[i].Kind
+	}
+	assert.Equal(t, []auditor.AnomalyKind{
+		auditor.AnomalyDecryptionFailed,
+		auditor.AnomalyCommitmentMismatch,
+		auditor.AnomalyMalformedMemo,
+		auditor.AnomalyUnknownNullifier,
+	}, kinds)
+	assert.Equal(t, uint64(3), report.Anomalies[0].Index)
+}
+
+func TestAuditor_NullifierBeforeNote(t *testing.T) {
+	auditSecretKey := big.NewInt(33333)
+	note, nullifier := newAuditedEvent(t, 0, 1, 100, utils.BuildPublicKey(*auditSecretKey))
+
+	audit := auditor.NewAuditor(*auditSecretKey)
+	require.NoError(t, audit.Process(&wallet.Event{Nullifier: &nullifier}))
+	require.NoError(t, audit.Process(&note))
+
+	report := audit.Report()
+	require.Len(t, report.Notes, 1)
+	assert.True(t, report.Notes[0].Spent)
+	assert.Empty(t, report.Anomalies)
+}
+
+func TestAuditor_TwoOutputTransfer(t *testing.T) {
+	auditSecretKey := big.NewInt(33333)
+	auditPublicKey := utils.BuildPublicKey(*auditSecretKey)
+
+	utxo, err := builder.GenerateUTXO(1, 10, 2, 2)
+	require.NoError(t, err)
+	utxo.AuditPublicKey = auditPublicKey
+	for i := range utxo.Commitment {
+		utxo.Commitment[i].AuditPubKey = auditPublicKey
+	}
+
+	result, err := utxo.BuildAndCheck()
+	require.NoError(t, err)
+
+	// Each output is opened from its own audit memo
+	audit := auditor.NewAuditor(*auditSecretKey)
+	for _, event := range wallet.TransferEvents(0, result) {
+		require.NoError(t, audit.Process(&event))
+	}
+
+	report := audit.Report()
+	assert.Empty(t, report.Anomalies)
+	require.Len(t, report.Notes, 2)
+	for i := range report.Notes {
+		assert.Equal(t, utxo.Commitment[i], report.Notes[i].Opening)
+	}
+
+	require.Len(t, report.Assets, 1)
+	assert.Equal(t, big.NewInt(12), report.Assets[0].Inflow)
+}
+
+func TestReport_Export(t *testing.T) {
+	report := newAuditedReport(t)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, report.WriteJSON(buf))
+
+	var raw map[string][]map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &raw))
+	assert.Len(t, raw["notes"], 3)
+	assert.Equal(t, "140", raw["assets"][0]["inflow"])
+	assert.Equal(t, "40", raw["assets"][0]["outstanding"])
+	assert.Len(t, raw["anomalies"], 4)
+
+	buf.Reset()
+	require.NoError(t, report.WriteNotesCSV(buf))
+	rows, err := csv.NewReader(buf).ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, rows, 4)
+	assert.Equal(t, []string{"0", "1", "100"}, []string{rows[1][0], rows[1][2], rows[1][3]})
+
+	buf.Reset()
+	require.NoError(t, report.WriteAssetsCSV(buf))
+	rows, err = csv.NewReader(buf).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "140", "100", "40"}, rows[1])
+
+	buf.Reset()
+	require.NoError(t, report.WriteAnomaliesCSV(buf))
+	rows, err = csv.NewReader(buf).ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, rows, 5)
+	assert.Equal(t, string(auditor.AnomalyUnknownNullifier), rows[4][0])
+}
@@ -0,0 +1,175 @@
+package auditor
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+type noteJSON struct {
+	Index      uint64 `json:"index"`
+	Commitment string `json:"commitment"`
+	Asset      string `json:"asset"`
+	Amount     string `json:"amount"`
+	OwnerX     string `json:"ownerX"`
+	OwnerY     string `json:"ownerY"`
+	Frozen     bool   `json:"frozen"`
+	Nullifier  string `json:"nullifier"`
+	Spent      bool   `json:"spent"`
+}
+
+type assetJSON struct {
+	Asset       string `json:"asset"`
+	Inflow      string `json:"inflow"`
+	Outflow     string `json:"outflow"`
+	Outstanding string `json:"outstanding"`
+}
+
+type anomalyJSON struct {
+	Kind       AnomalyKind `json:"kind"`
+	Index      uint64      `json:"index,omitempty"`
+	Commitment string      `json:"commitment,omitempty"`
+	Nullifier  string      `json:"nullifier,omitempty"`
+	Reason     string      `json:"reason"`
+}
+
+type reportJSON struct {
+	Notes     []noteJSON    `json:"notes"`
+	Assets    []assetJSON   `json:"assets"`
+	Anomalies []anomalyJSON `json:"anomalies"`
+}
+
+// WriteJSON writes the whole report. Amounts and assets are decimal strings,
+// commitments and nullifiers 0x-prefixed hex.
+func (report *Report) WriteJSON(w io.Writer) error {
+	raw := reportJSON{
+		Notes:     make([]noteJSON, len(report.Notes)),
+		Assets:    make([]assetJSON, len(report.Assets)),
+		Anomalies: make([]anomalyJSON, len(report.Anomalies)),
+	}
+
+	for i := range report.Notes {
+		note := &report.Notes[i]
+		raw.Notes[i] = noteJSON{
+			Index:      note.Index,
+			Commitment: encodeHex(&note.Commitment),
+			Asset:      note.Opening.Asset.Text(10),
+			Amount:     note.Opening.Amount.Text(10),
+			OwnerX:     encodeHex(&note.Opening.OwnerPubKey.X),
+			OwnerY:     encodeHex(&note.Opening.OwnerPubKey.Y),
+			Frozen:     !note.Opening.FreezeFlag.IsZero(),
+			Nullifier:  encodeHex(&note.Nullifier),
+			Spent:      note.Spent,
+		}
+	}
+
+	for i := range report.Assets {
+		summary := &report.Assets[i]
+		raw.Assets[i] = assetJSON{
+			Asset:       summary.Asset.Text(10),
+			Inflow:      summary.Inflow.String(),
+			Outflow:     summary.Outflow.String(),
+			Outstanding: summary.Outstanding().String(),
+		}
+	}
+
+	for i := range report.Anomalies {
+		anomaly := &report.Anomalies[i]
+		raw.Anomalies[i] = anomalyJSON{
+			Kind:   anomaly.Kind,
+			Reason: anomaly.Reason,
+		}
+
+		if anomaly.Kind == AnomalyUnknownNullifier {
+			raw.Anomalies[i].Nullifier = encodeHex(&anomaly.Nullifier)
+		} else {
+			raw.Anomalies[i].Index = anomaly.Index
+			raw.Anomalies[i].Commitment = encodeHex(&anomaly.Commitment)
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(raw)
+}
+
+// WriteNotesCSV writes one row per audited note.
+func (report *Report) WriteNotesCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	rows := [][]string{{"index", "commitment", "asset", "amount", "owner_x", "owner_y", "frozen", "nullifier", "spent"}}
+	for i := range report.Notes {
+		note := &report.Notes[i]
+		rows = append(rows, []string{
+			strconv.FormatUint(note.Index, 10),
+			encodeHex(&note.Commitment),
+			note.Opening.Asset.Text(10),
+			note.Opening.Amount.Text(10),
+			encodeHex(&note.Opening.OwnerPubKey.X),
+			encodeHex(&note.Opening.OwnerPubKey.Y),
+			strconv.FormatBool(!note.Opening.FreezeFlag.IsZero()),
+			encodeHex(&note.Nullifier),
+			strconv.FormatBool(note.Spent),
+		})
+	}
+
+	return writeCSV(writer, rows)
+}
+
+// WriteAssetsCSV writes one row of flow totals per asset.
+func (report *Report) WriteAssetsCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	rows := [][]string{{"asset", "inflow", "outflow", "outstanding"}}
+	for i := range report.Assets {
+		summary := &report.Assets[i]
+		rows = append(rows, []string{
+			summary.Asset.Text(10),
+			summary.Inflow.String(),
+			summary.Outflow.String(),
+			summary.Outstanding().String(),
+		})
+	}
+
+	return writeCSV(writer, rows)
+}
+
+// WriteAnomaliesCSV writes one row per anomaly.
+func (report *Report) WriteAnomaliesCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	rows := [][]string{{"kind", "index", "commitment", "nullifier", "reason"}}
+	for i := range report.Anomalies {
+		anomaly := &report.Anomalies[i]
+
+		row := []string{string(anomaly.Kind), "", "", "", anomaly.Reason}
+		if anomaly.Kind == AnomalyUnknownNullifier {
+			row[3] = encodeHex(&anomaly.Nullifier)
+		} else {
+			row[1] = strconv.FormatUint(anomaly.Index, 10)
+			row[2] = encodeHex(&anomaly.Commitment)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return writeCSV(writer, rows)
+}
+
+func writeCSV(writer *csv.Writer, rows [][]string) error {
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+
+	return nil
+}
+
+func encodeHex(element *fr.Element) string {
+	elementBytes := element.Bytes()
+	return fmt.Sprintf("0x%x", elementBytes[:])
+}
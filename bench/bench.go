@@ -0,0 +1,374 @@
+// Package bench measures what the gadgets and circuit variants cost:
+// constraints on both backends, witness solving, setup and proving time, and
+// peak heap. Reports are JSON so they can be saved and compared across
+// changes.
+package bench
+
+import (
+	"fmt"
+	"hide-pay/prover"
+	"runtime"
+	"runtime/metrics"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Stage is how far a target is taken. Each stage includes the previous ones.
+type Stage string
+
+const (
+	// StageCompile compiles the target for both backends.
+	StageCompile Stage = "compile"
+	// StageSolve also solves the witness of the target.
+	StageSolve Stage = "solve"
+	// StageProve also runs the setup and proves the witness.
+	StageProve Stage = "prove"
+)
+
+func ParseStage(name string) (Stage, error) {
+	switch stage := Stage(name); stage {
+	case StageCompile, StageSolve, StageProve:
+		return stage, nil
+	default:
+		return "", fmt.Errorf("unknown stage %q, expected %s, %s or %s", name, StageCompile, StageSolve, StageProve)
+	}
+}
+
+func (stage Stage) includes(other Stage) bool {
+	order := map[Stage]int{StageCompile: 0, StageSolve: 1, StageProve: 2}
+
+	return order[stage] >= order[other]
+}
+
+// Options selects the backend solving, setup and proving run on, and how far
+// targets are taken. Constraints are always counted for both backends.
+type Options struct {
+	Backend prover.Backend
+	Stage   Stage
+}
+
+// Result is the cost of one target. Times are in milliseconds and stay zero
+// for the stages that did not run.
+type Result struct {
+	Name string `json:"name"`
+	Kind Kind   `json:"kind"`
+
+	R1CSConstraints int `json:"r1csConstraints"`
+	SCSConstraints  int `json:"scsConstraints"`
+
+	CompileMs float64 `json:"compileMs"`
+	SolveMs   float64 `json:"solveMs,omitempty"`
+	SetupMs   float64 `json:"setupMs,omitempty"`
+	ProveMs   float64 `json:"proveMs,omitempty"`
+
+	// PeakHeapBytes is the largest heap in use over all the stages.
+	PeakHeapBytes uint64 `json:"peakHeapBytes"`
+}
+
+// Report is the result of a run with the machine it ran on, since times are
+// only comparable on the same one.
+type Report struct {
+	Backend   prover.Backend `json:"backend"`
+	Stage     Stage          `json:"stage"`
+	GoVersion string         `json:"goVersion"`
+	Platform  string         `json:"platform"`
+	CPUs      int            `json:"cpus"`
+	Results   []Result       `json:"results"`
+	// Comparison is set when the run was compared to a baseline.
+	Comparison *Comparison `json:"comparison,omitempty"`
+}
+
+func NewReport(options Options) *Report {
+	return &Report{
+		Backend:   options.Backend,
+		Stage:     options.Stage,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+	}
+}
+
+// Run measures each target in turn.
+func Run(targets []Target, options Options) (*Report, error) {
+	report := NewReport(options)
+
+	for _, target := range targets {
+		result, err := Measure(target, options)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Results = append(report.Results, *result)
+	}
+
+	return report, nil
+}
+
+// Measure takes target through the stages of options.
+func Measure(target Target, options Options) (*Result, error) {
+	if _, err := prover.ParseBackend(string(options.Backend)); err != nil {
+		return nil, err
+	}
+
+	if _, err := ParseStage(string(options.Stage)); err != nil {
+		return nil, err
+	}
+
+	result := &Result{Name: target.Name, Kind: target.Kind}
+
+	runtime.GC()
+	peak := startPeakHeap()
+	defer func() {
+		result.PeakHeapBytes = peak.stop()
+	}()
+
+	if err := measure(target, options, result); err != nil {
+		return nil, fmt.Errorf("failed to measure %s: %w", target.Name, err)
+	}
+
+	return result, nil
+}
+
+func measure(target Target, options Options, result *Result) error {
+	// The other backend is compiled first so its system can be collected
+	// before the later stages run
+	other := prover.Plonk
+	if options.Backend == prover.Plonk {
+		other = prover.Groth16
+	}
+
+	for _, backend := range []prover.Backend{other, options.Backend} {
+		circuit, err := target.Circuit()
+		if err != nil {
+			return err
+		}
+
+		start := time.Now()
+		cs, err := backend.Compile(circuit)
+		if err != nil {
+			return err
+		}
+		elapsed := time.Since(start)
+
+		if backend == prover.Groth16 {
+			result.R1CSConstraints = cs.GetNbConstraints()
+		} else {
+			result.SCSConstraints = cs.GetNbConstraints()
+		}
+
+		if backend != options.Backend {
+			continue
+		}
+
+		result.CompileMs = milliseconds(elapsed)
+
+		if !options.Stage.includes(StageSolve) {
+			return nil
+		}
+
+		w, err := target.Witness()
+		if err != nil {
+			return err
+		}
+
+		start = time.Now()
+		if _, err := cs.Solve(w); err != nil {
+			return fmt.Errorf("failed to solve: %w", err)
+		}
+		result.SolveMs = milliseconds(time.Since(start))
+
+		if !options.Stage.includes(StageProve) {
+			return nil
+		}
+
+		var srs *prover.SRS
+		if backend == prover.Plonk {
+			// Generating the SRS is not part of the setup a prover runs
+			if srs, err = prover.NewUnsafeSRS(cs); err != nil {
+				return err
+			}
+		}
+
+		start = time.Now()
+		pk, vk, err := prover.Setup(cs, srs)
+		if err != nil {
+			return err
+		}
+		result.SetupMs = milliseconds(time.Since(start))
+
+		start = time.Now()
+		proof, err := prover.Prove(cs, pk, w)
+		if err != nil {
+			return err
+		}
+		result.ProveMs = milliseconds(time.Since(start))
+
+		public, err := w.Public()
+		if err != nil {
+			return fmt.Errorf("failed to get public witness: %w", err)
+		}
+
+		if err := prover.Verify(proof, vk, public); err != nil {
+			return fmt.Errorf("failed to verify: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// peakHeap samples the heap in use until it is stopped. Sampling misses
+// peaks shorter than its period, which are not the ones that matter here.
+type peakHeap struct {
+	done chan struct{}
+	wg   sync.WaitGroup
+	peak uint64
+}
+
+const (
+	heapMetric       = "/memory/classes/heap/objects:bytes"
+	heapSamplePeriod = 10 * time.Millisecond
+)
+
+func startPeakHeap() *peakHeap {
+	peak := &peakHeap{done: make(chan struct{})}
+	peak.sample()
+
+	peak.wg.Add(1)
+	go func() {
+		defer peak.wg.Done()
+
+		ticker := time.NewTicker(heapSamplePeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-peak.done:
+				return
+			case <-ticker.C:
+				peak.sample()
+			}
+		}
+	}()
+
+	return peak
+}
+
+func (peak *peakHeap) sample() {
+	samples := []metrics.Sample{{Name: heapMetric}}
+	metrics.Read(samples)
+
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		peak.peak = max(peak.peak, samples[0].Value.Uint64())
+	}
+}
+
+func (peak *peakHeap) stop() uint64 {
+	close(peak.done)
+	peak.wg.Wait()
+	peak.sample()
+
+	return peak.peak
+}
+
+// Change is a metric of a target that differs from the baseline.
+type Change struct {
+	Name     string  `json:"name"`
+	Metric   string  `json:"metric"`
+	Baseline float64 `json:"baseline"`
+	Current  float64 `json:"current"`
+	// Delta is relative to the baseline: 0.1 is 10% more.
+	Delta float64 `json:"delta"`
+}
+
+// Comparison lists what changed between a baseline and a current report.
+type Comparison struct {
+	Changes []Change `json:"changes"`
+	// Added and Removed are the targets only in the current report or only
+	// in the baseline.
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Compare returns the metrics of current that differ from baseline. Metrics
+// that one of the reports did not measure are left out.
+func Compare(baseline *Report, current *Report) (*Comparison, error) {
+	// Times and memory depend on the backend the later stages ran on
+	if baseline.Backend != current.Backend {
+		return nil, fmt.Errorf("baseline was measured with %s, not %s", baseline.Backend, current.Backend)
+	}
+
+	comparison := &Comparison{Changes: []Change{}}
+
+	previous := make(map[string]Result, len(baseline.Results))
+	for _, result := range baseline.Results {
+		previous[result.Name] = result
+	}
+
+	seen := make(map[string]bool, len(current.Results))
+	for _, result := range current.Results {
+		seen[result.Name] = true
+
+		old, ok := previous[result.Name]
+		if !ok {
+			comparison.Added = append(comparison.Added, result.Name)
+			continue
+		}
+
+		oldMetrics, newMetrics := old.metrics(), result.metrics()
+		for _, metric := range metricNames {
+			before, after := oldMetrics[metric], newMetrics[metric]
+			if before == 0 || after == 0 || before == after {
+				continue
+			}
+
+			comparison.Changes = append(comparison.Changes, Change{
+				Name:     result.Name,
+				Metric:   metric,
+				Baseline: before,
+				Current:  after,
+				Delta:    (after - before) / before,
+			})
+		}
+	}
+
+	for _, result := range baseline.Results {
+		if !seen[result.Name] {
+			comparison.Removed = append(comparison.Removed, result.Name)
+		}
+	}
+	sort.Strings(comparison.Removed)
+
+	return comparison, nil
+}
+
+// Regressions returns the changes that grew by more than threshold, e.g.
+// 0.05 for 5%.
+func (comparison *Comparison) Regressions(threshold float64) []Change {
+	var regressions []Change
+	for _, change := range comparison.Changes {
+		if change.Delta > threshold {
+			regressions = append(regressions, change)
+		}
+	}
+
+	return regressions
+}
+
+var metricNames = []string{"r1csConstraints", "scsConstraints", "compileMs", "solveMs", "setupMs", "proveMs", "peakHeapBytes"}
+
+func (result Result) metrics() map[string]float64 {
+	return map[string]float64{
+		"r1csConstraints": float64(result.R1CSConstraints),
+		"scsConstraints":  float64(result.SCSConstraints),
+		"compileMs":       result.CompileMs,
+		"solveMs":         result.SolveMs,
+		"setupMs":         result.SetupMs,
+		"proveMs":         result.ProveMs,
+		"peakHeapBytes":   float64(result.PeakHeapBytes),
+	}
+}
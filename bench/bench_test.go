@@ -0,0 +1,178 @@
+package bench_test
+
+import (
+	"hide-pay/bench"
+	"hide-pay/prover"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargets(t *testing.T) {
+	names := map[string]bool{}
+	for _, target := range bench.Targets() {
+		assert.False(t, names[target.Name], "duplicate target %s", target.Name)
+		names[target.Name] = true
+	}
+
+	for _, variant := range prover.Variants() {
+		assert.True(t, names[variant.Name], variant.Name)
+	}
+}
+
+func TestMeasure_Gadgets(t *testing.T) {
+	for _, target := range bench.Targets() {
+		if target.Kind != bench.KindGadget {
+			continue
+		}
+
+		// Solving checks each gadget against its native counterpart
+		result, err := bench.Measure(target, bench.Options{Backend: prover.Groth16, Stage: bench.StageSolve})
+		require.NoError(t, err, target.Name)
+
+		assert.Positive(t, result.R1CSConstraints, target.Name)
+		assert.Positive(t, result.SCSConstraints, target.Name)
+		assert.Positive(t, result.SolveMs, target.Name)
+		assert.Zero(t, result.ProveMs, target.Name)
+		assert.Positive(t, result.PeakHeapBytes, target.Name)
+	}
+}
+
+func TestMeasure_Variants(t *testing.T) {
+	// Samples are valid at any depth; the registered depth is too slow here
+	for _, variant := range []prover.Variant{
+		{Name: "transfer", Kind: prover.KindTransfer, Shape: prover.Shape{Assets: 2, Depth: 4, Inputs: 2, Outputs: 3}},
+		{Name: "deposit", Kind: prover.KindDeposit},
+		{Name: "withdraw", Kind: prover.KindWithdraw, Shape: prover.Shape{Depth: 4}},
+		{Name: "freeze", Kind: prover.KindFreeze, Shape: prover.Shape{Depth: 4}},
+		{Name: "merkle-update", Kind: prover.KindMerkleUpdate, Shape: prover.Shape{Depth: 4}},
+	} {
+		result, err := bench.Measure(bench.VariantTarget(variant), bench.Options{Backend: prover.Groth16, Stage: bench.StageSolve})
+		require.NoError(t, err, variant.Name)
+		assert.Equal(t, bench.KindVariant, result.Kind)
+	}
+}
+
+func TestMeasure_Prove(t *testing.T) {
+	target := commitmentTarget(t)
+
+	for _, backend := range []prover.Backend{prover.Groth16, prover.Plonk} {
+		report, err := bench.Run([]bench.Target{target}, bench.Options{Backend: backend, Stage: bench.StageProve})
+		require.NoError(t, err)
+		require.Len(t, report.Results, 1)
+
+		result := report.Results[0]
+		assert.Positive(t, result.SetupMs, backend)
+		assert.Positive(t, result.ProveMs, backend)
+	}
+
+	_, err := bench.Measure(target, bench.Options{Backend: prover.Groth16, Stage: "verify"})
+	assert.ErrorContains(t, err, "unknown stage")
+}
+
+func commitmentTarget(t testing.TB) bench.Target {
+	for _, target := range bench.Targets() {
+		if target.Name == "commitment" {
+			return target
+		}
+	}
+
+	t.Fatal("no commitment target")
+	return bench.Target{}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := &bench.Report{Backend: prover.Groth16, Results: []bench.Result{
+		{Name: "memo", R1CSConstraints: 1000, SCSConstraints: 3000, CompileMs: 10},
+		{Name: "ecdh", R1CSConstraints: 500},
+	}}
+	current := &bench.Report{Backend: prover.Groth16, Results: []bench.Result{
+		{Name: "memo", R1CSConstraints: 1100, SCSConstraints: 3000, CompileMs: 9, ProveMs: 50},
+		{Name: "schnorr", R1CSConstraints: 800},
+	}}
+
+	comparison, err := bench.Compare(baseline, current)
+	require.NoError(t, err)
+
+	// Unchanged metrics and metrics the baseline lacks are left out
+	assert.Equal(t, []bench.Change{
+		{Name: "memo", Metric: "r1csConstraints", Baseline: 1000, Current: 1100, Delta: 0.1},
+		{Name: "memo", Metric: "compileMs", Baseline: 10, Current: 9, Delta: -0.1},
+	}, comparison.Changes)
+	assert.Equal(t, []string{"schnorr"}, comparison.Added)
+	assert.Equal(t, []string{"ecdh"}, comparison.Removed)
+
+	assert.Len(t, comparison.Regressions(0.05), 1)
+	assert.Empty(t, comparison.Regressions(0.2))
+
+	_, err = bench.Compare(&bench.Report{Backend: prover.Plonk}, current)
+	assert.Error(t, err)
+}
+
+// The benchmarks cover every target, registered variants at full size
+// included; select targets with -bench, e.g. -bench 'Prove/memo$'.
+
+func BenchmarkCompile(b *testing.B) {
+	for _, target := range bench.Targets() {
+		for _, backend := range []prover.Backend{prover.Groth16, prover.Plonk} {
+			b.Run(target.Name+"/"+string(backend), func(b *testing.B) {
+				var constraints int
+				for range b.N {
+					circuit, err := target.Circuit()
+					require.NoError(b, err)
+
+					cs, err := backend.Compile(circuit)
+					require.NoError(b, err)
+					constraints = cs.GetNbConstraints()
+				}
+				b.ReportMetric(float64(constraints), "constraints")
+			})
+		}
+	}
+}
+
+func BenchmarkSolve(b *testing.B) {
+	for _, target := range bench.Targets() {
+		b.Run(target.Name, func(b *testing.B) {
+			circuit, err := target.Circuit()
+			require.NoError(b, err)
+
+			cs, err := prover.Groth16.Compile(circuit)
+			require.NoError(b, err)
+
+			w, err := target.Witness()
+			require.NoError(b, err)
+
+			b.ResetTimer()
+			for range b.N {
+				_, err := cs.Solve(w)
+				require.NoError(b, err)
+			}
+		})
+	}
+}
+
+func BenchmarkProve(b *testing.B) {
+	for _, target := range bench.Targets() {
+		b.Run(target.Name, func(b *testing.B) {
+			circuit, err := target.Circuit()
+			require.NoError(b, err)
+
+			cs, err := prover.Groth16.Compile(circuit)
+			require.NoError(b, err)
+
+			pk, _, err := prover.Setup(cs, nil)
+			require.NoError(b, err)
+
+			w, err := target.Witness()
+			require.NoError(b, err)
+
+			b.ResetTimer()
+			for range b.N {
+				_, err := prover.Prove(cs, pk, w)
+				require.NoError(b, err)
+			}
+		})
+	}
+}
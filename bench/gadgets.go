@@ -0,0 +1,396 @@
+package bench
+
+import (
+	"fmt"
+	"hide-pay/builder"
+	"hide-pay/circuits"
+	"hide-pay/utils"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"github.com/consensys/gnark/frontend"
+)
+
+// The ciphers are measured at the sizes a memo encrypts: the two coordinates
+// of the ephemeral public key as associated data and an eleven-field note.
+const (
+	cipherAdSize        = 2
+	cipherPlaintextSize = 11
+)
+
+// multiMemoReaders is the number of readers of the measured multi-reader
+// memo: the owner, the auditor and one more.
+const multiMemoReaders = 3
+
+// Each gadget is measured inside a circuit that checks its output against a
+// value computed by the builder, so the solve stage also catches a gadget
+// drifting from its native counterpart.
+
+type streamCipherCircuit struct {
+	Key        [2]frontend.Variable
+	Ad         []frontend.Variable
+	Plaintext  []frontend.Variable
+	Ciphertext []frontend.Variable `gnark:",public"`
+}
+
+func newStreamCipherCircuit() *streamCipherCircuit {
+	return &streamCipherCircuit{
+		Ad:         make([]frontend.Variable, cipherAdSize),
+		Plaintext:  make([]frontend.Variable, cipherPlaintextSize),
+		Ciphertext: make([]frontend.Variable, cipherPlaintextSize+1),
+	}
+}
+
+func (circuit *streamCipherCircuit) Define(api frontend.API) error {
+	gadget := circuits.StreamCipherGadget{Key: circuit.Key}
+
+	ciphertext, err := gadget.Encrypt(api, circuit.Ad, circuit.Plaintext)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt: %w", err)
+	}
+
+	for i := range circuit.Ciphertext {
+		api.AssertIsEqual(circuit.Ciphertext[i], ciphertext[i])
+	}
+
+	return nil
+}
+
+type duplexCipherCircuit struct {
+	Width      int `gnark:"-"`
+	Key        [2]frontend.Variable
+	Ad         []frontend.Variable
+	Plaintext  []frontend.Variable
+	Ciphertext []frontend.Variable `gnark:",public"`
+}
+
+func newDuplexCipherCircuit(width int) *duplexCipherCircuit {
+	return &duplexCipherCircuit{
+		Width:      width,
+		Ad:         make([]frontend.Variable, cipherAdSize),
+		Plaintext:  make([]frontend.Variable, cipherPlaintextSize),
+		Ciphertext: make([]frontend.Variable, cipherPlaintextSize+1),
+	}
+}
+
+func (circuit *duplexCipherCircuit) Define(api frontend.API) error {
+	gadget := circuits.DuplexCipherGadget{Key: circuit.Key, Width: circuit.Width}
+
+	ciphertext, err := gadget.Encrypt(api, circuit.Ad, circuit.Plaintext)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt: %w", err)
+	}
+
+	for i := range circuit.Ciphertext {
+		api.AssertIsEqual(circuit.Ciphertext[i], ciphertext[i])
+	}
+
+	return nil
+}
+
+type commitmentCircuit struct {
+	Note       circuits.CommitmentGadget
+	Commitment frontend.Variable `gnark:",public"`
+}
+
+func (circuit *commitmentCircuit) Define(api frontend.API) error {
+	commitment, err := circuit.Note.Compute(api)
+	if err != nil {
+		return fmt.Errorf("failed to compute commitment: %w", err)
+	}
+
+	api.AssertIsEqual(circuit.Commitment, commitment)
+
+	return nil
+}
+
+type nullifierCircuit struct {
+	Note      circuits.NullifierGadget
+	Nullifier frontend.Variable `gnark:",public"`
+}
+
+func (circuit *nullifierCircuit) Define(api frontend.API) error {
+	nullifier, err := circuit.Note.Compute(api)
+	if err != nil {
+		return fmt.Errorf("failed to compute nullifier: %w", err)
+	}
+
+	api.AssertIsEqual(circuit.Nullifier, nullifier)
+
+	return nil
+}
+
+type ecdhCircuit struct {
+	ECDH      circuits.ECDHGadget
+	SharedKey [2]frontend.Variable `gnark:",public"`
+}
+
+func (circuit *ecdhCircuit) Define(api frontend.API) error {
+	sharedKey, err := circuit.ECDH.Compute(api)
+	if err != nil {
+		return fmt.Errorf("failed to compute shared key: %w", err)
+	}
+
+	api.AssertIsEqual(circuit.SharedKey[0], sharedKey[0])
+	api.AssertIsEqual(circuit.SharedKey[1], sharedKey[1])
+
+	return nil
+}
+
+type memoCircuit struct {
+	Memo     circuits.MemoGadget
+	Note     circuits.CommitmentGadget
+	SpentKey frontend.Variable
+	MemoHash frontend.Variable `gnark:",public"`
+}
+
+func (circuit *memoCircuit) Define(api frontend.API) error {
+	memo, err := circuit.Memo.Generate(api, circuit.Note, circuit.SpentKey)
+	if err != nil {
+		return fmt.Errorf("failed to generate memo: %w", err)
+	}
+
+	api.AssertIsEqual(circuit.MemoHash, memo.Hash)
+
+	return nil
+}
+
+type multiMemoCircuit struct {
+	Memo     circuits.MultiMemoGadget
+	Note     circuits.CommitmentGadget
+	SpentKey frontend.Variable
+	MemoHash frontend.Variable `gnark:",public"`
+}
+
+func (circuit *multiMemoCircuit) Define(api frontend.API) error {
+	memo, err := circuit.Memo.Generate(api, circuit.Note, circuit.SpentKey)
+	if err != nil {
+		return fmt.Errorf("failed to generate memo: %w", err)
+	}
+
+	api.AssertIsEqual(circuit.MemoHash, memo.Hash)
+
+	return nil
+}
+
+type merkleProofCircuit struct {
+	Proof circuits.MerkleProofGadget
+	Root  frontend.Variable `gnark:",public"`
+}
+
+func (circuit *merkleProofCircuit) Define(api frontend.API) error {
+	hasher, err := utils.NewPoseidonHasher(api)
+	if err != nil {
+		return fmt.Errorf("failed to create poseidon hasher: %w", err)
+	}
+
+	api.AssertIsEqual(circuit.Root, circuit.Proof.VerifyProof(api, hasher))
+
+	return nil
+}
+
+type schnorrCircuit struct {
+	Signature circuits.SchnorrGadget
+}
+
+func (circuit *schnorrCircuit) Define(api frontend.API) error {
+	return circuit.Signature.VerifySignature(api)
+}
+
+func gadgetTargets() []Target {
+	targets := []Target{
+		{
+			Name:    "commitment",
+			Kind:    KindGadget,
+			Circuit: func() (frontend.Circuit, error) { return &commitmentCircuit{}, nil },
+			Assignment: func() (frontend.Circuit, error) {
+				commitment, _ := builder.GenerateCommitment(1)
+				return &commitmentCircuit{Note: *commitment.ToGadget(), Commitment: commitment.Compute()}, nil
+			},
+		},
+		{
+			Name:    "nullifier",
+			Kind:    KindGadget,
+			Circuit: func() (frontend.Circuit, error) { return &nullifierCircuit{}, nil },
+			Assignment: func() (frontend.Circuit, error) {
+				commitment, spentKey := builder.GenerateCommitment(1)
+				nullifier := builder.Nullifier{Commitment: *commitment, SpentPrivateKey: *spentKey}
+				return &nullifierCircuit{Note: *nullifier.ToGadget(), Nullifier: nullifier.Compute()}, nil
+			},
+		},
+		{
+			Name:    "ecdh",
+			Kind:    KindGadget,
+			Circuit: func() (frontend.Circuit, error) { return &ecdhCircuit{}, nil },
+			Assignment: func() (frontend.Circuit, error) {
+				ecdh := builder.NewECDH(*big.NewInt(11111), *big.NewInt(22222))
+				sharedKey := ecdh.Compute()
+				return &ecdhCircuit{ECDH: *ecdh.ToGadget(), SharedKey: [2]frontend.Variable{sharedKey.X, sharedKey.Y}}, nil
+			},
+		},
+		{
+			Name:    "stream-cipher",
+			Kind:    KindGadget,
+			Circuit: func() (frontend.Circuit, error) { return newStreamCipherCircuit(), nil },
+			Assignment: func() (frontend.Circuit, error) {
+				cipher := builder.StreamCipher{Key: [2]fr.Element{fr.NewElement(12345), fr.NewElement(67890)}}
+				ad, plaintext := cipherInput()
+
+				ciphertext, err := cipher.Encrypt(ad, plaintext)
+				if err != nil {
+					return nil, fmt.Errorf("failed to encrypt: %w", err)
+				}
+
+				return &streamCipherCircuit{
+					Key:        cipher.ToGadget().Key,
+					Ad:         toVariables(ad),
+					Plaintext:  toVariables(plaintext),
+					Ciphertext: toVariables(ciphertext),
+				}, nil
+			},
+		},
+	}
+
+	for _, width := range []int{2, 3} {
+		targets = append(targets, Target{
+			Name:    fmt.Sprintf("duplex-cipher-%d", width),
+			Kind:    KindGadget,
+			Circuit: func() (frontend.Circuit, error) { return newDuplexCipherCircuit(width), nil },
+			Assignment: func() (frontend.Circuit, error) {
+				cipher := builder.DuplexCipher{Key: [2]fr.Element{fr.NewElement(12345), fr.NewElement(67890)}, Width: width}
+				ad, plaintext := cipherInput()
+
+				ciphertext, err := cipher.Encrypt(ad, plaintext)
+				if err != nil {
+					return nil, fmt.Errorf("failed to encrypt: %w", err)
+				}
+
+				return &duplexCipherCircuit{
+					Width:      width,
+					Key:        cipher.ToGadget().Key,
+					Ad:         toVariables(ad),
+					Plaintext:  toVariables(plaintext),
+					Ciphertext: toVariables(ciphertext),
+				}, nil
+			},
+		})
+	}
+
+	return append(targets,
+		Target{
+			Name:    "memo",
+			Kind:    KindGadget,
+			Circuit: func() (frontend.Circuit, error) { return &memoCircuit{}, nil },
+			Assignment: func() (frontend.Circuit, error) {
+				memo, err := builder.NewMemo(*big.NewInt(11111), utils.BuildPublicKey(*big.NewInt(22222)))
+				if err != nil {
+					return nil, err
+				}
+
+				commitment, spentKey := builder.GenerateCommitment(1)
+				envelope, err := memo.Encrypt(*commitment, *spentKey)
+				if err != nil {
+					return nil, fmt.Errorf("failed to encrypt memo: %w", err)
+				}
+
+				return &memoCircuit{
+					Memo:     *memo.ToGadget(),
+					Note:     *commitment.ToGadget(),
+					SpentKey: *spentKey,
+					MemoHash: envelope.Hash(),
+				}, nil
+			},
+		},
+		Target{
+			Name: fmt.Sprintf("multi-memo-%d", multiMemoReaders),
+			Kind: KindGadget,
+			Circuit: func() (frontend.Circuit, error) {
+				return &multiMemoCircuit{Memo: *circuits.NewMultiMemoGadget(multiMemoReaders)}, nil
+			},
+			Assignment: func() (frontend.Circuit, error) {
+				publicKeys := make([]twistededwardbn254.PointAffine, multiMemoReaders)
+				for i := range publicKeys {
+					publicKeys[i] = utils.BuildPublicKey(*big.NewInt(int64(11111 * (i + 1))))
+				}
+
+				memo, err := builder.NewMultiMemo(*big.NewInt(44444), publicKeys)
+				if err != nil {
+					return nil, err
+				}
+
+				commitment, spentKey := builder.GenerateCommitment(1)
+				envelope, err := memo.Encrypt(*commitment, *spentKey)
+				if err != nil {
+					return nil, fmt.Errorf("failed to encrypt memo: %w", err)
+				}
+
+				return &multiMemoCircuit{
+					Memo:     *memo.ToGadget(),
+					Note:     *commitment.ToGadget(),
+					SpentKey: *spentKey,
+					MemoHash: envelope.Hash(),
+				}, nil
+			},
+		},
+		Target{
+			Name: fmt.Sprintf("merkle-proof-%d", defaultDepth),
+			Kind: KindGadget,
+			Circuit: func() (frontend.Circuit, error) {
+				return &merkleProofCircuit{Proof: circuits.NewMerkleProofGadget(defaultDepth)}, nil
+			},
+			Assignment: func() (frontend.Circuit, error) {
+				tree := builder.NewMerkleTree(defaultDepth, poseidon2.NewMerkleDamgardHasher())
+				tree.Build([]fr.Element{fr.NewElement(1), fr.NewElement(2), fr.NewElement(3)})
+
+				proof := tree.GetProof(1)
+				return &merkleProofCircuit{Proof: *proof.ToGadget(), Root: proof.Verify()}, nil
+			},
+		},
+		Target{
+			Name:    "schnorr",
+			Kind:    KindGadget,
+			Circuit: func() (frontend.Circuit, error) { return &schnorrCircuit{}, nil },
+			Assignment: func() (frontend.Circuit, error) {
+				keypair, err := builder.GenerateKeypairWithSeed(fr.NewElement(12345))
+				if err != nil {
+					return nil, err
+				}
+
+				message := fr.NewElement(67890)
+				signature := keypair.Sign(fr.NewElement(13579), message)
+
+				return &schnorrCircuit{Signature: *circuits.NewSchnorrGadget(
+					message,
+					signature.S,
+					[2]frontend.Variable{signature.R.X, signature.R.Y},
+					[2]frontend.Variable{keypair.PublicKey.X, keypair.PublicKey.Y},
+				)}, nil
+			},
+		},
+	)
+}
+
+func cipherInput() ([]fr.Element, []fr.Element) {
+	ad := make([]fr.Element, cipherAdSize)
+	for i := range ad {
+		ad[i] = fr.NewElement(uint64(10 * (i + 1)))
+	}
+
+	plaintext := make([]fr.Element, cipherPlaintextSize)
+	for i := range plaintext {
+		plaintext[i] = fr.NewElement(uint64(100 * (i + 1)))
+	}
+
+	return ad, plaintext
+}
+
+func toVariables(elements []fr.Element) []frontend.Variable {
+	variables := make([]frontend.Variable, len(elements))
+	for i := range elements {
+		variables[i] = elements[i]
+	}
+
+	return variables
+}
@@ -0,0 +1,160 @@
+package bench
+
+import (
+	"fmt"
+	"hide-pay/builder"
+	"hide-pay/prover"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+)
+
+// defaultDepth is the depth gadgets working on the commitment tree are
+// measured at.
+const defaultDepth = prover.DefaultDepth
+
+// Kind tells gadgets, measured alone, from the circuit variants that are
+// proved on chain.
+type Kind string
+
+const (
+	KindGadget  Kind = "gadget"
+	KindVariant Kind = "variant"
+)
+
+// Target is a circuit to measure with a valid assignment of it.
+type Target struct {
+	Name string
+	Kind Kind
+	// Circuit returns the empty circuit to compile. Compiling overwrites its
+	// fields, so each call returns a fresh one.
+	Circuit func() (frontend.Circuit, error)
+	// Assignment returns a satisfying assignment of the circuit.
+	Assignment func() (frontend.Circuit, error)
+}
+
+// Witness returns the full witness of the assignment of the target.
+func (target Target) Witness() (witness.Witness, error) {
+	assignment, err := target.Assignment()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build assignment of %s: %w", target.Name, err)
+	}
+
+	w, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("failed to build witness of %s: %w", target.Name, err)
+	}
+
+	return w, nil
+}
+
+// Targets returns every gadget, then every registered circuit variant.
+func Targets() []Target {
+	targets := gadgetTargets()
+
+	for _, variant := range prover.Variants() {
+		targets = append(targets, VariantTarget(variant))
+	}
+
+	return targets
+}
+
+// VariantTarget measures a circuit variant on a generated sample.
+func VariantTarget(variant prover.Variant) Target {
+	return Target{
+		Name:    variant.Name,
+		Kind:    KindVariant,
+		Circuit: variant.Circuit,
+		Assignment: func() (frontend.Circuit, error) {
+			return sampleAssignment(variant)
+		},
+	}
+}
+
+// sampleAssignment builds a valid assignment of any shape of a variant. Notes
+// are spent from a tree holding them next to another note, so Merkle paths
+// are not all empty.
+func sampleAssignment(variant prover.Variant) (frontend.Circuit, error) {
+	depth := variant.Shape.Depth
+
+	switch variant.Kind {
+	case prover.KindTransfer:
+		utxo, err := builder.GenerateUTXO(1, depth, variant.Shape.Inputs, variant.Shape.Outputs)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := utxo.BuildAndCheck()
+		if err != nil {
+			return nil, fmt.Errorf("invalid transfer: %w", err)
+		}
+
+		// The sample moves a single asset, the other slots stay empty
+		if err := result.PadAllAsset(variant.Shape.Assets); err != nil {
+			return nil, err
+		}
+
+		return builder.NewUTXOCircuitWitness(utxo, result)
+	case prover.KindDeposit:
+		commitment, spentKey := builder.GenerateCommitment(1)
+		deposit := builder.Deposit{
+			Commitment:                 *commitment,
+			SpentKey:                   *spentKey,
+			EphemeralReceiverSecretKey: *big.NewInt(333),
+			EphemeralAuditSecretKey:    *big.NewInt(444),
+			AuditPublicKey:             commitment.AuditPubKey,
+		}
+
+		return deposit.ToWitness()
+	case prover.KindWithdraw:
+		nullifier, merkleProof := spendableNote(depth)
+		withdraw := builder.Withdraw{Nullifier: nullifier, MerkleProof: merkleProof, Recipient: fr.NewElement(0xbeef)}
+
+		return withdraw.ToWitness()
+	case prover.KindFreeze:
+		freezeKey, assetRandom := fr.NewElement(4242), fr.NewElement(77)
+		commitment, spentKey := builder.GenerateCommitment(1)
+		commitment.Asset = builder.AssetID(commitment.AuditPubKey, builder.FreezeAddress(freezeKey), assetRandom)
+
+		tree := builder.NewMerkleTree(depth, poseidon2.NewMerkleDamgardHasher())
+		tree.Build([]fr.Element{commitment.Compute()})
+
+		freeze := builder.Freeze{
+			Nullifier:                  builder.Nullifier{Commitment: *commitment, SpentPrivateKey: *spentKey},
+			MerkleProof:                tree.GetProof(0),
+			FreezeKey:                  freezeKey,
+			AssetAuditPublicKey:        commitment.AuditPubKey,
+			AssetRandom:                assetRandom,
+			Frozen:                     true,
+			Blinding:                   fr.NewElement(999),
+			EphemeralReceiverSecretKey: *big.NewInt(555),
+			EphemeralAuditSecretKey:    *big.NewInt(666),
+		}
+
+		return freeze.ToWitness()
+	case prover.KindMerkleUpdate:
+		leaves := []fr.Element{fr.NewElement(1), fr.NewElement(2)}
+		tree := builder.NewMerkleTree(depth, poseidon2.NewMerkleDamgardHasher())
+		tree.Build(leaves)
+
+		update := builder.MerkleUpdate{MerkleProof: tree.GetProof(len(leaves)), Leaf: fr.NewElement(3)}
+
+		return update.ToWitness()
+	default:
+		return nil, fmt.Errorf("no sample for circuit kind %q", variant.Kind)
+	}
+}
+
+func spendableNote(depth int) (builder.Nullifier, builder.MerkleProof) {
+	commitment, spentKey := builder.GenerateCommitment(1)
+	other, _ := builder.GenerateCommitment(2)
+
+	tree := builder.NewMerkleTree(depth, poseidon2.NewMerkleDamgardHasher())
+	tree.Build([]fr.Element{other.Compute(), commitment.Compute()})
+
+	return builder.Nullifier{Commitment: *commitment, SpentPrivateKey: *spentKey}, tree.GetProof(1)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"hide-pay/auditor"
+	"hide-pay/wallet"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+)
+
+// auditKeyEnv names the environment variable holding the audit secret key
+// when -key-file is not given. The key is never taken on the command line,
+// where it would show in the process list and the shell history.
+const auditKeyEnv = "HIDEPAY_AUDIT_KEY"
+
+func main() {
+	keyFile := flag.String("key-file", "", "file holding the audit secret key, decimal or 0x-prefixed hex; defaults to $"+auditKeyEnv)
+	events := flag.String("events", "-", "JSON-lines event file, - for stdin")
+	format := flag.String("format", "json", "report format: json or csv")
+	table := flag.String("table", "notes", "csv table: notes, assets or anomalies")
+	out := flag.String("out", "-", "output file, - for stdout")
+	flag.Parse()
+
+	auditSecretKey, err := readAuditKey(*keyFile, os.Getenv(auditKeyEnv))
+	if err == nil {
+		err = run(auditSecretKey, *events, *format, *table, *out)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "auditor:", err)
+		os.Exit(1)
+	}
+}
+
+// readAuditKey parses the key in keyFile, or env when keyFile is empty.
+// Errors do not quote the key.
+func readAuditKey(keyFile string, env string) (*big.Int, error) {
+	key := env
+	source := "$" + auditKeyEnv
+
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit key: %w", err)
+		}
+		key = string(data)
+		source = keyFile
+	}
+
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, fmt.Errorf("missing audit key, set -key-file or $%s", auditKeyEnv)
+	}
+
+	// Only 0x-prefixed hex or plain decimal, SetString with base 0 would also
+	// take octal, binary and underscores
+	base := 10
+	if strings.HasPrefix(key, "0x") {
+		key, base = key[2:], 16
+	}
+
+	auditSecretKey, ok := new(big.Int).SetString(key, base)
+	if !ok || strings.HasPrefix(key, "+") || strings.HasPrefix(key, "-") {
+		return nil, fmt.Errorf("invalid audit key in %s", source)
+	}
+
+	order := twistededwardbn254.GetEdwardsCurve().Order
+	if auditSecretKey.Sign() == 0 || auditSecretKey.Cmp(&order) >= 0 {
+		return nil, fmt.Errorf("audit key in %s must be positive and below the curve order", source)
+	}
+
+	return auditSecretKey, nil
+}
+
+// reportWriter picks how the report is written, so that a bad format or table
+// is refused before out is created or truncated.
+func reportWriter(format string, table string) (func(*auditor.Report, io.Writer) error, error) {
+	switch format {
+	case "json":
+		return (*auditor.Report).WriteJSON, nil
+	case "csv":
+		switch table {
+		case "notes":
+			return (*auditor.Report).WriteNotesCSV, nil
+		case "assets":
+			return (*auditor.Report).WriteAssetsCSV, nil
+		case "anomalies":
+			return (*auditor.Report).WriteAnomaliesCSV, nil
+		default:
+			return nil, fmt.Errorf("unknown csv table %q", table)
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+func run(auditSecretKey *big.Int, events string, format string, table string, out string) error {
+	write, err := reportWriter(format, table)
+	if err != nil {
+		return err
+	}
+
+	var input io.Reader = os.Stdin
+	if events != "-" {
+		file, err := os.Open(events)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	audit := auditor.NewAuditor(*auditSecretKey)
+	if err := audit.Audit(wallet.NewFileSource(input)); err != nil {
+		return err
+	}
+
+	report := audit.Report()
+
+	var output io.Writer = os.Stdout
+	if out != "-" {
+		file, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		output = file
+	}
+
+	return write(report, output)
+}
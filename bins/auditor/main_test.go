@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadAuditKey(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "audit.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("0x3039\n"), 0o600))
+
+	// The file wins over the environment
+	key, err := readAuditKey(keyFile, "22222")
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(12345), key)
+
+	key, err = readAuditKey("", "22222")
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(22222), key)
+
+	_, err = readAuditKey("", "")
+	assert.ErrorContains(t, err, "missing audit key")
+
+	_, err = readAuditKey(filepath.Join(t.TempDir(), "missing.key"), "22222")
+	assert.Error(t, err)
+
+	// Only 0x hex and decimal are keys, no octal, binary, signs or separators
+	for _, malformed := range []string{"0o17", "0b101", "017x", "1_000", "0x1_0", "+5", "-5", "0X10"} {
+		_, err = readAuditKey("", malformed)
+		assert.ErrorContains(t, err, "invalid audit key", malformed)
+	}
+
+	key, err = readAuditKey("", "017")
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(17), key)
+
+	// The key is a scalar of the curve
+	order := twistededwardbn254.GetEdwardsCurve().Order
+	for _, outOfRange := range []string{"0", "0x0", order.String(), "0x" + order.Text(16)} {
+		_, err = readAuditKey("", outOfRange)
+		assert.ErrorContains(t, err, "curve order", outOfRange)
+	}
+
+	key, err = readAuditKey("", new(big.Int).Sub(&order, big.NewInt(1)).String())
+	require.NoError(t, err)
+	assert.Equal(t, new(big.Int).Sub(&order, big.NewInt(1)), key)
+
+	// A malformed key is not echoed back
+	_, err = readAuditKey("", "secret-ish")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret-ish")
+}
+
+func TestRun_RefusesBadFormatBeforeOutput(t *testing.T) {
+	dir := t.TempDir()
+	events := filepath.Join(dir, "events.jsonl")
+	require.NoError(t, os.WriteFile(events, nil, 0o600))
+
+	out := filepath.Join(dir, "report.csv")
+	require.NoError(t, os.WriteFile(out, []byte("previous report"), 0o600))
+
+	// A bad choice leaves an existing report untouched
+	assert.ErrorContains(t, run(big.NewInt(1), events, "xml", "notes", out), "unknown format")
+	assert.ErrorContains(t, run(big.NewInt(1), events, "csv", "flows", out), "unknown csv table")
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "previous report", string(data))
+
+	// and creates no new one
+	missing := filepath.Join(dir, "missing.csv")
+	assert.Error(t, run(big.NewInt(1), events, "xml", "notes", missing))
+	assert.NoFileExists(t, missing)
+
+	require.NoError(t, run(big.NewInt(1), events, "csv", "assets", out))
+	data, err = os.ReadFile(out)
+	require.NoError(t, err)
+	assert.NotEqual(t, "previous report", string(data))
+}
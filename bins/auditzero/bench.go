@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"hide-pay/bench"
+	"io"
+	"regexp"
+)
+
+func runBench(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	backendName := backendFlag(fs)
+	stageName := fs.String("stage", string(bench.StageSolve), "last stage to run: compile, solve or prove (setup and prove)")
+	pattern := fs.String("run", "", "only measure the targets whose name matches this regular expression")
+	baselinePath := fs.String("baseline", "", "report of an earlier run to compare with")
+	maxRegression := fs.Float64("max-regression", 0, "fail when a metric grows by more than this percentage over -baseline, 0 to never fail")
+	out := fs.String("out", "-", "report file, - for stdout")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	backend, err := parseBackend(*backendName)
+	if err != nil {
+		return err
+	}
+
+	stage, err := bench.ParseStage(*stageName)
+	if err != nil {
+		return usageError{err}
+	}
+
+	filter, err := regexp.Compile(*pattern)
+	if err != nil {
+		return usageError{fmt.Errorf("invalid -run: %w", err)}
+	}
+
+	if *maxRegression != 0 && *baselinePath == "" {
+		return usageError{fmt.Errorf("-max-regression needs -baseline")}
+	}
+
+	// The baseline is read first so a bad path fails before a long run
+	var baseline *bench.Report
+	if *baselinePath != "" {
+		baseline = new(bench.Report)
+		if err := readJSON(*baselinePath, baseline); err != nil {
+			return err
+		}
+	}
+
+	options := bench.Options{Backend: backend, Stage: stage}
+	report := bench.NewReport(options)
+
+	for _, target := range bench.Targets() {
+		if !filter.MatchString(target.Name) {
+			continue
+		}
+
+		result, err := bench.Measure(target, options)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(stderr, "%s: %d r1cs, %d scs constraints\n", result.Name, result.R1CSConstraints, result.SCSConstraints)
+		report.Results = append(report.Results, *result)
+	}
+
+	if len(report.Results) == 0 {
+		return usageError{fmt.Errorf("no target matches -run %q", *pattern)}
+	}
+
+	if baseline != nil {
+		if report.Comparison, err = bench.Compare(baseline, report); err != nil {
+			return err
+		}
+	}
+
+	if err := writeOutput(*out, stdout, func(w io.Writer) error {
+		return writeJSON(w, report)
+	}); err != nil {
+		return err
+	}
+
+	if *maxRegression == 0 {
+		return nil
+	}
+
+	regressions := report.Comparison.Regressions(*maxRegression / 100)
+	if len(regressions) == 0 {
+		return nil
+	}
+
+	for _, change := range regressions {
+		fmt.Fprintf(stderr, "%s %s: %g -> %g (%+.1f%%)\n", change.Name, change.Metric, change.Baseline, change.Current, 100*change.Delta)
+	}
+
+	return fmt.Errorf("%d metrics regressed by more than %g%%", len(regressions), *maxRegression)
+}
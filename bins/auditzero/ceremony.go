@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"hide-pay/prover"
+	"io"
+	"strings"
+
+	"github.com/consensys/gnark/backend/groth16/bn254/mpcsetup"
+)
+
+// ceremonyFlags select the constraint system and phase-1 output a phase-2
+// ceremony runs on.
+type ceremonyFlags struct {
+	cs     *string
+	phase1 *string
+}
+
+func newCeremonyFlags(fs *flag.FlagSet) ceremonyFlags {
+	return ceremonyFlags{
+		cs:     fs.String("cs", "cs.dat", "Groth16 constraint system file"),
+		phase1: fs.String("phase1", "", "sealed phase-1 output, at least the domain size of the constraint system (required)"),
+	}
+}
+
+// load recomputes the initial parameters of the ceremony, which takes a
+// while for a large circuit.
+func (flags ceremonyFlags) load() (*prover.Ceremony, error) {
+	if err := requireFlag("phase1", *flags.phase1); err != nil {
+		return nil, err
+	}
+
+	cs, err := prover.ReadConstraintSystem(prover.Groth16, *flags.cs)
+	if err != nil {
+		return nil, err
+	}
+
+	commons, err := prover.ReadPhase1(*flags.phase1, cs)
+	if err != nil {
+		return nil, err
+	}
+
+	return prover.NewCeremony(cs, commons)
+}
+
+// readContributions reads the comma-separated contribution files of list, in
+// the order they were made.
+func readContributions(list string) ([]*mpcsetup.Phase2, error) {
+	if err := requireFlag("contributions", list); err != nil {
+		return nil, err
+	}
+
+	var contributions []*mpcsetup.Phase2
+	for _, path := range strings.Split(list, ",") {
+		contribution, err := prover.ReadContribution(strings.TrimSpace(path))
+		if err != nil {
+			return nil, err
+		}
+		contributions = append(contributions, contribution)
+	}
+
+	return contributions, nil
+}
+
+func runCeremonyInit(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("ceremony-init", flag.ContinueOnError)
+	flags := newCeremonyFlags(fs)
+	out := fs.String("out", "phase2-0.dat", "output file for the initial parameters")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	ceremony, err := flags.load()
+	if err != nil {
+		return err
+	}
+
+	initial, err := ceremony.Initial()
+	if err != nil {
+		return err
+	}
+
+	if err := prover.WriteFile(*out, initial); err != nil {
+		return err
+	}
+
+	hash, err := prover.ContributionHash(initial)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "wrote %s, hash %s\n", *out, hash)
+
+	return nil
+}
+
+func runCeremonyContribute(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("ceremony-contribute", flag.ContinueOnError)
+	in := fs.String("in", "", "latest contribution, or the initial parameters (required)")
+	out := fs.String("out", "", "output file for the new contribution (required)")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	if err := requireFlag("in", *in); err != nil {
+		return err
+	}
+
+	if err := requireFlag("out", *out); err != nil {
+		return err
+	}
+
+	previous, err := prover.ReadContribution(*in)
+	if err != nil {
+		return err
+	}
+
+	next, err := prover.Contribute(previous)
+	if err != nil {
+		return err
+	}
+
+	if err := prover.WriteFile(*out, next); err != nil {
+		return err
+	}
+
+	hash, err := prover.ContributionHash(next)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "wrote %s, contribution hash %s\n", *out, hash)
+
+	return nil
+}
+
+func runCeremonyVerify(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("ceremony-verify", flag.ContinueOnError)
+	flags := newCeremonyFlags(fs)
+	list := fs.String("contributions", "", "comma-separated contribution files, in order (required)")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	contributions, err := readContributions(*list)
+	if err != nil {
+		return err
+	}
+
+	ceremony, err := flags.load()
+	if err != nil {
+		return err
+	}
+
+	if err := ceremony.Verify(contributions...); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidCeremony, err)
+	}
+
+	// The hashes let each contributor find their own in the chain
+	for i, contribution := range contributions {
+		hash, err := prover.ContributionHash(contribution)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "contribution %d: %s\n", i+1, hash)
+	}
+
+	fmt.Fprintln(stdout, "ceremony is valid")
+
+	return nil
+}
+
+func runCeremonySeal(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("ceremony-seal", flag.ContinueOnError)
+	flags := newCeremonyFlags(fs)
+	list := fs.String("contributions", "", "comma-separated contribution files, in order (required)")
+	beaconHex := fs.String("beacon", "", "hex public random value published after the last contribution (required)")
+	pkPath := fs.String("pk", "pk.dat", "output proving key file")
+	vkPath := fs.String("vk", "vk.dat", "output verifying key file")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	if err := requireFlag("beacon", *beaconHex); err != nil {
+		return err
+	}
+
+	beacon, err := hex.DecodeString(strings.TrimPrefix(*beaconHex, "0x"))
+	if err != nil {
+		return usageError{fmt.Errorf("invalid -beacon: %w", err)}
+	}
+
+	contributions, err := readContributions(*list)
+	if err != nil {
+		return err
+	}
+
+	ceremony, err := flags.load()
+	if err != nil {
+		return err
+	}
+
+	pk, vk, err := ceremony.Seal(beacon, contributions...)
+	if err != nil {
+		if errors.Is(err, prover.ErrInvalidContribution) {
+			return fmt.Errorf("%w: %v", errInvalidCeremony, err)
+		}
+		return err
+	}
+
+	if err := prover.WriteProvingKey(*pkPath, pk); err != nil {
+		return err
+	}
+
+	if err := prover.WriteFile(*vkPath, vk); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "sealed %d contributions, wrote %s and %s\n", len(contributions), *pkPath, *vkPath)
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"hide-pay/prover"
+	"io"
+	"sync"
+
+	"github.com/consensys/gnark/backend/witness"
+)
+
+// runCheck solves a witness against a circuit compiled with the stack of each
+// constraint, and reports the first constraint it does not satisfy where the
+// gadget asserts it. A transfer input is also run through the transfer
+// gadgets and the native builder side by side to find the first value they
+// disagree on. Nothing is set up or proved, so a bad witness is found in
+// seconds.
+func runCheck(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	backendName := backendFlag(fs)
+	circuitName := fs.String("circuit", "", "registered circuit variant, instead of the transfer shape flags")
+	shape := shapeFlags(fs)
+	witnessPath := fs.String("witness", "", "full witness file")
+	inputPath := fs.String("input", "", "JSON or binary transfer input file, to trace and to build the witness from without -witness")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	if *witnessPath == "" && *inputPath == "" {
+		return usageError{fmt.Errorf("-witness or -input is required")}
+	}
+
+	backend, err := parseBackend(*backendName)
+	if err != nil {
+		return err
+	}
+
+	var input *prover.TransferInput
+	if *inputPath != "" {
+		if input, err = prover.ReadTransferInput(*inputPath); err != nil {
+			return err
+		}
+	}
+
+	variant := prover.Variant{Name: "transfer", Kind: prover.KindTransfer, Shape: *shape}
+	switch {
+	case *circuitName != "":
+		if variant, err = prover.LookupVariant(*circuitName); err != nil {
+			return usageError{err}
+		}
+	case input != nil:
+		variant.Shape = input.Shape(shape.Assets)
+	}
+
+	if input != nil && variant.Kind != prover.KindTransfer {
+		return usageError{fmt.Errorf("-input is a transfer, %s is a %s circuit", variant.Name, variant.Kind)}
+	}
+
+	circuit, err := variant.Circuit()
+	if err != nil {
+		return usageError{err}
+	}
+
+	var (
+		trace    []prover.Intermediate
+		traceErr error
+		wg       sync.WaitGroup
+	)
+	if input != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			trace, traceErr = prover.TraceTransfer(input.ToUTXO())
+		}()
+	}
+
+	var w witness.Witness
+	var checkErr error
+	if *witnessPath != "" {
+		if w, err = prover.ReadWitness(*witnessPath); err != nil {
+			wg.Wait()
+			return err
+		}
+	} else if w, err = prover.NewWitness(variant.Shape, input.ToUTXO()); err != nil {
+		// A transfer the builder rejects has no witness to solve; the trace
+		// still shows where the gadgets and the builder part
+		checkErr = err
+		fmt.Fprintf(stdout, "no witness to check: %v\n", err)
+	}
+
+	if w != nil {
+		cs, err := prover.CompileDebug(circuit, backend)
+		if err != nil {
+			wg.Wait()
+			return err
+		}
+
+		checkErr = cs.Check(w)
+		printCheck(stdout, variant, checkErr)
+	}
+
+	wg.Wait()
+
+	var divergence *prover.Intermediate
+	if input != nil {
+		if traceErr != nil {
+			fmt.Fprint(stdout, "trace failed: ")
+			printCheckError(stdout, traceErr)
+		} else if divergence = prover.FirstDivergence(trace); divergence != nil {
+			fmt.Fprintf(stdout, "first diverging value is %s: circuit %s, native %s\n", divergence.Name, divergence.Circuit.String(), divergence.Native.String())
+		} else {
+			fmt.Fprintf(stdout, "circuit and native builder agree on all %d traced values\n", len(trace))
+		}
+	}
+
+	if checkErr != nil || traceErr != nil || divergence != nil {
+		return errInvalidWitness
+	}
+
+	return nil
+}
+
+func printCheck(w io.Writer, variant prover.Variant, err error) {
+	if err == nil {
+		fmt.Fprintf(w, "witness satisfies %s\n", variant)
+		return
+	}
+
+	printCheckError(w, err)
+}
+
+// printCheckError prints err, followed by where the unsatisfied constraint
+// it holds was added, from the gadget out to the circuit.
+func printCheckError(w io.Writer, err error) {
+	var unsatisfied *prover.UnsatisfiedError
+	if !errors.As(err, &unsatisfied) {
+		fmt.Fprintln(w, err)
+		return
+	}
+
+	fmt.Fprintf(w, "constraint #%d is not satisfied: %v\n", unsatisfied.Constraint, unsatisfied.Err)
+	for i, frame := range unsatisfied.CircuitStack() {
+		if i == 0 {
+			fmt.Fprintf(w, "  at %s\n", frame)
+		} else {
+			fmt.Fprintf(w, "  called from %s\n", frame)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"hide-pay/prover"
+	"io"
+)
+
+func runExportVK(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("export-vk", flag.ContinueOnError)
+	backendName := backendFlag(fs)
+	vkPath := fs.String("vk", "vk.dat", "verifying key file")
+	out := fs.String("out", "-", "output file, - for stdout")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	backend, err := parseBackend(*backendName)
+	if err != nil {
+		return err
+	}
+
+	vk, err := prover.ReadVerifyingKey(backend, *vkPath)
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(*out, stdout, func(w io.Writer) error {
+		return prover.ExportVerifyingKeyJSON(vk, w)
+	})
+}
+
+func runExportSolidity(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("export-solidity", flag.ContinueOnError)
+	backendName := backendFlag(fs)
+	vkPath := fs.String("vk", "vk.dat", "verifying key file")
+	out := fs.String("out", "-", "output Solidity file, - for stdout")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	backend, err := parseBackend(*backendName)
+	if err != nil {
+		return err
+	}
+
+	vk, err := prover.ReadVerifyingKey(backend, *vkPath)
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(*out, stdout, func(w io.Writer) error {
+		return prover.ExportSolidity(vk, w)
+	})
+}
+
+func runExportLayout(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("export-layout", flag.ContinueOnError)
+	backendName := backendFlag(fs)
+	csPath := fs.String("cs", "cs.dat", "constraint system file")
+	out := fs.String("out", "-", "output file, - for stdout")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	backend, err := parseBackend(*backendName)
+	if err != nil {
+		return err
+	}
+
+	cs, err := prover.ReadConstraintSystem(backend, *csPath)
+	if err != nil {
+		return err
+	}
+
+	layout, err := prover.NewPublicLayout(cs)
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(*out, stdout, func(w io.Writer) error {
+		return writeJSON(w, layout)
+	})
+}
+
+func runExportCalldata(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("export-calldata", flag.ContinueOnError)
+	backendName := backendFlag(fs)
+	proofPath := fs.String("proof", "proof.dat", "proof file")
+	publicPath := fs.String("public", "public.dat", "public witness file")
+	out := fs.String("out", "-", "output file, - for stdout")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	backend, err := parseBackend(*backendName)
+	if err != nil {
+		return err
+	}
+
+	proof, err := prover.ReadProof(backend, *proofPath)
+	if err != nil {
+		return err
+	}
+
+	public, err := prover.ReadWitness(*publicPath)
+	if err != nil {
+		return err
+	}
+
+	calldata, err := prover.NewSolidityProof(proof, public)
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(*out, stdout, func(w io.Writer) error {
+		return writeJSON(w, calldata)
+	})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"hide-pay/prover"
+	"io"
+
+	"github.com/consensys/gnark/backend/groth16"
+	plonkbn254 "github.com/consensys/gnark/backend/plonk/bn254"
+)
+
+func runInspect(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	backendName := backendFlag(fs)
+	kind := fs.String("kind", "", "file kind: cs, pk, vk, proof or witness (required)")
+	in := fs.String("in", "", "file to inspect (required)")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	if err := requireFlag("kind", *kind); err != nil {
+		return err
+	}
+
+	if err := requireFlag("in", *in); err != nil {
+		return err
+	}
+
+	backend, err := parseBackend(*backendName)
+	if err != nil {
+		return err
+	}
+
+	switch *kind {
+	case "cs":
+		cs, err := prover.ReadConstraintSystem(backend, *in)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(stdout, "constraints: %d\n", cs.GetNbConstraints())
+		fmt.Fprintf(stdout, "public:      %d\n", prover.NbPublicInputs(cs))
+		fmt.Fprintf(stdout, "secret:      %d\n", cs.GetNbSecretVariables())
+		fmt.Fprintf(stdout, "internal:    %d\n", cs.GetNbInternalVariables())
+	case "pk":
+		pk, err := prover.ReadProvingKey(backend, *in)
+		if err != nil {
+			return err
+		}
+
+		switch key := pk.(type) {
+		case groth16.ProvingKey:
+			fmt.Fprintf(stdout, "curve: %s\n", key.CurveID())
+			fmt.Fprintf(stdout, "g1:    %d\n", key.NbG1())
+			fmt.Fprintf(stdout, "g2:    %d\n", key.NbG2())
+		case *plonkbn254.ProvingKey:
+			fmt.Fprintf(stdout, "domain: %d\n", key.Vk.Size)
+			fmt.Fprintf(stdout, "public: %d\n", key.Vk.NbPublicVariables)
+		}
+	case "vk":
+		vk, err := prover.ReadVerifyingKey(backend, *in)
+		if err != nil {
+			return err
+		}
+
+		switch key := vk.(type) {
+		case groth16.VerifyingKey:
+			fmt.Fprintf(stdout, "curve:  %s\n", key.CurveID())
+		case *plonkbn254.VerifyingKey:
+			fmt.Fprintf(stdout, "domain: %d\n", key.Size)
+		}
+		fmt.Fprintf(stdout, "public: %d\n", prover.NbPublic(vk))
+	case "proof":
+		proof, err := prover.ReadProof(backend, *in)
+		if err != nil {
+			return err
+		}
+
+		size, err := proof.WriteTo(io.Discard)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(stdout, "backend: %s\n", backend)
+		fmt.Fprintf(stdout, "size:    %d bytes\n", size)
+	case "witness":
+		w, err := prover.ReadWitness(*in)
+		if err != nil {
+			return err
+		}
+
+		public, err := w.Public()
+		if err != nil {
+			return fmt.Errorf("failed to extract public witness: %w", err)
+		}
+
+		fmt.Fprintf(stdout, "public: %d\n", prover.WitnessSize(public))
+		fmt.Fprintf(stdout, "total:  %d\n", prover.WitnessSize(w))
+	default:
+		return usageError{fmt.Errorf("unknown kind %q", *kind)}
+	}
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hide-pay/prover"
+	"io"
+	"os"
+	"sort"
+)
+
+const (
+	exitOK = 0
+	// exitError reports a failure to read, write or compute
+	exitError = 1
+	// exitUsage reports an unknown command or invalid flags
+	exitUsage = 2
+	// exitInvalid reports a proof or ceremony that does not verify, or a
+	// witness that does not satisfy its circuit
+	exitInvalid = 3
+)
+
+var (
+	errInvalidProof    = errors.New("proof is invalid")
+	errInvalidCeremony = errors.New("ceremony is invalid")
+	errInvalidWitness  = errors.New("witness is invalid")
+)
+
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+type command struct {
+	summary string
+	run     func(args []string, stdout io.Writer, stderr io.Writer) error
+}
+
+var commands = map[string]command{
+	"compile":             {"compile the transfer circuit of a shape", runCompile},
+	"setup":               {"run the Groth16 or PLONK setup of a constraint system", runSetup},
+	"ceremony-init":       {"start the Groth16 phase-2 ceremony of a constraint system", runCeremonyInit},
+	"ceremony-contribute": {"add a contribution with fresh randomness to a ceremony", runCeremonyContribute},
+	"ceremony-verify":     {"verify the contribution chain of a ceremony", runCeremonyVerify},
+	"ceremony-seal":       {"verify a ceremony and seal it into the final keys", runCeremonySeal},
+	"check":               {"find the constraint and value a witness or transfer input fails on", runCheck},
+	"prove":               {"prove a witness or transfer input file", runProve},
+	"verify":              {"verify a proof against its public witness", runVerify},
+	"export-vk":           {"export a verifying key as JSON", runExportVK},
+	"export-solidity":     {"export the Solidity verifier of a verifying key", runExportSolidity},
+	"export-layout":       {"export the public input layout of a constraint system as JSON", runExportLayout},
+	"export-calldata":     {"encode a proof and public witness as verifier calldata", runExportCalldata},
+	"inspect":             {"describe a constraint system, key, proof or witness file", runInspect},
+	"circuits":            {"list the registered circuit variants", runCircuits},
+	"keys":                {"compile a circuit variant and set up its keys in a cache", runKeys},
+	"serve":               {"serve a proving API for cached circuit variants", runServe},
+	"bench":               {"measure the constraints and proving cost of gadgets and circuit variants", runBench},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return exitUsage
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+			printUsage(stdout)
+			return exitOK
+		}
+
+		fmt.Fprintf(stderr, "auditzero: unknown command %q\n", args[0])
+		printUsage(stderr)
+		return exitUsage
+	}
+
+	err := cmd.run(args[1:], stdout, stderr)
+
+	var usage usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &usage):
+		fmt.Fprintf(stderr, "auditzero %s: %v\n", args[0], err)
+		return exitUsage
+	case errors.Is(err, errInvalidProof), errors.Is(err, errInvalidCeremony), errors.Is(err, errInvalidWitness):
+		fmt.Fprintf(stderr, "auditzero %s: %v\n", args[0], err)
+		return exitInvalid
+	default:
+		fmt.Fprintf(stderr, "auditzero %s: %v\n", args[0], err)
+		return exitError
+	}
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: auditzero <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-19s %s\n", name, commands[name].summary)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "run auditzero <command> -h for the flags of a command")
+}
+
+// parseFlags parses args and turns flag errors and positional arguments into
+// usage errors.
+func parseFlags(fs *flag.FlagSet, args []string, stderr io.Writer) error {
+	fs.SetOutput(stderr)
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usageError{err}
+	}
+
+	if fs.NArg() > 0 {
+		return usageError{fmt.Errorf("unexpected argument %q", fs.Arg(0))}
+	}
+
+	return nil
+}
+
+func requireFlag(name string, value string) error {
+	if value == "" {
+		return usageError{fmt.Errorf("-%s is required", name)}
+	}
+
+	return nil
+}
+
+func backendFlag(fs *flag.FlagSet) *string {
+	return fs.String("backend", string(prover.Groth16), "proof system: groth16 or plonk")
+}
+
+func parseBackend(name string) (prover.Backend, error) {
+	backend, err := prover.ParseBackend(name)
+	if err != nil {
+		return "", usageError{err}
+	}
+
+	return backend, nil
+}
+
+type srsFlags struct {
+	path   *string
+	unsafe *bool
+}
+
+func newSRSFlags(fs *flag.FlagSet) srsFlags {
+	return srsFlags{
+		path:   fs.String("srs", "", "canonical KZG SRS file, with -backend plonk"),
+		unsafe: fs.Bool("unsafe-srs", false, "generate an insecure SRS for testing, with -backend plonk"),
+	}
+}
+
+// source returns where the PLONK setup takes its SRS from. Groth16 takes
+// none, so either flag is a mistake there.
+func (flags srsFlags) source(backend prover.Backend) (prover.SRSSource, error) {
+	hasPath := *flags.path != ""
+
+	if backend != prover.Plonk {
+		if hasPath || *flags.unsafe {
+			return nil, usageError{fmt.Errorf("-srs and -unsafe-srs only apply to -backend %s", prover.Plonk)}
+		}
+		return nil, nil
+	}
+
+	if hasPath == *flags.unsafe {
+		return nil, usageError{fmt.Errorf("exactly one of -srs and -unsafe-srs is required with -backend %s", prover.Plonk)}
+	}
+
+	if *flags.unsafe {
+		return prover.NewUnsafeSRS, nil
+	}
+
+	return prover.SRSFile(*flags.path), nil
+}
+
+func shapeFlags(fs *flag.FlagSet) *prover.Shape {
+	shape := &prover.Shape{}
+
+	fs.IntVar(&shape.Assets, "assets", 1, "number of distinct assets per transfer")
+	fs.IntVar(&shape.Depth, "depth", 34, "depth of the commitment Merkle tree")
+	fs.IntVar(&shape.Inputs, "inputs", 4, "number of spent notes")
+	fs.IntVar(&shape.Outputs, "outputs", 4, "number of created notes")
+
+	return shape
+}
+
+// writeOutput runs write on the file at path, or on stdout when path is "-".
+func writeOutput(path string, stdout io.Writer, write func(io.Writer) error) error {
+	if path == "-" {
+		return write(stdout)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func writeJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(value)
+}
+
+func readJSON(path string, value any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return nil
+}
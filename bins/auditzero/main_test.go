@@ -0,0 +1,452 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hide-pay/bench"
+	"hide-pay/builder"
+	"hide-pay/prover"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16/bn254/mpcsetup"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runCommand(args ...string) (int, string, string) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run(args, stdout, stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_EndToEnd(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	shape := prover.Shape{Assets: 1, Depth: 4, Inputs: 1, Outputs: 1}
+
+	utxo, err := builder.GenerateUTXO(1, shape.Depth, shape.Inputs, shape.Outputs)
+	require.NoError(t, err)
+
+	w, err := prover.NewWitness(shape, utxo)
+	require.NoError(t, err)
+	require.NoError(t, prover.WriteFile(path("witness.dat"), w))
+
+	code, stdout, stderr := runCommand("compile",
+		"-assets", strconv.Itoa(shape.Assets), "-depth", strconv.Itoa(shape.Depth),
+		"-inputs", strconv.Itoa(shape.Inputs), "-outputs", strconv.Itoa(shape.Outputs),
+		"-cs", path("cs.dat"))
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "constraints")
+
+	code, _, stderr = runCommand("setup", "-cs", path("cs.dat"), "-pk", path("pk.dat"), "-vk", path("vk.dat"))
+	require.Equal(t, exitOK, code, stderr)
+
+	code, _, stderr = runCommand("prove", "-cs", path("cs.dat"), "-pk", path("pk.dat"),
+		"-witness", path("witness.dat"), "-proof", path("proof.dat"), "-public", path("public.dat"))
+	require.Equal(t, exitOK, code, stderr)
+
+	code, stdout, stderr = runCommand("verify", "-vk", path("vk.dat"), "-proof", path("proof.dat"), "-public", path("public.dat"))
+	require.Equal(t, exitOK, code, stderr)
+	assert.Equal(t, "proof is valid\n", stdout)
+
+	// The same transfer proves from its transfer input
+	input, err := prover.NewTransferInput(utxo)
+	require.NoError(t, err)
+	data, err := input.MarshalJSON()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path("input.json"), data, 0o600))
+
+	code, _, stderr = runCommand("prove", "-cs", path("cs.dat"), "-pk", path("pk.dat"),
+		"-input", path("input.json"), "-proof", path("input-proof.dat"), "-public", path("input-public.dat"))
+	require.Equal(t, exitOK, code, stderr)
+
+	code, _, stderr = runCommand("verify", "-vk", path("vk.dat"), "-proof", path("input-proof.dat"), "-public", path("input-public.dat"))
+	require.Equal(t, exitOK, code, stderr)
+
+	code, stdout, stderr = runCommand("export-vk", "-vk", path("vk.dat"))
+	require.Equal(t, exitOK, code, stderr)
+
+	var exported map[string]any
+	require.NoError(t, json.Unmarshal([]byte(stdout), &exported))
+	assert.Equal(t, "groth16", exported["protocol"])
+	// audit key, nullifier, commitment, two memo hashes and the root
+	assert.Equal(t, float64(7), exported["nbPublic"])
+
+	code, stdout, stderr = runCommand("inspect", "-kind", "witness", "-in", path("public.dat"))
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "public: 7")
+
+	code, _, stderr = runCommand("export-solidity", "-vk", path("vk.dat"), "-out", path("Verifier.sol"))
+	require.Equal(t, exitOK, code, stderr)
+	contract, err := os.ReadFile(path("Verifier.sol"))
+	require.NoError(t, err)
+	assert.Contains(t, string(contract), "function verifyProof(")
+
+	code, stdout, stderr = runCommand("export-layout", "-cs", path("cs.dat"))
+	require.Equal(t, exitOK, code, stderr)
+
+	var layout prover.PublicLayout
+	require.NoError(t, json.Unmarshal([]byte(stdout), &layout))
+	assert.Equal(t, 7, layout.NbPublic)
+	assert.Equal(t, prover.PublicField{Name: "merkleRoot", Offset: 6, Length: 1}, layout.Fields[len(layout.Fields)-1])
+
+	code, stdout, stderr = runCommand("export-calldata", "-proof", path("proof.dat"), "-public", path("public.dat"))
+	require.Equal(t, exitOK, code, stderr)
+
+	var calldata prover.SolidityProof
+	require.NoError(t, json.Unmarshal([]byte(stdout), &calldata))
+	assert.Equal(t, prover.Groth16, calldata.Backend)
+	assert.Len(t, calldata.Input, 7)
+
+	// A public input from another transfer does not verify
+	other, err := builder.GenerateUTXO(2, shape.Depth, shape.Inputs, shape.Outputs)
+	require.NoError(t, err)
+	otherWitness, err := prover.NewWitness(shape, other)
+	require.NoError(t, err)
+	otherPublic, err := otherWitness.Public()
+	require.NoError(t, err)
+	require.NoError(t, prover.WriteFile(path("other.dat"), otherPublic))
+
+	code, _, _ = runCommand("verify", "-vk", path("vk.dat"), "-proof", path("proof.dat"), "-public", path("other.dat"))
+	assert.Equal(t, exitInvalid, code)
+
+	// A public witness cannot be proved
+	code, _, stderr = runCommand("prove", "-cs", path("cs.dat"), "-pk", path("pk.dat"),
+		"-witness", path("public.dat"), "-proof", path("proof.dat"), "-public", path("public.dat"))
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "constraint system expects")
+
+	// A proving key of another circuit is refused before proving
+	code, _, stderr = runCommand("compile", "-depth", "5", "-inputs", "1", "-outputs", "1", "-cs", path("other-cs.dat"))
+	require.Equal(t, exitOK, code, stderr)
+
+	code, _, stderr = runCommand("prove", "-cs", path("other-cs.dat"), "-pk", path("pk.dat"),
+		"-input", path("input.json"), "-proof", path("proof.dat"), "-public", path("public.dat"))
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "key does not match the constraint system")
+
+	// Files of the wrong kind are reported, not panicked on
+	code, _, _ = runCommand("verify", "-vk", path("pk.dat"), "-proof", path("proof.dat"), "-public", path("public.dat"))
+	assert.Equal(t, exitError, code)
+}
+
+func TestRun_Plonk(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	shape := prover.Shape{Assets: 1, Depth: 4, Inputs: 1, Outputs: 1}
+
+	utxo, err := builder.GenerateUTXO(1, shape.Depth, shape.Inputs, shape.Outputs)
+	require.NoError(t, err)
+
+	input, err := prover.NewTransferInput(utxo)
+	require.NoError(t, err)
+	data, err := input.MarshalJSON()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path("input.json"), data, 0o600))
+
+	code, stdout, stderr := runCommand("compile", "-backend", "plonk",
+		"-depth", strconv.Itoa(shape.Depth), "-inputs", "1", "-outputs", "1", "-cs", path("cs.dat"))
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "for plonk")
+
+	// PLONK needs an SRS, and only one
+	code, _, stderr = runCommand("setup", "-backend", "plonk", "-cs", path("cs.dat"))
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "exactly one of -srs and -unsafe-srs is required")
+
+	code, _, stderr = runCommand("setup", "-backend", "plonk", "-cs", path("cs.dat"),
+		"-unsafe-srs", "-pk", path("pk.dat"), "-vk", path("vk.dat"))
+	require.Equal(t, exitOK, code, stderr)
+
+	code, _, stderr = runCommand("prove", "-backend", "plonk", "-cs", path("cs.dat"), "-pk", path("pk.dat"),
+		"-input", path("input.json"), "-proof", path("proof.dat"), "-public", path("public.dat"))
+	require.Equal(t, exitOK, code, stderr)
+
+	code, stdout, stderr = runCommand("verify", "-backend", "plonk", "-vk", path("vk.dat"), "-proof", path("proof.dat"), "-public", path("public.dat"))
+	require.Equal(t, exitOK, code, stderr)
+	assert.Equal(t, "proof is valid\n", stdout)
+
+	code, stdout, stderr = runCommand("export-vk", "-backend", "plonk", "-vk", path("vk.dat"))
+	require.Equal(t, exitOK, code, stderr)
+
+	var exported map[string]any
+	require.NoError(t, json.Unmarshal([]byte(stdout), &exported))
+	assert.Equal(t, "plonk", exported["protocol"])
+	assert.Equal(t, float64(7), exported["nbPublic"])
+
+	code, stdout, stderr = runCommand("inspect", "-backend", "plonk", "-kind", "cs", "-in", path("cs.dat"))
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "public:      7")
+
+	code, stdout, stderr = runCommand("inspect", "-backend", "plonk", "-kind", "vk", "-in", path("vk.dat"))
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "public: 7")
+
+	code, stdout, stderr = runCommand("export-layout", "-backend", "plonk", "-cs", path("cs.dat"))
+	require.Equal(t, exitOK, code, stderr)
+
+	var layout prover.PublicLayout
+	require.NoError(t, json.Unmarshal([]byte(stdout), &layout))
+	assert.Equal(t, 7, layout.NbPublic)
+	assert.Equal(t, prover.PublicField{Name: "auditPublicKey", Offset: 0, Length: 2}, layout.Fields[0])
+	assert.Equal(t, prover.PublicField{Name: "merkleRoot", Offset: 6, Length: 1}, layout.Fields[len(layout.Fields)-1])
+
+	// PLONK files are not read as Groth16 ones
+	code, _, _ = runCommand("verify", "-vk", path("vk.dat"), "-proof", path("proof.dat"), "-public", path("public.dat"))
+	assert.Equal(t, exitError, code)
+}
+
+// squareCircuit keeps the ceremony test fast; the prover tests cover a
+// ceremony over a circuit with commitments.
+type squareCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (circuit *squareCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(api.Mul(circuit.X, circuit.X), circuit.Y)
+	return nil
+}
+
+func TestRun_Ceremony(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &squareCircuit{})
+	require.NoError(t, err)
+	require.NoError(t, prover.WriteFile(path("cs.dat"), cs))
+
+	w, err := frontend.NewWitness(&squareCircuit{X: 3, Y: 9}, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+	require.NoError(t, prover.WriteFile(path("witness.dat"), w))
+
+	// The phase-1 output usually comes from a public ceremony
+	phase1 := mpcsetup.NewPhase1(8)
+	phase1.Contribute()
+	commons, err := mpcsetup.VerifyPhase1(8, []byte("phase 1 beacon"), phase1)
+	require.NoError(t, err)
+	require.NoError(t, prover.WriteFile(path("phase1.dat"), &commons))
+
+	code, stdout, stderr := runCommand("ceremony-init", "-cs", path("cs.dat"), "-phase1", path("phase1.dat"), "-out", path("phase2-0.dat"))
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "hash")
+
+	for i := 1; i <= 2; i++ {
+		code, stdout, stderr = runCommand("ceremony-contribute",
+			"-in", path(fmt.Sprintf("phase2-%d.dat", i-1)), "-out", path(fmt.Sprintf("phase2-%d.dat", i)))
+		require.Equal(t, exitOK, code, stderr)
+		assert.Contains(t, stdout, "contribution hash")
+	}
+
+	contributions := path("phase2-1.dat") + "," + path("phase2-2.dat")
+
+	code, stdout, stderr = runCommand("ceremony-verify", "-cs", path("cs.dat"), "-phase1", path("phase1.dat"), "-contributions", contributions)
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "contribution 2: ")
+	assert.Contains(t, stdout, "ceremony is valid")
+
+	code, _, stderr = runCommand("ceremony-verify", "-cs", path("cs.dat"), "-phase1", path("phase1.dat"), "-contributions", path("phase2-2.dat"))
+	assert.Equal(t, exitInvalid, code)
+	assert.Contains(t, stderr, "invalid contribution 1")
+
+	code, _, stderr = runCommand("ceremony-seal", "-cs", path("cs.dat"), "-phase1", path("phase1.dat"), "-contributions", contributions,
+		"-beacon", "0x00", "-pk", path("pk.dat"), "-vk", path("vk.dat"))
+	require.Equal(t, exitOK, code, stderr)
+
+	code, _, stderr = runCommand("prove", "-cs", path("cs.dat"), "-pk", path("pk.dat"),
+		"-witness", path("witness.dat"), "-proof", path("proof.dat"), "-public", path("public.dat"))
+	require.Equal(t, exitOK, code, stderr)
+
+	code, stdout, stderr = runCommand("verify", "-vk", path("vk.dat"), "-proof", path("proof.dat"), "-public", path("public.dat"))
+	require.Equal(t, exitOK, code, stderr)
+	assert.Equal(t, "proof is valid\n", stdout)
+
+	code, _, stderr = runCommand("ceremony-seal", "-cs", path("cs.dat"), "-phase1", path("phase1.dat"), "-contributions", contributions, "-beacon", "beacon")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "invalid -beacon")
+}
+
+func TestRun_Bench(t *testing.T) {
+	dir := t.TempDir()
+	baselinePath := filepath.Join(dir, "baseline.json")
+
+	code, _, stderr := runCommand("bench", "-run", "^(commitment|ecdh)$", "-stage", "compile", "-out", baselinePath)
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stderr, "ecdh: ")
+
+	var baseline bench.Report
+	require.NoError(t, readJSON(baselinePath, &baseline))
+	require.Len(t, baseline.Results, 2)
+	assert.Equal(t, "commitment", baseline.Results[0].Name)
+	assert.Positive(t, baseline.Results[0].SCSConstraints)
+
+	code, stdout, stderr := runCommand("bench", "-run", "^commitment$", "-stage", "solve", "-baseline", baselinePath)
+	require.Equal(t, exitOK, code, stderr)
+
+	var report bench.Report
+	require.NoError(t, json.Unmarshal([]byte(stdout), &report))
+	require.NotNil(t, report.Comparison)
+	assert.Positive(t, report.Results[0].SolveMs)
+	assert.Equal(t, []string{"ecdh"}, report.Comparison.Removed)
+	for _, change := range report.Comparison.Changes {
+		assert.NotContains(t, change.Metric, "Constraints")
+	}
+
+	// A baseline with fewer constraints makes the run a regression
+	baseline.Results[0].R1CSConstraints /= 2
+	data, err := json.Marshal(baseline)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(baselinePath, data, 0o600))
+
+	code, _, stderr = runCommand("bench", "-run", "^commitment$", "-stage", "compile", "-baseline", baselinePath, "-max-regression", "10000", "-out", filepath.Join(dir, "report.json"))
+	require.Equal(t, exitOK, code, stderr)
+
+	code, _, stderr = runCommand("bench", "-run", "^commitment$", "-stage", "compile", "-baseline", baselinePath, "-max-regression", "50", "-out", filepath.Join(dir, "report.json"))
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "commitment r1csConstraints")
+}
+
+func TestRun_Check(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	shape := prover.Shape{Assets: 1, Depth: 4, Inputs: 1, Outputs: 1}
+
+	utxo, err := builder.GenerateUTXO(1, shape.Depth, shape.Inputs, shape.Outputs)
+	require.NoError(t, err)
+
+	input, err := prover.NewTransferInput(utxo)
+	require.NoError(t, err)
+	data, err := input.MarshalJSON()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path("input.json"), data, 0o600))
+
+	code, stdout, stderr := runCommand("check", "-input", path("input.json"))
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "witness satisfies transfer")
+	assert.Contains(t, stdout, "agree on all 10 traced values")
+
+	// A witness spending the note with a key it was not sent to fails in
+	// the note gadget
+	result, err := utxo.BuildAndCheck()
+	require.NoError(t, err)
+	assignment, err := builder.NewUTXOCircuitWitness(utxo, result)
+	require.NoError(t, err)
+	assignment.UTXO.Nullifier[0].PrivateKey = 12345
+
+	w, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+	require.NoError(t, prover.WriteFile(path("witness.dat"), w))
+
+	code, stdout, _ = runCommand("check", "-backend", "plonk",
+		"-depth", strconv.Itoa(shape.Depth), "-inputs", "1", "-outputs", "1", "-witness", path("witness.dat"))
+	assert.Equal(t, exitInvalid, code)
+	assert.Contains(t, stdout, "is not satisfied")
+	assert.Contains(t, stdout, "circuits/note.go:")
+	assert.Contains(t, stdout, "called from hide-pay/circuits.(*UTXOCircuit).Define")
+
+	// An output worth more than the inputs has no witness, and the gadgets
+	// still agree with the builder on every value
+	input.Outputs[0].Note.Amount.SetUint64(1 << 40)
+	data, err = input.MarshalJSON()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path("unbalanced.json"), data, 0o600))
+
+	code, stdout, _ = runCommand("check", "-input", path("unbalanced.json"))
+	assert.Equal(t, exitInvalid, code)
+	assert.Contains(t, stdout, "no witness to check")
+	assert.Contains(t, stdout, "agree on all 10 traced values")
+
+	code, _, stderr = runCommand("check")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "-witness or -input is required")
+
+	code, _, _ = runCommand("check", "-circuit", "deposit", "-input", path("input.json"))
+	assert.Equal(t, exitUsage, code)
+}
+
+func TestRun_Usage(t *testing.T) {
+	code, _, _ := runCommand()
+	assert.Equal(t, exitUsage, code)
+
+	code, _, stderr := runCommand("frobnicate")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "unknown command")
+
+	code, _, _ = runCommand("compile", "-depth", "one")
+	assert.Equal(t, exitUsage, code)
+
+	code, _, _ = runCommand("compile", "-inputs", "0")
+	assert.Equal(t, exitUsage, code)
+
+	code, _, stderr = runCommand("prove")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "exactly one of -witness and -input is required")
+
+	code, _, _ = runCommand("inspect", "-kind", "key", "-in", os.DevNull)
+	assert.Equal(t, exitUsage, code)
+
+	code, _, _ = runCommand("setup", "-cs", filepath.Join(t.TempDir(), "missing.dat"))
+	assert.Equal(t, exitError, code)
+
+	code, stdout, _ := runCommand("circuits")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "transfer-4x16 (transfer assets=1 depth=34 inputs=4 outputs=16)")
+
+	code, _, stderr = runCommand("keys", "-circuit", "transfer-1x1")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "unknown circuit variant")
+
+	code, _, stderr = runCommand("compile", "-backend", "stark")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "unknown backend")
+
+	code, _, stderr = runCommand("keys", "-circuit", "deposit", "-unsafe-srs")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "only apply to -backend plonk")
+
+	code, _, stderr = runCommand("serve")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "-circuits is required")
+
+	code, _, stderr = runCommand("serve", "-circuits", "deposit", "-cache", t.TempDir())
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "run auditzero keys -circuit deposit")
+
+	code, _, stderr = runCommand("ceremony-init", "-cs", filepath.Join(t.TempDir(), "cs.dat"))
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "-phase1 is required")
+
+	code, _, stderr = runCommand("ceremony-contribute", "-in", "phase2-0.dat")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "-out is required")
+
+	code, _, stderr = runCommand("bench", "-run", "^nothing$")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "no target matches")
+
+	code, _, stderr = runCommand("bench", "-max-regression", "5")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "-max-regression needs -baseline")
+
+	code, _, _ = runCommand("compile", "-h")
+	assert.Equal(t, exitOK, code)
+}
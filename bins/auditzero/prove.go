@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"hide-pay/prover"
+	"io"
+	"time"
+
+	"github.com/consensys/gnark/backend/witness"
+)
+
+func runProve(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("prove", flag.ContinueOnError)
+	backendName := backendFlag(fs)
+	csPath := fs.String("cs", "cs.dat", "constraint system file")
+	pkPath := fs.String("pk", "pk.dat", "proving key file")
+	witnessPath := fs.String("witness", "", "full witness file")
+	inputPath := fs.String("input", "", "JSON or binary transfer input file, instead of -witness")
+	assets := fs.Int("assets", 1, "number of distinct assets the circuit was compiled for, with -input")
+	proofPath := fs.String("proof", "proof.dat", "output proof file")
+	publicPath := fs.String("public", "public.dat", "output public witness file")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	if (*witnessPath == "") == (*inputPath == "") {
+		return usageError{fmt.Errorf("exactly one of -witness and -input is required")}
+	}
+
+	backend, err := parseBackend(*backendName)
+	if err != nil {
+		return err
+	}
+
+	cs, err := prover.ReadConstraintSystem(backend, *csPath)
+	if err != nil {
+		return err
+	}
+
+	pk, err := prover.ReadProvingKey(backend, *pkPath)
+	if err != nil {
+		return err
+	}
+
+	if err := prover.CheckProvingKey(cs, pk); err != nil {
+		return fmt.Errorf("%s and %s: %w", *csPath, *pkPath, err)
+	}
+
+	var w witness.Witness
+	if *inputPath != "" {
+		input, err := prover.ReadTransferInput(*inputPath)
+		if err != nil {
+			return err
+		}
+
+		w, err = prover.NewWitness(input.Shape(*assets), input.ToUTXO())
+		if err != nil {
+			return err
+		}
+	} else {
+		w, err = prover.ReadWitness(*witnessPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := prover.CheckWitness(cs, w); err != nil {
+		return err
+	}
+
+	start := time.Now()
+
+	proof, err := prover.Prove(cs, pk, w)
+	if err != nil {
+		return err
+	}
+
+	elapsed := time.Since(start)
+
+	public, err := w.Public()
+	if err != nil {
+		return fmt.Errorf("failed to extract public witness: %w", err)
+	}
+
+	if err := prover.WriteFile(*proofPath, proof); err != nil {
+		return err
+	}
+
+	if err := prover.WriteFile(*publicPath, public); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "proved in %s, wrote %s and %s\n", elapsed.Round(time.Millisecond), *proofPath, *publicPath)
+
+	return nil
+}
+
+func runVerify(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	backendName := backendFlag(fs)
+	vkPath := fs.String("vk", "vk.dat", "verifying key file")
+	proofPath := fs.String("proof", "proof.dat", "proof file")
+	publicPath := fs.String("public", "public.dat", "public witness file")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	backend, err := parseBackend(*backendName)
+	if err != nil {
+		return err
+	}
+
+	vk, err := prover.ReadVerifyingKey(backend, *vkPath)
+	if err != nil {
+		return err
+	}
+
+	proof, err := prover.ReadProof(backend, *proofPath)
+	if err != nil {
+		return err
+	}
+
+	public, err := prover.ReadWitness(*publicPath)
+	if err != nil {
+		return err
+	}
+
+	if err := prover.CheckPublicWitness(vk, public); err != nil {
+		return err
+	}
+
+	if err := prover.Verify(proof, vk, public); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidProof, err)
+	}
+
+	fmt.Fprintln(stdout, "proof is valid")
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"hide-pay/daemon"
+	"hide-pay/prover"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func runServe(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	names := fs.String("circuits", "", "comma separated circuit variants to serve (required)")
+	dir := fs.String("cache", "keys", "key cache directory")
+	backendName := backendFlag(fs)
+	addr := fs.String("addr", "127.0.0.1:8080", "listen address")
+	workers := fs.Int("workers", daemon.DefaultConfig.Workers, "jobs proved at once")
+	queue := fs.Int("queue", daemon.DefaultConfig.QueueSize, "jobs that may wait for a worker")
+	timeout := fs.Duration("shutdown-timeout", time.Minute, "time given to accepted jobs on shutdown")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	if err := requireFlag("circuits", *names); err != nil {
+		return err
+	}
+
+	backend, err := parseBackend(*backendName)
+	if err != nil {
+		return err
+	}
+
+	config := daemon.DefaultConfig
+	config.Workers = *workers
+	config.QueueSize = *queue
+
+	cache, err := prover.OpenCache(*dir)
+	if err != nil {
+		return err
+	}
+
+	// Keys are only loaded here: a setup at full depth takes too long to run
+	// implicitly, so it stays with the keys command
+	var entries []*prover.Entry
+	for _, name := range strings.Split(*names, ",") {
+		variant, err := prover.LookupVariant(strings.TrimSpace(name))
+		if err != nil {
+			return usageError{err}
+		}
+
+		entry, err := cache.Load(variant, backend)
+		if err != nil {
+			if errors.Is(err, prover.ErrNotCached) {
+				return fmt.Errorf("%w, run auditzero keys -circuit %s -backend %s first", err, variant.Name, backend)
+			}
+			return err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	service, err := daemon.New(entries, config)
+	if err != nil {
+		return usageError{err}
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{Handler: service.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	fmt.Fprintf(stdout, "serving %d circuits on %s\n", len(entries), listener.Addr())
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintln(stdout, "shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	// Stop taking requests first, then let the workers finish accepted jobs
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	return service.Shutdown(shutdownCtx)
+}
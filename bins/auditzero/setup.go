@@ -0,0 +1,148 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"hide-pay/prover"
+	"io"
+)
+
+func runCompile(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("compile", flag.ContinueOnError)
+	shape := shapeFlags(fs)
+	backendName := backendFlag(fs)
+	csPath := fs.String("cs", "cs.dat", "output constraint system file")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	backend, err := parseBackend(*backendName)
+	if err != nil {
+		return err
+	}
+
+	if err := shape.Validate(); err != nil {
+		return usageError{err}
+	}
+
+	cs, err := prover.Compile(*shape, backend)
+	if err != nil {
+		return err
+	}
+
+	if err := prover.WriteFile(*csPath, cs); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "compiled %s for %s: %d constraints\n", shape, backend, cs.GetNbConstraints())
+
+	return nil
+}
+
+func runSetup(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("setup", flag.ContinueOnError)
+	backendName := backendFlag(fs)
+	srs := newSRSFlags(fs)
+	csPath := fs.String("cs", "cs.dat", "constraint system file")
+	pkPath := fs.String("pk", "pk.dat", "output proving key file")
+	vkPath := fs.String("vk", "vk.dat", "output verifying key file")
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	backend, err := parseBackend(*backendName)
+	if err != nil {
+		return err
+	}
+
+	source, err := srs.source(backend)
+	if err != nil {
+		return err
+	}
+
+	cs, err := prover.ReadConstraintSystem(backend, *csPath)
+	if err != nil {
+		return err
+	}
+
+	var loaded *prover.SRS
+	if source != nil {
+		if loaded, err = source(cs); err != nil {
+			return err
+		}
+	}
+
+	pk, vk, err := prover.Setup(cs, loaded)
+	if err != nil {
+		return err
+	}
+
+	if err := prover.WriteProvingKey(*pkPath, pk); err != nil {
+		return err
+	}
+
+	if err := prover.WriteFile(*vkPath, vk); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "wrote %s and %s\n", *pkPath, *vkPath)
+
+	return nil
+}
+
+func runCircuits(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("circuits", flag.ContinueOnError)
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	for _, variant := range prover.Variants() {
+		fmt.Fprintln(stdout, variant)
+	}
+
+	return nil
+}
+
+func runKeys(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := flag.NewFlagSet("keys", flag.ContinueOnError)
+	name := fs.String("circuit", "", "registered circuit variant (required)")
+	dir := fs.String("cache", "keys", "key cache directory")
+	backendName := backendFlag(fs)
+	srs := newSRSFlags(fs)
+	if err := parseFlags(fs, args, stderr); err != nil {
+		return err
+	}
+
+	if err := requireFlag("circuit", *name); err != nil {
+		return err
+	}
+
+	backend, err := parseBackend(*backendName)
+	if err != nil {
+		return err
+	}
+
+	source, err := srs.source(backend)
+	if err != nil {
+		return err
+	}
+
+	variant, err := prover.LookupVariant(*name)
+	if err != nil {
+		return usageError{err}
+	}
+
+	cache, err := prover.OpenCache(*dir)
+	if err != nil {
+		return err
+	}
+
+	entry, err := cache.Ensure(variant, backend, source)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "%s for %s: %d constraints, keys in %s\n", variant, backend, entry.CS.GetNbConstraints(), cache.Dir(entry.Hash))
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package builder
+
+import (
+	"fmt"
+	"hide-pay/circuits"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	stdgroth16 "github.com/consensys/gnark/std/recursion/groth16"
+)
+
+// ProveForAggregation proves w so the proof can go into a Block. Its
+// commitment is hashed the way the aggregate circuit recomputes it, so the
+// Solidity verifier of the inner circuit does not accept it.
+func ProveForAggregation(cs constraint.ConstraintSystem, pk groth16.ProvingKey, w witness.Witness) (groth16.Proof, error) {
+	field := ecc.BN254.ScalarField()
+
+	proof, err := groth16.Prove(cs, pk, w, stdgroth16.GetNativeProverOptions(field, field))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prove: %w", err)
+	}
+
+	return proof, nil
+}
+
+// VerifyForAggregation checks natively a proof made by ProveForAggregation.
+func VerifyForAggregation(proof groth16.Proof, vk groth16.VerifyingKey, public witness.Witness) error {
+	field := ecc.BN254.ScalarField()
+
+	return groth16.Verify(proof, vk, public, stdgroth16.GetNativeVerifierOptions(field, field))
+}
+
+// Block is a batch of inner proofs in the order they are aggregated.
+type Block struct {
+	Proofs []groth16.Proof
+	// Public holds the public witness of each proof.
+	Public []witness.Witness
+}
+
+// Add appends a proof made by ProveForAggregation and its public witness.
+func (block *Block) Add(proof groth16.Proof, public witness.Witness) {
+	block.Proofs = append(block.Proofs, proof)
+	block.Public = append(block.Public, public)
+}
+
+// Inputs returns the public inputs of every proof, in block order.
+func (block *Block) Inputs() ([][]fr.Element, error) {
+	if len(block.Proofs) != len(block.Public) {
+		return nil, fmt.Errorf("block has %d proofs but %d public witnesses", len(block.Proofs), len(block.Public))
+	}
+
+	inputs := make([][]fr.Element, len(block.Public))
+	for i, public := range block.Public {
+		vector, ok := public.Vector().(fr.Vector)
+		if !ok {
+			return nil, fmt.Errorf("public witness %d is not a BN254 witness", i)
+		}
+		inputs[i] = vector
+	}
+
+	return inputs, nil
+}
+
+// InputsHash is the single public input of the aggregate proof: the Poseidon2
+// hash of the public inputs of every proof, in block order. A verifier given
+// the inputs recomputes it to bind them to the aggregate proof.
+func InputsHash(inputs [][]fr.Element) fr.Element {
+	hasher := poseidon2.NewMerkleDamgardHasher()
+
+	for _, vector := range inputs {
+		for i := range vector {
+			bytes := vector[i].Bytes()
+			hasher.Write(bytes[:])
+		}
+	}
+
+	var hash fr.Element
+	hash.SetBytes(hasher.Sum(nil))
+
+	return hash
+}
+
+// ToWitness returns the assignment of the aggregate circuit of the block
+// size.
+func (block *Block) ToWitness() (*circuits.AggregateCircuit, error) {
+	inputs, err := block.Inputs()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("block is empty")
+	}
+
+	assignment := &circuits.AggregateCircuit{
+		Proofs:     make([]circuits.InnerProof, len(block.Proofs)),
+		Inputs:     make([]circuits.InnerWitness, len(block.Public)),
+		InputsHash: InputsHash(inputs),
+	}
+
+	for i := range block.Proofs {
+		if assignment.Proofs[i], err = stdgroth16.ValueOfProof[circuits.InnerG1, circuits.InnerG2](block.Proofs[i]); err != nil {
+			return nil, fmt.Errorf("failed to convert proof %d: %w", i, err)
+		}
+
+		if assignment.Inputs[i], err = stdgroth16.ValueOfWitness[circuits.InnerScalar](block.Public[i]); err != nil {
+			return nil, fmt.Errorf("failed to convert public witness %d: %w", i, err)
+		}
+	}
+
+	return assignment, nil
+}
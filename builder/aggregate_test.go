@@ -0,0 +1,145 @@
+package builder_test
+
+import (
+	"hide-pay/builder"
+	"hide-pay/circuits"
+	"hide-pay/prover"
+	"os"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBlock proves size transfers of the smallest shape for aggregation and
+// returns them with the transfer circuit and its keys.
+func newBlock(t *testing.T, size int) (constraint.ConstraintSystem, prover.ProvingKey, groth16.VerifyingKey, *builder.Block) {
+	cs, err := prover.Compile(transferShape, prover.Groth16)
+	require.NoError(t, err)
+
+	pk, vk, err := prover.Setup(cs, nil)
+	require.NoError(t, err)
+
+	block := new(builder.Block)
+	for i := range size {
+		w := transferWitness(t, int64(i+1))
+
+		proof, err := builder.ProveForAggregation(cs, pk.(groth16.ProvingKey), w)
+		require.NoError(t, err)
+
+		public, err := w.Public()
+		require.NoError(t, err)
+		require.NoError(t, builder.VerifyForAggregation(proof, vk.(groth16.VerifyingKey), public))
+
+		block.Add(proof, public)
+	}
+
+	return cs, pk, vk.(groth16.VerifyingKey), block
+}
+
+var transferShape = prover.Shape{Assets: 1, Depth: 4, Inputs: 1, Outputs: 1}
+
+func transferWitness(t *testing.T, seed int64) witness.Witness {
+	utxo, err := builder.GenerateUTXO(seed, transferShape.Depth, transferShape.Inputs, transferShape.Outputs)
+	require.NoError(t, err)
+
+	w, err := prover.NewWitness(transferShape, utxo)
+	require.NoError(t, err)
+
+	return w
+}
+
+func compileAggregate(t *testing.T, inner constraint.ConstraintSystem, vk groth16.VerifyingKey, size int) constraint.ConstraintSystem {
+	circuit, err := circuits.NewAggregateCircuit(inner, vk, size)
+	require.NoError(t, err)
+
+	cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, circuit)
+	require.NoError(t, err)
+
+	return cs
+}
+
+func TestBlock_Aggregate(t *testing.T) {
+	const size = 2
+
+	inner, pk, vk, block := newBlock(t, size)
+
+	inputs, err := block.Inputs()
+	require.NoError(t, err)
+	require.Len(t, inputs, size)
+	// audit key, nullifier, commitment, two memo hashes and the root
+	assert.Len(t, inputs[0], 7)
+
+	// Emulated pairings make the aggregate circuit large, so the test stops
+	// at solving it; TestBlock_AggregateProve goes on to prove
+	cs := compileAggregate(t, inner, vk, size)
+	require.Equal(t, 1, prover.NbPublicInputs(cs))
+
+	assignment, err := block.ToWitness()
+	require.NoError(t, err)
+	assert.Equal(t, builder.InputsHash(inputs), assignment.InputsHash)
+
+	w, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+	require.NoError(t, cs.IsSolved(w))
+
+	// A hash that leaves out inputs is refused
+	assignment.InputsHash = builder.InputsHash(inputs[:1])
+	w, err = frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+	assert.Error(t, cs.IsSolved(w))
+
+	// So is a proof made for the Solidity verifier, whose commitment is
+	// hashed differently
+	proof, err := prover.Prove(inner, pk, transferWitness(t, 1))
+	require.NoError(t, err)
+	block.Proofs[0] = proof.(groth16.Proof)
+
+	assignment, err = block.ToWitness()
+	require.NoError(t, err)
+	w, err = frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+	assert.Error(t, cs.IsSolved(w))
+}
+
+func TestInputsHash(t *testing.T) {
+	inputs := [][]fr.Element{{fr.NewElement(1), fr.NewElement(2)}, {fr.NewElement(3)}}
+
+	// Block boundaries do not matter, only the order of the inputs
+	assert.Equal(t, builder.InputsHash(inputs), builder.InputsHash([][]fr.Element{{fr.NewElement(1)}, {fr.NewElement(2), fr.NewElement(3)}}))
+	assert.NotEqual(t, builder.InputsHash(inputs), builder.InputsHash([][]fr.Element{{fr.NewElement(3)}, {fr.NewElement(1), fr.NewElement(2)}}))
+}
+
+// TestBlock_AggregateProve runs the aggregate setup and proof, which take
+// several gigabytes and tens of minutes; set AGGREGATE_PROVE=1 to run it.
+func TestBlock_AggregateProve(t *testing.T) {
+	if os.Getenv("AGGREGATE_PROVE") == "" {
+		t.Skip("set AGGREGATE_PROVE=1 to prove an aggregate block")
+	}
+
+	inner, _, vk, block := newBlock(t, 1)
+	cs := compileAggregate(t, inner, vk, 1)
+
+	pk, aggregateVK, err := prover.Setup(cs, nil)
+	require.NoError(t, err)
+
+	assignment, err := block.ToWitness()
+	require.NoError(t, err)
+
+	w, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+
+	proof, err := prover.Prove(cs, pk, w)
+	require.NoError(t, err)
+
+	public, err := w.Public()
+	require.NoError(t, err)
+	require.NoError(t, prover.Verify(proof, aggregateVK, public))
+}
@@ -0,0 +1,145 @@
+package builder
+
+import (
+	"fmt"
+	"hide-pay/circuits"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+)
+
+// AuditMemoRecord is a stored audit memo together with the commitment it opens.
+type AuditMemoRecord struct {
+	Commitment fr.Element
+	Memo       EncryptedMemo
+}
+
+// AuditKeyRotation re-encrypts audit memos from an old auditor key to a new
+// one. Attestor is optional; when set, the report carries a signed statement
+// of the migration.
+type AuditKeyRotation struct {
+	OldSecretKey big.Int
+	NewPublicKey twistededwardbn254.PointAffine
+	Attestor     *Keypair
+}
+
+type RotationFailure struct {
+	Commitment fr.Element
+	Err        error
+}
+
+// RotationAttestation binds the old and new audit keys to the ordered list of
+// migrated commitments.
+type RotationAttestation struct {
+	OldPublicKey twistededwardbn254.PointAffine
+	NewPublicKey twistededwardbn254.PointAffine
+	Commitments  []fr.Element
+	Signature    *Signature
+}
+
+type RotationReport struct {
+	Migrated    []AuditMemoRecord
+	Failed      []RotationFailure
+	Attestation *RotationAttestation
+}
+
+// Rotate decrypts every record with the old key, checks that the opening
+// matches its commitment and re-encrypts it to the new key with a fresh
+// ephemeral key. Records that fail are reported and left out of Migrated, so
+// a rotation never fails as a whole.
+func (rotation *AuditKeyRotation) Rotate(records []AuditMemoRecord) *RotationReport {
+	report := &RotationReport{}
+
+	for i := range records {
+		migrated, err := rotation.rotateRecord(&records[i])
+		if err != nil {
+			report.Failed = append(report.Failed, RotationFailure{
+				Commitment: records[i].Commitment,
+				Err:        err,
+			})
+			continue
+		}
+
+		report.Migrated = append(report.Migrated, *migrated)
+	}
+
+	if rotation.Attestor != nil {
+		commitments := make([]fr.Element, len(report.Migrated))
+		for i := range report.Migrated {
+			commitments[i] = report.Migrated[i].Commitment
+		}
+
+		base := twistededwardbn254.GetEdwardsCurve().Base
+
+		attestation := &RotationAttestation{
+			NewPublicKey: rotation.NewPublicKey,
+			Commitments:  commitments,
+		}
+		attestation.OldPublicKey.ScalarMultiplication(&base, &rotation.OldSecretKey)
+		attestation.Signature = rotation.Attestor.SignDeterministic(attestation.Message())
+
+		report.Attestation = attestation
+	}
+
+	return report
+}
+
+func (rotation *AuditKeyRotation) rotateRecord(record *AuditMemoRecord) (*AuditMemoRecord, error) {
+	commitment, spentKey, err := record.Memo.Decrypt(rotation.OldSecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt audit memo: %w", err)
+	}
+
+	if computed := commitment.Compute(); computed != record.Commitment {
+		return nil, fmt.Errorf("decrypted note does not open commitment %s", record.Commitment.Text(10))
+	}
+
+	ephemeralSecretKey, err := circuits.CreateSeedFromRand()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+
+	newMemo := Memo{
+		SecretKey: ephemeralSecretKey,
+		PublicKey: rotation.NewPublicKey,
+	}
+
+	envelope, err := newMemo.Encrypt(*commitment, *spentKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-encrypt audit memo: %w", err)
+	}
+
+	return &AuditMemoRecord{
+		Commitment: record.Commitment,
+		Memo:       *envelope,
+	}, nil
+}
+
+// Message returns H(OldPublicKey, NewPublicKey, Commitments...), the value
+// signed by the attestor.
+func (attestation *RotationAttestation) Message() fr.Element {
+	hasher := poseidon2.NewMerkleDamgardHasher()
+
+	writePoint(hasher, &attestation.OldPublicKey)
+	writePoint(hasher, &attestation.NewPublicKey)
+
+	for i := range attestation.Commitments {
+		commitmentBytes := attestation.Commitments[i].Bytes()
+		hasher.Write(commitmentBytes[:])
+	}
+
+	message := fr.Element{}
+	message.SetBytes(hasher.Sum(nil))
+
+	return message
+}
+
+func (attestation *RotationAttestation) Verify(publicKey *twistededwardbn254.PointAffine) bool {
+	if attestation.Signature == nil {
+		return false
+	}
+
+	return Verify(attestation.Message(), attestation.Signature, publicKey)
+}
@@ -0,0 +1,114 @@
+package builder_test
+
+import (
+	"hide-pay/builder"
+	"hide-pay/utils"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAuditMemoRecord(t *testing.T, seed int64, auditSecretKey *big.Int) (builder.AuditMemoRecord, *builder.Commitment) {
+	commitment, spentKey := builder.GenerateCommitment(seed)
+
+	memo := &builder.Memo{
+		SecretKey: *big.NewInt(seed + 1),
+		PublicKey: utils.BuildPublicKey(*auditSecretKey),
+	}
+
+	envelope, err := memo.Encrypt(*commitment, *spentKey)
+	require.NoError(t, err)
+
+	return builder.AuditMemoRecord{
+		Commitment: commitment.Compute(),
+		Memo:       *envelope,
+	}, commitment
+}
+
+func TestAuditKeyRotation_Rotate(t *testing.T) {
+	oldSecretKey := big.NewInt(22222)
+	newSecretKey := big.NewInt(33333)
+
+	record1, commitment1 := newAuditMemoRecord(t, 12345, oldSecretKey)
+	record2, commitment2 := newAuditMemoRecord(t, 54321, oldSecretKey)
+
+	rotation := &builder.AuditKeyRotation{
+		OldSecretKey: *oldSecretKey,
+		NewPublicKey: utils.BuildPublicKey(*newSecretKey),
+	}
+
+	report := rotation.Rotate([]builder.AuditMemoRecord{record1, record2})
+	require.Len(t, report.Migrated, 2)
+	assert.Empty(t, report.Failed)
+	assert.Nil(t, report.Attestation)
+
+	for i, expected := range []*builder.Commitment{commitment1, commitment2} {
+		migrated := report.Migrated[i]
+		assert.Equal(t, expected.Compute(), migrated.Commitment)
+
+		decrypted, _, err := migrated.Memo.Decrypt(*newSecretKey)
+		require.NoError(t, err)
+		assert.Equal(t, expected.Compute(), decrypted.Compute())
+
+		_, _, err = migrated.Memo.Decrypt(*oldSecretKey)
+		assert.Error(t, err)
+	}
+}
+
+func TestAuditKeyRotation_ReportsFailures(t *testing.T) {
+	oldSecretKey := big.NewInt(22222)
+
+	valid, _ := newAuditMemoRecord(t, 12345, oldSecretKey)
+	foreign, _ := newAuditMemoRecord(t, 54321, big.NewInt(44444))
+	mismatched, _ := newAuditMemoRecord(t, 11111, oldSecretKey)
+	mismatched.Commitment = fr.NewElement(99999)
+
+	rotation := &builder.AuditKeyRotation{
+		OldSecretKey: *oldSecretKey,
+		NewPublicKey: utils.BuildPublicKey(*big.NewInt(33333)),
+	}
+
+	report := rotation.Rotate([]builder.AuditMemoRecord{valid, foreign, mismatched})
+
+	require.Len(t, report.Migrated, 1)
+	assert.Equal(t, valid.Commitment, report.Migrated[0].Commitment)
+
+	require.Len(t, report.Failed, 2)
+	assert.Equal(t, foreign.Commitment, report.Failed[0].Commitment)
+	assert.Contains(t, report.Failed[0].Err.Error(), "failed to decrypt")
+	assert.Equal(t, mismatched.Commitment, report.Failed[1].Commitment)
+	assert.Contains(t, report.Failed[1].Err.Error(), "does not open commitment")
+}
+
+func TestAuditKeyRotation_Attestation(t *testing.T) {
+	oldSecretKey := big.NewInt(22222)
+
+	record, _ := newAuditMemoRecord(t, 12345, oldSecretKey)
+
+	attestor, err := builder.GenerateKeypair()
+	require.NoError(t, err)
+
+	rotation := &builder.AuditKeyRotation{
+		OldSecretKey: *oldSecretKey,
+		NewPublicKey: utils.BuildPublicKey(*big.NewInt(33333)),
+		Attestor:     attestor,
+	}
+
+	report := rotation.Rotate([]builder.AuditMemoRecord{record})
+	require.NotNil(t, report.Attestation)
+
+	attestation := report.Attestation
+	assert.Equal(t, utils.BuildPublicKey(*oldSecretKey), attestation.OldPublicKey)
+	assert.Equal(t, []fr.Element{record.Commitment}, attestation.Commitments)
+	assert.True(t, attestation.Verify(&attestor.PublicKey))
+
+	other, err := builder.GenerateKeypair()
+	require.NoError(t, err)
+	assert.False(t, attestation.Verify(&other.PublicKey))
+
+	attestation.Commitments[0] = fr.NewElement(99999)
+	assert.False(t, attestation.Verify(&attestor.PublicKey))
+}
@@ -14,28 +14,26 @@ import (
 )
 
 type Commitment struct {
-	Asset       fr.Element
-	Amount      fr.Element
-	OwnerPubKey twistededwardbn254.PointAffine
-	SpentKey    fr.Element
-	// SpentAddress fr.Element
-	SpentSecKey fr.Element
-	ViewPubKey  twistededwardbn254.PointAffine
-	AuditPubKey twistededwardbn254.PointAffine
-	FreezeFlag  fr.Element
-	Blinding    fr.Element
+	Asset        fr.Element
+	Amount       fr.Element
+	OwnerPubKey  twistededwardbn254.PointAffine
+	SpentAddress fr.Element
+	ViewPubKey   twistededwardbn254.PointAffine
+	AuditPubKey  twistededwardbn254.PointAffine
+	FreezeFlag   fr.Element
+	Blinding     fr.Element
 }
 
 func (commitment *Commitment) ToGadget() *circuits.CommitmentGadget {
 	return &circuits.CommitmentGadget{
-		Asset:       commitment.Asset,
-		Amount:      commitment.Amount,
-		OwnerPubKey: [2]frontend.Variable{commitment.OwnerPubKey.X, commitment.OwnerPubKey.Y},
-		SpentSecKey: commitment.SpentSecKey,
-		ViewPubKey:  [2]frontend.Variable{commitment.ViewPubKey.X, commitment.ViewPubKey.Y},
-		AuditPubKey: [2]frontend.Variable{commitment.AuditPubKey.X, commitment.AuditPubKey.Y},
-		FreezeFlag:  commitment.FreezeFlag,
-		Blinding:    commitment.Blinding,
+		Asset:        commitment.Asset,
+		Amount:       commitment.Amount,
+		OwnerPubKey:  [2]frontend.Variable{commitment.OwnerPubKey.X, commitment.OwnerPubKey.Y},
+		SpentAddress: commitment.SpentAddress,
+		ViewPubKey:   [2]frontend.Variable{commitment.ViewPubKey.X, commitment.ViewPubKey.Y},
+		AuditPubKey:  [2]frontend.Variable{commitment.AuditPubKey.X, commitment.AuditPubKey.Y},
+		FreezeFlag:   commitment.FreezeFlag,
+		Blinding:     commitment.Blinding,
 	}
 }
 
@@ -48,6 +46,7 @@ func (commitment *Commitment) String() string {
 	amountStr := fmt.Sprintf("Amount: %s", commitment.Amount.Text(10))
 	blindingStr := fmt.Sprintf("Blinding: %s", commitment.Blinding.Text(10))
 	ownerPubKey := fmt.Sprintf("OwnerPubKey: %s", formatPoint(commitment.OwnerPubKey))
+	spentAddress := fmt.Sprintf("SpentAddress: %s", commitment.SpentAddress.Text(10))
 	viewPubKey := fmt.Sprintf("ViewPubKey: %s", formatPoint(commitment.ViewPubKey))
 	auditPubKey := fmt.Sprintf("AuditPubKey: %s", formatPoint(commitment.AuditPubKey))
 	freezeFlag := fmt.Sprintf("FreezeFlag: %s", commitment.FreezeFlag.Text(10))
@@ -56,25 +55,20 @@ func (commitment *Commitment) String() string {
 		amountStr,
 		blindingStr,
 		ownerPubKey,
+		spentAddress,
 		viewPubKey,
 		auditPubKey,
 		freezeFlag)
 }
 
 func (commitment *Commitment) Compute() fr.Element {
-	spentHasher := poseidon2.NewMerkleDamgardHasher()
-
-	spentSecKeyBytes := commitment.SpentSecKey.Bytes()
-	spentHasher.Write(spentSecKeyBytes[:])
-
-	spentAddress := spentHasher.Sum(nil)
-
 	hasher := poseidon2.NewMerkleDamgardHasher()
 
 	assetBytes := commitment.Asset.Bytes()
 	amountBytes := commitment.Amount.Bytes()
 	ownerPubKeyXBytes := commitment.OwnerPubKey.X.Bytes()
 	ownerPubKeyYBytes := commitment.OwnerPubKey.Y.Bytes()
+	spentAddressBytes := commitment.SpentAddress.Bytes()
 	viewPubKeyXBytes := commitment.ViewPubKey.X.Bytes()
 	viewPubKeyYBytes := commitment.ViewPubKey.Y.Bytes()
 	auditPubKeyXBytes := commitment.AuditPubKey.X.Bytes()
@@ -86,7 +80,7 @@ func (commitment *Commitment) Compute() fr.Element {
 	hasher.Write(amountBytes[:])
 	hasher.Write(ownerPubKeyXBytes[:])
 	hasher.Write(ownerPubKeyYBytes[:])
-	hasher.Write(spentAddress)
+	hasher.Write(spentAddressBytes[:])
 	hasher.Write(viewPubKeyXBytes[:])
 	hasher.Write(viewPubKeyYBytes[:])
 	hasher.Write(auditPubKeyXBytes[:])
@@ -122,16 +116,17 @@ func GenerateCommitment(seed int64) (*Commitment, *fr.Element) {
 	spentSecKeyBigInt := new(big.Int).Rand(rnd, max)
 	spentSecKey := fr.Element{}
 	spentSecKey.SetBigInt(spentSecKeyBigInt)
+	spentAddress := utils.BuildAddress(*spentSecKey.BigInt(new(big.Int)))
 
 	commitment := &Commitment{
-		Asset:       fr.NewElement(asset),
-		Amount:      fr.NewElement(amount),
-		OwnerPubKey: ownerPubKey,
-		SpentSecKey: spentSecKey,
-		ViewPubKey:  viewPubKey,
-		AuditPubKey: auditPubKey,
-		FreezeFlag:  fr.NewElement(0),
-		Blinding:    fr.NewElement(blinding),
+		Asset:        fr.NewElement(asset),
+		Amount:       fr.NewElement(amount),
+		OwnerPubKey:  ownerPubKey,
+		SpentAddress: spentAddress,
+		ViewPubKey:   viewPubKey,
+		AuditPubKey:  auditPubKey,
+		FreezeFlag:   fr.NewElement(0),
+		Blinding:     fr.NewElement(blinding),
 	}
 
 	return commitment, &spentSecKey
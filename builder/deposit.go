@@ -0,0 +1,76 @@
+package builder
+
+import (
+	"fmt"
+	"hide-pay/circuits"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"github.com/consensys/gnark/frontend"
+)
+
+// Deposit shields the amount of Commitment into a new note, with memos as in
+// a transfer.
+type Deposit struct {
+	Commitment                 Commitment
+	SpentKey                   fr.Element
+	EphemeralReceiverSecretKey big.Int
+	EphemeralAuditSecretKey    big.Int
+	AuditPublicKey             twistededwardbn254.PointAffine
+}
+
+func (deposit *Deposit) ToWitness() (*circuits.DepositCircuit, error) {
+	if !deposit.Commitment.FreezeFlag.IsZero() {
+		return nil, fmt.Errorf("deposited note must not be frozen")
+	}
+
+	if !checkAmount(deposit.Commitment.Amount) {
+		return nil, fmt.Errorf("deposited amount exceeds %d bits", circuits.AmountBits)
+	}
+
+	if !CheckSpentAddress(deposit.Commitment.SpentAddress, deposit.SpentKey) {
+		return nil, fmt.Errorf("spent key does not match the spent address of the note")
+	}
+
+	if deposit.Commitment.AuditPubKey != deposit.AuditPublicKey {
+		return nil, fmt.Errorf("deposited note is not for the audit public key of the deposit")
+	}
+
+	ownerMemo, auditMemo, err := encryptMemos(deposit.Commitment, deposit.SpentKey,
+		Memo{SecretKey: deposit.EphemeralReceiverSecretKey, PublicKey: deposit.Commitment.ViewPubKey},
+		Memo{SecretKey: deposit.EphemeralAuditSecretKey, PublicKey: deposit.AuditPublicKey},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &circuits.DepositCircuit{
+		Note:                       *deposit.Commitment.ToGadget(),
+		SpentKey:                   deposit.SpentKey,
+		EphemeralReceiverSecretKey: deposit.EphemeralReceiverSecretKey,
+		EphemeralAuditSecretKey:    deposit.EphemeralAuditSecretKey,
+		AuditPublicKey:             [2]frontend.Variable{deposit.AuditPublicKey.X, deposit.AuditPublicKey.Y},
+
+		Asset:         deposit.Commitment.Asset,
+		Amount:        deposit.Commitment.Amount,
+		Commitment:    deposit.Commitment.Compute(),
+		OwnerMemoHash: ownerMemo.Hash(),
+		AuditMemoHash: auditMemo.Hash(),
+	}, nil
+}
+
+// encryptMemos seals the opening of commitment in its owner and audit memos.
+func encryptMemos(commitment Commitment, spentKey fr.Element, ownerMemo Memo, auditMemo Memo) (*EncryptedMemo, *EncryptedMemo, error) {
+	ownerEnvelope, err := ownerMemo.Encrypt(commitment, spentKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encrypt owner memo: %w", err)
+	}
+
+	auditEnvelope, err := auditMemo.Encrypt(commitment, spentKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encrypt audit memo: %w", err)
+	}
+
+	return ownerEnvelope, auditEnvelope, nil
+}
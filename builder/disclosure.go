@@ -0,0 +1,144 @@
+package builder
+
+import (
+	"fmt"
+	"hide-pay/circuits"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+)
+
+// Disclosure selects which fields of a note to reveal to a counterparty.
+// Context is the value the counterparty asked the proof to be bound to.
+type Disclosure struct {
+	Commitment Commitment
+	Context    fr.Element
+
+	DiscloseAsset  bool
+	DiscloseAmount bool
+	DiscloseOwner  bool
+}
+
+// DisclosureStatement is the public side of a disclosure. Fields that are not
+// disclosed are zero.
+type DisclosureStatement struct {
+	Context    fr.Element
+	Commitment fr.Element
+
+	DiscloseAsset  bool
+	DiscloseAmount bool
+	DiscloseOwner  bool
+
+	Asset       fr.Element
+	Amount      fr.Element
+	OwnerPubKey twistededwardbn254.PointAffine
+}
+
+type DisclosureProof struct {
+	Statement DisclosureStatement
+	Proof     groth16.Proof
+}
+
+func (disclosure *Disclosure) Statement() DisclosureStatement {
+	statement := DisclosureStatement{
+		Context:        disclosure.Context,
+		Commitment:     disclosure.Commitment.Compute(),
+		DiscloseAsset:  disclosure.DiscloseAsset,
+		DiscloseAmount: disclosure.DiscloseAmount,
+		DiscloseOwner:  disclosure.DiscloseOwner,
+	}
+
+	if disclosure.DiscloseAsset {
+		statement.Asset = disclosure.Commitment.Asset
+	}
+
+	if disclosure.DiscloseAmount {
+		statement.Amount = disclosure.Commitment.Amount
+	}
+
+	if disclosure.DiscloseOwner {
+		statement.OwnerPubKey = disclosure.Commitment.OwnerPubKey
+	}
+
+	return statement
+}
+
+func (disclosure *Disclosure) ToWitness() *circuits.DisclosureCircuit {
+	statement := disclosure.Statement()
+
+	witness := statement.ToWitness()
+	witness.Opening = *disclosure.Commitment.ToGadget()
+
+	return witness
+}
+
+// ToWitness returns the public part of the disclosure circuit assignment.
+func (statement *DisclosureStatement) ToWitness() *circuits.DisclosureCircuit {
+	return &circuits.DisclosureCircuit{
+		Context:        statement.Context,
+		Commitment:     statement.Commitment,
+		DiscloseAsset:  boolToVariable(statement.DiscloseAsset),
+		DiscloseAmount: boolToVariable(statement.DiscloseAmount),
+		DiscloseOwner:  boolToVariable(statement.DiscloseOwner),
+		Asset:          statement.Asset,
+		Amount:         statement.Amount,
+		OwnerPubKey:    [2]frontend.Variable{statement.OwnerPubKey.X, statement.OwnerPubKey.Y},
+	}
+}
+
+func (disclosure *Disclosure) Prove(cs constraint.ConstraintSystem, pk groth16.ProvingKey) (*DisclosureProof, error) {
+	witness, err := frontend.NewWitness(disclosure.ToWitness(), ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("failed to build witness: %w", err)
+	}
+
+	proof, err := groth16.Prove(cs, pk, witness)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prove: %w", err)
+	}
+
+	return &DisclosureProof{
+		Statement: disclosure.Statement(),
+		Proof:     proof,
+	}, nil
+}
+
+// VerifyDisclosure checks that the disclosure was made for context, that the
+// disclosed commitment is the leaf of merkleProof, that the proof reaches
+// root, and that the disclosure proof holds for the statement.
+func VerifyDisclosure(vk groth16.VerifyingKey, disclosure *DisclosureProof, merkleProof *MerkleProof, root fr.Element, context fr.Element) error {
+	if disclosure.Statement.Context != context {
+		return fmt.Errorf("disclosure is for context %s, not %s", disclosure.Statement.Context.Text(10), context.Text(10))
+	}
+
+	if leaf := merkleProof.Leaf(); leaf != disclosure.Statement.Commitment {
+		return fmt.Errorf("merkle proof is for commitment %s, not %s", leaf.Text(10), disclosure.Statement.Commitment.Text(10))
+	}
+
+	if computed := merkleProof.Verify(); computed != root {
+		return fmt.Errorf("commitment is not in the tree with root %s", root.Text(10))
+	}
+
+	publicWitness, err := frontend.NewWitness(disclosure.Statement.ToWitness(), ecc.BN254.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		return fmt.Errorf("failed to build public witness: %w", err)
+	}
+
+	if err := groth16.Verify(disclosure.Proof, vk, publicWitness); err != nil {
+		return fmt.Errorf("invalid disclosure proof: %w", err)
+	}
+
+	return nil
+}
+
+func boolToVariable(b bool) frontend.Variable {
+	if b {
+		return 1
+	}
+
+	return 0
+}
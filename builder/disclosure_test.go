@@ -0,0 +1,79 @@
+package builder_test
+
+import (
+	"hide-pay/builder"
+	"hide-pay/circuits"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisclosure_ProveAndVerify(t *testing.T) {
+	cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &circuits.DisclosureCircuit{})
+	require.NoError(t, err)
+
+	pk, vk, err := groth16.Setup(cs)
+	require.NoError(t, err)
+
+	commitment, _ := builder.GenerateCommitment(12345)
+	other, _ := builder.GenerateCommitment(54321)
+
+	tree := builder.NewMerkleTree(4, poseidon2.NewMerkleDamgardHasher())
+	tree.Build([]fr.Element{other.Compute(), commitment.Compute()})
+	root := tree.GetRoot()
+	merkleProof := tree.GetProof(1)
+
+	// The verifier's challenge
+	context := fr.NewElement(777)
+
+	disclosure := builder.Disclosure{
+		Commitment:     *commitment,
+		Context:        context,
+		DiscloseAsset:  true,
+		DiscloseAmount: true,
+	}
+
+	proof, err := disclosure.Prove(cs, pk)
+	require.NoError(t, err)
+
+	assert.Equal(t, commitment.Asset, proof.Statement.Asset)
+	assert.Equal(t, commitment.Amount, proof.Statement.Amount)
+	assert.True(t, proof.Statement.OwnerPubKey.X.IsZero())
+
+	require.NoError(t, builder.VerifyDisclosure(vk, proof, &merkleProof, root, context))
+
+	// Proof for a different leaf
+	otherProof := tree.GetProof(0)
+	assert.Error(t, builder.VerifyDisclosure(vk, proof, &otherProof, root, context))
+
+	// Wrong root
+	assert.Error(t, builder.VerifyDisclosure(vk, proof, &merkleProof, fr.NewElement(1), context))
+
+	// Tampered disclosed value
+	tampered := *proof
+	tampered.Statement.Amount.SetUint64(1)
+	assert.Error(t, builder.VerifyDisclosure(vk, &tampered, &merkleProof, root, context))
+
+	// Revealing a field that was proven hidden
+	tampered = *proof
+	tampered.Statement.DiscloseOwner = true
+	tampered.Statement.OwnerPubKey = commitment.OwnerPubKey
+	assert.Error(t, builder.VerifyDisclosure(vk, &tampered, &merkleProof, root, context))
+
+	// A proof made for one verifier is refused by another
+	fresh := fr.NewElement(778)
+	assert.ErrorContains(t, builder.VerifyDisclosure(vk, proof, &merkleProof, root, fresh), "context")
+
+	// and relabelling its context breaks the proof
+	tampered = *proof
+	tampered.Statement.Context = fresh
+	err = builder.VerifyDisclosure(vk, &tampered, &merkleProof, root, fresh)
+	assert.ErrorContains(t, err, "invalid disclosure proof")
+}
@@ -0,0 +1,144 @@
+package builder
+
+import (
+	"fmt"
+	"hide-pay/circuits"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	poseidonbn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	"github.com/consensys/gnark/frontend"
+)
+
+// DuplexCipher is the native counterpart of circuits.DuplexCipherGadget: a
+// Poseidon2 duplex of width Width and rate Rate, or Width-1 when Rate is zero.
+type DuplexCipher struct {
+	Key   [2]fr.Element
+	Width int
+	Rate  int
+}
+
+func (cipher *DuplexCipher) ToGadget() *circuits.DuplexCipherGadget {
+	return &circuits.DuplexCipherGadget{
+		Key:   [2]frontend.Variable{cipher.Key[0], cipher.Key[1]},
+		Width: cipher.Width,
+		Rate:  cipher.Rate,
+	}
+}
+
+type duplexState struct {
+	perm  *poseidonbn254.Permutation
+	state []fr.Element
+	rate  int
+}
+
+// newDuplexState seeds the capacity and absorbs the key and associated data.
+func (cipher *DuplexCipher) newDuplexState(ad []fr.Element, plaintextSize int) (*duplexState, error) {
+	nbFullRounds, nbPartialRounds, err := circuits.DuplexParameters(cipher.Width)
+	if err != nil {
+		return nil, err
+	}
+
+	rate, err := circuits.DuplexRate(cipher.Width, cipher.Rate)
+	if err != nil {
+		return nil, err
+	}
+
+	duplex := &duplexState{
+		perm:  poseidonbn254.NewPermutation(cipher.Width, nbFullRounds, nbPartialRounds),
+		state: make([]fr.Element, cipher.Width),
+		rate:  rate,
+	}
+	duplex.state[duplex.rate].SetBigInt(circuits.DuplexIV(rate, len(ad), plaintextSize))
+
+	if err := duplex.absorb(cipher.Key[:]); err != nil {
+		return nil, fmt.Errorf("failed to absorb key: %w", err)
+	}
+
+	if err := duplex.absorb(ad); err != nil {
+		return nil, fmt.Errorf("failed to absorb associated data: %w", err)
+	}
+
+	return duplex, nil
+}
+
+func (duplex *duplexState) absorb(input []fr.Element) error {
+	for begin := 0; begin < len(input); begin += duplex.rate {
+		end := min(begin+duplex.rate, len(input))
+
+		for j := begin; j < end; j++ {
+			duplex.state[j-begin].Add(&duplex.state[j-begin], &input[j])
+		}
+
+		if err := duplex.perm.Permutation(duplex.state); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cipher *DuplexCipher) Encrypt(
+	ad []fr.Element,
+	plaintext []fr.Element,
+) ([]fr.Element, error) {
+	duplex, err := cipher.newDuplexState(ad, len(plaintext))
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := make([]fr.Element, len(plaintext)+1)
+
+	for begin := 0; begin < len(plaintext); begin += duplex.rate {
+		end := min(begin+duplex.rate, len(plaintext))
+
+		for j := begin; j < end; j++ {
+			ciphertext[j].Add(&duplex.state[j-begin], &plaintext[j])
+			duplex.state[j-begin] = ciphertext[j]
+		}
+
+		if err := duplex.perm.Permutation(duplex.state); err != nil {
+			return nil, fmt.Errorf("failed to permute: %w", err)
+		}
+	}
+
+	// HMAC
+	ciphertext[len(ciphertext)-1] = duplex.state[0]
+
+	return ciphertext, nil
+}
+
+func (cipher *DuplexCipher) Decrypt(
+	ad []fr.Element,
+	ciphertext []fr.Element,
+) ([]fr.Element, error) {
+	if len(ciphertext) == 0 {
+		return nil, fmt.Errorf("ciphertext must have at least one element")
+	}
+
+	duplex, err := cipher.newDuplexState(ad, len(ciphertext)-1)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := make([]fr.Element, len(ciphertext)-1)
+
+	for begin := 0; begin < len(plaintext); begin += duplex.rate {
+		end := min(begin+duplex.rate, len(plaintext))
+
+		for j := begin; j < end; j++ {
+			plaintext[j].Sub(&ciphertext[j], &duplex.state[j-begin])
+			duplex.state[j-begin] = ciphertext[j]
+		}
+
+		if err := duplex.perm.Permutation(duplex.state); err != nil {
+			return nil, fmt.Errorf("failed to permute: %w", err)
+		}
+	}
+
+	// HMAC
+	if duplex.state[0] != ciphertext[len(ciphertext)-1] {
+		return nil, fmt.Errorf("HMAC verification failed")
+	}
+
+	return plaintext, nil
+}
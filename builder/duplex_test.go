@@ -0,0 +1,124 @@
+package builder_test
+
+import (
+	"hide-pay/builder"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newElements(values ...uint64) []fr.Element {
+	elements := make([]fr.Element, len(values))
+	for i := range values {
+		elements[i] = fr.NewElement(values[i])
+	}
+
+	return elements
+}
+
+func TestDuplexCipher_EncryptDecrypt(t *testing.T) {
+	ad := newElements(10, 20)
+
+	for _, shape := range []struct{ width, rate int }{{2, 0}, {3, 0}, {3, 1}, {3, 2}} {
+		width := shape.width
+		cipher := &builder.DuplexCipher{
+			Key:   [2]fr.Element{fr.NewElement(12345), fr.NewElement(67890)},
+			Width: width,
+			Rate:  shape.rate,
+		}
+
+		// Lengths around the rate boundary
+		for size := 0; size <= 5; size++ {
+			plaintext := newElements(100, 200, 300, 400, 500)[:size]
+
+			ciphertext, err := cipher.Encrypt(ad, plaintext)
+			require.NoError(t, err)
+			require.Len(t, ciphertext, size+1)
+
+			decrypted, err := cipher.Decrypt(ad, ciphertext)
+			require.NoError(t, err)
+			assert.Equal(t, plaintext, decrypted, "width %d, rate %d, size %d", width, shape.rate, size)
+		}
+	}
+}
+
+func TestDuplexCipher_Rate(t *testing.T) {
+	ad := newElements(10, 20)
+	plaintext := newElements(100, 200, 300)
+
+	narrow := &builder.DuplexCipher{
+		Key:   [2]fr.Element{fr.NewElement(12345), fr.NewElement(67890)},
+		Width: 3,
+		Rate:  1,
+	}
+
+	// The default rate of width 3 is 2
+	wide := *narrow
+	wide.Rate = 0
+	explicit := *narrow
+	explicit.Rate = 2
+
+	narrowCiphertext, err := narrow.Encrypt(ad, plaintext)
+	require.NoError(t, err)
+	wideCiphertext, err := wide.Encrypt(ad, plaintext)
+	require.NoError(t, err)
+	explicitCiphertext, err := explicit.Encrypt(ad, plaintext)
+	require.NoError(t, err)
+
+	assert.Equal(t, wideCiphertext, explicitCiphertext)
+	assert.NotEqual(t, narrowCiphertext, wideCiphertext)
+
+	// A ciphertext only opens at the rate it was made with
+	_, err = wide.Decrypt(ad, narrowCiphertext)
+	assert.Error(t, err)
+}
+
+func TestDuplexCipher_Decrypt_Tampered(t *testing.T) {
+	cipher := &builder.DuplexCipher{
+		Key:   [2]fr.Element{fr.NewElement(12345), fr.NewElement(67890)},
+		Width: 3,
+	}
+
+	ad := newElements(10, 20)
+	plaintext := newElements(100, 200, 300)
+
+	ciphertext, err := cipher.Encrypt(ad, plaintext)
+	require.NoError(t, err)
+
+	for i := range ciphertext {
+		tampered := append([]fr.Element{}, ciphertext...)
+		tampered[i].SetOne()
+
+		_, err := cipher.Decrypt(ad, tampered)
+		assert.Error(t, err, "element %d", i)
+	}
+
+	_, err = cipher.Decrypt(newElements(10, 21), ciphertext)
+	assert.Error(t, err)
+
+	// Truncation changes the IV and fails the tag check
+	_, err = cipher.Decrypt(ad, append(append([]fr.Element{}, ciphertext[:2]...), ciphertext[3]))
+	assert.Error(t, err)
+
+	wrongKey := *cipher
+	wrongKey.Key[1] = fr.NewElement(1)
+	_, err = wrongKey.Decrypt(ad, ciphertext)
+	assert.Error(t, err)
+}
+
+func TestDuplexCipher_UnsupportedWidth(t *testing.T) {
+	cipher := &builder.DuplexCipher{Width: 4}
+
+	_, err := cipher.Encrypt(nil, newElements(1))
+	assert.Error(t, err)
+
+	// The rate must leave at least one capacity element
+	for _, rate := range []int{-1, 3, 4} {
+		cipher := &builder.DuplexCipher{Width: 3, Rate: rate}
+
+		_, err := cipher.Encrypt(nil, newElements(1))
+		assert.Error(t, err, "rate %d", rate)
+	}
+}
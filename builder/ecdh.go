@@ -1,6 +1,7 @@
 package builder
 
 import (
+	"fmt"
 	"hide-pay/circuits"
 	"math/big"
 
@@ -24,6 +25,20 @@ func NewECDH(receiverSecretKey big.Int, senderSecretKey big.Int) *ECDH {
 	}
 }
 
+// NewECDHFromPublicKey pairs a local secret key with a peer's public key after
+// checking that the key is a valid subgroup point.
+func NewECDHFromPublicKey(publicKey twistededwardbn254.PointAffine, secretKey big.Int) (*ECDH, error) {
+	if err := ValidatePublicKey(&publicKey); err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	return &ECDH{
+		PublicKey: publicKey,
+		SecretKey: secretKey,
+	}, nil
+}
+
+// Compute does not validate PublicKey; build peer keys with NewECDHFromPublicKey.
 func (ecdh *ECDH) Compute() twistededwardbn254.PointAffine {
 	sharedKey := twistededwardbn254.PointAffine{}
 	sharedKey.ScalarMultiplication(&ecdh.PublicKey, &ecdh.SecretKey)
@@ -0,0 +1,117 @@
+package builder
+
+import (
+	"fmt"
+	"hide-pay/circuits"
+	"hide-pay/utils"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"github.com/consensys/gnark/frontend"
+)
+
+// Freeze replaces a note by a copy with its freeze flag set, or cleared when
+// Frozen is false, and a new blinding. FreezeKey, AssetAuditPublicKey and
+// AssetRandom open the asset id of the note, see AssetID.
+type Freeze struct {
+	Nullifier                  Nullifier
+	MerkleProof                MerkleProof
+	FreezeKey                  fr.Element
+	AssetAuditPublicKey        twistededwardbn254.PointAffine
+	AssetRandom                fr.Element
+	Frozen                     bool
+	Blinding                   fr.Element
+	EphemeralReceiverSecretKey big.Int
+	EphemeralAuditSecretKey    big.Int
+}
+
+// Note returns the replacement note.
+func (freeze *Freeze) Note() Commitment {
+	note := freeze.Nullifier.Commitment
+	note.Blinding = freeze.Blinding
+	note.FreezeFlag = fr.NewElement(0)
+	if freeze.Frozen {
+		note.FreezeFlag = fr.NewElement(1)
+	}
+
+	return note
+}
+
+// FreezeAddress is the address of a freeze key, H(FreezeKey).
+func FreezeAddress(freezeKey fr.Element) fr.Element {
+	return utils.BuildAddress(*freezeKey.BigInt(new(big.Int)))
+}
+
+// AssetID is the id of the asset audited by auditPublicKey and frozen by the
+// key of freezeAddress, H(AuditPublicKey, FreezeAddr, Random).
+func AssetID(auditPublicKey twistededwardbn254.PointAffine, freezeAddress fr.Element, random fr.Element) fr.Element {
+	hasher := poseidon2.NewMerkleDamgardHasher()
+
+	auditPubKeyXBytes := auditPublicKey.X.Bytes()
+	auditPubKeyYBytes := auditPublicKey.Y.Bytes()
+	freezeAddressBytes := freezeAddress.Bytes()
+	randomBytes := random.Bytes()
+
+	hasher.Write(auditPubKeyXBytes[:])
+	hasher.Write(auditPubKeyYBytes[:])
+	hasher.Write(freezeAddressBytes[:])
+
+	resBytes := hasher.Sum(randomBytes[:])
+
+	res := fr.Element{}
+	res.Unmarshal(resBytes)
+
+	return res
+}
+
+func (freeze *Freeze) ToWitness() (*circuits.FreezeCircuit, error) {
+	if err := checkSpendable(&freeze.Nullifier, &freeze.MerkleProof); err != nil {
+		return nil, err
+	}
+
+	if freeze.Frozen != freeze.Nullifier.FreezeFlag.IsZero() {
+		if freeze.Frozen {
+			return nil, fmt.Errorf("note is already frozen")
+		}
+		return nil, fmt.Errorf("note is not frozen")
+	}
+
+	if AssetID(freeze.AssetAuditPublicKey, FreezeAddress(freeze.FreezeKey), freeze.AssetRandom) != freeze.Nullifier.Asset {
+		return nil, fmt.Errorf("freeze key is not the one of the note's asset")
+	}
+
+	note := freeze.Note()
+
+	ownerMemo, auditMemo, err := encryptMemos(note, freeze.Nullifier.SpentPrivateKey,
+		Memo{SecretKey: freeze.EphemeralReceiverSecretKey, PublicKey: note.ViewPubKey},
+		Memo{SecretKey: freeze.EphemeralAuditSecretKey, PublicKey: note.AuditPubKey},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	merkleProof := freeze.MerkleProof.ToGadget()
+
+	return &circuits.FreezeCircuit{
+		Note:             *freeze.Nullifier.ToGadget(),
+		MerkleProofPath:  merkleProof.Path,
+		MerkleProofIndex: merkleProof.Leaf,
+
+		FreezeKey:           freeze.FreezeKey,
+		AssetAuditPublicKey: [2]frontend.Variable{freeze.AssetAuditPublicKey.X, freeze.AssetAuditPublicKey.Y},
+		AssetRandom:         freeze.AssetRandom,
+
+		Blinding:                   freeze.Blinding,
+		EphemeralReceiverSecretKey: freeze.EphemeralReceiverSecretKey,
+		EphemeralAuditSecretKey:    freeze.EphemeralAuditSecretKey,
+
+		FreezeFlag:    note.FreezeFlag,
+		Nullifier:     freeze.Nullifier.Compute(),
+		MerkleRoot:    freeze.MerkleProof.Verify(),
+		Commitment:    note.Compute(),
+		OwnerMemoHash: ownerMemo.Hash(),
+		AuditMemoHash: auditMemo.Hash(),
+	}, nil
+}
@@ -7,6 +7,7 @@ import (
 	"math/big"
 
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
 	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
 	"github.com/consensys/gnark/frontend"
 )
@@ -16,6 +17,19 @@ type Memo struct {
 	PublicKey twistededwardbn254.PointAffine
 }
 
+// NewMemo prepares an encryption to publicKey with the ephemeral secretKey,
+// checking that the receiver key is a valid subgroup point.
+func NewMemo(secretKey big.Int, publicKey twistededwardbn254.PointAffine) (*Memo, error) {
+	if err := ValidatePublicKey(&publicKey); err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	return &Memo{
+		SecretKey: secretKey,
+		PublicKey: publicKey,
+	}, nil
+}
+
 func (memo *Memo) ToGadget() *circuits.MemoGadget {
 	return &circuits.MemoGadget{
 		EphemeralSecretKey: memo.SecretKey,
@@ -23,10 +37,12 @@ func (memo *Memo) ToGadget() *circuits.MemoGadget {
 	}
 }
 
-func (memo *Memo) Encrypt(commitment Commitment, spentKey fr.Element) (*twistededwardbn254.PointAffine, []fr.Element, error) {
-	ecdh := ECDH{
-		PublicKey: memo.PublicKey,
-		SecretKey: memo.SecretKey,
+// Encrypt seals the opening of commitment to PublicKey using SecretKey as the
+// ephemeral key. The ephemeral public key is the associated data.
+func (memo *Memo) Encrypt(commitment Commitment, spentKey fr.Element) (*EncryptedMemo, error) {
+	ecdh, err := NewECDHFromPublicKey(memo.PublicKey, memo.SecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid receiver key: %w", err)
 	}
 
 	sharedKey := ecdh.Compute()
@@ -38,12 +54,39 @@ func (memo *Memo) Encrypt(commitment Commitment, spentKey fr.Element) (*twistede
 	basePoint := twistededwardbn254.GetEdwardsCurve().Base
 	ephemeralPublicKey := basePoint.ScalarMultiplication(&basePoint, &memo.SecretKey)
 
-	ad := []fr.Element{
-		ephemeralPublicKey.X,
-		ephemeralPublicKey.Y,
+	ciphertext, err := streamCipher.Encrypt(memoAssociatedData(ephemeralPublicKey), memoPlaintext(&commitment, spentKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
 	}
 
-	plaintext := []fr.Element{
+	return &EncryptedMemo{
+		Version:            MemoVersion,
+		EphemeralPublicKey: *ephemeralPublicKey,
+		ViewTag:            ComputeViewTag(&sharedKey),
+		Ciphertext:         ciphertext[:len(ciphertext)-1],
+		Tag:                ciphertext[len(ciphertext)-1],
+	}, nil
+}
+
+// ComputeViewTag returns H(domain, S.x, S.y) for the ECDH shared key S. It is
+// published with the memo so a wallet can discard memos addressed to someone
+// else after a single scalar multiplication, without running the cipher.
+func ComputeViewTag(sharedKey *twistededwardbn254.PointAffine) fr.Element {
+	hasher := poseidon2.NewMerkleDamgardHasher()
+
+	domain := fr.NewElement(circuits.ViewTagDomain)
+	domainBytes := domain.Bytes()
+	hasher.Write(domainBytes[:])
+	writePoint(hasher, sharedKey)
+
+	viewTag := fr.Element{}
+	viewTag.SetBytes(hasher.Sum(nil))
+
+	return viewTag
+}
+
+func memoPlaintext(commitment *Commitment, spentKey fr.Element) []fr.Element {
+	return []fr.Element{
 		commitment.Asset,
 		commitment.Amount,
 		commitment.Blinding,
@@ -56,39 +99,22 @@ func (memo *Memo) Encrypt(commitment Commitment, spentKey fr.Element) (*twistede
 		commitment.AuditPubKey.Y,
 		commitment.FreezeFlag,
 	}
-
-	ciphertext, err := streamCipher.Encrypt(ad, plaintext)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to encrypt: %w", err)
-	}
-
-	return ephemeralPublicKey, ciphertext, nil
 }
 
-func (memo *Memo) Decrypt(ciphertext []fr.Element) (*Commitment, *fr.Element, error) {
-	ecdh := ECDH{
-		PublicKey: memo.PublicKey,
-		SecretKey: memo.SecretKey,
-	}
-
-	sharedKey := ecdh.Compute()
-
-	streamCipher := StreamCipher{
-		Key: [2]fr.Element{sharedKey.X, sharedKey.Y},
-	}
-
-	ad := []fr.Element{
-		memo.PublicKey.X,
-		memo.PublicKey.Y,
+func memoAssociatedData(ephemeralPublicKey *twistededwardbn254.PointAffine) []fr.Element {
+	return []fr.Element{
+		ephemeralPublicKey.X,
+		ephemeralPublicKey.Y,
 	}
+}
 
-	plaintext, err := streamCipher.Decrypt(ad, ciphertext)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to decrypt: %w", err)
+func openMemo(plaintext []fr.Element) (*Commitment, *fr.Element, error) {
+	if len(plaintext) != memoPlaintextSize {
+		return nil, nil, fmt.Errorf("memo plaintext must have %d elements, got %d", memoPlaintextSize, len(plaintext))
 	}
 
-	spentAddressBigInt := plaintext[5].BigInt(new(big.Int))
-	spentAddress := utils.BuildAddress(*spentAddressBigInt)
+	spentKeyBigInt := plaintext[5].BigInt(new(big.Int))
+	spentAddress := utils.BuildAddress(*spentKeyBigInt)
 
 	return &Commitment{
 		Asset:    plaintext[0],
@@ -0,0 +1,385 @@
+package builder
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hide-pay/circuits"
+	"math/big"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+)
+
+// MemoVersion is the only EncryptedMemo layout understood by this package.
+// Version 2 added the view tag.
+const MemoVersion uint8 = circuits.MemoVersion
+
+// memoPlaintextSize is the number of field elements in a memo opening.
+const memoPlaintextSize = 11
+
+// EncryptedMemo is a self-describing memo ciphertext. The ephemeral public key
+// is both the ECDH peer key and the associated data of the stream cipher.
+//
+// The canonical binary layout is
+//
+//	version (1) || ephemeral key, compressed (32) || view tag (32) ||
+//	count, big-endian (4) || ciphertext (32 * count) || tag (32)
+//
+// with every field element in canonical big-endian form.
+type EncryptedMemo struct {
+	Version            uint8
+	EphemeralPublicKey twistededwardbn254.PointAffine
+	ViewTag            fr.Element
+	Ciphertext         []fr.Element
+	Tag                fr.Element
+}
+
+var ErrViewTagMismatch = errors.New("view tag does not match")
+
+// CheckViewTag runs ECDH against the ephemeral key and reports whether the memo
+// is addressed to secretKey. The shared key is returned so a match can be
+// opened with DecryptWithSharedKey without repeating the scalar multiplication.
+func (envelope *EncryptedMemo) CheckViewTag(secretKey big.Int) (twistededwardbn254.PointAffine, bool, error) {
+	ecdh, err := NewECDHFromPublicKey(envelope.EphemeralPublicKey, secretKey)
+	if err != nil {
+		return twistededwardbn254.PointAffine{}, false, fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+
+	sharedKey := ecdh.Compute()
+
+	return sharedKey, ComputeViewTag(&sharedKey) == envelope.ViewTag, nil
+}
+
+// Decrypt opens the memo with the recipient's secret key and returns the note
+// and its spent key.
+func (envelope *EncryptedMemo) Decrypt(secretKey big.Int) (*Commitment, *fr.Element, error) {
+	ecdh, err := NewECDHFromPublicKey(envelope.EphemeralPublicKey, secretKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+
+	return envelope.DecryptWithSharedKey(ecdh.Compute())
+}
+
+// DecryptWithSharedKey opens the memo with an ECDH shared key obtained
+// elsewhere, e.g. combined from threshold partial decryptions.
+func (envelope *EncryptedMemo) DecryptWithSharedKey(sharedKey twistededwardbn254.PointAffine) (*Commitment, *fr.Element, error) {
+	if envelope.Version != MemoVersion {
+		return nil, nil, fmt.Errorf("unsupported memo version %d", envelope.Version)
+	}
+
+	if ComputeViewTag(&sharedKey) != envelope.ViewTag {
+		return nil, nil, ErrViewTagMismatch
+	}
+
+	streamCipher := StreamCipher{
+		Key: [2]fr.Element{sharedKey.X, sharedKey.Y},
+	}
+
+	plaintext, err := streamCipher.Decrypt(memoAssociatedData(&envelope.EphemeralPublicKey), envelope.Elements())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	return openMemo(plaintext)
+}
+
+// Elements returns the ciphertext followed by the tag, the form produced by
+// StreamCipher.Encrypt.
+func (envelope *EncryptedMemo) Elements() []fr.Element {
+	elements := make([]fr.Element, 0, len(envelope.Ciphertext)+1)
+	elements = append(elements, envelope.Ciphertext...)
+
+	return append(elements, envelope.Tag)
+}
+
+// Hash returns H(Version, EphemeralPublicKey.X, EphemeralPublicKey.Y,
+// ViewTag, Ciphertext..., Tag), the memo commitment exposed by MemoGadget. It
+// covers every field of MarshalWords, with the ephemeral key uncompressed, so
+// a memo cannot be altered after proving without changing its hash.
+func (envelope *EncryptedMemo) Hash() fr.Element {
+	hasher := poseidon2.NewMerkleDamgardHasher()
+
+	version := fr.NewElement(uint64(envelope.Version))
+	versionBytes := version.Bytes()
+	hasher.Write(versionBytes[:])
+
+	ephemeralXBytes := envelope.EphemeralPublicKey.X.Bytes()
+	ephemeralYBytes := envelope.EphemeralPublicKey.Y.Bytes()
+	hasher.Write(ephemeralXBytes[:])
+	hasher.Write(ephemeralYBytes[:])
+
+	viewTagBytes := envelope.ViewTag.Bytes()
+	hasher.Write(viewTagBytes[:])
+
+	for _, element := range envelope.Elements() {
+		elementBytes := element.Bytes()
+		hasher.Write(elementBytes[:])
+	}
+
+	hash := fr.Element{}
+	hash.SetBytes(hasher.Sum(nil))
+
+	return hash
+}
+
+func (envelope *EncryptedMemo) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 1+2*fr.Bytes+4+fr.Bytes*(len(envelope.Ciphertext)+1))
+
+	buf = append(buf, envelope.Version)
+
+	ephemeralPublicKey := envelope.EphemeralPublicKey.Bytes()
+	buf = append(buf, ephemeralPublicKey[:]...)
+
+	viewTag := envelope.ViewTag.Bytes()
+	buf = append(buf, viewTag[:]...)
+
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(envelope.Ciphertext)))
+
+	for i := range envelope.Ciphertext {
+		elementBytes := envelope.Ciphertext[i].Bytes()
+		buf = append(buf, elementBytes[:]...)
+	}
+
+	tagBytes := envelope.Tag.Bytes()
+	buf = append(buf, tagBytes[:]...)
+
+	return buf, nil
+}
+
+func (envelope *EncryptedMemo) UnmarshalBinary(data []byte) error {
+	header := 1 + 2*fr.Bytes + 4
+	if len(data) < header+fr.Bytes {
+		return fmt.Errorf("memo envelope too short: %d bytes", len(data))
+	}
+
+	version := data[0]
+	if version != MemoVersion {
+		return fmt.Errorf("unsupported memo version %d", version)
+	}
+
+	ephemeralPublicKey, err := decodePoint(data[1 : 1+fr.Bytes])
+	if err != nil {
+		return fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+
+	var viewTag fr.Element
+	if err := viewTag.SetBytesCanonical(data[1+fr.Bytes : 1+2*fr.Bytes]); err != nil {
+		return fmt.Errorf("invalid view tag: %w", err)
+	}
+
+	count := int(binary.BigEndian.Uint32(data[1+2*fr.Bytes : header]))
+	if len(data) != header+fr.Bytes*(count+1) {
+		return fmt.Errorf("memo envelope length %d does not match %d ciphertext elements", len(data), count)
+	}
+
+	ciphertext := make([]fr.Element, count)
+	for i := range ciphertext {
+		offset := header + i*fr.Bytes
+		if err := ciphertext[i].SetBytesCanonical(data[offset : offset+fr.Bytes]); err != nil {
+			return fmt.Errorf("invalid ciphertext element %d: %w", i, err)
+		}
+	}
+
+	var tag fr.Element
+	if err := tag.SetBytesCanonical(data[len(data)-fr.Bytes:]); err != nil {
+		return fmt.Errorf("invalid tag: %w", err)
+	}
+
+	envelope.Version = version
+	envelope.EphemeralPublicKey = ephemeralPublicKey
+	envelope.ViewTag = viewTag
+	envelope.Ciphertext = ciphertext
+	envelope.Tag = tag
+
+	return nil
+}
+
+// MarshalWords encodes the memo as the bytes32[] carried by the contract:
+// the version right-aligned in the first word, then the compressed ephemeral
+// key, the view tag, the ciphertext and the tag.
+func (envelope *EncryptedMemo) MarshalWords() [][32]byte {
+	words := make([][32]byte, 0, 4+len(envelope.Ciphertext))
+
+	var version [32]byte
+	version[31] = envelope.Version
+	words = append(words, version)
+
+	words = append(words, envelope.EphemeralPublicKey.Bytes())
+	words = append(words, envelope.ViewTag.Bytes())
+
+	for i := range envelope.Ciphertext {
+		words = append(words, envelope.Ciphertext[i].Bytes())
+	}
+
+	return append(words, envelope.Tag.Bytes())
+}
+
+func (envelope *EncryptedMemo) UnmarshalWords(words [][32]byte) error {
+	if len(words) < 4 {
+		return fmt.Errorf("memo too short: %d words", len(words))
+	}
+
+	var zero [31]byte
+	if string(words[0][:31]) != string(zero[:]) || words[0][31] != MemoVersion {
+		return fmt.Errorf("unsupported memo version word %x", words[0])
+	}
+
+	ephemeralPublicKey, err := decodePoint(words[1][:])
+	if err != nil {
+		return fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+
+	var viewTag fr.Element
+	if err := viewTag.SetBytesCanonical(words[2][:]); err != nil {
+		return fmt.Errorf("invalid view tag: %w", err)
+	}
+
+	ciphertext := make([]fr.Element, len(words)-4)
+	for i := range ciphertext {
+		if err := ciphertext[i].SetBytesCanonical(words[3+i][:]); err != nil {
+			return fmt.Errorf("invalid ciphertext element %d: %w", i, err)
+		}
+	}
+
+	var tag fr.Element
+	if err := tag.SetBytesCanonical(words[len(words)-1][:]); err != nil {
+		return fmt.Errorf("invalid tag: %w", err)
+	}
+
+	envelope.Version = MemoVersion
+	envelope.EphemeralPublicKey = ephemeralPublicKey
+	envelope.ViewTag = viewTag
+	envelope.Ciphertext = ciphertext
+	envelope.Tag = tag
+
+	return nil
+}
+
+type encryptedMemoJSON struct {
+	Version            uint8    `json:"version"`
+	EphemeralPublicKey string   `json:"ephemeralPublicKey"`
+	ViewTag            string   `json:"viewTag"`
+	Ciphertext         []string `json:"ciphertext"`
+	Tag                string   `json:"tag"`
+}
+
+// MarshalJSON encodes the compressed ephemeral key and every field element as
+// 0x-prefixed, 32-byte big-endian hex.
+func (envelope *EncryptedMemo) MarshalJSON() ([]byte, error) {
+	ephemeralPublicKey := envelope.EphemeralPublicKey.Bytes()
+
+	ciphertext := make([]string, len(envelope.Ciphertext))
+	for i := range envelope.Ciphertext {
+		ciphertext[i] = encodeElementHex(&envelope.Ciphertext[i])
+	}
+
+	return json.Marshal(encryptedMemoJSON{
+		Version:            envelope.Version,
+		EphemeralPublicKey: "0x" + hex.EncodeToString(ephemeralPublicKey[:]),
+		ViewTag:            encodeElementHex(&envelope.ViewTag),
+		Ciphertext:         ciphertext,
+		Tag:                encodeElementHex(&envelope.Tag),
+	})
+}
+
+func (envelope *EncryptedMemo) UnmarshalJSON(data []byte) error {
+	var raw encryptedMemoJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	if raw.Version != MemoVersion {
+		return fmt.Errorf("unsupported memo version %d", raw.Version)
+	}
+
+	ephemeralPublicKeyBytes, err := decodeHex32(raw.EphemeralPublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+
+	ephemeralPublicKey, err := decodePoint(ephemeralPublicKeyBytes)
+	if err != nil {
+		return fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+
+	var viewTag fr.Element
+	if err := decodeElementHex(raw.ViewTag, &viewTag); err != nil {
+		return fmt.Errorf("invalid view tag: %w", err)
+	}
+
+	ciphertext := make([]fr.Element, len(raw.Ciphertext))
+	for i := range raw.Ciphertext {
+		if err := decodeElementHex(raw.Ciphertext[i], &ciphertext[i]); err != nil {
+			return fmt.Errorf("invalid ciphertext element %d: %w", i, err)
+		}
+	}
+
+	var tag fr.Element
+	if err := decodeElementHex(raw.Tag, &tag); err != nil {
+		return fmt.Errorf("invalid tag: %w", err)
+	}
+
+	envelope.Version = raw.Version
+	envelope.EphemeralPublicKey = ephemeralPublicKey
+	envelope.ViewTag = viewTag
+	envelope.Ciphertext = ciphertext
+	envelope.Tag = tag
+
+	return nil
+}
+
+// decodePoint decompresses a point and rejects non-canonical encodings and
+// keys outside the prime-order subgroup.
+func decodePoint(data []byte) (twistededwardbn254.PointAffine, error) {
+	var point twistededwardbn254.PointAffine
+	if _, err := point.SetBytes(data); err != nil {
+		return twistededwardbn254.PointAffine{}, err
+	}
+
+	if err := ValidatePublicKey(&point); err != nil {
+		return twistededwardbn254.PointAffine{}, err
+	}
+
+	if encoded := point.Bytes(); string(encoded[:]) != string(data) {
+		return twistededwardbn254.PointAffine{}, fmt.Errorf("non-canonical point encoding")
+	}
+
+	return point, nil
+}
+
+func encodeElementHex(element *fr.Element) string {
+	elementBytes := element.Bytes()
+	return "0x" + hex.EncodeToString(elementBytes[:])
+}
+
+func decodeElementHex(s string, element *fr.Element) error {
+	data, err := decodeHex32(s)
+	if err != nil {
+		return err
+	}
+
+	return element.SetBytesCanonical(data)
+}
+
+func decodeHex32(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return nil, fmt.Errorf("missing 0x prefix")
+	}
+
+	data, err := hex.DecodeString(s[2:])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) != fr.Bytes {
+		return nil, fmt.Errorf("expected %d bytes, got %d", fr.Bytes, len(data))
+	}
+
+	return data, nil
+}
@@ -0,0 +1,134 @@
+package builder_test
+
+import (
+	"encoding/json"
+	"hide-pay/builder"
+	"hide-pay/utils"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEncryptedMemo(t *testing.T) (*builder.EncryptedMemo, *big.Int) {
+	receiverSecretKey := big.NewInt(22222)
+
+	memo := &builder.Memo{
+		SecretKey: *big.NewInt(11111),
+		PublicKey: utils.BuildPublicKey(*receiverSecretKey),
+	}
+
+	commitment, spentKey := builder.GenerateCommitment(12345)
+
+	envelope, err := memo.Encrypt(*commitment, *spentKey)
+	require.NoError(t, err)
+
+	return envelope, receiverSecretKey
+}
+
+func TestEncryptedMemo_Binary(t *testing.T) {
+	envelope, receiverSecretKey := newEncryptedMemo(t)
+
+	data, err := envelope.MarshalBinary()
+	require.NoError(t, err)
+	assert.Len(t, data, 1+32+32+4+32*12)
+
+	decoded := &builder.EncryptedMemo{}
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, envelope, decoded)
+
+	_, _, err = decoded.Decrypt(*receiverSecretKey)
+	require.NoError(t, err)
+
+	// Trailing bytes are rejected
+	assert.Error(t, decoded.UnmarshalBinary(append(data, 0)))
+
+	// Truncated input is rejected
+	assert.Error(t, decoded.UnmarshalBinary(data[:len(data)-1]))
+
+	// Unknown version is rejected
+	wrongVersion := append([]byte{}, data...)
+	wrongVersion[0] = builder.MemoVersion + 1
+	assert.Error(t, decoded.UnmarshalBinary(wrongVersion))
+
+	// Non-canonical field elements are rejected
+	nonCanonical := append([]byte{}, data...)
+	for i := len(nonCanonical) - 32; i < len(nonCanonical); i++ {
+		nonCanonical[i] = 0xff
+	}
+	assert.Error(t, decoded.UnmarshalBinary(nonCanonical))
+}
+
+func TestEncryptedMemo_JSON(t *testing.T) {
+	envelope, receiverSecretKey := newEncryptedMemo(t)
+
+	data, err := json.Marshal(envelope)
+	require.NoError(t, err)
+
+	var raw map[string]any
+	require.NoError(t, json.Unmarshal(data, &raw))
+	assert.Equal(t, float64(builder.MemoVersion), raw["version"])
+	assert.Len(t, raw["ciphertext"], 11)
+
+	decoded := &builder.EncryptedMemo{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+	assert.Equal(t, envelope, decoded)
+
+	_, _, err = decoded.Decrypt(*receiverSecretKey)
+	require.NoError(t, err)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"version":2,"ephemeralPublicKey":"0x00","viewTag":"0x00","ciphertext":[],"tag":"0x00"}`), decoded))
+	assert.Error(t, json.Unmarshal([]byte(`{"version":9}`), decoded))
+}
+
+func TestEncryptedMemo_Words(t *testing.T) {
+	envelope, receiverSecretKey := newEncryptedMemo(t)
+
+	words := envelope.MarshalWords()
+	assert.Len(t, words, 4+11)
+
+	decoded := &builder.EncryptedMemo{}
+	require.NoError(t, decoded.UnmarshalWords(words))
+	assert.Equal(t, envelope, decoded)
+
+	_, _, err := decoded.Decrypt(*receiverSecretKey)
+	require.NoError(t, err)
+
+	assert.Error(t, decoded.UnmarshalWords(words[:3]))
+
+	wrongVersion := append([][32]byte{}, words...)
+	wrongVersion[0][0] = 1
+	assert.Error(t, decoded.UnmarshalWords(wrongVersion))
+}
+
+func TestEncryptedMemo_Hash(t *testing.T) {
+	envelope, _ := newEncryptedMemo(t)
+
+	hash := envelope.Hash()
+
+	// The hash covers the version, the ephemeral key, the view tag, every
+	// ciphertext element and the tag
+	tampered := *envelope
+	tampered.Version++
+	assert.NotEqual(t, hash, tampered.Hash())
+
+	// A relayer swapping the ephemeral key for another point changes it
+	tampered = *envelope
+	tampered.EphemeralPublicKey = utils.BuildPublicKey(*big.NewInt(99999))
+	assert.NotEqual(t, hash, tampered.Hash())
+
+	tampered = *envelope
+	tampered.ViewTag.SetOne()
+	assert.NotEqual(t, hash, tampered.Hash())
+
+	tampered = *envelope
+	tampered.Ciphertext = append([]fr.Element{}, envelope.Ciphertext...)
+	tampered.Ciphertext[5].SetOne()
+	assert.NotEqual(t, hash, tampered.Hash())
+
+	tampered = *envelope
+	tampered.Tag.SetOne()
+	assert.NotEqual(t, hash, tampered.Hash())
+}
@@ -21,10 +21,13 @@ func TestMemo_Encrypt(t *testing.T) {
 
 	commitment, spentKey := builder.GenerateCommitment(12345)
 
-	_, ciphertext, err := memo.Encrypt(*commitment, *spentKey)
+	envelope, err := memo.Encrypt(*commitment, *spentKey)
 	require.NoError(t, err)
-	require.NotNil(t, ciphertext)
-	assert.NotEmpty(t, ciphertext)
+	require.NotNil(t, envelope)
+	assert.Equal(t, builder.MemoVersion, envelope.Version)
+	assert.Len(t, envelope.Ciphertext, 11)
+
+	ciphertext := envelope.Ciphertext
 
 	// Verify ciphertext is different from plaintext
 	assert.NotEqual(t, commitment.Asset, ciphertext[0])
@@ -43,10 +46,10 @@ func TestMemo_Encrypt_DifferentInputs(t *testing.T) {
 
 	commitment2, spentKey2 := builder.GenerateCommitment(54321)
 
-	_, ciphertext1, err := memo.Encrypt(*commitment1, *spentKey1)
+	ciphertext1, err := memo.Encrypt(*commitment1, *spentKey1)
 	require.NoError(t, err)
 
-	_, ciphertext2, err := memo.Encrypt(*commitment2, *spentKey2)
+	ciphertext2, err := memo.Encrypt(*commitment2, *spentKey2)
 	require.NoError(t, err)
 
 	// Ciphertexts should be different
@@ -62,13 +65,13 @@ func TestMemo_Encrypt_Deterministic(t *testing.T) {
 
 	commitment, spentKey := builder.GenerateCommitment(12345)
 
-	_, ciphertext1, err := memo.Encrypt(*commitment, *spentKey)
+	ciphertext1, err := memo.Encrypt(*commitment, *spentKey)
 	require.NoError(t, err)
 
-	_, ciphertext2, err := memo.Encrypt(*commitment, *spentKey)
+	ciphertext2, err := memo.Encrypt(*commitment, *spentKey)
 	require.NoError(t, err)
 
-	_, ciphertext3, err := memo.Encrypt(*commitment, *spentKey)
+	ciphertext3, err := memo.Encrypt(*commitment, *spentKey)
 	require.NoError(t, err)
 
 	// All ciphertexts should be the same
@@ -87,11 +90,11 @@ func TestMemo_Decrypt(t *testing.T) {
 	originalCommitment, spentKey := builder.GenerateCommitment(12345)
 
 	// Encrypt first
-	_, ciphertext, err := memo.Encrypt(*originalCommitment, *spentKey)
+	envelope, err := memo.Encrypt(*originalCommitment, *spentKey)
 	require.NoError(t, err)
 
 	// Then decrypt
-	decryptedCommitment, _, err := memo.Decrypt(ciphertext)
+	decryptedCommitment, decryptedSpentKey, err := envelope.Decrypt(*big.NewInt(11111))
 	require.NoError(t, err)
 	require.NotNil(t, decryptedCommitment)
 
@@ -99,6 +102,8 @@ func TestMemo_Decrypt(t *testing.T) {
 	assert.Equal(t, originalCommitment.Asset, decryptedCommitment.Asset)
 	assert.Equal(t, originalCommitment.Amount, decryptedCommitment.Amount)
 	assert.Equal(t, originalCommitment.Blinding, decryptedCommitment.Blinding)
+	assert.Equal(t, *spentKey, *decryptedSpentKey)
+	assert.Equal(t, originalCommitment.Compute(), decryptedCommitment.Compute())
 }
 
 func TestMemo_Decrypt_InvalidCiphertext(t *testing.T) {
@@ -109,13 +114,17 @@ func TestMemo_Decrypt_InvalidCiphertext(t *testing.T) {
 	}
 
 	// Invalid ciphertext (wrong length)
-	invalidCiphertext := []fr.Element{
-		fr.NewElement(12345),
-		fr.NewElement(67890),
-		// Missing elements
+	invalidEnvelope := &builder.EncryptedMemo{
+		Version:            builder.MemoVersion,
+		EphemeralPublicKey: memo.PublicKey,
+		Ciphertext: []fr.Element{
+			fr.NewElement(12345),
+			// Missing elements
+		},
+		Tag: fr.NewElement(67890),
 	}
 
-	_, _, err := memo.Decrypt(invalidCiphertext)
+	_, _, err := invalidEnvelope.Decrypt(memo.SecretKey)
 	assert.Error(t, err)
 }
 
@@ -134,11 +143,43 @@ func TestMemo_Decrypt_WrongKey(t *testing.T) {
 	commitment, spentKey := builder.GenerateCommitment(12345)
 
 	// Encrypt with memo1
-	_, ciphertext, err := memo1.Encrypt(*commitment, *spentKey)
+	envelope, err := memo1.Encrypt(*commitment, *spentKey)
 	require.NoError(t, err)
 
 	// Try to decrypt with memo2 (wrong key)
-	_, _, err = memo2.Decrypt(ciphertext)
+	_, _, err = envelope.Decrypt(memo2.SecretKey)
+
+	assert.Error(t, err)
+}
+
+func TestMemo_Decrypt_AssociatedData(t *testing.T) {
+	// The ephemeral key is authenticated, so swapping it breaks decryption
+	receiverSecretKey := big.NewInt(22222)
+
+	memo := &builder.Memo{
+		SecretKey: *big.NewInt(11111),
+		PublicKey: utils.BuildPublicKey(*receiverSecretKey),
+	}
+
+	commitment, spentKey := builder.GenerateCommitment(12345)
+
+	envelope, err := memo.Encrypt(*commitment, *spentKey)
+	require.NoError(t, err)
+
+	assert.Equal(t, utils.BuildPublicKey(*big.NewInt(11111)), envelope.EphemeralPublicKey)
+
+	_, _, err = envelope.Decrypt(*receiverSecretKey)
+	require.NoError(t, err)
+
+	tampered := *envelope
+	tampered.EphemeralPublicKey = utils.BuildPublicKey(*big.NewInt(33333))
+
+	_, _, err = tampered.Decrypt(*receiverSecretKey)
+	assert.Error(t, err)
+
+	tampered = *envelope
+	tampered.Version = builder.MemoVersion + 1
 
+	_, _, err = tampered.Decrypt(*receiverSecretKey)
 	assert.Error(t, err)
 }
@@ -8,6 +8,7 @@ import (
 	"math"
 
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
 	"github.com/consensys/gnark/frontend"
 )
 
@@ -144,6 +145,32 @@ func (mp *MerkleProof) PrintProof() {
 	}
 }
 
+// NewMerkleProof rebuilds a proof for the leaf at index from its path, as
+// returned by Path: the leaf followed by one sibling per level.
+func NewMerkleProof(path []fr.Element, index int) MerkleProof {
+	return MerkleProof{
+		proof:  append([]fr.Element{}, path...),
+		depth:  len(path),
+		hasher: poseidon2.NewMerkleDamgardHasher(),
+		index:  index,
+	}
+}
+
+// Path returns a copy of the leaf and its siblings.
+func (mp *MerkleProof) Path() []fr.Element {
+	return append([]fr.Element{}, mp.proof...)
+}
+
+// Index returns the position of the leaf in the tree.
+func (mp *MerkleProof) Index() int {
+	return mp.index
+}
+
+// Leaf returns the element the proof is for.
+func (mp *MerkleProof) Leaf() fr.Element {
+	return mp.proof[0]
+}
+
 func (mp *MerkleProof) Verify() fr.Element {
 	flag := utils.IntToBits(mp.index, mp.depth)
 
@@ -0,0 +1,47 @@
+package builder
+
+import (
+	"fmt"
+	"hide-pay/circuits"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+)
+
+// MerkleUpdate writes Leaf into the empty slot MerkleProof is for.
+type MerkleUpdate struct {
+	MerkleProof MerkleProof
+	Leaf        fr.Element
+}
+
+// Roots returns the roots of the tree before and after the update.
+func (update *MerkleUpdate) Roots() (fr.Element, fr.Element) {
+	path := update.MerkleProof.Path()
+	path[0] = update.Leaf
+
+	updated := NewMerkleProof(path, update.MerkleProof.Index())
+
+	return update.MerkleProof.Verify(), updated.Verify()
+}
+
+func (update *MerkleUpdate) ToWitness() (*circuits.MerkleUpdateCircuit, error) {
+	if leaf := update.MerkleProof.Leaf(); !leaf.IsZero() {
+		return nil, fmt.Errorf("merkle proof is not for an empty slot")
+	}
+
+	path := update.MerkleProof.Path()
+	siblings := make([]frontend.Variable, len(path)-1)
+	for i := range siblings {
+		siblings[i] = path[i+1]
+	}
+
+	oldRoot, newRoot := update.Roots()
+
+	return &circuits.MerkleUpdateCircuit{
+		Siblings: siblings,
+		Leaf:     update.Leaf,
+		Index:    update.MerkleProof.Index(),
+		OldRoot:  oldRoot,
+		NewRoot:  newRoot,
+	}, nil
+}
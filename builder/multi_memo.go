@@ -0,0 +1,200 @@
+package builder
+
+import (
+	"fmt"
+	"hide-pay/circuits"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"github.com/consensys/gnark/frontend"
+)
+
+// MultiMemo encrypts one opening for several readers, e.g. owner, auditor and
+// an accountant. The payload is encrypted once under ContentKey, which is then
+// wrapped for every receiver with the ECDH key of the single ephemeral key.
+type MultiMemo struct {
+	SecretKey  big.Int
+	ContentKey fr.Element
+	PublicKeys []twistededwardbn254.PointAffine
+}
+
+// KeyWrap is one receiver's entry: the view tag of its shared key and the
+// content key masked with H(domain, S.x, S.y).
+type KeyWrap struct {
+	ViewTag    fr.Element
+	WrappedKey fr.Element
+}
+
+type EncryptedMultiMemo struct {
+	EphemeralPublicKey twistededwardbn254.PointAffine
+	Wraps              []KeyWrap
+	Ciphertext         []fr.Element
+	Tag                fr.Element
+}
+
+// NewMultiMemo validates every receiver key and draws a fresh content key.
+func NewMultiMemo(secretKey big.Int, publicKeys []twistededwardbn254.PointAffine) (*MultiMemo, error) {
+	if len(publicKeys) == 0 {
+		return nil, fmt.Errorf("at least one receiver is required")
+	}
+
+	for i := range publicKeys {
+		if err := ValidatePublicKey(&publicKeys[i]); err != nil {
+			return nil, fmt.Errorf("invalid public key %d: %w", i, err)
+		}
+	}
+
+	var contentKey fr.Element
+	if _, err := contentKey.SetRandom(); err != nil {
+		return nil, fmt.Errorf("failed to generate content key: %w", err)
+	}
+
+	return &MultiMemo{
+		SecretKey:  secretKey,
+		ContentKey: contentKey,
+		PublicKeys: publicKeys,
+	}, nil
+}
+
+func (memo *MultiMemo) ToGadget() *circuits.MultiMemoGadget {
+	publicKeys := make([][2]frontend.Variable, len(memo.PublicKeys))
+	for i := range memo.PublicKeys {
+		publicKeys[i] = [2]frontend.Variable{memo.PublicKeys[i].X, memo.PublicKeys[i].Y}
+	}
+
+	return &circuits.MultiMemoGadget{
+		EphemeralSecretKey: memo.SecretKey,
+		ContentKey:         memo.ContentKey,
+		ReceiverPublicKeys: publicKeys,
+	}
+}
+
+func (memo *MultiMemo) Encrypt(commitment Commitment, spentKey fr.Element) (*EncryptedMultiMemo, error) {
+	wraps := make([]KeyWrap, len(memo.PublicKeys))
+
+	for i := range memo.PublicKeys {
+		ecdh, err := NewECDHFromPublicKey(memo.PublicKeys[i], memo.SecretKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid receiver key %d: %w", i, err)
+		}
+
+		sharedKey := ecdh.Compute()
+		mask := computeKeyWrapMask(&sharedKey)
+
+		wraps[i].ViewTag = ComputeViewTag(&sharedKey)
+		wraps[i].WrappedKey.Add(&memo.ContentKey, &mask)
+	}
+
+	basePoint := twistededwardbn254.GetEdwardsCurve().Base
+	ephemeralPublicKey := basePoint.ScalarMultiplication(&basePoint, &memo.SecretKey)
+
+	streamCipher := contentCipher(memo.ContentKey)
+
+	ciphertext, err := streamCipher.Encrypt(memoAssociatedData(ephemeralPublicKey), memoPlaintext(&commitment, spentKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+
+	return &EncryptedMultiMemo{
+		EphemeralPublicKey: *ephemeralPublicKey,
+		Wraps:              wraps,
+		Ciphertext:         ciphertext[:len(ciphertext)-1],
+		Tag:                ciphertext[len(ciphertext)-1],
+	}, nil
+}
+
+// Decrypt finds the receiver's wrap by view tag, unwraps the content key and
+// opens the payload.
+func (envelope *EncryptedMultiMemo) Decrypt(secretKey big.Int) (*Commitment, *fr.Element, error) {
+	ecdh, err := NewECDHFromPublicKey(envelope.EphemeralPublicKey, secretKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+
+	sharedKey := ecdh.Compute()
+	viewTag := ComputeViewTag(&sharedKey)
+
+	for i := range envelope.Wraps {
+		if envelope.Wraps[i].ViewTag != viewTag {
+			continue
+		}
+
+		mask := computeKeyWrapMask(&sharedKey)
+
+		var contentKey fr.Element
+		contentKey.Sub(&envelope.Wraps[i].WrappedKey, &mask)
+
+		return envelope.DecryptWithContentKey(contentKey)
+	}
+
+	return nil, nil, ErrViewTagMismatch
+}
+
+func (envelope *EncryptedMultiMemo) DecryptWithContentKey(contentKey fr.Element) (*Commitment, *fr.Element, error) {
+	streamCipher := contentCipher(contentKey)
+
+	elements := append(append([]fr.Element{}, envelope.Ciphertext...), envelope.Tag)
+
+	plaintext, err := streamCipher.Decrypt(memoAssociatedData(&envelope.EphemeralPublicKey), elements)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	return openMemo(plaintext)
+}
+
+// Hash returns H(EphemeralPublicKey.X, EphemeralPublicKey.Y, ViewTag_i,
+// WrappedKey_i ..., Ciphertext..., Tag), matching MultiMemoGadget.
+func (envelope *EncryptedMultiMemo) Hash() fr.Element {
+	hasher := poseidon2.NewMerkleDamgardHasher()
+
+	ephemeralXBytes := envelope.EphemeralPublicKey.X.Bytes()
+	ephemeralYBytes := envelope.EphemeralPublicKey.Y.Bytes()
+	hasher.Write(ephemeralXBytes[:])
+	hasher.Write(ephemeralYBytes[:])
+
+	for i := range envelope.Wraps {
+		viewTagBytes := envelope.Wraps[i].ViewTag.Bytes()
+		wrappedKeyBytes := envelope.Wraps[i].WrappedKey.Bytes()
+
+		hasher.Write(viewTagBytes[:])
+		hasher.Write(wrappedKeyBytes[:])
+	}
+
+	for i := range envelope.Ciphertext {
+		elementBytes := envelope.Ciphertext[i].Bytes()
+		hasher.Write(elementBytes[:])
+	}
+
+	tagBytes := envelope.Tag.Bytes()
+	hasher.Write(tagBytes[:])
+
+	hash := fr.Element{}
+	hash.SetBytes(hasher.Sum(nil))
+
+	return hash
+}
+
+// contentCipher keys the payload cipher with the content key in the first
+// slot, as MultiMemoGadget does.
+func contentCipher(contentKey fr.Element) *StreamCipher {
+	return &StreamCipher{
+		Key: [2]fr.Element{contentKey, fr.NewElement(0)},
+	}
+}
+
+func computeKeyWrapMask(sharedKey *twistededwardbn254.PointAffine) fr.Element {
+	hasher := poseidon2.NewMerkleDamgardHasher()
+
+	domain := fr.NewElement(circuits.KeyWrapDomain)
+	domainBytes := domain.Bytes()
+	hasher.Write(domainBytes[:])
+	writePoint(hasher, sharedKey)
+
+	mask := fr.Element{}
+	mask.SetBytes(hasher.Sum(nil))
+
+	return mask
+}
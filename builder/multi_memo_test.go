@@ -0,0 +1,67 @@
+package builder_test
+
+import (
+	"hide-pay/builder"
+	"hide-pay/utils"
+	"math/big"
+	"testing"
+
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiMemo_Encrypt(t *testing.T) {
+	secretKeys := []*big.Int{big.NewInt(11111), big.NewInt(22222), big.NewInt(33333)}
+
+	publicKeys := make([]twistededwardbn254.PointAffine, len(secretKeys))
+	for i := range secretKeys {
+		publicKeys[i] = utils.BuildPublicKey(*secretKeys[i])
+	}
+
+	memo, err := builder.NewMultiMemo(*big.NewInt(44444), publicKeys)
+	require.NoError(t, err)
+
+	commitment, spentKey := builder.GenerateCommitment(12345)
+
+	envelope, err := memo.Encrypt(*commitment, *spentKey)
+	require.NoError(t, err)
+	require.Len(t, envelope.Wraps, 3)
+
+	// Every reader recovers the same note
+	for i := range secretKeys {
+		decrypted, decryptedSpentKey, err := envelope.Decrypt(*secretKeys[i])
+		require.NoError(t, err)
+		assert.Equal(t, commitment.Compute(), decrypted.Compute())
+		assert.Equal(t, *spentKey, *decryptedSpentKey)
+	}
+
+	_, _, err = envelope.Decrypt(*big.NewInt(55555))
+	assert.ErrorIs(t, err, builder.ErrViewTagMismatch)
+
+	// A wrap of a different key fails the tag check of the payload
+	tampered := *envelope
+	tampered.Wraps = append([]builder.KeyWrap{}, envelope.Wraps...)
+	tampered.Wraps[1].WrappedKey.SetOne()
+
+	_, _, err = tampered.Decrypt(*secretKeys[1])
+	assert.Error(t, err)
+	assert.NotEqual(t, envelope.Hash(), tampered.Hash())
+
+	// The hash also covers the ephemeral key the wraps were derived from
+	tampered = *envelope
+	tampered.EphemeralPublicKey = utils.BuildPublicKey(*big.NewInt(99999))
+	assert.NotEqual(t, envelope.Hash(), tampered.Hash())
+}
+
+func TestNewMultiMemo_InvalidKey(t *testing.T) {
+	_, err := builder.NewMultiMemo(*big.NewInt(44444), nil)
+	assert.Error(t, err)
+
+	order2, order4, mixed := lowOrderPoints(t)
+
+	for _, point := range []twistededwardbn254.PointAffine{order2, order4, mixed} {
+		_, err := builder.NewMultiMemo(*big.NewInt(44444), []twistededwardbn254.PointAffine{utils.BuildPublicKey(*big.NewInt(1)), point})
+		assert.Error(t, err)
+	}
+}
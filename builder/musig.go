@@ -0,0 +1,225 @@
+package builder
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+)
+
+// AggregateKey is the MuSig aggregation of a fixed, ordered set of signer keys.
+// PublicKey verifies aggregated signatures with the plain Verify function.
+type AggregateKey struct {
+	PublicKey    twistededwardbn254.PointAffine
+	Signers      []twistededwardbn254.PointAffine
+	Coefficients []fr.Element
+}
+
+// AggregatePublicKeys computes X = sum(a_i * P_i) with a_i = H(L, P_i), where L
+// commits to the whole signer list. The coefficients prevent rogue-key attacks.
+func AggregatePublicKeys(publicKeys []twistededwardbn254.PointAffine) (*AggregateKey, error) {
+	if len(publicKeys) == 0 {
+		return nil, fmt.Errorf("at least one public key is required")
+	}
+
+	listHasher := poseidon2.NewMerkleDamgardHasher()
+	for i := range publicKeys {
+		writePoint(listHasher, &publicKeys[i])
+	}
+	listHash := listHasher.Sum(nil)
+
+	signers := make([]twistededwardbn254.PointAffine, len(publicKeys))
+	coefficients := make([]fr.Element, len(publicKeys))
+
+	var aggregated twistededwardbn254.PointAffine
+	aggregated.X.SetZero()
+	aggregated.Y.SetOne()
+
+	for i := range publicKeys {
+		hasher := poseidon2.NewMerkleDamgardHasher()
+		hasher.Write(listHash)
+		writePoint(hasher, &publicKeys[i])
+
+		coefficients[i].SetBytes(hasher.Sum(nil))
+		signers[i] = publicKeys[i]
+
+		var weighted twistededwardbn254.PointAffine
+		weighted.ScalarMultiplication(&publicKeys[i], coefficients[i].BigInt(new(big.Int)))
+		aggregated.Add(&aggregated, &weighted)
+	}
+
+	return &AggregateKey{
+		PublicKey:    aggregated,
+		Signers:      signers,
+		Coefficients: coefficients,
+	}, nil
+}
+
+// MuSigSession holds one signer's state for a single interactive signing run:
+// nonce commitment, nonce reveal, then partial signature. A session commits
+// and signs at most once; start a new one to sign again.
+type MuSigSession struct {
+	keypair     *Keypair
+	key         *AggregateKey
+	index       int
+	messageHash fr.Element
+
+	committed   bool
+	nonce       fr.Element
+	noncePoint  twistededwardbn254.PointAffine
+	commitments []fr.Element
+}
+
+func NewMuSigSession(keypair *Keypair, key *AggregateKey, messageHash fr.Element) (*MuSigSession, error) {
+	for i := range key.Signers {
+		if key.Signers[i].Equal(&keypair.PublicKey) {
+			return &MuSigSession{
+				keypair:     keypair,
+				key:         key,
+				index:       i,
+				messageHash: messageHash,
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("public key is not part of the aggregate key")
+}
+
+// Commit draws a fresh nonce and returns H(R_i) for the first round. It fails
+// if the session has already committed, since a second nonce would let the
+// other signers pick which one to sign with.
+func (session *MuSigSession) Commit() (fr.Element, error) {
+	if session.committed {
+		return fr.Element{}, fmt.Errorf("nonce has already been committed")
+	}
+	session.committed = true
+
+	if _, err := session.nonce.SetRandom(); err != nil {
+		return fr.Element{}, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	base := twistededwardbn254.GetEdwardsCurve().Base
+	session.noncePoint.ScalarMultiplication(&base, session.nonce.BigInt(new(big.Int)))
+
+	return nonceCommitment(&session.noncePoint), nil
+}
+
+// Reveal returns R_i once every signer's commitment has been received.
+func (session *MuSigSession) Reveal(commitments []fr.Element) (twistededwardbn254.PointAffine, error) {
+	if len(commitments) != len(session.key.Signers) {
+		return twistededwardbn254.PointAffine{}, fmt.Errorf("expected %d nonce commitments, got %d", len(session.key.Signers), len(commitments))
+	}
+
+	if commitments[session.index] != nonceCommitment(&session.noncePoint) {
+		return twistededwardbn254.PointAffine{}, fmt.Errorf("own nonce commitment does not match")
+	}
+
+	session.commitments = commitments
+
+	return session.noncePoint, nil
+}
+
+// PartialSign checks every revealed nonce against its commitment and returns
+// s_i = r_i + c * a_i * x_i. The secret nonce is cleared before signing, so a
+// session signs once.
+func (session *MuSigSession) PartialSign(nonces []twistededwardbn254.PointAffine) (fr.Element, error) {
+	if session.commitments == nil {
+		return fr.Element{}, fmt.Errorf("nonce commitments have not been exchanged")
+	}
+
+	if session.nonce.IsZero() {
+		return fr.Element{}, fmt.Errorf("nonce has already been used")
+	}
+
+	if len(nonces) != len(session.commitments) {
+		return fr.Element{}, fmt.Errorf("expected %d nonces, got %d", len(session.commitments), len(nonces))
+	}
+
+	if !nonces[session.index].Equal(&session.noncePoint) {
+		return fr.Element{}, fmt.Errorf("own nonce %d is not the one this session revealed", session.index)
+	}
+
+	for i := range nonces {
+		if nonceCommitment(&nonces[i]) != session.commitments[i] {
+			return fr.Element{}, fmt.Errorf("nonce %d does not match its commitment", i)
+		}
+	}
+
+	R := aggregateNonces(nonces)
+	c := computeHash(session.messageHash, &R, &session.key.PublicKey)
+
+	order := twistededwardbn254.GetEdwardsCurve().Order
+
+	cax := new(big.Int).Mul(c.BigInt(new(big.Int)), session.key.Coefficients[session.index].BigInt(new(big.Int)))
+	cax.Mul(cax, session.keypair.SecretKey.BigInt(new(big.Int)))
+	cax.Mod(cax, &order)
+
+	// A nonce must never sign twice
+	r := session.nonce.BigInt(new(big.Int))
+	session.nonce.SetZero()
+
+	s := new(big.Int).Add(r, cax)
+	s.Mod(s, &order)
+
+	var partial fr.Element
+	partial.SetBigInt(s)
+
+	return partial, nil
+}
+
+// AggregatePartialSignatures combines the revealed nonces and the partial
+// signatures into a Signature that verifies against AggregateKey.PublicKey.
+func AggregatePartialSignatures(nonces []twistededwardbn254.PointAffine, partials []fr.Element) (*Signature, error) {
+	if len(nonces) != len(partials) {
+		return nil, fmt.Errorf("expected %d partial signatures, got %d", len(nonces), len(partials))
+	}
+
+	order := twistededwardbn254.GetEdwardsCurve().Order
+
+	s := big.NewInt(0)
+	for i := range partials {
+		s.Add(s, partials[i].BigInt(new(big.Int)))
+	}
+	s.Mod(s, &order)
+
+	var sign fr.Element
+	sign.SetBigInt(s)
+
+	return &Signature{
+		R: aggregateNonces(nonces),
+		S: sign,
+	}, nil
+}
+
+func aggregateNonces(nonces []twistededwardbn254.PointAffine) twistededwardbn254.PointAffine {
+	var R twistededwardbn254.PointAffine
+	R.X.SetZero()
+	R.Y.SetOne()
+
+	for i := range nonces {
+		R.Add(&R, &nonces[i])
+	}
+
+	return R
+}
+
+func nonceCommitment(R *twistededwardbn254.PointAffine) fr.Element {
+	hasher := poseidon2.NewMerkleDamgardHasher()
+	writePoint(hasher, R)
+
+	commitment := fr.Element{}
+	commitment.SetBytes(hasher.Sum(nil))
+
+	return commitment
+}
+
+func writePoint(hasher io.Writer, point *twistededwardbn254.PointAffine) {
+	xBytes := point.X.Bytes()
+	yBytes := point.Y.Bytes()
+
+	hasher.Write(xBytes[:])
+	hasher.Write(yBytes[:])
+}
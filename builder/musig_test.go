@@ -0,0 +1,197 @@
+package builder_test
+
+import (
+	"hide-pay/builder"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMuSigParties(t *testing.T, n int) ([]*builder.Keypair, *builder.AggregateKey) {
+	keypairs := make([]*builder.Keypair, n)
+	publicKeys := make([]twistededwardbn254.PointAffine, n)
+
+	for i := range keypairs {
+		kp, err := builder.GenerateKeypair()
+		require.NoError(t, err)
+
+		keypairs[i] = kp
+		publicKeys[i] = kp.PublicKey
+	}
+
+	key, err := builder.AggregatePublicKeys(publicKeys)
+	require.NoError(t, err)
+
+	return keypairs, key
+}
+
+func runMuSig(t *testing.T, keypairs []*builder.Keypair, key *builder.AggregateKey, messageHash fr.Element) *builder.Signature {
+	sessions := make([]*builder.MuSigSession, len(keypairs))
+	commitments := make([]fr.Element, len(keypairs))
+
+	for i := range keypairs {
+		session, err := builder.NewMuSigSession(keypairs[i], key, messageHash)
+		require.NoError(t, err)
+
+		commitments[i], err = session.Commit()
+		require.NoError(t, err)
+
+		sessions[i] = session
+	}
+
+	nonces := make([]twistededwardbn254.PointAffine, len(keypairs))
+	for i := range sessions {
+		nonce, err := sessions[i].Reveal(commitments)
+		require.NoError(t, err)
+
+		nonces[i] = nonce
+	}
+
+	partials := make([]fr.Element, len(keypairs))
+	for i := range sessions {
+		partial, err := sessions[i].PartialSign(nonces)
+		require.NoError(t, err)
+
+		partials[i] = partial
+	}
+
+	signature, err := builder.AggregatePartialSignatures(nonces, partials)
+	require.NoError(t, err)
+
+	return signature
+}
+
+func TestMuSig_TwoOfTwo(t *testing.T) {
+	keypairs, key := newMuSigParties(t, 2)
+
+	messageHash := fr.NewElement(12345)
+	signature := runMuSig(t, keypairs, key, messageHash)
+
+	assert.True(t, builder.Verify(messageHash, signature, &key.PublicKey))
+	assert.False(t, builder.Verify(fr.NewElement(67890), signature, &key.PublicKey))
+	assert.False(t, builder.Verify(messageHash, signature, &keypairs[0].PublicKey))
+}
+
+func TestMuSig_NOfN(t *testing.T) {
+	keypairs, key := newMuSigParties(t, 5)
+
+	messageHash := fr.NewElement(12345)
+	signature := runMuSig(t, keypairs, key, messageHash)
+
+	assert.True(t, builder.Verify(messageHash, signature, &key.PublicKey))
+}
+
+func TestMuSig_SingleSigner(t *testing.T) {
+	keypairs, key := newMuSigParties(t, 1)
+
+	messageHash := fr.NewElement(12345)
+	signature := runMuSig(t, keypairs, key, messageHash)
+
+	assert.True(t, builder.Verify(messageHash, signature, &key.PublicKey))
+}
+
+func TestAggregatePublicKeys_OrderMatters(t *testing.T) {
+	kp1, err := builder.GenerateKeypair()
+	require.NoError(t, err)
+
+	kp2, err := builder.GenerateKeypair()
+	require.NoError(t, err)
+
+	key1, err := builder.AggregatePublicKeys([]twistededwardbn254.PointAffine{kp1.PublicKey, kp2.PublicKey})
+	require.NoError(t, err)
+
+	key2, err := builder.AggregatePublicKeys([]twistededwardbn254.PointAffine{kp2.PublicKey, kp1.PublicKey})
+	require.NoError(t, err)
+
+	assert.NotEqual(t, key1.PublicKey, key2.PublicKey)
+
+	_, err = builder.AggregatePublicKeys(nil)
+	assert.Error(t, err)
+}
+
+func TestMuSigSession_UnknownSigner(t *testing.T) {
+	_, key := newMuSigParties(t, 2)
+
+	outsider, err := builder.GenerateKeypair()
+	require.NoError(t, err)
+
+	_, err = builder.NewMuSigSession(outsider, key, fr.NewElement(12345))
+	assert.Error(t, err)
+}
+
+func TestMuSigSession_NonceMismatch(t *testing.T) {
+	keypairs, key := newMuSigParties(t, 2)
+
+	messageHash := fr.NewElement(12345)
+
+	session0, err := builder.NewMuSigSession(keypairs[0], key, messageHash)
+	require.NoError(t, err)
+
+	session1, err := builder.NewMuSigSession(keypairs[1], key, messageHash)
+	require.NoError(t, err)
+
+	commitment0, err := session0.Commit()
+	require.NoError(t, err)
+
+	commitment1, err := session1.Commit()
+	require.NoError(t, err)
+
+	commitments := []fr.Element{commitment0, commitment1}
+
+	nonce0, err := session0.Reveal(commitments)
+	require.NoError(t, err)
+
+	_, err = session1.Reveal(commitments)
+	require.NoError(t, err)
+
+	// Signer 1 swaps its nonce after seeing signer 0's reveal
+	_, err = session0.PartialSign([]twistededwardbn254.PointAffine{nonce0, nonce0})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match its commitment")
+
+	// A coordinator cannot substitute the signer's own nonce either
+	nonce1, err := session1.Reveal(commitments)
+	require.NoError(t, err)
+
+	_, err = session0.PartialSign([]twistededwardbn254.PointAffine{nonce1, nonce1})
+	assert.ErrorContains(t, err, "own nonce 0")
+}
+
+func TestMuSigSession_NonceReuse(t *testing.T) {
+	keypairs, key := newMuSigParties(t, 1)
+
+	session, err := builder.NewMuSigSession(keypairs[0], key, fr.NewElement(12345))
+	require.NoError(t, err)
+
+	commitment, err := session.Commit()
+	require.NoError(t, err)
+
+	nonce, err := session.Reveal([]fr.Element{commitment})
+	require.NoError(t, err)
+
+	_, err = session.PartialSign([]twistededwardbn254.PointAffine{nonce})
+	require.NoError(t, err)
+
+	_, err = session.PartialSign([]twistededwardbn254.PointAffine{nonce})
+	assert.Error(t, err)
+
+	// nor can the session draw a new nonce and sign again
+	_, err = session.Commit()
+	assert.Error(t, err)
+}
+
+func TestMuSigSession_CommitTwice(t *testing.T) {
+	keypairs, key := newMuSigParties(t, 1)
+
+	session, err := builder.NewMuSigSession(keypairs[0], key, fr.NewElement(12345))
+	require.NoError(t, err)
+
+	_, err = session.Commit()
+	require.NoError(t, err)
+
+	_, err = session.Commit()
+	assert.ErrorContains(t, err, "already been committed")
+}
@@ -0,0 +1,174 @@
+package builder
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+)
+
+// OutgoingMemoVersion is the only outgoing memo layout understood by this
+// package.
+const OutgoingMemoVersion uint8 = 1
+
+// outgoingPlaintextSize covers the ephemeral secret key and the receiver key.
+const outgoingPlaintextSize = 3
+
+// EncryptedOutgoingMemo lets a sender reopen an owner memo it created, in the
+// manner of a Zcash outgoing ciphertext. It holds the ephemeral secret key and
+// the receiver key under a cipher keyed with the sender's outgoing viewing key
+// and the commitment, with the owner memo's ephemeral public key as associated
+// data.
+type EncryptedOutgoingMemo struct {
+	Version    uint8
+	Ciphertext []fr.Element
+	Tag        fr.Element
+}
+
+// EncryptOutgoing seals the memo's ephemeral secret key and receiver key for
+// the holder of outgoingViewingKey.
+func (memo *Memo) EncryptOutgoing(outgoingViewingKey fr.Element, commitment fr.Element) (*EncryptedOutgoingMemo, error) {
+	basePoint := twistededwardbn254.GetEdwardsCurve().Base
+	ephemeralPublicKey := basePoint.ScalarMultiplication(&basePoint, &memo.SecretKey)
+
+	var ephemeralSecretKey fr.Element
+	ephemeralSecretKey.SetBigInt(&memo.SecretKey)
+
+	plaintext := []fr.Element{
+		ephemeralSecretKey,
+		memo.PublicKey.X,
+		memo.PublicKey.Y,
+	}
+
+	streamCipher := outgoingCipher(outgoingViewingKey, commitment)
+
+	ciphertext, err := streamCipher.Encrypt(memoAssociatedData(ephemeralPublicKey), plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+
+	return &EncryptedOutgoingMemo{
+		Version:    OutgoingMemoVersion,
+		Ciphertext: ciphertext[:len(ciphertext)-1],
+		Tag:        ciphertext[len(ciphertext)-1],
+	}, nil
+}
+
+// Decrypt recovers the ephemeral secret key and receiver key of ownerMemo.
+func (envelope *EncryptedOutgoingMemo) Decrypt(outgoingViewingKey fr.Element, commitment fr.Element, ownerMemo *EncryptedMemo) (*Memo, error) {
+	if envelope.Version != OutgoingMemoVersion {
+		return nil, fmt.Errorf("unsupported outgoing memo version %d", envelope.Version)
+	}
+
+	streamCipher := outgoingCipher(outgoingViewingKey, commitment)
+
+	elements := append(append([]fr.Element{}, envelope.Ciphertext...), envelope.Tag)
+
+	plaintext, err := streamCipher.Decrypt(memoAssociatedData(&ownerMemo.EphemeralPublicKey), elements)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	if len(plaintext) != outgoingPlaintextSize {
+		return nil, fmt.Errorf("outgoing memo plaintext must have %d elements, got %d", outgoingPlaintextSize, len(plaintext))
+	}
+
+	memo := &Memo{
+		PublicKey: twistededwardbn254.PointAffine{
+			X: plaintext[1],
+			Y: plaintext[2],
+		},
+	}
+	plaintext[0].BigInt(&memo.SecretKey)
+
+	// The recovered secret must be the one behind the owner memo
+	basePoint := twistededwardbn254.GetEdwardsCurve().Base
+	ephemeralPublicKey := basePoint.ScalarMultiplication(&basePoint, &memo.SecretKey)
+	if !ephemeralPublicKey.Equal(&ownerMemo.EphemeralPublicKey) {
+		return nil, fmt.Errorf("ephemeral secret key does not match the owner memo")
+	}
+
+	return memo, nil
+}
+
+// RecoverNote reopens ownerMemo from the sender side and returns the note and
+// its spent key, as the receiver would see them.
+func (envelope *EncryptedOutgoingMemo) RecoverNote(outgoingViewingKey fr.Element, commitment fr.Element, ownerMemo *EncryptedMemo) (*Commitment, *fr.Element, *twistededwardbn254.PointAffine, error) {
+	memo, err := envelope.Decrypt(outgoingViewingKey, commitment, ownerMemo)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	ecdh, err := NewECDHFromPublicKey(memo.PublicKey, memo.SecretKey)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid receiver key: %w", err)
+	}
+
+	opening, spentKey, err := ownerMemo.DecryptWithSharedKey(ecdh.Compute())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to open owner memo: %w", err)
+	}
+
+	return opening, spentKey, &memo.PublicKey, nil
+}
+
+// MarshalWords encodes the memo as a bytes32[]: the version right-aligned in
+// the first word, then the ciphertext and the tag.
+func (envelope *EncryptedOutgoingMemo) MarshalWords() [][32]byte {
+	words := make([][32]byte, 0, 2+len(envelope.Ciphertext))
+
+	var version [32]byte
+	version[31] = envelope.Version
+	words = append(words, version)
+
+	for i := range envelope.Ciphertext {
+		words = append(words, envelope.Ciphertext[i].Bytes())
+	}
+
+	return append(words, envelope.Tag.Bytes())
+}
+
+func (envelope *EncryptedOutgoingMemo) UnmarshalWords(words [][32]byte) error {
+	if len(words) < 2 {
+		return fmt.Errorf("outgoing memo too short: %d words", len(words))
+	}
+
+	var zero [31]byte
+	if string(words[0][:31]) != string(zero[:]) || words[0][31] != OutgoingMemoVersion {
+		return fmt.Errorf("unsupported outgoing memo version word %x", words[0])
+	}
+
+	ciphertext := make([]fr.Element, len(words)-2)
+	for i := range ciphertext {
+		if err := ciphertext[i].SetBytesCanonical(words[1+i][:]); err != nil {
+			return fmt.Errorf("invalid ciphertext element %d: %w", i, err)
+		}
+	}
+
+	var tag fr.Element
+	if err := tag.SetBytesCanonical(words[len(words)-1][:]); err != nil {
+		return fmt.Errorf("invalid tag: %w", err)
+	}
+
+	envelope.Version = OutgoingMemoVersion
+	envelope.Ciphertext = ciphertext
+	envelope.Tag = tag
+
+	return nil
+}
+
+func outgoingCipher(outgoingViewingKey fr.Element, commitment fr.Element) *StreamCipher {
+	return &StreamCipher{
+		Key: [2]fr.Element{outgoingViewingKey, commitment},
+	}
+}
+
+// NewOutgoingViewingKey draws a random outgoing viewing key.
+func NewOutgoingViewingKey() (fr.Element, error) {
+	var outgoingViewingKey fr.Element
+	if _, err := outgoingViewingKey.SetRandom(); err != nil {
+		return fr.Element{}, fmt.Errorf("failed to generate outgoing viewing key: %w", err)
+	}
+
+	return outgoingViewingKey, nil
+}
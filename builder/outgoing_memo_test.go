@@ -0,0 +1,81 @@
+package builder_test
+
+import (
+	"hide-pay/builder"
+	"hide-pay/utils"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedOutgoingMemo_RecoverNote(t *testing.T) {
+	receiverPublicKey := utils.BuildPublicKey(*big.NewInt(22222))
+
+	memo := &builder.Memo{
+		SecretKey: *big.NewInt(11111),
+		PublicKey: receiverPublicKey,
+	}
+
+	commitment, spentKey := builder.GenerateCommitment(12345)
+
+	ownerMemo, err := memo.Encrypt(*commitment, *spentKey)
+	require.NoError(t, err)
+
+	outgoingViewingKey, err := builder.NewOutgoingViewingKey()
+	require.NoError(t, err)
+
+	outgoing, err := memo.EncryptOutgoing(outgoingViewingKey, commitment.Compute())
+	require.NoError(t, err)
+
+	decoded := &builder.EncryptedOutgoingMemo{}
+	require.NoError(t, decoded.UnmarshalWords(outgoing.MarshalWords()))
+	assert.Equal(t, outgoing, decoded)
+
+	opening, recoveredSpentKey, receiver, err := decoded.RecoverNote(outgoingViewingKey, commitment.Compute(), ownerMemo)
+	require.NoError(t, err)
+	assert.Equal(t, commitment, opening)
+	assert.Equal(t, spentKey, recoveredSpentKey)
+	assert.Equal(t, receiverPublicKey, *receiver)
+
+	// Another outgoing viewing key does not authenticate
+	otherKey, err := builder.NewOutgoingViewingKey()
+	require.NoError(t, err)
+	_, _, _, err = decoded.RecoverNote(otherKey, commitment.Compute(), ownerMemo)
+	assert.Error(t, err)
+
+	// The ciphertext is bound to its commitment
+	_, _, _, err = decoded.RecoverNote(outgoingViewingKey, fr.NewElement(1), ownerMemo)
+	assert.Error(t, err)
+
+	// and to the ephemeral key of its owner memo
+	otherMemo := &builder.Memo{
+		SecretKey: *big.NewInt(33333),
+		PublicKey: receiverPublicKey,
+	}
+	otherOwnerMemo, err := otherMemo.Encrypt(*commitment, *spentKey)
+	require.NoError(t, err)
+	_, _, _, err = decoded.RecoverNote(outgoingViewingKey, commitment.Compute(), otherOwnerMemo)
+	assert.Error(t, err)
+}
+
+func TestEncryptedOutgoingMemo_Words(t *testing.T) {
+	memo := &builder.Memo{
+		SecretKey: *big.NewInt(11111),
+		PublicKey: utils.BuildPublicKey(*big.NewInt(22222)),
+	}
+
+	outgoing, err := memo.EncryptOutgoing(fr.NewElement(7), fr.NewElement(8))
+	require.NoError(t, err)
+
+	words := outgoing.MarshalWords()
+	assert.Len(t, words, 1+3+1)
+
+	decoded := &builder.EncryptedOutgoingMemo{}
+	assert.Error(t, decoded.UnmarshalWords(words[:1]))
+
+	words[0][31] = builder.OutgoingMemoVersion + 1
+	assert.Error(t, decoded.UnmarshalWords(words))
+}
@@ -0,0 +1,36 @@
+package builder
+
+import (
+	"errors"
+
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+)
+
+var (
+	ErrPointIsIdentity    = errors.New("point is the identity")
+	ErrPointNotOnCurve    = errors.New("point is not on the curve")
+	ErrPointNotInSubgroup = errors.New("point is not in the prime-order subgroup")
+)
+
+// ValidatePublicKey rejects externally supplied keys that would let a peer
+// force the ECDH shared key into a small subgroup or onto another curve.
+func ValidatePublicKey(point *twistededwardbn254.PointAffine) error {
+	if point.IsZero() {
+		return ErrPointIsIdentity
+	}
+
+	if !point.IsOnCurve() {
+		return ErrPointNotOnCurve
+	}
+
+	order := twistededwardbn254.GetEdwardsCurve().Order
+
+	var check twistededwardbn254.PointAffine
+	check.ScalarMultiplication(point, &order)
+
+	if !check.IsZero() {
+		return ErrPointNotInSubgroup
+	}
+
+	return nil
+}
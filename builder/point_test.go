@@ -0,0 +1,93 @@
+package builder_test
+
+import (
+	"hide-pay/builder"
+	"hide-pay/utils"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lowOrderPoints returns the points of order 2 and 4 and a full-group point
+// G + T2 that is on the curve but outside the prime-order subgroup.
+func lowOrderPoints(t *testing.T) (twistededwardbn254.PointAffine, twistededwardbn254.PointAffine, twistededwardbn254.PointAffine) {
+	var minusOne fr.Element
+	minusOne.SetOne()
+	minusOne.Neg(&minusOne)
+
+	order2 := twistededwardbn254.NewPointAffine(fr.NewElement(0), minusOne)
+
+	// a*x^2 = 1 with a = -1
+	var x fr.Element
+	require.NotNil(t, x.Sqrt(&minusOne))
+	order4 := twistededwardbn254.NewPointAffine(x, fr.NewElement(0))
+
+	base := twistededwardbn254.GetEdwardsCurve().Base
+	var mixed twistededwardbn254.PointAffine
+	mixed.Add(&base, &order2)
+
+	require.True(t, order2.IsOnCurve())
+	require.True(t, order4.IsOnCurve())
+	require.True(t, mixed.IsOnCurve())
+
+	return order2, order4, mixed
+}
+
+func TestValidatePublicKey(t *testing.T) {
+	order2, order4, mixed := lowOrderPoints(t)
+
+	identity := twistededwardbn254.NewPointAffine(fr.NewElement(0), fr.NewElement(1))
+	offCurve := twistededwardbn254.NewPointAffine(fr.NewElement(1), fr.NewElement(2))
+	valid := utils.BuildPublicKey(*big.NewInt(11111))
+
+	assert.NoError(t, builder.ValidatePublicKey(&valid))
+	assert.ErrorIs(t, builder.ValidatePublicKey(&identity), builder.ErrPointIsIdentity)
+	assert.ErrorIs(t, builder.ValidatePublicKey(&offCurve), builder.ErrPointNotOnCurve)
+	assert.ErrorIs(t, builder.ValidatePublicKey(&twistededwardbn254.PointAffine{}), builder.ErrPointNotOnCurve)
+	assert.ErrorIs(t, builder.ValidatePublicKey(&order2), builder.ErrPointNotInSubgroup)
+	assert.ErrorIs(t, builder.ValidatePublicKey(&order4), builder.ErrPointNotInSubgroup)
+	assert.ErrorIs(t, builder.ValidatePublicKey(&mixed), builder.ErrPointNotInSubgroup)
+}
+
+func TestNewECDHFromPublicKey_LowOrder(t *testing.T) {
+	order2, order4, mixed := lowOrderPoints(t)
+
+	for _, point := range []twistededwardbn254.PointAffine{order2, order4, mixed} {
+		_, err := builder.NewECDHFromPublicKey(point, *big.NewInt(11111))
+		assert.ErrorIs(t, err, builder.ErrPointNotInSubgroup)
+	}
+
+	ecdh, err := builder.NewECDHFromPublicKey(utils.BuildPublicKey(*big.NewInt(22222)), *big.NewInt(11111))
+	require.NoError(t, err)
+	assert.Equal(t, builder.NewECDH(*big.NewInt(11111), *big.NewInt(22222)).Compute(), ecdh.Compute())
+}
+
+func TestMemo_RejectsLowOrderKeys(t *testing.T) {
+	order2, _, mixed := lowOrderPoints(t)
+
+	commitment, spentKey := builder.GenerateCommitment(12345)
+
+	_, err := builder.NewMemo(*big.NewInt(11111), order2)
+	assert.ErrorIs(t, err, builder.ErrPointNotInSubgroup)
+
+	memo := &builder.Memo{
+		SecretKey: *big.NewInt(11111),
+		PublicKey: mixed,
+	}
+
+	_, err = memo.Encrypt(*commitment, *spentKey)
+	assert.ErrorIs(t, err, builder.ErrPointNotInSubgroup)
+
+	envelope := &builder.EncryptedMemo{
+		Version:            builder.MemoVersion,
+		EphemeralPublicKey: mixed,
+		Ciphertext:         make([]fr.Element, 11),
+	}
+
+	_, _, err = envelope.Decrypt(*big.NewInt(11111))
+	assert.ErrorIs(t, err, builder.ErrPointNotInSubgroup)
+}
@@ -0,0 +1,156 @@
+package builder
+
+import (
+	"fmt"
+	"hide-pay/circuits"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+)
+
+// ReceiverInfo is what a receiver publishes to get paid: the owner key notes
+// are locked to, the viewing key owner memos are encrypted to and, optionally,
+// where to upload owner memos.
+type ReceiverInfo struct {
+	OwnerPubKey twistededwardbn254.PointAffine
+	ViewPubKey  twistededwardbn254.PointAffine
+	MemoStore   string
+}
+
+func (info *ReceiverInfo) Validate() error {
+	if err := ValidatePublicKey(&info.OwnerPubKey); err != nil {
+		return fmt.Errorf("invalid owner key: %w", err)
+	}
+
+	if err := ValidatePublicKey(&info.ViewPubKey); err != nil {
+		return fmt.Errorf("invalid viewing key: %w", err)
+	}
+
+	return nil
+}
+
+// Payment is the sender's view of one output at send time.
+type Payment struct {
+	Commitment         Commitment
+	SpentKey           fr.Element
+	EphemeralSecretKey big.Int
+	Receiver           ReceiverInfo
+}
+
+// PaymentReceipt lets a sender prove offline that the output Commitment pays
+// Amount of Asset to a receiver, without revealing the rest of the note.
+type PaymentReceipt struct {
+	Commitment         fr.Element
+	EphemeralPublicKey twistededwardbn254.PointAffine
+	Asset              fr.Element
+	Amount             fr.Element
+	Proof              groth16.Proof
+}
+
+// Memo returns the owner memo to publish with the output.
+func (payment *Payment) Memo() (*EncryptedMemo, error) {
+	memo, err := NewMemo(payment.EphemeralSecretKey, payment.Receiver.ViewPubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return memo.Encrypt(payment.Commitment, payment.SpentKey)
+}
+
+func (payment *Payment) ToWitness() (*circuits.ReceiptCircuit, error) {
+	envelope, err := payment.Memo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt memo: %w", err)
+	}
+
+	witness := receiptPublicWitness(
+		payment.Commitment.Compute(),
+		payment.Commitment.Asset,
+		payment.Commitment.Amount,
+		&payment.Receiver,
+		envelope,
+	)
+
+	witness.Opening = *payment.Commitment.ToGadget()
+	witness.SpentKey = payment.SpentKey
+	witness.EphemeralSecretKey = payment.EphemeralSecretKey
+
+	return witness, nil
+}
+
+// Receipt proves the payment. It is meant to be called at send time, while
+// the ephemeral secret key is still at hand.
+func (payment *Payment) Receipt(cs constraint.ConstraintSystem, pk groth16.ProvingKey) (*PaymentReceipt, error) {
+	assignment, err := payment.ToWitness()
+	if err != nil {
+		return nil, err
+	}
+
+	witness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("failed to build witness: %w", err)
+	}
+
+	proof, err := groth16.Prove(cs, pk, witness)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prove: %w", err)
+	}
+
+	receipt := &PaymentReceipt{
+		Commitment: payment.Commitment.Compute(),
+		Asset:      payment.Commitment.Asset,
+		Amount:     payment.Commitment.Amount,
+		Proof:      proof,
+	}
+
+	base := twistededwardbn254.GetEdwardsCurve().Base
+	receipt.EphemeralPublicKey.ScalarMultiplication(&base, &payment.EphemeralSecretKey)
+
+	return receipt, nil
+}
+
+// VerifyPaymentReceipt checks a receipt against the commitment and owner memo
+// published on the ledger and the receiver's published info.
+func VerifyPaymentReceipt(vk groth16.VerifyingKey, receipt *PaymentReceipt, commitment fr.Element, memo *EncryptedMemo, receiver ReceiverInfo) error {
+	if receipt.Commitment != commitment {
+		return fmt.Errorf("receipt is for commitment %s, not %s", receipt.Commitment.Text(10), commitment.Text(10))
+	}
+
+	if !receipt.EphemeralPublicKey.Equal(&memo.EphemeralPublicKey) {
+		return fmt.Errorf("receipt ephemeral key does not match the published memo")
+	}
+
+	if err := receiver.Validate(); err != nil {
+		return fmt.Errorf("invalid receiver info: %w", err)
+	}
+
+	assignment := receiptPublicWitness(receipt.Commitment, receipt.Asset, receipt.Amount, &receiver, memo)
+
+	publicWitness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		return fmt.Errorf("failed to build public witness: %w", err)
+	}
+
+	if err := groth16.Verify(receipt.Proof, vk, publicWitness); err != nil {
+		return fmt.Errorf("invalid receipt proof: %w", err)
+	}
+
+	return nil
+}
+
+func receiptPublicWitness(commitment fr.Element, asset fr.Element, amount fr.Element, receiver *ReceiverInfo, memo *EncryptedMemo) *circuits.ReceiptCircuit {
+	return &circuits.ReceiptCircuit{
+		Commitment:          commitment,
+		Asset:               asset,
+		Amount:              amount,
+		ReceiverOwnerPubKey: [2]frontend.Variable{receiver.OwnerPubKey.X, receiver.OwnerPubKey.Y},
+		ReceiverViewPubKey:  [2]frontend.Variable{receiver.ViewPubKey.X, receiver.ViewPubKey.Y},
+		EphemeralPublicKey:  [2]frontend.Variable{memo.EphemeralPublicKey.X, memo.EphemeralPublicKey.Y},
+		MemoHash:            memo.Hash(),
+	}
+}
@@ -0,0 +1,79 @@
+package builder_test
+
+import (
+	"hide-pay/builder"
+	"hide-pay/circuits"
+	"hide-pay/utils"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPayment() *builder.Payment {
+	receiver := builder.ReceiverInfo{
+		OwnerPubKey: utils.BuildPublicKey(*big.NewInt(11111)),
+		ViewPubKey:  utils.BuildPublicKey(*big.NewInt(22222)),
+	}
+
+	commitment, spentKey := builder.GenerateCommitment(12345)
+	commitment.OwnerPubKey = receiver.OwnerPubKey
+	commitment.ViewPubKey = receiver.ViewPubKey
+
+	return &builder.Payment{
+		Commitment:         *commitment,
+		SpentKey:           *spentKey,
+		EphemeralSecretKey: *big.NewInt(33333),
+		Receiver:           receiver,
+	}
+}
+
+func TestPaymentReceipt(t *testing.T) {
+	cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &circuits.ReceiptCircuit{})
+	require.NoError(t, err)
+
+	pk, vk, err := groth16.Setup(cs)
+	require.NoError(t, err)
+
+	payment := newPayment()
+
+	memo, err := payment.Memo()
+	require.NoError(t, err)
+	commitment := payment.Commitment.Compute()
+
+	receipt, err := payment.Receipt(cs, pk)
+	require.NoError(t, err)
+	assert.Equal(t, payment.Commitment.Amount, receipt.Amount)
+
+	require.NoError(t, builder.VerifyPaymentReceipt(vk, receipt, commitment, memo, payment.Receiver))
+
+	// The receiver can open the memo the receipt vouches for
+	opening, _, err := memo.Decrypt(*big.NewInt(22222))
+	require.NoError(t, err)
+	assert.Equal(t, commitment, opening.Compute())
+
+	// Another receiver
+	other := payment.Receiver
+	other.OwnerPubKey = utils.BuildPublicKey(*big.NewInt(44444))
+	assert.Error(t, builder.VerifyPaymentReceipt(vk, receipt, commitment, memo, other))
+
+	// Overstated amount
+	tampered := *receipt
+	tampered.Amount.SetUint64(1)
+	assert.Error(t, builder.VerifyPaymentReceipt(vk, &tampered, commitment, memo, payment.Receiver))
+
+	// Another memo for the same note
+	payment.EphemeralSecretKey = *big.NewInt(55555)
+	otherMemo, err := payment.Memo()
+	require.NoError(t, err)
+	assert.Error(t, builder.VerifyPaymentReceipt(vk, receipt, commitment, otherMemo, payment.Receiver))
+
+	// Another commitment
+	assert.Error(t, builder.VerifyPaymentReceipt(vk, receipt, fr.NewElement(1), memo, payment.Receiver))
+}
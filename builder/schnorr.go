@@ -84,6 +84,15 @@ func (kp *Keypair) Sign(random fr.Element, messageHash fr.Element) *Signature {
 	return signature
 }
 
+// SignDeterministic signs messageHash with a nonce derived from the secret key
+// and the message, so the same key never reuses a nonce across messages.
+// Optional extraEntropy is mixed into the derivation.
+func (kp *Keypair) SignDeterministic(messageHash fr.Element, extraEntropy ...fr.Element) *Signature {
+	nonce := deriveNonce(kp.SecretKey, messageHash, extraEntropy)
+
+	return kp.Sign(nonce, messageHash)
+}
+
 func Verify(messageHash fr.Element, signature *Signature, publicKey *twistededwardbn254.PointAffine) bool {
 	c := computeHash(messageHash, &signature.R, publicKey)
 
@@ -125,3 +134,37 @@ func computeHash(message fr.Element, R *twistededwardbn254.PointAffine, P *twist
 
 	return hash
 }
+
+// deriveNonce follows RFC6979 in spirit with Poseidon2 in place of HMAC-DRBG:
+// candidates H(secretKey, message, extraEntropy..., counter) are drawn until
+// one is a non-zero scalar below the curve order.
+func deriveNonce(secretKey fr.Element, message fr.Element, extraEntropy []fr.Element) fr.Element {
+	order := twistededwardbn254.GetEdwardsCurve().Order
+
+	secretKeyBytes := secretKey.Bytes()
+	messageBytes := message.Bytes()
+
+	for counter := uint64(0); ; counter++ {
+		hasher := poseidon2.NewMerkleDamgardHasher()
+
+		hasher.Write(secretKeyBytes[:])
+		hasher.Write(messageBytes[:])
+
+		for i := range extraEntropy {
+			entropyBytes := extraEntropy[i].Bytes()
+			hasher.Write(entropyBytes[:])
+		}
+
+		counterElement := fr.NewElement(counter)
+		counterBytes := counterElement.Bytes()
+
+		nonceBytes := hasher.Sum(counterBytes[:])
+
+		nonce := fr.Element{}
+		nonce.SetBytes(nonceBytes)
+
+		if !nonce.IsZero() && nonce.BigInt(new(big.Int)).Cmp(&order) < 0 {
+			return nonce
+		}
+	}
+}
@@ -265,6 +265,76 @@ func TestComputeHash_DifferentInputs(t *testing.T) {
 	assert.NotEqual(t, sig1.R.X, sig2.R.X)
 }
 
+func TestSignDeterministic_Verify(t *testing.T) {
+	kp, err := builder.GenerateKeypair()
+	require.NoError(t, err)
+
+	messageHash := fr.NewElement(12345)
+	signature := kp.SignDeterministic(messageHash)
+
+	result := builder.Verify(messageHash, signature, &kp.PublicKey)
+	assert.True(t, result)
+}
+
+func TestSignDeterministic_SameMessage(t *testing.T) {
+	kp, err := builder.GenerateKeypairWithSeed(fr.NewElement(12345))
+	require.NoError(t, err)
+
+	messageHash := fr.NewElement(12345)
+
+	sig1 := kp.SignDeterministic(messageHash)
+	sig2 := kp.SignDeterministic(messageHash)
+
+	assert.Equal(t, sig1.R, sig2.R)
+	assert.Equal(t, sig1.S, sig2.S)
+}
+
+func TestSignDeterministic_DifferentMessages(t *testing.T) {
+	kp, err := builder.GenerateKeypairWithSeed(fr.NewElement(12345))
+	require.NoError(t, err)
+
+	sig1 := kp.SignDeterministic(fr.NewElement(12345))
+	sig2 := kp.SignDeterministic(fr.NewElement(67890))
+
+	// Distinct messages must never share a nonce
+	assert.NotEqual(t, sig1.R, sig2.R)
+	assert.NotEqual(t, sig1.S, sig2.S)
+}
+
+func TestSignDeterministic_DifferentKeys(t *testing.T) {
+	kp1, err := builder.GenerateKeypairWithSeed(fr.NewElement(12345))
+	require.NoError(t, err)
+
+	kp2, err := builder.GenerateKeypairWithSeed(fr.NewElement(54321))
+	require.NoError(t, err)
+
+	messageHash := fr.NewElement(12345)
+
+	sig1 := kp1.SignDeterministic(messageHash)
+	sig2 := kp2.SignDeterministic(messageHash)
+
+	assert.NotEqual(t, sig1.R, sig2.R)
+}
+
+func TestSignDeterministic_ExtraEntropy(t *testing.T) {
+	kp, err := builder.GenerateKeypairWithSeed(fr.NewElement(12345))
+	require.NoError(t, err)
+
+	messageHash := fr.NewElement(12345)
+
+	sig1 := kp.SignDeterministic(messageHash)
+	sig2 := kp.SignDeterministic(messageHash, fr.NewElement(1))
+	sig3 := kp.SignDeterministic(messageHash, fr.NewElement(2))
+	sig4 := kp.SignDeterministic(messageHash, fr.NewElement(2))
+
+	assert.NotEqual(t, sig1.R, sig2.R)
+	assert.NotEqual(t, sig2.R, sig3.R)
+	assert.Equal(t, sig3, sig4)
+
+	assert.True(t, builder.Verify(messageHash, sig2, &kp.PublicKey))
+	assert.True(t, builder.Verify(messageHash, sig3, &kp.PublicKey))
+}
+
 // 基准测试
 func BenchmarkGenerateKeypair(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -294,6 +364,20 @@ func BenchmarkSign(b *testing.B) {
 	}
 }
 
+func BenchmarkSignDeterministic(b *testing.B) {
+	kp, err := builder.GenerateKeypair()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	messageHash := fr.NewElement(12345)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		kp.SignDeterministic(messageHash)
+	}
+}
+
 func BenchmarkVerify(b *testing.B) {
 	random := fr.NewElement(12345)
 
@@ -0,0 +1,285 @@
+package builder
+
+import (
+	"fmt"
+	"hide-pay/circuits"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+)
+
+// AuditKeyShare is one auditor's Shamir share x_i = f(i) of the audit secret
+// key, together with its public share X_i = x_i * G.
+type AuditKeyShare struct {
+	Index       int
+	SecretShare big.Int
+	PublicShare twistededwardbn254.PointAffine
+}
+
+// DLEQProof proves log_G(X_i) == log_E(D_i) without revealing the share.
+type DLEQProof struct {
+	Challenge fr.Element
+	Response  fr.Element
+}
+
+// PartialDecryption is an auditor's contribution D_i = x_i * E to the shared
+// key of a memo with ephemeral public key E.
+type PartialDecryption struct {
+	Index int
+	Point twistededwardbn254.PointAffine
+	Proof DLEQProof
+}
+
+// SplitAuditKey shares secretKey among total auditors so that any threshold of
+// them can rebuild ECDH shared keys. The returned Feldman commitments a_k * G
+// let every auditor check its share; commitments[0] is the AuditPubKey.
+func SplitAuditKey(secretKey big.Int, threshold int, total int) ([]AuditKeyShare, []twistededwardbn254.PointAffine, error) {
+	if threshold < 1 || threshold > total {
+		return nil, nil, fmt.Errorf("threshold must be between 1 and %d, got %d", total, threshold)
+	}
+
+	curve := twistededwardbn254.GetEdwardsCurve()
+
+	coefficients := make([]big.Int, threshold)
+	coefficients[0].Mod(&secretKey, &curve.Order)
+
+	for k := 1; k < threshold; k++ {
+		coefficient, err := circuits.CreateSeedFromRand()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate coefficient: %w", err)
+		}
+		coefficients[k] = coefficient
+	}
+
+	commitments := make([]twistededwardbn254.PointAffine, threshold)
+	for k := range coefficients {
+		commitments[k].ScalarMultiplication(&curve.Base, &coefficients[k])
+	}
+
+	shares := make([]AuditKeyShare, total)
+	for i := range shares {
+		index := big.NewInt(int64(i + 1))
+
+		// Horner evaluation of f(index)
+		value := new(big.Int)
+		for k := threshold - 1; k >= 0; k-- {
+			value.Mul(value, index)
+			value.Add(value, &coefficients[k])
+			value.Mod(value, &curve.Order)
+		}
+
+		shares[i].Index = i + 1
+		shares[i].SecretShare = *value
+		shares[i].PublicShare.ScalarMultiplication(&curve.Base, value)
+	}
+
+	return shares, commitments, nil
+}
+
+// VerifyAuditKeyShare checks x_i * G == sum(C_k * i^k) against the dealer's
+// Feldman commitments.
+func VerifyAuditKeyShare(share *AuditKeyShare, commitments []twistededwardbn254.PointAffine) bool {
+	curve := twistededwardbn254.GetEdwardsCurve()
+
+	var expected twistededwardbn254.PointAffine
+	expected.ScalarMultiplication(&curve.Base, &share.SecretShare)
+
+	if !expected.Equal(&share.PublicShare) {
+		return false
+	}
+
+	return expected.Equal(evaluateCommitments(share.Index, commitments))
+}
+
+// PublicShareFromCommitments derives auditor index's public share X_i from the
+// Feldman commitments, so partial decryptions can be checked by anyone.
+func PublicShareFromCommitments(index int, commitments []twistededwardbn254.PointAffine) twistededwardbn254.PointAffine {
+	return *evaluateCommitments(index, commitments)
+}
+
+func evaluateCommitments(index int, commitments []twistededwardbn254.PointAffine) *twistededwardbn254.PointAffine {
+	curve := twistededwardbn254.GetEdwardsCurve()
+
+	var result twistededwardbn254.PointAffine
+	result.X.SetZero()
+	result.Y.SetOne()
+
+	power := big.NewInt(1)
+	base := big.NewInt(int64(index))
+
+	for k := range commitments {
+		var term twistededwardbn254.PointAffine
+		term.ScalarMultiplication(&commitments[k], power)
+		result.Add(&result, &term)
+
+		power = new(big.Int).Mul(power, base)
+		power.Mod(power, &curve.Order)
+	}
+
+	return &result
+}
+
+// PartialDecrypt computes D_i = x_i * E and proves it is consistent with the
+// auditor's public share.
+func (share *AuditKeyShare) PartialDecrypt(ephemeralPublicKey twistededwardbn254.PointAffine) (*PartialDecryption, error) {
+	if err := ValidatePublicKey(&ephemeralPublicKey); err != nil {
+		return nil, fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+
+	curve := twistededwardbn254.GetEdwardsCurve()
+
+	var point twistededwardbn254.PointAffine
+	point.ScalarMultiplication(&ephemeralPublicKey, &share.SecretShare)
+
+	k, err := circuits.CreateSeedFromRand()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate proof nonce: %w", err)
+	}
+
+	var a1, a2 twistededwardbn254.PointAffine
+	a1.ScalarMultiplication(&curve.Base, &k)
+	a2.ScalarMultiplication(&ephemeralPublicKey, &k)
+
+	challenge := dleqChallenge(&share.PublicShare, &ephemeralPublicKey, &point, &a1, &a2)
+
+	response := new(big.Int).Mul(challenge.BigInt(new(big.Int)), &share.SecretShare)
+	response.Add(response, &k)
+	response.Mod(response, &curve.Order)
+
+	proof := DLEQProof{Challenge: challenge}
+	proof.Response.SetBigInt(response)
+
+	return &PartialDecryption{
+		Index: share.Index,
+		Point: point,
+		Proof: proof,
+	}, nil
+}
+
+// VerifyPartialDecryption checks the DLEQ proof of a partial decryption
+// against the auditor's public share.
+func VerifyPartialDecryption(partial *PartialDecryption, publicShare twistededwardbn254.PointAffine, ephemeralPublicKey twistededwardbn254.PointAffine) bool {
+	if ValidatePublicKey(&ephemeralPublicKey) != nil || ValidatePublicKey(&partial.Point) != nil {
+		return false
+	}
+
+	curve := twistededwardbn254.GetEdwardsCurve()
+
+	challenge := partial.Proof.Challenge.BigInt(new(big.Int))
+	response := partial.Proof.Response.BigInt(new(big.Int))
+
+	// A1 = z*G - c*X_i, A2 = z*E - c*D_i
+	var zG, cX, a1 twistededwardbn254.PointAffine
+	zG.ScalarMultiplication(&curve.Base, response)
+	cX.ScalarMultiplication(&publicShare, challenge)
+	cX.Neg(&cX)
+	a1.Add(&zG, &cX)
+
+	var zE, cD, a2 twistededwardbn254.PointAffine
+	zE.ScalarMultiplication(&ephemeralPublicKey, response)
+	cD.ScalarMultiplication(&partial.Point, challenge)
+	cD.Neg(&cD)
+	a2.Add(&zE, &cD)
+
+	expected := dleqChallenge(&publicShare, &ephemeralPublicKey, &partial.Point, &a1, &a2)
+
+	return expected == partial.Proof.Challenge
+}
+
+// CombinePartialDecryptions checks every partial decryption of the memo with
+// ephemeral public key ephemeralPublicKey against the public share derived
+// from the dealer's Feldman commitments, then interpolates threshold of them in
+// the exponent. The threshold is len(commitments). It returns the same point
+// ECDH.Compute yields for the full key, and refuses to combine if any partial
+// fails its DLEQ proof.
+func CombinePartialDecryptions(partials []PartialDecryption, commitments []twistededwardbn254.PointAffine, ephemeralPublicKey twistededwardbn254.PointAffine) (twistededwardbn254.PointAffine, error) {
+	threshold := len(commitments)
+	if threshold < 1 {
+		return twistededwardbn254.PointAffine{}, fmt.Errorf("at least one commitment is required")
+	}
+
+	if len(partials) < threshold {
+		return twistededwardbn254.PointAffine{}, fmt.Errorf("need %d partial decryptions, got %d", threshold, len(partials))
+	}
+
+	seen := make(map[int]bool, len(partials))
+	for i := range partials {
+		if partials[i].Index < 1 {
+			return twistededwardbn254.PointAffine{}, fmt.Errorf("invalid share index %d", partials[i].Index)
+		}
+		if seen[partials[i].Index] {
+			return twistededwardbn254.PointAffine{}, fmt.Errorf("duplicate share index %d", partials[i].Index)
+		}
+		seen[partials[i].Index] = true
+	}
+
+	for i := range partials {
+		publicShare := PublicShareFromCommitments(partials[i].Index, commitments)
+		if !VerifyPartialDecryption(&partials[i], publicShare, ephemeralPublicKey) {
+			return twistededwardbn254.PointAffine{}, fmt.Errorf("partial decryption of share index %d fails its proof", partials[i].Index)
+		}
+	}
+
+	partials = partials[:threshold]
+
+	order := twistededwardbn254.GetEdwardsCurve().Order
+
+	var sharedKey twistededwardbn254.PointAffine
+	sharedKey.X.SetZero()
+	sharedKey.Y.SetOne()
+
+	for i := range partials {
+		lambda := lagrangeCoefficient(i, partials, &order)
+
+		var term twistededwardbn254.PointAffine
+		term.ScalarMultiplication(&partials[i].Point, lambda)
+		sharedKey.Add(&sharedKey, &term)
+	}
+
+	return sharedKey, nil
+}
+
+// lagrangeCoefficient returns prod_{j != i} x_j / (x_j - x_i) mod order.
+func lagrangeCoefficient(i int, partials []PartialDecryption, order *big.Int) *big.Int {
+	xi := big.NewInt(int64(partials[i].Index))
+
+	numerator := big.NewInt(1)
+	denominator := big.NewInt(1)
+
+	for j := range partials {
+		if j == i {
+			continue
+		}
+
+		xj := big.NewInt(int64(partials[j].Index))
+
+		numerator.Mul(numerator, xj)
+		numerator.Mod(numerator, order)
+
+		diff := new(big.Int).Sub(xj, xi)
+		denominator.Mul(denominator, diff)
+		denominator.Mod(denominator, order)
+	}
+
+	denominator.ModInverse(denominator, order)
+
+	lambda := numerator.Mul(numerator, denominator)
+	return lambda.Mod(lambda, order)
+}
+
+func dleqChallenge(publicShare, ephemeralPublicKey, point, a1, a2 *twistededwardbn254.PointAffine) fr.Element {
+	hasher := poseidon2.NewMerkleDamgardHasher()
+
+	writePoint(hasher, publicShare)
+	writePoint(hasher, ephemeralPublicKey)
+	writePoint(hasher, point)
+	writePoint(hasher, a1)
+	writePoint(hasher, a2)
+
+	challenge := fr.Element{}
+	challenge.SetBytes(hasher.Sum(nil))
+
+	return challenge
+}
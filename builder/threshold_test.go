@@ -0,0 +1,141 @@
+package builder_test
+
+import (
+	"hide-pay/builder"
+	"hide-pay/utils"
+	"math/big"
+	"testing"
+
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitAuditKey_VerifyShares(t *testing.T) {
+	auditSecretKey := big.NewInt(22222)
+
+	shares, commitments, err := builder.SplitAuditKey(*auditSecretKey, 3, 5)
+	require.NoError(t, err)
+	require.Len(t, shares, 5)
+	require.Len(t, commitments, 3)
+
+	auditPublicKey := utils.BuildPublicKey(*auditSecretKey)
+	assert.Equal(t, auditPublicKey, commitments[0])
+
+	for i := range shares {
+		assert.True(t, builder.VerifyAuditKeyShare(&shares[i], commitments))
+		assert.Equal(t, shares[i].PublicShare, builder.PublicShareFromCommitments(shares[i].Index, commitments))
+	}
+
+	tampered := shares[0]
+	tampered.SecretShare = *new(big.Int).Add(&tampered.SecretShare, big.NewInt(1))
+	assert.False(t, builder.VerifyAuditKeyShare(&tampered, commitments))
+}
+
+func TestSplitAuditKey_InvalidThreshold(t *testing.T) {
+	_, _, err := builder.SplitAuditKey(*big.NewInt(22222), 0, 5)
+	assert.Error(t, err)
+
+	_, _, err = builder.SplitAuditKey(*big.NewInt(22222), 6, 5)
+	assert.Error(t, err)
+}
+
+func TestThresholdAudit_DecryptMemo(t *testing.T) {
+	auditSecretKey := big.NewInt(22222)
+	auditPublicKey := utils.BuildPublicKey(*auditSecretKey)
+
+	shares, commitments, err := builder.SplitAuditKey(*auditSecretKey, 3, 5)
+	require.NoError(t, err)
+
+	commitment, spentKey := builder.GenerateCommitment(12345)
+
+	memo := &builder.Memo{
+		SecretKey: *big.NewInt(11111),
+		PublicKey: auditPublicKey,
+	}
+
+	envelope, err := memo.Encrypt(*commitment, *spentKey)
+	require.NoError(t, err)
+
+	ephemeralPublicKey := &envelope.EphemeralPublicKey
+
+	// Any three auditors are enough
+	partials := make([]builder.PartialDecryption, 0, 3)
+	for _, i := range []int{4, 1, 2} {
+		partial, err := shares[i].PartialDecrypt(*ephemeralPublicKey)
+		require.NoError(t, err)
+
+		publicShare := builder.PublicShareFromCommitments(partial.Index, commitments)
+		require.True(t, builder.VerifyPartialDecryption(partial, publicShare, *ephemeralPublicKey))
+
+		partials = append(partials, *partial)
+	}
+
+	sharedKey, err := builder.CombinePartialDecryptions(partials, commitments, *ephemeralPublicKey)
+	require.NoError(t, err)
+
+	ecdh := builder.ECDH{
+		PublicKey: *ephemeralPublicKey,
+		SecretKey: *auditSecretKey,
+	}
+	assert.Equal(t, ecdh.Compute(), sharedKey)
+
+	decrypted, decryptedSpentKey, err := envelope.DecryptWithSharedKey(sharedKey)
+	require.NoError(t, err)
+	assert.Equal(t, commitment.Asset, decrypted.Asset)
+	assert.Equal(t, commitment.Amount, decrypted.Amount)
+	assert.Equal(t, commitment.Blinding, decrypted.Blinding)
+	assert.Equal(t, *spentKey, *decryptedSpentKey)
+
+	// Fewer than threshold shares do not reconstruct the key
+	_, err = builder.CombinePartialDecryptions(partials[:2], commitments, *ephemeralPublicKey)
+	assert.Error(t, err)
+
+	// and a lower threshold derives other public shares, so the partials
+	// are refused instead of combined into a wrong key
+	_, err = builder.CombinePartialDecryptions(partials[:2], commitments[:2], *ephemeralPublicKey)
+	assert.Error(t, err)
+}
+
+func TestThresholdAudit_RejectForgedPartial(t *testing.T) {
+	auditSecretKey := big.NewInt(22222)
+
+	shares, commitments, err := builder.SplitAuditKey(*auditSecretKey, 2, 3)
+	require.NoError(t, err)
+
+	ephemeralPublicKey := utils.BuildPublicKey(*big.NewInt(11111))
+
+	partial, err := shares[0].PartialDecrypt(ephemeralPublicKey)
+	require.NoError(t, err)
+
+	publicShare := builder.PublicShareFromCommitments(partial.Index, commitments)
+
+	forged := *partial
+	forged.Point = utils.BuildPublicKey(*big.NewInt(33333))
+	assert.False(t, builder.VerifyPartialDecryption(&forged, publicShare, ephemeralPublicKey))
+
+	otherShare := builder.PublicShareFromCommitments(shares[1].Index, commitments)
+	assert.False(t, builder.VerifyPartialDecryption(partial, otherShare, ephemeralPublicKey))
+
+	// Combining refuses the forged partial and names its share
+	honest, err := shares[2].PartialDecrypt(ephemeralPublicKey)
+	require.NoError(t, err)
+
+	_, err = builder.CombinePartialDecryptions([]builder.PartialDecryption{forged, *honest}, commitments, ephemeralPublicKey)
+	assert.ErrorContains(t, err, "share index 1")
+
+	_, err = builder.CombinePartialDecryptions([]builder.PartialDecryption{*partial, *honest}, commitments, ephemeralPublicKey)
+	assert.NoError(t, err)
+}
+
+func TestCombinePartialDecryptions_DuplicateIndex(t *testing.T) {
+	partial := builder.PartialDecryption{
+		Index: 1,
+		Point: twistededwardbn254.GetEdwardsCurve().Base,
+	}
+
+	commitments := []twistededwardbn254.PointAffine{utils.BuildPublicKey(*big.NewInt(22222)), utils.BuildPublicKey(*big.NewInt(33333))}
+
+	_, err := builder.CombinePartialDecryptions([]builder.PartialDecryption{partial, partial}, commitments, utils.BuildPublicKey(*big.NewInt(11111)))
+	assert.ErrorContains(t, err, "duplicate")
+}
@@ -1,212 +1,339 @@
 package builder
 
-// import (
-// 	"fmt"
-// 	"hide-pay/circuits"
-// 	"math/big"
-// 	"reflect"
-
-// 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
-// 	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
-// 	"github.com/consensys/gnark/frontend"
-// )
-
-// type UTXO struct {
-// 	Nullifier   []Nullifier
-// 	MerkleProof []MerkleProof
-
-// 	Commitment                 []Commitment
-// 	EphemeralReceiverSecretKey []big.Int
-// 	EphemeralAuditSecretKey    []big.Int
-
-// 	ReceiverPublicKey twistededwardbn254.PointAffine
-// 	AuditPublicKey    twistededwardbn254.PointAffine
-// }
-
-// func (utxo *UTXO) ToGadget(allAsset []frontend.Variable) (*circuits.UTXOGadget, error) {
-// 	nullifiers := make([]circuits.NullifierGadget, len(utxo.Nullifier))
-// 	merkleProofPath := make([]frontend.Variable, 0)
-// 	merkleProofIndex := make([]frontend.Variable, len(utxo.MerkleProof))
-
-// 	commitments := make([]circuits.CommitmentGadget, len(utxo.Commitment))
-
-// 	for i := range utxo.Nullifier {
-// 		nullifiers[i] = *utxo.Nullifier[i].ToGadget()
-
-// 		merkleProof := utxo.MerkleProof[i].ToGadget()
-
-// 		merkleProofPath = append(merkleProofPath, merkleProof.Path...)
-// 		merkleProofIndex[i] = merkleProof.Leaf
-// 	}
-
-// 	for i := range utxo.Commitment {
-// 		commitments[i] = *utxo.Commitment[i].ToGadget()
-// 	}
-
-// 	ephemeralReceiverSecretKeys := make([]frontend.Variable, len(utxo.EphemeralReceiverSecretKey))
-// 	ephemeralAuditSecretKeys := make([]frontend.Variable, len(utxo.EphemeralAuditSecretKey))
-
-// 	for i := range utxo.EphemeralReceiverSecretKey {
-// 		ephemeralReceiverSecretKeys[i] = utxo.EphemeralReceiverSecretKey[i]
-// 	}
-
-// 	for i := range utxo.EphemeralAuditSecretKey {
-// 		ephemeralAuditSecretKeys[i] = utxo.EphemeralAuditSecretKey[i]
-// 	}
-
-// 	receiverPublicKey := [2]frontend.Variable{
-// 		utxo.ReceiverPublicKey.X,
-// 		utxo.ReceiverPublicKey.Y,
-// 	}
-
-// 	auditPublicKey := [2]frontend.Variable{
-// 		utxo.AuditPublicKey.X,
-// 		utxo.AuditPublicKey.Y,
-// 	}
-
-// 	return &circuits.UTXOGadget{
-// 		AllAsset:                   allAsset,
-// 		Nullifier:                  nullifiers,
-// 		Commitment:                 commitments,
-// 		EphemeralReceiverSecretKey: ephemeralReceiverSecretKeys,
-// 		EphemeralAuditSecretKey:    ephemeralAuditSecretKeys,
-// 		ReceiverPublicKey:          receiverPublicKey,
-// 		AuditPublicKey:             auditPublicKey,
-// 		MerkleProofPath:            merkleProofPath,
-// 		MerkleProofIndex:           merkleProofIndex,
-// 	}, nil
-// }
-
-// func addToAssetMapping(assetMapping map[fr.Element]*fr.Element, asset fr.Element, amount fr.Element) {
-// 	if _, ok := assetMapping[asset]; !ok {
-// 		assetMapping[asset] = &amount
-// 	} else {
-// 		assetMapping[asset].Add(assetMapping[asset], &amount)
-// 	}
-// }
-
-// func (utxo *UTXO) BuildAndCheck(spentKey fr.Element) (*UTXOResult, error) {
-// 	nullifiers := make([]fr.Element, len(utxo.Nullifier))
-// 	commitments := make([]UTXOCommitment, len(utxo.Commitment))
-
-// 	allAssetInput := make(map[fr.Element]*fr.Element)
-
-// 	currentRoot := fr.NewElement(0)
-
-// 	for i := range utxo.Nullifier {
-// 		utxoNullifier := utxo.Nullifier[i]
-
-// 		// zero := fr.NewElement(0)
-// 		// if utxoNullifier.Amount.Cmp(&zero) != 1 {
-// 		// 	return nil, fmt.Errorf("nullifier must be greater than 0")
-// 		// }
-
-// 		addToAssetMapping(allAssetInput, utxoNullifier.Asset, utxoNullifier.Amount)
-
-// 		nullifiers[i] = utxoNullifier.Compute()
-
-// 		merkleProof := utxo.MerkleProof[i]
-// 		merkleRoot := merkleProof.Verify()
-
-// 		if currentRoot.IsZero() {
-// 			currentRoot = merkleRoot
-// 		} else {
-// 			if currentRoot.Cmp(&merkleRoot) != 0 {
-// 				return nil, fmt.Errorf("merkle root mismatch")
-// 			}
-// 		}
-// 	}
-
-// 	result := UTXOResult{
-// 		Nullifiers:  nullifiers,
-// 		Commitments: commitments,
-// 		Root:        currentRoot,
-// 	}
-
-// 	allAssetOutput := make(map[fr.Element]*fr.Element)
-
-// 	for i := range utxo.Commitment {
-// 		utxoCommitment := utxo.Commitment[i]
-
-// 		zero := fr.NewElement(0)
-// 		if utxoCommitment.Amount.Cmp(&zero) != 1 {
-// 			return nil, fmt.Errorf("commitment must be greater than 0")
-// 		}
-
-// 		addToAssetMapping(allAssetOutput, utxoCommitment.Asset, utxoCommitment.Amount)
-
-// 		commitment := utxoCommitment.Compute()
-
-// 		ownerMemo := Memo{
-// 			SecretKey: utxo.EphemeralReceiverSecretKey[i],
-// 			PublicKey: utxo.ReceiverPublicKey,
-// 		}
-
-// 		ownerMemoEphemeralPublickKey, ownerMemoCiphertext, err := ownerMemo.Encrypt(utxoCommitment, spentKey)
-// 		if err != nil {
-// 			return nil, fmt.Errorf("failed to encrypt owner memo: %w", err)
-// 		}
-
-// 		ownerMemoData := [3]fr.Element{
-// 			ownerMemoCiphertext[0],
-// 			ownerMemoCiphertext[1],
-// 			ownerMemoCiphertext[2],
-// 		}
-// 		ownerHMAC := ownerMemoCiphertext[3]
-
-// 		auditMemo := Memo{
-// 			SecretKey: utxo.EphemeralAuditSecretKey[i],
-// 			PublicKey: utxo.AuditPublicKey,
-// 		}
-
-// 		auditMemoEphemeralPublickKey, auditMemoCiphertext, err := auditMemo.Encrypt(utxoCommitment, spentKey)
-// 		if err != nil {
-// 			return nil, fmt.Errorf("failed to encrypt audit memo: %w", err)
-// 		}
-
-// 		auditMemoData := [3]fr.Element{
-// 			auditMemoCiphertext[0],
-// 			auditMemoCiphertext[1],
-// 			auditMemoCiphertext[2],
-// 		}
-// 		auditHMAC := auditMemoCiphertext[3]
-
-// 		commitments[i] = UTXOCommitment{
-// 			Commitment:               commitment,
-// 			OwnerMemo:                ownerMemoData,
-// 			OwnerHMAC:                ownerHMAC,
-// 			OwnerEphemeralPublickKey: *ownerMemoEphemeralPublickKey,
-// 			AuditMemo:                auditMemoData,
-// 			AuditHMAC:                auditHMAC,
-// 			AuditEphemeralPublickKey: *auditMemoEphemeralPublickKey,
-// 		}
-// 	}
-
-// 	if !reflect.DeepEqual(allAssetInput, allAssetOutput) {
-// 		return nil, fmt.Errorf("input and output asset mapping must be the same")
-// 	}
-
-// 	for asset := range allAssetInput {
-// 		result.AllAsset = append(result.AllAsset, asset)
-// 	}
-
-// 	return &result, nil
-// }
-
-// func NewUTXOCircuitWitness(utxo *UTXO, utxoResult *UTXOResult) (*circuits.UTXOCircuit, error) {
-// 	allAsset := make([]frontend.Variable, len(utxoResult.AllAsset))
-
-// 	for i := range utxoResult.AllAsset {
-// 		allAsset[i] = utxoResult.AllAsset[i]
-// 	}
-
-// 	utxoGadget, err := utxo.ToGadget(allAsset)
-// 	if err != nil {
-// 		return nil, fmt.Errorf("failed to convert UTXO to gadget: %w", err)
-// 	}
-
-// 	return &circuits.UTXOCircuit{
-// 		UTXO:   *utxoGadget,
-// 		Result: *utxoResult.ToGadget(),
-// 	}, nil
-// }
+import (
+	"fmt"
+	"hide-pay/circuits"
+	"hide-pay/utils"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"sort"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"github.com/consensys/gnark/frontend"
+)
+
+type UTXO struct {
+	Nullifier   []Nullifier
+	MerkleProof []MerkleProof
+
+	Commitment []Commitment
+	// SpentKey holds, for every output, the key whose hash is its spent
+	// address. It is delivered to the receiver in the memos.
+	SpentKey                   []fr.Element
+	EphemeralReceiverSecretKey []big.Int
+	EphemeralAuditSecretKey    []big.Int
+
+	AuditPublicKey twistededwardbn254.PointAffine
+
+	// OutgoingViewingKey is optional. When set, every output also gets an
+	// outgoing memo the sender can reopen it with later.
+	OutgoingViewingKey *fr.Element
+}
+
+func (utxo *UTXO) ToGadget(allAsset []frontend.Variable) (*circuits.UTXOGadget, error) {
+	if len(utxo.Nullifier) != len(utxo.MerkleProof) {
+		return nil, fmt.Errorf("number of nullifiers and merkle proofs must be the same")
+	}
+
+	nullifiers := make([]circuits.NullifierGadget, len(utxo.Nullifier))
+	merkleProofPath := make([]frontend.Variable, 0)
+	merkleProofIndex := make([]frontend.Variable, len(utxo.MerkleProof))
+
+	commitments := make([]circuits.CommitmentGadget, len(utxo.Commitment))
+	spentKeys := make([]frontend.Variable, len(utxo.SpentKey))
+
+	for i := range utxo.Nullifier {
+		nullifiers[i] = *utxo.Nullifier[i].ToGadget()
+
+		merkleProof := utxo.MerkleProof[i].ToGadget()
+
+		merkleProofPath = append(merkleProofPath, merkleProof.Path...)
+		merkleProofIndex[i] = merkleProof.Leaf
+	}
+
+	for i := range utxo.Commitment {
+		commitments[i] = *utxo.Commitment[i].ToGadget()
+	}
+
+	for i := range utxo.SpentKey {
+		spentKeys[i] = utxo.SpentKey[i]
+	}
+
+	ephemeralReceiverSecretKeys := make([]frontend.Variable, len(utxo.EphemeralReceiverSecretKey))
+	ephemeralAuditSecretKeys := make([]frontend.Variable, len(utxo.EphemeralAuditSecretKey))
+
+	for i := range utxo.EphemeralReceiverSecretKey {
+		ephemeralReceiverSecretKeys[i] = utxo.EphemeralReceiverSecretKey[i]
+	}
+
+	for i := range utxo.EphemeralAuditSecretKey {
+		ephemeralAuditSecretKeys[i] = utxo.EphemeralAuditSecretKey[i]
+	}
+
+	auditPublicKey := [2]frontend.Variable{
+		utxo.AuditPublicKey.X,
+		utxo.AuditPublicKey.Y,
+	}
+
+	return &circuits.UTXOGadget{
+		AllAsset:                   allAsset,
+		Nullifier:                  nullifiers,
+		Commitment:                 commitments,
+		SpentKey:                   spentKeys,
+		EphemeralReceiverSecretKey: ephemeralReceiverSecretKeys,
+		EphemeralAuditSecretKey:    ephemeralAuditSecretKeys,
+		AuditPublicKey:             auditPublicKey,
+		MerkleProofPath:            merkleProofPath,
+		MerkleProofIndex:           merkleProofIndex,
+	}, nil
+}
+
+func addToAssetMapping(assetMapping map[fr.Element]fr.Element, asset fr.Element, amount fr.Element) {
+	sum := assetMapping[asset]
+	sum.Add(&sum, &amount)
+	assetMapping[asset] = sum
+}
+
+// checkAmount tells whether amount fits in circuits.AmountBits bits.
+func checkAmount(amount fr.Element) bool {
+	var value big.Int
+	return amount.BigInt(&value).BitLen() <= circuits.AmountBits
+}
+
+// BuildAndCheck runs the checks of circuits.UTXOGadget natively and encrypts
+// the owner and audit memos of every output, plus its outgoing memo when the
+// transfer has an outgoing viewing key.
+func (utxo *UTXO) BuildAndCheck() (*UTXOResult, error) {
+	if len(utxo.Nullifier) == 0 {
+		return nil, fmt.Errorf("at least one nullifier is required")
+	}
+
+	if len(utxo.Nullifier) != len(utxo.MerkleProof) {
+		return nil, fmt.Errorf("number of nullifiers and merkle proofs must be the same")
+	}
+
+	if len(utxo.Commitment) != len(utxo.SpentKey) || len(utxo.Commitment) != len(utxo.EphemeralReceiverSecretKey) || len(utxo.Commitment) != len(utxo.EphemeralAuditSecretKey) {
+		return nil, fmt.Errorf("number of commitments, spent keys, and ephemeral receiver and audit secret keys must be the same")
+	}
+
+	nullifiers := make([]fr.Element, len(utxo.Nullifier))
+	commitments := make([]UTXOCommitment, len(utxo.Commitment))
+
+	allAssetInput := make(map[fr.Element]fr.Element)
+
+	var root fr.Element
+
+	for i := range utxo.Nullifier {
+		utxoNullifier := utxo.Nullifier[i]
+
+		if !utxoNullifier.FreezeFlag.IsZero() {
+			return nil, fmt.Errorf("nullifier %d spends a frozen note", i)
+		}
+
+		if !checkAmount(utxoNullifier.Amount) {
+			return nil, fmt.Errorf("nullifier %d amount exceeds %d bits", i, circuits.AmountBits)
+		}
+
+		addToAssetMapping(allAssetInput, utxoNullifier.Asset, utxoNullifier.Amount)
+
+		nullifiers[i] = utxoNullifier.Compute()
+
+		merkleProof := utxo.MerkleProof[i]
+		if merkleProof.depth != utxo.MerkleProof[0].depth {
+			return nil, fmt.Errorf("merkle proof %d has depth %d, expected %d", i, merkleProof.depth, utxo.MerkleProof[0].depth)
+		}
+
+		if merkleProof.Leaf() != utxoNullifier.Commitment.Compute() {
+			return nil, fmt.Errorf("merkle proof %d is not for the commitment of nullifier %d", i, i)
+		}
+
+		if !CheckSpentAddress(utxoNullifier.SpentAddress, utxoNullifier.SpentPrivateKey) {
+			return nil, fmt.Errorf("nullifier %d private key does not match its spent address", i)
+		}
+
+		merkleRoot := merkleProof.Verify()
+
+		if i == 0 {
+			root = merkleRoot
+		} else if root != merkleRoot {
+			return nil, fmt.Errorf("merkle root mismatch")
+		}
+	}
+
+	allAssetOutput := make(map[fr.Element]fr.Element)
+
+	for i := range utxo.Commitment {
+		utxoCommitment := utxo.Commitment[i]
+
+		if utxoCommitment.Amount.IsZero() {
+			return nil, fmt.Errorf("commitment must be greater than 0")
+		}
+
+		if !checkAmount(utxoCommitment.Amount) {
+			return nil, fmt.Errorf("commitment %d amount exceeds %d bits", i, circuits.AmountBits)
+		}
+
+		if !utxoCommitment.FreezeFlag.IsZero() {
+			return nil, fmt.Errorf("commitment %d is frozen, only a freeze creates frozen notes", i)
+		}
+
+		if !CheckSpentAddress(utxoCommitment.SpentAddress, utxo.SpentKey[i]) {
+			return nil, fmt.Errorf("spent key %d does not match the spent address of commitment %d", i, i)
+		}
+
+		if utxoCommitment.AuditPubKey != utxo.AuditPublicKey {
+			return nil, fmt.Errorf("commitment %d is not for the audit public key of the transfer", i)
+		}
+
+		addToAssetMapping(allAssetOutput, utxoCommitment.Asset, utxoCommitment.Amount)
+
+		ownerMemo := Memo{
+			SecretKey: utxo.EphemeralReceiverSecretKey[i],
+			PublicKey: utxoCommitment.ViewPubKey,
+		}
+
+		ownerEnvelope, err := ownerMemo.Encrypt(utxoCommitment, utxo.SpentKey[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt owner memo: %w", err)
+		}
+
+		auditMemo := Memo{
+			SecretKey: utxo.EphemeralAuditSecretKey[i],
+			PublicKey: utxo.AuditPublicKey,
+		}
+
+		auditEnvelope, err := auditMemo.Encrypt(utxoCommitment, utxo.SpentKey[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt audit memo: %w", err)
+		}
+
+		commitments[i] = UTXOCommitment{
+			Commitment: utxoCommitment.Compute(),
+			OwnerMemo:  *ownerEnvelope,
+			AuditMemo:  *auditEnvelope,
+		}
+
+		if utxo.OutgoingViewingKey != nil {
+			outgoingEnvelope, err := ownerMemo.EncryptOutgoing(*utxo.OutgoingViewingKey, commitments[i].Commitment)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encrypt outgoing memo: %w", err)
+			}
+
+			commitments[i].OutgoingMemo = outgoingEnvelope
+		}
+	}
+
+	if !reflect.DeepEqual(allAssetInput, allAssetOutput) {
+		return nil, fmt.Errorf("input and output asset mapping must be the same")
+	}
+
+	result := UTXOResult{
+		Nullifiers:  nullifiers,
+		Commitments: commitments,
+		Root:        root,
+	}
+
+	for asset := range allAssetInput {
+		result.AllAsset = append(result.AllAsset, asset)
+	}
+
+	// Map order is random, keep the witness reproducible
+	sort.Slice(result.AllAsset, func(i, j int) bool {
+		return result.AllAsset[i].Cmp(&result.AllAsset[j]) < 0
+	})
+
+	return &result, nil
+}
+
+// CheckSpentAddress tells whether spentKey hashes to spentAddress, as the
+// circuits require of every spent and created note.
+func CheckSpentAddress(spentAddress fr.Element, spentKey fr.Element) bool {
+	return utils.BuildAddress(*spentKey.BigInt(new(big.Int))) == spentAddress
+}
+
+func NewUTXOCircuitWitness(utxo *UTXO, utxoResult *UTXOResult) (*circuits.UTXOCircuit, error) {
+	allAsset := make([]frontend.Variable, len(utxoResult.AllAsset))
+
+	for i := range utxoResult.AllAsset {
+		allAsset[i] = utxoResult.AllAsset[i]
+	}
+
+	utxoGadget, err := utxo.ToGadget(allAsset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert UTXO to gadget: %w", err)
+	}
+
+	return &circuits.UTXOCircuit{
+		UTXO:   *utxoGadget,
+		Result: *utxoResult.ToGadget(),
+	}, nil
+}
+
+// GenerateUTXO builds a balanced transfer of one asset from nullifierSize
+// notes in a tree of the given depth to commitmentSize new notes, for tests
+// and benchmarks.
+func GenerateUTXO(seed int64, depth int, nullifierSize int, commitmentSize int) (*UTXO, error) {
+	if nullifierSize < 1 || commitmentSize < 1 {
+		return nil, fmt.Errorf("at least one nullifier and one commitment are required")
+	}
+
+	if nullifierSize > 1<<(depth-1) {
+		return nil, fmt.Errorf("a tree of depth %d cannot hold %d notes", depth, nullifierSize)
+	}
+
+	rnd := rand.New(rand.NewSource(seed))
+
+	asset := fr.NewElement(rnd.Uint64())
+	total := uint64(6 * nullifierSize)
+
+	if uint64(commitmentSize) > total {
+		return nil, fmt.Errorf("cannot split %d into %d positive amounts", total, commitmentSize)
+	}
+
+	utxo := &UTXO{
+		AuditPublicKey: utils.BuildPublicKey(*big.NewInt(rnd.Int63())),
+	}
+
+	leaves := make([]fr.Element, nullifierSize)
+
+	for i := 0; i < nullifierSize; i++ {
+		commitment, spentKey := GenerateCommitment(rnd.Int63())
+		commitment.Asset = asset
+		commitment.Amount = fr.NewElement(6)
+
+		utxo.Nullifier = append(utxo.Nullifier, Nullifier{
+			Commitment:      *commitment,
+			SpentPrivateKey: *spentKey,
+		})
+		leaves[i] = commitment.Compute()
+	}
+
+	merkleTree := NewMerkleTree(depth, poseidon2.NewMerkleDamgardHasher())
+	merkleTree.Build(leaves)
+
+	for i := range leaves {
+		utxo.MerkleProof = append(utxo.MerkleProof, merkleTree.GetProof(i))
+	}
+
+	for i := 0; i < commitmentSize; i++ {
+		amount := total / uint64(commitmentSize)
+		if i == commitmentSize-1 {
+			amount = total - amount*uint64(commitmentSize-1)
+		}
+
+		commitment, spentKey := GenerateCommitment(rnd.Int63())
+		commitment.Asset = asset
+		commitment.Amount = fr.NewElement(amount)
+		commitment.AuditPubKey = utxo.AuditPublicKey
+
+		utxo.Commitment = append(utxo.Commitment, *commitment)
+		utxo.SpentKey = append(utxo.SpentKey, *spentKey)
+		utxo.EphemeralReceiverSecretKey = append(utxo.EphemeralReceiverSecretKey, *big.NewInt(rnd.Int63()))
+		utxo.EphemeralAuditSecretKey = append(utxo.EphemeralAuditSecretKey, *big.NewInt(rnd.Int63()))
+	}
+
+	return utxo, nil
+}
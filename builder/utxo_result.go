@@ -1,48 +1,72 @@
 package builder
 
-// type UTXOResult struct {
-// 	Nullifiers  []fr.Element
-// 	Commitments []UTXOCommitment
-// 	AllAsset    []fr.Element
-// 	Root        fr.Element
-// }
-
-// type UTXOCommitment struct {
-// 	Commitment               fr.Element
-// 	OwnerMemo                [3]fr.Element
-// 	OwnerHMAC                fr.Element
-// 	OwnerEphemeralPublickKey twistededwardbn254.PointAffine
-// 	AuditMemo                [3]fr.Element
-// 	AuditHMAC                fr.Element
-// 	AuditEphemeralPublickKey twistededwardbn254.PointAffine
-// }
-
-// func (result *UTXOResult) ToGadget() *circuits.UTXOResultGadget {
-// 	nullifiers := make([]frontend.Variable, len(result.Nullifiers))
-// 	commitments := make([]frontend.Variable, len(result.Commitments))
-// 	ownerMemoHashes := make([]frontend.Variable, len(result.Commitments))
-// 	auditMemoHashes := make([]frontend.Variable, len(result.Commitments))
-// 	allAsset := make([]frontend.Variable, len(result.AllAsset))
-
-// 	for i := range result.Nullifiers {
-// 		nullifiers[i] = result.Nullifiers[i]
-// 	}
-
-// 	for i := range result.Commitments {
-// 		commitments[i] = result.Commitments[i].Commitment
-// 		ownerMemoHashes[i] = result.Commitments[i].OwnerHMAC
-// 		auditMemoHashes[i] = result.Commitments[i].AuditHMAC
-// 	}
-
-// 	for i := range result.AllAsset {
-// 		allAsset[i] = result.AllAsset[i]
-// 	}
-
-// 	return &circuits.UTXOResultGadget{
-// 		Nullifiers:      nullifiers,
-// 		Commitments:     commitments,
-// 		OwnerMemoHashes: ownerMemoHashes,
-// 		AuditMemoHashes: auditMemoHashes,
-// 		MerkleRoot:      result.Root,
-// 	}
-// }
+import (
+	"fmt"
+	"hide-pay/circuits"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+)
+
+type UTXOResult struct {
+	Nullifiers  []fr.Element
+	Commitments []UTXOCommitment
+	AllAsset    []fr.Element
+	Root        fr.Element
+}
+
+type UTXOCommitment struct {
+	Commitment fr.Element
+	OwnerMemo  EncryptedMemo
+	AuditMemo  EncryptedMemo
+	// OutgoingMemo is nil when the transfer has no outgoing viewing key. It
+	// is not part of the proof, the circuit only commits to the other memos.
+	OutgoingMemo *EncryptedOutgoingMemo
+}
+
+// PadAllAsset fills AllAsset up to size so the result fits a circuit compiled
+// for size assets. The circuit requires distinct entries, so padding uses the
+// smallest assets no note of the transaction holds; their balances are zero.
+func (result *UTXOResult) PadAllAsset(size int) error {
+	if len(result.AllAsset) > size {
+		return fmt.Errorf("transaction moves %d assets, circuit supports %d", len(result.AllAsset), size)
+	}
+
+	listed := make(map[fr.Element]bool, size)
+	for _, asset := range result.AllAsset {
+		listed[asset] = true
+	}
+
+	for next := uint64(0); len(result.AllAsset) < size; next++ {
+		if asset := fr.NewElement(next); !listed[asset] {
+			result.AllAsset = append(result.AllAsset, asset)
+		}
+	}
+
+	return nil
+}
+
+func (result *UTXOResult) ToGadget() *circuits.UTXOResultGadget {
+	nullifiers := make([]frontend.Variable, len(result.Nullifiers))
+	commitments := make([]frontend.Variable, len(result.Commitments))
+	ownerMemoHashes := make([]frontend.Variable, len(result.Commitments))
+	auditMemoHashes := make([]frontend.Variable, len(result.Commitments))
+
+	for i := range result.Nullifiers {
+		nullifiers[i] = result.Nullifiers[i]
+	}
+
+	for i := range result.Commitments {
+		commitments[i] = result.Commitments[i].Commitment
+		ownerMemoHashes[i] = result.Commitments[i].OwnerMemo.Hash()
+		auditMemoHashes[i] = result.Commitments[i].AuditMemo.Hash()
+	}
+
+	return &circuits.UTXOResultGadget{
+		Nullifiers:      nullifiers,
+		Commitments:     commitments,
+		OwnerMemoHashes: ownerMemoHashes,
+		AuditMemoHashes: auditMemoHashes,
+		MerkleRoot:      result.Root,
+	}
+}
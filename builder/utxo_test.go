@@ -1,116 +1,127 @@
 package builder_test
 
-// func TestUTXO_BuildAndCheck(t *testing.T) {
-// 	receiverSecretKey := big.NewInt(11111)
-// 	auditSecretKey := big.NewInt(22222)
-
-// 	receiverPublicKey := utils.BuildPublicKey(*receiverSecretKey)
-// 	auditPublicKey := utils.BuildPublicKey(*auditSecretKey)
-
-// 	nullifier1 := builder.Nullifier{
-// 		Commitment: builder.Commitment{
-// 			Asset:    fr.NewElement(1),
-// 			Amount:   fr.NewElement(2),
-// 			Blinding: fr.NewElement(3),
-// 		},
-// 		SpentPrivateKey: fr.NewElement(1),
-// 	}
-
-// 	nullifier2 := builder.Nullifier{
-// 		Commitment: builder.Commitment{
-// 			Asset:    fr.NewElement(1),
-// 			Amount:   fr.NewElement(2),
-// 			Blinding: fr.NewElement(4),
-// 		},
-// 		SpentPrivateKey: fr.NewElement(2),
-// 	}
-
-// 	nullifier1_commit := nullifier1.Commitment.Compute()
-// 	nullifier2_commit := nullifier2.Commitment.Compute()
-
-// 	merkleTree := builder.NewMerkleTree(10, poseidon2.NewMerkleDamgardHasher())
-// 	elems := []fr.Element{
-// 		nullifier1_commit,
-// 		nullifier2_commit,
-// 	}
-// 	merkleTree.Build(elems)
-
-// 	merkleProof1 := merkleTree.GetProof(0)
-// 	merkleProof2 := merkleTree.GetProof(1)
-
-// 	utxo := &builder.UTXO{
-// 		Nullifier: []builder.Nullifier{
-// 			nullifier1,
-// 			nullifier2,
-// 		},
-// 		MerkleProof: []builder.MerkleProof{
-// 			merkleProof1,
-// 			merkleProof2,
-// 		},
-// 		Commitment: []builder.Commitment{
-// 			{
-// 				Asset:    fr.NewElement(1),
-// 				Amount:   fr.NewElement(2),
-// 				Blinding: fr.NewElement(3),
-// 			},
-// 			{
-// 				Asset:    fr.NewElement(1),
-// 				Amount:   fr.NewElement(2),
-// 				Blinding: fr.NewElement(3),
-// 			},
-// 		},
-// 		EphemeralReceiverSecretKey: []big.Int{
-// 			*big.NewInt(1),
-// 			*big.NewInt(2),
-// 		},
-// 		EphemeralAuditSecretKey: []big.Int{
-// 			*big.NewInt(3),
-// 			*big.NewInt(4),
-// 		},
-// 		ReceiverPublicKey: receiverPublicKey,
-// 		AuditPublicKey:    auditPublicKey,
-// 	}
-
-// 	result, err := utxo.BuildAndCheck()
-// 	assert.NoError(t, err)
-
-// 	for i := range result.Commitments {
-// 		commitment := result.Commitments[i]
-
-// 		memo1 := builder.Memo{
-// 			SecretKey: *receiverSecretKey,
-// 			PublicKey: commitment.OwnerEphemeralPublickKey,
-// 		}
-
-// 		ownerMemoCiphertext := []fr.Element{
-// 			commitment.OwnerMemo[0],
-// 			commitment.OwnerMemo[1],
-// 			commitment.OwnerMemo[2],
-// 			commitment.OwnerHMAC,
-// 		}
-
-// 		decryptedOwnerMemo, err := memo1.Decrypt(ownerMemoCiphertext)
-// 		assert.NoError(t, err)
-// 		assert.Equal(t, decryptedOwnerMemo.Asset, utxo.Commitment[i].Asset)
-// 		assert.Equal(t, decryptedOwnerMemo.Amount, utxo.Commitment[i].Amount)
-// 		assert.Equal(t, decryptedOwnerMemo.Blinding, utxo.Commitment[i].Blinding)
-
-// 		memo2 := builder.Memo{
-// 			SecretKey: *auditSecretKey,
-// 			PublicKey: commitment.AuditEphemeralPublickKey,
-// 		}
-
-// 		auditMemoCiphertext := []fr.Element{
-// 			commitment.AuditMemo[0],
-// 			commitment.AuditMemo[1],
-// 			commitment.AuditMemo[2],
-// 			commitment.AuditHMAC,
-// 		}
-
-// 		decryptedAuditMemo, err := memo2.Decrypt(auditMemoCiphertext)
-// 		assert.NoError(t, err)
-// 		assert.Equal(t, decryptedAuditMemo.Asset, utxo.Commitment[i].Asset)
-// 		assert.Equal(t, decryptedAuditMemo.Amount, utxo.Commitment[i].Amount)
-// 		assert.Equal(t, decryptedAuditMemo.Blinding, utxo.Commitment[i].Blinding)
-// 	}
-// }
+import (
+	"hide-pay/builder"
+	"hide-pay/utils"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUTXO_BuildAndCheck(t *testing.T) {
+	receiverSecretKey := big.NewInt(11111)
+	auditSecretKey := big.NewInt(22222)
+
+	utxo, err := builder.GenerateUTXO(1, 10, 2, 3)
+	require.NoError(t, err)
+
+	utxo.AuditPublicKey = utils.BuildPublicKey(*auditSecretKey)
+	for i := range utxo.Commitment {
+		utxo.Commitment[i].ViewPubKey = utils.BuildPublicKey(*receiverSecretKey)
+		utxo.Commitment[i].AuditPubKey = utxo.AuditPublicKey
+	}
+
+	result, err := utxo.BuildAndCheck()
+	require.NoError(t, err)
+
+	assert.Len(t, result.Nullifiers, 2)
+	assert.Len(t, result.AllAsset, 1)
+	assert.Equal(t, utxo.MerkleProof[0].Verify(), result.Root)
+
+	for i := range result.Commitments {
+		commitment := result.Commitments[i]
+
+		assert.Equal(t, utxo.Commitment[i].Compute(), commitment.Commitment)
+
+		opening, spentKey, err := commitment.OwnerMemo.Decrypt(*receiverSecretKey)
+		require.NoError(t, err)
+		assert.Equal(t, utxo.Commitment[i], *opening)
+		assert.Equal(t, utxo.SpentKey[i], *spentKey)
+
+		opening, _, err = commitment.AuditMemo.Decrypt(*auditSecretKey)
+		require.NoError(t, err)
+		assert.Equal(t, utxo.Commitment[i], *opening)
+
+		assert.Nil(t, commitment.OutgoingMemo)
+	}
+
+	// With an outgoing viewing key the sender can reopen every output
+	outgoingViewingKey, err := builder.NewOutgoingViewingKey()
+	require.NoError(t, err)
+	utxo.OutgoingViewingKey = &outgoingViewingKey
+
+	sent, err := utxo.BuildAndCheck()
+	require.NoError(t, err)
+
+	for i := range sent.Commitments {
+		commitment := sent.Commitments[i]
+		require.NotNil(t, commitment.OutgoingMemo)
+
+		opening, spentKey, receiver, err := commitment.OutgoingMemo.RecoverNote(outgoingViewingKey, commitment.Commitment, &commitment.OwnerMemo)
+		require.NoError(t, err)
+		assert.Equal(t, utxo.Commitment[i], *opening)
+		assert.Equal(t, utxo.SpentKey[i], *spentKey)
+		assert.Equal(t, utxo.Commitment[i].ViewPubKey, *receiver)
+	}
+
+	// Padding assets are distinct from each other and the moved asset
+	require.NoError(t, result.PadAllAsset(3))
+	assert.Equal(t, []fr.Element{fr.NewElement(0), fr.NewElement(1)}, result.AllAsset[1:])
+	assert.Error(t, result.PadAllAsset(2))
+}
+
+func TestUTXO_BuildAndCheckRejects(t *testing.T) {
+	unbalanced, err := builder.GenerateUTXO(1, 10, 2, 2)
+	require.NoError(t, err)
+	unbalanced.Commitment[0].Amount = fr.NewElement(1000)
+	unbalanced.Commitment[0].Blinding.SetOne()
+	_, err = unbalanced.BuildAndCheck()
+	assert.Error(t, err)
+
+	wrongKey, err := builder.GenerateUTXO(1, 10, 2, 2)
+	require.NoError(t, err)
+	wrongKey.Nullifier[1].SpentPrivateKey.SetOne()
+	_, err = wrongKey.BuildAndCheck()
+	assert.Error(t, err)
+
+	wrongSpentKey, err := builder.GenerateUTXO(1, 10, 2, 2)
+	require.NoError(t, err)
+	wrongSpentKey.SpentKey[0].SetOne()
+	_, err = wrongSpentKey.BuildAndCheck()
+	assert.Error(t, err)
+
+	wrongAuditKey, err := builder.GenerateUTXO(1, 10, 2, 2)
+	require.NoError(t, err)
+	wrongAuditKey.AuditPublicKey = utils.BuildPublicKey(*big.NewInt(33333))
+	_, err = wrongAuditKey.BuildAndCheck()
+	assert.Error(t, err)
+
+	wrongLeaf, err := builder.GenerateUTXO(1, 10, 2, 2)
+	require.NoError(t, err)
+	wrongLeaf.MerkleProof[0], wrongLeaf.MerkleProof[1] = wrongLeaf.MerkleProof[1], wrongLeaf.MerkleProof[0]
+	_, err = wrongLeaf.BuildAndCheck()
+	assert.Error(t, err)
+
+	frozenInput, err := builder.GenerateUTXO(1, 10, 2, 2)
+	require.NoError(t, err)
+	frozenInput.Nullifier[1].FreezeFlag = fr.NewElement(1)
+	_, err = frozenInput.BuildAndCheck()
+	assert.ErrorContains(t, err, "frozen")
+
+	frozenOutput, err := builder.GenerateUTXO(1, 10, 2, 2)
+	require.NoError(t, err)
+	frozenOutput.Commitment[0].FreezeFlag = fr.NewElement(1)
+	_, err = frozenOutput.BuildAndCheck()
+	assert.ErrorContains(t, err, "frozen")
+
+	// Two 253 bit outputs that sum to the 12 spent plus the field modulus
+	wrapped, err := builder.GenerateUTXO(1, 10, 2, 2)
+	require.NoError(t, err)
+	wrapped.Commitment[0].Amount.SetBigInt(new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 253), big.NewInt(1)))
+	wrapped.Commitment[1].Amount.Sub(new(fr.Element).SetUint64(12), &wrapped.Commitment[0].Amount)
+	_, err = wrapped.BuildAndCheck()
+	assert.ErrorContains(t, err, "bits")
+}
@@ -0,0 +1,102 @@
+package builder_test
+
+import (
+	"hide-pay/builder"
+	"hide-pay/circuits"
+	"hide-pay/utils"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedMemo_CheckViewTag(t *testing.T) {
+	envelope, receiverSecretKey := newEncryptedMemo(t)
+
+	sharedKey, matched, err := envelope.CheckViewTag(*receiverSecretKey)
+	require.NoError(t, err)
+	assert.True(t, matched)
+	assert.Equal(t, builder.ComputeViewTag(&sharedKey), envelope.ViewTag)
+
+	_, _, err = envelope.DecryptWithSharedKey(sharedKey)
+	require.NoError(t, err)
+
+	_, matched, err = envelope.CheckViewTag(*big.NewInt(33333))
+	require.NoError(t, err)
+	assert.False(t, matched)
+}
+
+func TestEncryptedMemo_Decrypt_ViewTagMismatch(t *testing.T) {
+	envelope, receiverSecretKey := newEncryptedMemo(t)
+
+	envelope.ViewTag.SetOne()
+
+	_, _, err := envelope.Decrypt(*receiverSecretKey)
+	assert.ErrorIs(t, err, builder.ErrViewTagMismatch)
+}
+
+// newForeignMemos returns memos addressed to other receivers, the common case
+// when a wallet scans the ledger.
+func newForeignMemos(b *testing.B, count int) []*builder.EncryptedMemo {
+	envelopes := make([]*builder.EncryptedMemo, count)
+
+	for i := range envelopes {
+		receiverSecretKey, err := circuits.CreateSeedFromRand()
+		require.NoError(b, err)
+
+		ephemeralSecretKey, err := circuits.CreateSeedFromRand()
+		require.NoError(b, err)
+
+		memo := &builder.Memo{
+			SecretKey: ephemeralSecretKey,
+			PublicKey: utils.BuildPublicKey(receiverSecretKey),
+		}
+
+		commitment, spentKey := builder.GenerateCommitment(int64(i + 1))
+
+		envelopes[i], err = memo.Encrypt(*commitment, *spentKey)
+		require.NoError(b, err)
+	}
+
+	return envelopes
+}
+
+func BenchmarkScan_TrialDecrypt(b *testing.B) {
+	envelopes := newForeignMemos(b, 64)
+	walletSecretKey := big.NewInt(22222)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		envelope := envelopes[i%len(envelopes)]
+
+		ecdh, err := builder.NewECDHFromPublicKey(envelope.EphemeralPublicKey, *walletSecretKey)
+		require.NoError(b, err)
+
+		sharedKey := ecdh.Compute()
+		streamCipher := builder.StreamCipher{
+			Key: [2]fr.Element{sharedKey.X, sharedKey.Y},
+		}
+
+		ad := []fr.Element{envelope.EphemeralPublicKey.X, envelope.EphemeralPublicKey.Y}
+		if _, err := streamCipher.Decrypt(ad, envelope.Elements()); err == nil {
+			b.Fatal("foreign memo decrypted")
+		}
+	}
+}
+
+func BenchmarkScan_ViewTag(b *testing.B) {
+	envelopes := newForeignMemos(b, 64)
+	walletSecretKey := big.NewInt(22222)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, matched, err := envelopes[i%len(envelopes)].CheckViewTag(*walletSecretKey)
+		require.NoError(b, err)
+
+		if matched {
+			b.Fatal("foreign memo matched")
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package builder
+
+import (
+	"fmt"
+	"hide-pay/circuits"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// Withdraw unshields a whole note to Recipient.
+type Withdraw struct {
+	Nullifier   Nullifier
+	MerkleProof MerkleProof
+	Recipient   fr.Element
+}
+
+func (withdraw *Withdraw) ToWitness() (*circuits.WithdrawCircuit, error) {
+	if !withdraw.Nullifier.FreezeFlag.IsZero() {
+		return nil, fmt.Errorf("a frozen note cannot be withdrawn")
+	}
+
+	if err := checkSpendable(&withdraw.Nullifier, &withdraw.MerkleProof); err != nil {
+		return nil, err
+	}
+
+	merkleProof := withdraw.MerkleProof.ToGadget()
+
+	return &circuits.WithdrawCircuit{
+		Note:             *withdraw.Nullifier.ToGadget(),
+		MerkleProofPath:  merkleProof.Path,
+		MerkleProofIndex: merkleProof.Leaf,
+
+		Recipient:  withdraw.Recipient,
+		Asset:      withdraw.Nullifier.Asset,
+		Amount:     withdraw.Nullifier.Amount,
+		Nullifier:  withdraw.Nullifier.Compute(),
+		MerkleRoot: withdraw.MerkleProof.Verify(),
+	}, nil
+}
+
+// checkSpendable fails when merkleProof is not for the note of nullifier or
+// the nullifier key does not match the note.
+func checkSpendable(nullifier *Nullifier, merkleProof *MerkleProof) error {
+	if merkleProof.Leaf() != nullifier.Commitment.Compute() {
+		return fmt.Errorf("merkle proof is not for the commitment of the nullifier")
+	}
+
+	if !CheckSpentAddress(nullifier.SpentAddress, nullifier.SpentPrivateKey) {
+		return fmt.Errorf("nullifier private key does not match its spent address")
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package circuits
+
+import (
+	"fmt"
+	"hide-pay/utils"
+
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bn254"
+	"github.com/consensys/gnark/std/math/emulated"
+	stdgroth16 "github.com/consensys/gnark/std/recursion/groth16"
+)
+
+// The inner proofs are BN254 proofs, verified with emulated arithmetic.
+type (
+	InnerG1     = sw_bn254.G1Affine
+	InnerG2     = sw_bn254.G2Affine
+	InnerGT     = sw_bn254.GTEl
+	InnerScalar = sw_bn254.ScalarField
+
+	InnerProof        = stdgroth16.Proof[InnerG1, InnerG2]
+	InnerVerifyingKey = stdgroth16.VerifyingKey[InnerG1, InnerG2, InnerGT]
+	InnerWitness      = stdgroth16.Witness[InnerScalar]
+)
+
+// AggregateCircuit verifies a block of Groth16 proofs of one BN254 circuit,
+// usually a transfer, and exposes a single hash of all their public inputs.
+//
+// The inner proofs live on the same curve as the aggregate proof, so their
+// pairings are computed with emulated arithmetic: a 2-chain such as
+// BLS12-377/BW6-761 would be much cheaper, but the transfer circuits and the
+// contracts are bound to BN254. Inner proofs must be made with
+// stdgroth16.GetNativeProverOptions so their commitment is hashed to the
+// field the way the verifier below does it.
+type AggregateCircuit struct {
+	// VerifyingKey of the inner circuit is compiled in as constants, so one
+	// aggregate circuit only accepts proofs of that circuit.
+	VerifyingKey InnerVerifyingKey `gnark:"-"`
+
+	Proofs []InnerProof   `gnark:"proofs"`
+	Inputs []InnerWitness `gnark:"inputs"`
+
+	// InputsHash is the Poseidon2 hash of the public inputs of every proof,
+	// in block order.
+	InputsHash frontend.Variable `gnark:"inputsHash,public"`
+}
+
+// NewAggregateCircuit returns the circuit aggregating size proofs of inner,
+// which vk verifies.
+func NewAggregateCircuit(inner constraint.ConstraintSystem, vk groth16.VerifyingKey, size int) (*AggregateCircuit, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("block size must be positive, got %d", size)
+	}
+
+	fixed, err := stdgroth16.ValueOfVerifyingKeyFixed[InnerG1, InnerG2, InnerGT](vk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert verifying key: %w", err)
+	}
+
+	circuit := &AggregateCircuit{
+		VerifyingKey: fixed,
+		Proofs:       make([]InnerProof, size),
+		Inputs:       make([]InnerWitness, size),
+	}
+
+	for i := range size {
+		circuit.Proofs[i] = stdgroth16.PlaceholderProof[InnerG1, InnerG2](inner)
+		circuit.Inputs[i] = stdgroth16.PlaceholderWitness[InnerScalar](inner)
+	}
+
+	return circuit, nil
+}
+
+func (circuit *AggregateCircuit) Define(api frontend.API) error {
+	if len(circuit.Proofs) != len(circuit.Inputs) {
+		return fmt.Errorf("%d proofs but %d public inputs", len(circuit.Proofs), len(circuit.Inputs))
+	}
+
+	verifier, err := stdgroth16.NewVerifier[InnerScalar, InnerG1, InnerG2, InnerGT](api)
+	if err != nil {
+		return fmt.Errorf("failed to create verifier: %w", err)
+	}
+
+	scalars, err := emulated.NewField[InnerScalar](api)
+	if err != nil {
+		return fmt.Errorf("failed to create scalar field: %w", err)
+	}
+
+	hasher, err := utils.NewPoseidonHasher(api)
+	if err != nil {
+		return fmt.Errorf("failed to create poseidon hasher: %w", err)
+	}
+
+	for i := range circuit.Proofs {
+		if err := verifier.AssertProof(circuit.VerifyingKey, circuit.Proofs[i], circuit.Inputs[i]); err != nil {
+			return fmt.Errorf("failed to verify proof %d: %w", i, err)
+		}
+
+		// The scalar field of the inner curve is the native field, so each
+		// input is hashed as the native value its canonical bits encode
+		for j := range circuit.Inputs[i].Public {
+			hasher.Write(api.FromBinary(scalars.ToBitsCanonical(&circuit.Inputs[i].Public[j])...))
+		}
+	}
+
+	api.AssertIsEqual(circuit.InputsHash, hasher.Sum())
+
+	return nil
+}
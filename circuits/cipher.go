@@ -12,7 +12,9 @@ type StreamCipherGadget struct {
 	Key [2]frontend.Variable
 }
 
-func (gadget *StreamCipherGadget) Encrypt(api frontend.API, ad []frontend.Variable, plaintext []frontend.Variable) (frontend.Variable, error) {
+// Encrypt returns the ciphertext followed by the tag, as StreamCipher.Encrypt
+// does natively.
+func (gadget *StreamCipherGadget) Encrypt(api frontend.API, ad []frontend.Variable, plaintext []frontend.Variable) ([]frontend.Variable, error) {
 	params := poseidonbn254.GetDefaultParameters()
 	perm, err := poseidon.NewPoseidon2FromParameters(api, 2, params.NbFullRounds, params.NbPartialRounds)
 	if err != nil {
@@ -36,12 +38,18 @@ func (gadget *StreamCipherGadget) Encrypt(api frontend.API, ad []frontend.Variab
 		perm.Permutation(state)
 	}
 
+	ciphertext := make([]frontend.Variable, len(plaintext)+1)
+
 	for i := range plaintext {
+		ciphertext[i] = api.Add(state[0], plaintext[i])
 		perm.Permutation(state)
 
 		state[0] = api.Add(state[0], plaintext[i])
 		perm.Permutation(state)
 	}
 
-	return state[0], nil
+	// HMAC
+	ciphertext[len(ciphertext)-1] = state[0]
+
+	return ciphertext, nil
 }
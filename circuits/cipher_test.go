@@ -14,16 +14,17 @@ import (
 )
 
 type StreamCipherCircuit struct {
-	Key            [2]frontend.Variable
-	Ad             []frontend.Variable
-	Plaintext      []frontend.Variable
-	CiphertextHash frontend.Variable `gnark:",public"`
+	Key        [2]frontend.Variable
+	Ad         []frontend.Variable
+	Plaintext  []frontend.Variable
+	Ciphertext []frontend.Variable `gnark:",public"`
 }
 
 func NewStreamCipherCircuit(adSize int, plaintextSize int) *StreamCipherCircuit {
 	return &StreamCipherCircuit{
-		Ad:        make([]frontend.Variable, adSize),
-		Plaintext: make([]frontend.Variable, plaintextSize),
+		Ad:         make([]frontend.Variable, adSize),
+		Plaintext:  make([]frontend.Variable, plaintextSize),
+		Ciphertext: make([]frontend.Variable, plaintextSize+1),
 	}
 }
 
@@ -37,7 +38,9 @@ func (circuit *StreamCipherCircuit) Define(api frontend.API) error {
 		return fmt.Errorf("failed to encrypt: %w", err)
 	}
 
-	api.AssertIsEqual(circuit.CiphertextHash, ciphertext)
+	for i := range circuit.Ciphertext {
+		api.AssertIsEqual(circuit.Ciphertext[i], ciphertext[i])
+	}
 
 	return nil
 }
@@ -80,12 +83,21 @@ func TestStreamCipher_ToCircuit(t *testing.T) {
 		plaintext_witness[i] = plaintext[i]
 	}
 
+	ciphertext_witness := make([]frontend.Variable, len(ciphertext))
+	for i := range ciphertext {
+		ciphertext_witness[i] = ciphertext[i]
+	}
+
 	witness := StreamCipherCircuit{
-		Key:            cipher.ToGadget().Key,
-		Ad:             ad_witness,
-		Plaintext:      plaintext_witness,
-		CiphertextHash: ciphertext[len(ciphertext)-1],
+		Key:        cipher.ToGadget().Key,
+		Ad:         ad_witness,
+		Plaintext:  plaintext_witness,
+		Ciphertext: ciphertext_witness,
 	}
 
 	assert.ProverSucceeded(circuit, &witness, test.WithCurves(ecc.BN254))
+
+	// Every ciphertext element is bound, not only the tag
+	witness.Ciphertext[1] = fr.NewElement(1)
+	assert.ProverFailed(circuit, &witness, test.WithCurves(ecc.BN254))
 }
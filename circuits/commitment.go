@@ -7,6 +7,11 @@ import (
 	"github.com/consensys/gnark/frontend"
 )
 
+// AmountBits bounds the amount of a note. A transfer sums the amounts of at
+// most a few hundred notes, so with 64 bit amounts no sum can reach the field
+// modulus and wrap around to balance a larger output.
+const AmountBits = 64
+
 type CommitmentGadget struct {
 	Asset        frontend.Variable    `gnark:"asset"`
 	Amount       frontend.Variable    `gnark:"amount"`
@@ -0,0 +1,53 @@
+package circuits
+
+import (
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/rangecheck"
+)
+
+// DepositCircuit shields a public amount of an asset into a new note. The
+// note is created unfrozen, with its memos as in a transfer: the owner memo
+// goes to the note's ViewPubKey. As in a
+// transfer, the audit key is public so the verifier can require the key of
+// the auditors, and the note must commit to it.
+type DepositCircuit struct {
+	Note                       CommitmentGadget  `gnark:"note"`
+	SpentKey                   frontend.Variable `gnark:"spentKey"`
+	EphemeralReceiverSecretKey frontend.Variable `gnark:"ephemeralReceiverSecretKey"`
+	EphemeralAuditSecretKey    frontend.Variable `gnark:"ephemeralAuditSecretKey"`
+
+	AuditPublicKey [2]frontend.Variable `gnark:"auditPublicKey,public"`
+	Asset          frontend.Variable    `gnark:"asset,public"`
+	Amount         frontend.Variable    `gnark:"amount,public"`
+	Commitment     frontend.Variable    `gnark:"commitment,public"`
+	OwnerMemoHash  frontend.Variable    `gnark:"ownerMemoHash,public"`
+	AuditMemoHash  frontend.Variable    `gnark:"auditMemoHash,public"`
+}
+
+func (circuit *DepositCircuit) Define(api frontend.API) error {
+	rangecheck.New(api).Check(circuit.Note.Amount, AmountBits)
+
+	api.AssertIsEqual(circuit.Note.Asset, circuit.Asset)
+	api.AssertIsEqual(circuit.Note.Amount, circuit.Amount)
+	api.AssertIsEqual(circuit.Note.FreezeFlag, 0)
+
+	created, err := createNote(api, circuit.Note, circuit.SpentKey,
+		MemoGadget{
+			EphemeralSecretKey: circuit.EphemeralReceiverSecretKey,
+			ReceiverPublicKey:  circuit.Note.ViewPubKey,
+		},
+		MemoGadget{
+			EphemeralSecretKey: circuit.EphemeralAuditSecretKey,
+			ReceiverPublicKey:  circuit.AuditPublicKey,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	api.AssertIsEqual(circuit.Commitment, created.Commitment)
+	api.AssertIsEqual(circuit.OwnerMemoHash, created.OwnerMemoHash)
+	api.AssertIsEqual(circuit.AuditMemoHash, created.AuditMemoHash)
+
+	return nil
+}
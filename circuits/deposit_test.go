@@ -0,0 +1,84 @@
+package circuits_test
+
+import (
+	"hide-pay/builder"
+	"hide-pay/circuits"
+	"hide-pay/utils"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeposit_Circuit(t *testing.T) {
+	commitment, spentKey := builder.GenerateCommitment(7)
+
+	deposit := builder.Deposit{
+		Commitment:                 *commitment,
+		SpentKey:                   *spentKey,
+		EphemeralReceiverSecretKey: *big.NewInt(333),
+		EphemeralAuditSecretKey:    *big.NewInt(444),
+		AuditPublicKey:             commitment.AuditPubKey,
+	}
+
+	witness, err := deposit.ToWitness()
+	require.NoError(t, err)
+
+	assert := test.NewAssert(t)
+	circuit := circuits.DepositCircuit{}
+
+	assert.ProverSucceeded(&circuit, witness, test.WithCurves(ecc.BN254))
+
+	// The public amount must be the amount of the note
+	witness, err = deposit.ToWitness()
+	require.NoError(t, err)
+	witness.Amount = fr.NewElement(1)
+	assert.ProverFailed(&circuit, witness, test.WithCurves(ecc.BN254))
+
+	// and the note cannot be created frozen
+	witness, err = deposit.ToWitness()
+	require.NoError(t, err)
+	witness.Note.FreezeFlag = 1
+	assert.ProverFailed(&circuit, witness, test.WithCurves(ecc.BN254))
+
+	// The amount is bounded as in a transfer
+	tooLarge := deposit
+	tooLarge.Commitment.Amount.SetBigInt(new(big.Int).Lsh(big.NewInt(1), circuits.AmountBits))
+	_, err = tooLarge.ToWitness()
+	require.Error(t, err)
+
+	witness, err = deposit.ToWitness()
+	require.NoError(t, err)
+	ownerMemo := builder.Memo{SecretKey: tooLarge.EphemeralReceiverSecretKey, PublicKey: tooLarge.Commitment.ViewPubKey}
+	ownerEnvelope, err := ownerMemo.Encrypt(tooLarge.Commitment, tooLarge.SpentKey)
+	require.NoError(t, err)
+	auditMemo := builder.Memo{SecretKey: tooLarge.EphemeralAuditSecretKey, PublicKey: tooLarge.AuditPublicKey}
+	auditEnvelope, err := auditMemo.Encrypt(tooLarge.Commitment, tooLarge.SpentKey)
+	require.NoError(t, err)
+	witness.Note.Amount = tooLarge.Commitment.Amount
+	witness.Amount = tooLarge.Commitment.Amount
+	witness.Commitment = tooLarge.Commitment.Compute()
+	witness.OwnerMemoHash = ownerEnvelope.Hash()
+	witness.AuditMemoHash = auditEnvelope.Hash()
+	assert.ProverFailed(&circuit, witness, test.WithCurves(ecc.BN254))
+
+	// The builder refuses an audit key the note is not for
+	wrongKey := deposit
+	wrongKey.AuditPublicKey = utils.BuildPublicKey(*big.NewInt(22222))
+	_, err = wrongKey.ToWitness()
+	require.Error(t, err)
+
+	// and so does the circuit, with the audit memo encrypted to that key
+	witness, err = deposit.ToWitness()
+	require.NoError(t, err)
+	auditMemo = builder.Memo{SecretKey: wrongKey.EphemeralAuditSecretKey, PublicKey: wrongKey.AuditPublicKey}
+	envelope, err := auditMemo.Encrypt(wrongKey.Commitment, wrongKey.SpentKey)
+	require.NoError(t, err)
+	witness.AuditPublicKey = [2]frontend.Variable{wrongKey.AuditPublicKey.X, wrongKey.AuditPublicKey.Y}
+	witness.AuditMemoHash = envelope.Hash()
+	assert.ProverFailed(&circuit, witness, test.WithCurves(ecc.BN254))
+}
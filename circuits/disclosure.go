@@ -0,0 +1,53 @@
+package circuits
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// DisclosureCircuit proves knowledge of an opening of Commitment and reveals
+// the fields whose Disclose flag is set. Hidden fields must be zero in the
+// public inputs, so a single key pair serves every choice of fields.
+type DisclosureCircuit struct {
+	Opening CommitmentGadget `gnark:"opening"`
+
+	// Context is chosen by the verifier, e.g. a fresh nonce, so a proof made
+	// for one verifier cannot be replayed to another.
+	Context frontend.Variable `gnark:"context,public"`
+
+	Commitment frontend.Variable `gnark:"commitment,public"`
+
+	DiscloseAsset  frontend.Variable `gnark:"discloseAsset,public"`
+	DiscloseAmount frontend.Variable `gnark:"discloseAmount,public"`
+	DiscloseOwner  frontend.Variable `gnark:"discloseOwner,public"`
+
+	Asset       frontend.Variable    `gnark:"asset,public"`
+	Amount      frontend.Variable    `gnark:"amount,public"`
+	OwnerPubKey [2]frontend.Variable `gnark:"ownerPubKey,public"`
+}
+
+func (circuit *DisclosureCircuit) Define(api frontend.API) error {
+	commitment, err := circuit.Opening.Compute(api)
+	if err != nil {
+		return fmt.Errorf("failed to compute commitment: %w", err)
+	}
+
+	api.AssertIsEqual(circuit.Commitment, commitment)
+
+	// Nothing else reads the context, so bind it to the proof explicitly
+	api.Mul(circuit.Context, circuit.Context)
+
+	assertDisclosed(api, circuit.DiscloseAsset, circuit.Asset, circuit.Opening.Asset)
+	assertDisclosed(api, circuit.DiscloseAmount, circuit.Amount, circuit.Opening.Amount)
+	assertDisclosed(api, circuit.DiscloseOwner, circuit.OwnerPubKey[0], circuit.Opening.OwnerPubKey[0])
+	assertDisclosed(api, circuit.DiscloseOwner, circuit.OwnerPubKey[1], circuit.Opening.OwnerPubKey[1])
+
+	return nil
+}
+
+// assertDisclosed constrains public == disclose ? private : 0.
+func assertDisclosed(api frontend.API, disclose frontend.Variable, public frontend.Variable, private frontend.Variable) {
+	api.AssertIsBoolean(disclose)
+	api.AssertIsEqual(public, api.Mul(disclose, private))
+}
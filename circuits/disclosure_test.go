@@ -0,0 +1,48 @@
+package circuits_test
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/test"
+
+	"hide-pay/builder"
+	"hide-pay/circuits"
+)
+
+func TestDisclosure_Circuit(t *testing.T) {
+	commitment, _ := builder.GenerateCommitment(12345)
+
+	disclosure := builder.Disclosure{
+		Commitment:     *commitment,
+		Context:        fr.NewElement(777),
+		DiscloseAsset:  true,
+		DiscloseAmount: true,
+	}
+
+	assert := test.NewAssert(t)
+	circuit := circuits.DisclosureCircuit{}
+
+	assert.ProverSucceeded(&circuit, disclosure.ToWitness(), test.WithCurves(ecc.BN254))
+
+	disclosure.DiscloseAmount = false
+	disclosure.DiscloseOwner = true
+	assert.ProverSucceeded(&circuit, disclosure.ToWitness(), test.WithCurves(ecc.BN254))
+
+	// A hidden field cannot be published
+	witness := disclosure.ToWitness()
+	witness.Amount = commitment.Amount
+	assert.ProverFailed(&circuit, witness, test.WithCurves(ecc.BN254))
+
+	// A disclosed field cannot be misreported
+	witness = disclosure.ToWitness()
+	witness.Asset = 1
+	assert.ProverFailed(&circuit, witness, test.WithCurves(ecc.BN254))
+
+	// Flags are boolean
+	witness = disclosure.ToWitness()
+	witness.DiscloseAmount = 2
+	witness.Amount = 0
+	assert.ProverFailed(&circuit, witness, test.WithCurves(ecc.BN254))
+}
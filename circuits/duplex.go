@@ -0,0 +1,136 @@
+package circuits
+
+import (
+	"fmt"
+	"math/big"
+
+	poseidonbn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	"github.com/consensys/gnark/frontend"
+	poseidon "github.com/consensys/gnark/std/permutation/poseidon2"
+)
+
+// DuplexDomain is the ASCII encoding of "duplex". It seeds the capacity
+// element together with the input lengths.
+const DuplexDomain = 0x6475706c6578
+
+// DuplexParameters returns the Poseidon2 round numbers for a duplex of the
+// given width. Only widths 2 and 3 are available: gnark and gnark-crypto do
+// not implement the internal matrix of wider BN254 permutations.
+func DuplexParameters(width int) (nbFullRounds int, nbPartialRounds int, err error) {
+	switch width {
+	case 2:
+		params := poseidonbn254.GetDefaultParameters()
+		return params.NbFullRounds, params.NbPartialRounds, nil
+	case 3:
+		// Poseidon2 paper, BN254 with t = 3
+		return 8, 56, nil
+	default:
+		return 0, 0, fmt.Errorf("unsupported duplex width %d, expected 2 or 3", width)
+	}
+}
+
+// DuplexRate returns the number of elements a duplex of the given width
+// absorbs per permutation; the other width-rate elements are its capacity. A
+// zero rate selects the default, width-1. A lower rate costs more
+// permutations for a larger capacity.
+func DuplexRate(width int, rate int) (int, error) {
+	if rate == 0 {
+		rate = width - 1
+	}
+
+	if rate < 1 || rate >= width {
+		return 0, fmt.Errorf("duplex rate must be between 1 and %d for width %d, got %d", width-1, width, rate)
+	}
+
+	return rate, nil
+}
+
+// DuplexIV returns the initial capacity element for the given rate and
+// lengths, so that inputs of different shapes never share a state.
+func DuplexIV(rate int, adSize int, plaintextSize int) *big.Int {
+	iv := new(big.Int).Lsh(big.NewInt(int64(rate)), 96)
+	iv.Add(iv, new(big.Int).Lsh(big.NewInt(int64(plaintextSize)), 64))
+	iv.Add(iv, new(big.Int).Lsh(big.NewInt(int64(adSize)), 32))
+
+	return iv.Add(iv, big.NewInt(DuplexDomain))
+}
+
+// DuplexCipherGadget is an authenticated cipher over a Poseidon2 duplex of
+// width Width and rate Rate, or Width-1 when Rate is zero. The key, the
+// associated data and the plaintext are processed rate elements per
+// permutation, instead of the two permutations per element of
+// StreamCipherGadget.
+type DuplexCipherGadget struct {
+	Key   [2]frontend.Variable
+	Width int
+	Rate  int
+}
+
+// Encrypt returns the ciphertext followed by the tag, as DuplexCipher.Encrypt
+// does natively.
+func (gadget *DuplexCipherGadget) Encrypt(api frontend.API, ad []frontend.Variable, plaintext []frontend.Variable) ([]frontend.Variable, error) {
+	nbFullRounds, nbPartialRounds, err := DuplexParameters(gadget.Width)
+	if err != nil {
+		return nil, err
+	}
+
+	perm, err := poseidon.NewPoseidon2FromParameters(api, gadget.Width, nbFullRounds, nbPartialRounds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create poseidon permutation: %w", err)
+	}
+
+	rate, err := DuplexRate(gadget.Width, gadget.Rate)
+	if err != nil {
+		return nil, err
+	}
+
+	state := make([]frontend.Variable, gadget.Width)
+	for i := range state {
+		state[i] = 0
+	}
+	state[rate] = DuplexIV(rate, len(ad), len(plaintext))
+
+	absorb := func(input []frontend.Variable) error {
+		for begin := 0; begin < len(input); begin += rate {
+			end := min(begin+rate, len(input))
+
+			for j := begin; j < end; j++ {
+				state[j-begin] = api.Add(state[j-begin], input[j])
+			}
+
+			if err := perm.Permutation(state); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := absorb(gadget.Key[:]); err != nil {
+		return nil, fmt.Errorf("failed to absorb key: %w", err)
+	}
+
+	if err := absorb(ad); err != nil {
+		return nil, fmt.Errorf("failed to absorb associated data: %w", err)
+	}
+
+	ciphertext := make([]frontend.Variable, len(plaintext)+1)
+
+	for begin := 0; begin < len(plaintext); begin += rate {
+		end := min(begin+rate, len(plaintext))
+
+		for j := begin; j < end; j++ {
+			ciphertext[j] = api.Add(state[j-begin], plaintext[j])
+			state[j-begin] = ciphertext[j]
+		}
+
+		if err := perm.Permutation(state); err != nil {
+			return nil, fmt.Errorf("failed to permute: %w", err)
+		}
+	}
+
+	// HMAC
+	ciphertext[len(ciphertext)-1] = state[0]
+
+	return ciphertext, nil
+}
@@ -0,0 +1,129 @@
+package circuits_test
+
+import (
+	"fmt"
+	"hide-pay/builder"
+	"hide-pay/circuits"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+type DuplexCipherCircuit struct {
+	Width      int `gnark:"-"`
+	Rate       int `gnark:"-"`
+	Key        [2]frontend.Variable
+	Ad         []frontend.Variable
+	Plaintext  []frontend.Variable
+	Ciphertext []frontend.Variable `gnark:",public"`
+}
+
+func NewDuplexCipherCircuit(width int, adSize int, plaintextSize int) *DuplexCipherCircuit {
+	return &DuplexCipherCircuit{
+		Width:      width,
+		Ad:         make([]frontend.Variable, adSize),
+		Plaintext:  make([]frontend.Variable, plaintextSize),
+		Ciphertext: make([]frontend.Variable, plaintextSize+1),
+	}
+}
+
+func (circuit *DuplexCipherCircuit) Define(api frontend.API) error {
+	gadget := circuits.DuplexCipherGadget{
+		Key:   circuit.Key,
+		Width: circuit.Width,
+		Rate:  circuit.Rate,
+	}
+
+	ciphertext, err := gadget.Encrypt(api, circuit.Ad, circuit.Plaintext)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt: %w", err)
+	}
+
+	for i := range circuit.Ciphertext {
+		api.AssertIsEqual(circuit.Ciphertext[i], ciphertext[i])
+	}
+
+	return nil
+}
+
+func toVariables(elements []fr.Element) []frontend.Variable {
+	variables := make([]frontend.Variable, len(elements))
+	for i := range elements {
+		variables[i] = elements[i]
+	}
+
+	return variables
+}
+
+func TestDuplexCipher_ToCircuit(t *testing.T) {
+	ad := []fr.Element{fr.NewElement(10), fr.NewElement(20), fr.NewElement(30)}
+	plaintext := []fr.Element{
+		fr.NewElement(100),
+		fr.NewElement(200),
+		fr.NewElement(300),
+		fr.NewElement(400),
+		fr.NewElement(500),
+	}
+
+	assert := test.NewAssert(t)
+
+	for _, shape := range []struct{ width, rate int }{{2, 0}, {3, 0}, {3, 1}} {
+		width := shape.width
+		cipher := &builder.DuplexCipher{
+			Key:   [2]fr.Element{fr.NewElement(12345), fr.NewElement(67890)},
+			Width: width,
+			Rate:  shape.rate,
+		}
+
+		ciphertext, err := cipher.Encrypt(ad, plaintext)
+		require.NoError(t, err)
+
+		circuit := NewDuplexCipherCircuit(width, len(ad), len(plaintext))
+		circuit.Rate = shape.rate
+
+		witness := DuplexCipherCircuit{
+			Width:      width,
+			Rate:       shape.rate,
+			Key:        cipher.ToGadget().Key,
+			Ad:         toVariables(ad),
+			Plaintext:  toVariables(plaintext),
+			Ciphertext: toVariables(ciphertext),
+		}
+
+		assert.ProverSucceeded(circuit, &witness, test.WithCurves(ecc.BN254))
+
+		witness.Ciphertext = toVariables(ciphertext)
+		witness.Ciphertext[len(plaintext)] = fr.NewElement(1)
+		assert.ProverFailed(circuit, &witness, test.WithCurves(ecc.BN254))
+	}
+
+	// A rate without capacity does not compile
+	circuit := NewDuplexCipherCircuit(3, len(ad), len(plaintext))
+	circuit.Rate = 3
+	_, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, circuit)
+	require.Error(t, err)
+}
+
+func TestDuplexCipher_Constraints(t *testing.T) {
+	// Shape of a memo: two AD elements and an eleven element opening
+	const adSize, plaintextSize = 2, 11
+
+	stream, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, NewStreamCipherCircuit(adSize, plaintextSize))
+	require.NoError(t, err)
+
+	t.Logf("stream cipher: %d constraints", stream.GetNbConstraints())
+
+	for _, width := range []int{2, 3} {
+		duplex, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, NewDuplexCipherCircuit(width, adSize, plaintextSize))
+		require.NoError(t, err)
+
+		t.Logf("duplex width %d: %d constraints", width, duplex.GetNbConstraints())
+
+		require.Less(t, duplex.GetNbConstraints(), stream.GetNbConstraints())
+	}
+}
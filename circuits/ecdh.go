@@ -14,6 +14,10 @@ type ECDHGadget struct {
 }
 
 func (gadget *ECDHGadget) Compute(api frontend.API) ([2]frontend.Variable, error) {
+	if err := AssertIsValidPublicKey(api, gadget.PublicKey); err != nil {
+		return [2]frontend.Variable{}, fmt.Errorf("failed to validate public key: %w", err)
+	}
+
 	te, err := twistededwards.NewEdCurve(api, twistededwardscrypto.BN254)
 	if err != nil {
 		return [2]frontend.Variable{}, fmt.Errorf("failed to create twistededwards curve: %w", err)
@@ -7,6 +7,7 @@ import (
 
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/test"
 	"github.com/stretchr/testify/assert"
@@ -134,3 +135,48 @@ func TestECDH_Circuit_DifferentInputs(t *testing.T) {
 		})
 	}
 }
+
+func TestECDH_Circuit_LowOrderPublicKey(t *testing.T) {
+	var minusOne fr.Element
+	minusOne.SetOne()
+	minusOne.Neg(&minusOne)
+
+	var sqrtMinusOne fr.Element
+	sqrtMinusOne.Sqrt(&minusOne)
+
+	order2 := twistededwardbn254.NewPointAffine(fr.NewElement(0), minusOne)
+	order4 := twistededwardbn254.NewPointAffine(sqrtMinusOne, fr.NewElement(0))
+
+	base := twistededwardbn254.GetEdwardsCurve().Base
+	var mixed twistededwardbn254.PointAffine
+	mixed.Add(&base, &order2)
+
+	testCases := []struct {
+		name      string
+		publicKey twistededwardbn254.PointAffine
+	}{
+		{"order_2", order2},
+		{"order_4", order4},
+		{"mixed_order", mixed},
+		{"off_curve", twistededwardbn254.NewPointAffine(fr.NewElement(1), fr.NewElement(2))},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ecdh := builder.ECDH{
+				PublicKey: tc.publicKey,
+				SecretKey: *big.NewInt(11111),
+			}
+			sharedKey := ecdh.Compute()
+
+			witness := ECDHCircuit{
+				PublicKey: [2]frontend.Variable{tc.publicKey.X, tc.publicKey.Y},
+				SecretKey: ecdh.SecretKey,
+				SharedKey: [2]frontend.Variable{sharedKey.X, sharedKey.Y},
+			}
+
+			assert := test.NewAssert(t)
+			assert.ProverFailed(NewECDHCircuit(), &witness, test.WithCurves(ecc.BN254))
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package circuits
+
+import (
+	"fmt"
+	"hide-pay/utils"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// FreezeCircuit replaces a note by a copy with FreezeFlag set or cleared and
+// a fresh blinding. Only the holder of the asset's FreezeKey can prove it:
+// the asset id is H(AuditPublicKey, FreezeAddr, Random) with FreezeAddr =
+// H(FreezeKey). Freezing takes a note with flag 0 to flag 1, unfreezing the
+// reverse. The copy keeps the owner, keys and spent address, and its memos go
+// to the note's own view and audit keys.
+type FreezeCircuit struct {
+	Note             NullifierGadget     `gnark:"note"`
+	MerkleProofPath  []frontend.Variable `gnark:"merkleProofPath"`
+	MerkleProofIndex frontend.Variable   `gnark:"merkleProofIndex"`
+
+	FreezeKey           frontend.Variable    `gnark:"freezeKey"`
+	AssetAuditPublicKey [2]frontend.Variable `gnark:"assetAuditPublicKey"`
+	AssetRandom         frontend.Variable    `gnark:"assetRandom"`
+
+	Blinding                   frontend.Variable `gnark:"blinding"`
+	EphemeralReceiverSecretKey frontend.Variable `gnark:"ephemeralReceiverSecretKey"`
+	EphemeralAuditSecretKey    frontend.Variable `gnark:"ephemeralAuditSecretKey"`
+
+	FreezeFlag    frontend.Variable `gnark:"freezeFlag,public"`
+	Nullifier     frontend.Variable `gnark:"nullifier,public"`
+	MerkleRoot    frontend.Variable `gnark:"merkleRoot,public"`
+	Commitment    frontend.Variable `gnark:"commitment,public"`
+	OwnerMemoHash frontend.Variable `gnark:"ownerMemoHash,public"`
+	AuditMemoHash frontend.Variable `gnark:"auditMemoHash,public"`
+}
+
+func NewFreezeCircuit(depth int) *FreezeCircuit {
+	return &FreezeCircuit{
+		MerkleProofPath: make([]frontend.Variable, depth),
+	}
+}
+
+func (circuit *FreezeCircuit) Define(api frontend.API) error {
+	api.AssertIsBoolean(circuit.FreezeFlag)
+
+	// The flag flips, so a note is frozen or unfrozen only once per proof
+	api.AssertIsEqual(api.Add(circuit.Note.FreezeFlag, circuit.FreezeFlag), 1)
+
+	asset, err := assetID(api, circuit.AssetAuditPublicKey, circuit.FreezeKey, circuit.AssetRandom)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(circuit.Note.Asset, asset)
+
+	merkleProof := MerkleProofGadget{
+		Path: circuit.MerkleProofPath,
+		Leaf: circuit.MerkleProofIndex,
+	}
+
+	nullifier, root, err := spendNote(api, &circuit.Note, &merkleProof)
+	if err != nil {
+		return err
+	}
+
+	api.AssertIsEqual(circuit.Nullifier, nullifier)
+	api.AssertIsEqual(circuit.MerkleRoot, root)
+
+	frozen := circuit.Note.CommitmentGadget
+	frozen.FreezeFlag = circuit.FreezeFlag
+	frozen.Blinding = circuit.Blinding
+
+	created, err := createNote(api, frozen, circuit.Note.PrivateKey,
+		MemoGadget{
+			EphemeralSecretKey: circuit.EphemeralReceiverSecretKey,
+			ReceiverPublicKey:  circuit.Note.ViewPubKey,
+		},
+		MemoGadget{
+			EphemeralSecretKey: circuit.EphemeralAuditSecretKey,
+			ReceiverPublicKey:  circuit.Note.AuditPubKey,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	api.AssertIsEqual(circuit.Commitment, created.Commitment)
+	api.AssertIsEqual(circuit.OwnerMemoHash, created.OwnerMemoHash)
+	api.AssertIsEqual(circuit.AuditMemoHash, created.AuditMemoHash)
+
+	return nil
+}
+
+// assetID computes the id of the asset with the given audit public key and
+// freeze key, H(AuditPublicKey, H(FreezeKey), Random).
+func assetID(api frontend.API, auditPubKey [2]frontend.Variable, freezeKey frontend.Variable, random frontend.Variable) (frontend.Variable, error) {
+	hasher, err := utils.NewPoseidonHasher(api)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create poseidon hasher: %w", err)
+	}
+
+	hasher.Write(freezeKey)
+	freezeAddress := hasher.Sum()
+	hasher.Reset()
+
+	hasher.Write(auditPubKey[0])
+	hasher.Write(auditPubKey[1])
+	hasher.Write(freezeAddress)
+	hasher.Write(random)
+
+	return hasher.Sum(), nil
+}
@@ -0,0 +1,117 @@
+package circuits_test
+
+import (
+	"hide-pay/builder"
+	"hide-pay/circuits"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+// inTree puts note in a tree of the given depth and returns it ready to spend.
+func inTree(t *testing.T, note builder.Commitment, spentKey fr.Element, depth int) (builder.Nullifier, builder.MerkleProof) {
+	merkleTree := builder.NewMerkleTree(depth, poseidon2.NewMerkleDamgardHasher())
+	merkleTree.Build([]fr.Element{note.Compute()})
+
+	merkleProof := merkleTree.GetProof(0)
+	require.Equal(t, note.Compute(), merkleProof.Leaf())
+
+	return builder.Nullifier{Commitment: note, SpentPrivateKey: spentKey}, merkleProof
+}
+
+func TestFreeze_Circuit(t *testing.T) {
+	depth := 6
+
+	freezeKey := fr.NewElement(4242)
+	assetRandom := fr.NewElement(77)
+
+	commitment, spentKey := builder.GenerateCommitment(31)
+	commitment.Asset = builder.AssetID(commitment.AuditPubKey, builder.FreezeAddress(freezeKey), assetRandom)
+
+	nullifier, merkleProof := inTree(t, *commitment, *spentKey, depth)
+
+	freeze := builder.Freeze{
+		Nullifier:                  nullifier,
+		MerkleProof:                merkleProof,
+		FreezeKey:                  freezeKey,
+		AssetAuditPublicKey:        commitment.AuditPubKey,
+		AssetRandom:                assetRandom,
+		Frozen:                     true,
+		Blinding:                   fr.NewElement(999),
+		EphemeralReceiverSecretKey: *big.NewInt(555),
+		EphemeralAuditSecretKey:    *big.NewInt(666),
+	}
+
+	witness, err := freeze.ToWitness()
+	require.NoError(t, err)
+
+	assert := test.NewAssert(t)
+	circuit := circuits.NewFreezeCircuit(depth)
+
+	assert.ProverSucceeded(circuit, witness, test.WithCurves(ecc.BN254))
+
+	// The copy cannot change the amount
+	witness, err = freeze.ToWitness()
+	require.NoError(t, err)
+	changed := freeze.Note()
+	changed.Amount = fr.NewElement(1)
+	witness.Commitment = changed.Compute()
+	assert.ProverFailed(circuit, witness, test.WithCurves(ecc.BN254))
+
+	// The flag is boolean
+	witness, err = freeze.ToWitness()
+	require.NoError(t, err)
+	witness.FreezeFlag = 2
+	assert.ProverFailed(circuit, witness, test.WithCurves(ecc.BN254))
+
+	// The holder of the note cannot freeze it with their own key
+	holder := freeze
+	holder.FreezeKey = *spentKey
+	_, err = holder.ToWitness()
+	require.Error(t, err)
+
+	witness, err = freeze.ToWitness()
+	require.NoError(t, err)
+	witness.FreezeKey = *spentKey
+	assert.ProverFailed(circuit, witness, test.WithCurves(ecc.BN254))
+
+	// An unfrozen note cannot be unfrozen
+	unfreeze := freeze
+	unfreeze.Frozen = false
+	_, err = unfreeze.ToWitness()
+	require.Error(t, err)
+
+	// The frozen copy is unfrozen by the same key
+	unfreeze.Nullifier, unfreeze.MerkleProof = inTree(t, freeze.Note(), *spentKey, depth)
+	unfreeze.Blinding = fr.NewElement(1000)
+	witness, err = unfreeze.ToWitness()
+	require.NoError(t, err)
+	assert.ProverSucceeded(circuit, witness, test.WithCurves(ecc.BN254))
+
+	// but cannot be frozen again
+	refreeze := unfreeze
+	refreeze.Frozen = true
+	_, err = refreeze.ToWitness()
+	require.Error(t, err)
+
+	witness, err = unfreeze.ToWitness()
+	require.NoError(t, err)
+	again := refreeze.Nullifier.Commitment
+	again.Blinding = refreeze.Blinding
+	ownerMemo := builder.Memo{SecretKey: refreeze.EphemeralReceiverSecretKey, PublicKey: again.ViewPubKey}
+	ownerEnvelope, err := ownerMemo.Encrypt(again, *spentKey)
+	require.NoError(t, err)
+	auditMemo := builder.Memo{SecretKey: refreeze.EphemeralAuditSecretKey, PublicKey: again.AuditPubKey}
+	auditEnvelope, err := auditMemo.Encrypt(again, *spentKey)
+	require.NoError(t, err)
+	witness.FreezeFlag = 1
+	witness.Commitment = again.Compute()
+	witness.OwnerMemoHash = ownerEnvelope.Hash()
+	witness.AuditMemoHash = auditEnvelope.Hash()
+	assert.ProverFailed(circuit, witness, test.WithCurves(ecc.BN254))
+}
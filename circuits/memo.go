@@ -2,18 +2,38 @@ package circuits
 
 import (
 	"fmt"
+	"hide-pay/utils"
 
 	twistededwardcrypto "github.com/consensys/gnark-crypto/ecc/twistededwards"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/std/algebra/native/twistededwards"
 )
 
+// ViewTagDomain separates the memo view tag hash from other uses of the shared
+// key. It is the ASCII encoding of "view".
+const ViewTagDomain = 0x76696577
+
+// MemoVersion is the memo layout the circuit encrypts and hashes, the version
+// of builder.EncryptedMemo.
+const MemoVersion = 2
+
 type MemoGadget struct {
 	EphemeralSecretKey frontend.Variable    `gnark:"ephemeralSecretKey"`
 	ReceiverPublicKey  [2]frontend.Variable `gnark:"receiverPublicKey"`
 }
 
-func (gadget *MemoGadget) Generate(api frontend.API, output CommitmentGadget) (frontend.Variable, error) {
+// MemoResultGadget holds the public parts of a memo fixed by the circuit.
+// Hash commits to the memo body as published and is the value to expose as a
+// public input.
+type MemoResultGadget struct {
+	EphemeralPublicKey [2]frontend.Variable
+	ViewTag            frontend.Variable
+	Ciphertext         []frontend.Variable
+	Tag                frontend.Variable
+	Hash               frontend.Variable
+}
+
+func (gadget *MemoGadget) Generate(api frontend.API, output CommitmentGadget, spentKey frontend.Variable) (*MemoResultGadget, error) {
 	ecdh := ECDHGadget{
 		PublicKey: gadget.ReceiverPublicKey,
 		SecretKey: gadget.EphemeralSecretKey,
@@ -24,27 +44,65 @@ func (gadget *MemoGadget) Generate(api frontend.API, output CommitmentGadget) (f
 		return nil, fmt.Errorf("failed to compute shared key: %w", err)
 	}
 
+	viewTag, err := computeViewTag(api, sharedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute view tag: %w", err)
+	}
+
 	streamCipher := StreamCipherGadget{
 		Key: sharedKey,
 	}
 
-	plaintext := []frontend.Variable{
+	ephemeralPublicKey, err := computeEphemeralPublicKey(api, gadget.EphemeralSecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute ephemeral public key: %w", err)
+	}
+
+	ad := []frontend.Variable{
+		ephemeralPublicKey.X,
+		ephemeralPublicKey.Y,
+	}
+
+	ciphertext, err := streamCipher.Encrypt(api, ad, memoPlaintext(output, spentKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+
+	hash, err := computeMemoHash(api, [2]frontend.Variable{ephemeralPublicKey.X, ephemeralPublicKey.Y}, viewTag, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute memo hash: %w", err)
+	}
+
+	return &MemoResultGadget{
+		EphemeralPublicKey: [2]frontend.Variable{ephemeralPublicKey.X, ephemeralPublicKey.Y},
+		ViewTag:            viewTag,
+		Ciphertext:         ciphertext[:len(ciphertext)-1],
+		Tag:                ciphertext[len(ciphertext)-1],
+		Hash:               hash,
+	}, nil
+}
+
+// memoPlaintext lays out an opening in the order used by builder.Memo.
+func memoPlaintext(output CommitmentGadget, spentKey frontend.Variable) []frontend.Variable {
+	return []frontend.Variable{
 		output.Asset,
 		output.Amount,
+		output.Blinding,
 		output.OwnerPubKey[0],
 		output.OwnerPubKey[1],
-		output.SpentAddress,
+		spentKey,
 		output.ViewPubKey[0],
 		output.ViewPubKey[1],
 		output.AuditPubKey[0],
 		output.AuditPubKey[1],
 		output.FreezeFlag,
-		output.Blinding,
 	}
+}
 
+func computeEphemeralPublicKey(api frontend.API, ephemeralSecretKey frontend.Variable) (twistededwards.Point, error) {
 	curve, err := twistededwards.NewEdCurve(api, twistededwardcrypto.BN254)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create curve: %w", err)
+		return twistededwards.Point{}, fmt.Errorf("failed to create curve: %w", err)
 	}
 
 	basePointOriginal := curve.Params().Base
@@ -53,17 +111,35 @@ func (gadget *MemoGadget) Generate(api frontend.API, output CommitmentGadget) (f
 		Y: basePointOriginal[1],
 	}
 
-	ephemeralPublicKey := curve.ScalarMul(basePoint, gadget.EphemeralSecretKey)
+	return curve.ScalarMul(basePoint, ephemeralSecretKey), nil
+}
 
-	ad := []frontend.Variable{
-		ephemeralPublicKey.X,
-		ephemeralPublicKey.Y,
+// computeMemoHash returns H(version, ephemeral key x, y, viewTag,
+// ciphertext..., tag), binding every part of the memo the contract
+// publishes: a memo whose ephemeral key was swapped no longer matches.
+func computeMemoHash(api frontend.API, ephemeralPublicKey [2]frontend.Variable, viewTag frontend.Variable, ciphertext []frontend.Variable) (frontend.Variable, error) {
+	hasher, err := utils.NewPoseidonHasher(api)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create poseidon hasher: %w", err)
 	}
 
-	ciphertext, err := streamCipher.Encrypt(api, ad, plaintext)
+	hasher.Write(MemoVersion)
+	hasher.Write(ephemeralPublicKey[0], ephemeralPublicKey[1])
+	hasher.Write(viewTag)
+	hasher.Write(ciphertext...)
+
+	return hasher.Sum(), nil
+}
+
+func computeViewTag(api frontend.API, sharedKey [2]frontend.Variable) (frontend.Variable, error) {
+	hasher, err := utils.NewPoseidonHasher(api)
 	if err != nil {
-		return nil, fmt.Errorf("failed to encrypt: %w", err)
+		return nil, fmt.Errorf("failed to create poseidon hasher: %w", err)
 	}
 
-	return ciphertext, nil
+	hasher.Write(ViewTagDomain)
+	hasher.Write(sharedKey[0])
+	hasher.Write(sharedKey[1])
+
+	return hasher.Sum(), nil
 }
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/test"
 	"github.com/stretchr/testify/require"
@@ -19,9 +20,12 @@ type MemoCircuit struct {
 	SecretKey  frontend.Variable
 	PublicKey  [2]frontend.Variable
 	Commitment circuits.CommitmentGadget
+	SpentKey   frontend.Variable
 
-	OwnerMemoHash frontend.Variable
-	AuditMemoHash frontend.Variable
+	OwnerMemoHash    frontend.Variable
+	OwnerMemoViewTag frontend.Variable
+	OwnerMemoBody    []frontend.Variable
+	AuditMemoHash    frontend.Variable
 }
 
 func (circuit *MemoCircuit) Define(api frontend.API) error {
@@ -30,19 +34,24 @@ func (circuit *MemoCircuit) Define(api frontend.API) error {
 		ReceiverPublicKey:  circuit.PublicKey,
 	}
 
-	ownerMemo, err := gadget.Generate(api, circuit.Commitment)
+	ownerMemo, err := gadget.Generate(api, circuit.Commitment, circuit.SpentKey)
 	if err != nil {
 		return fmt.Errorf("failed to generate commitment: %w", err)
 	}
 
-	api.AssertIsEqual(circuit.OwnerMemoHash, ownerMemo)
+	api.AssertIsEqual(circuit.OwnerMemoHash, ownerMemo.Hash)
+	api.AssertIsEqual(circuit.OwnerMemoViewTag, ownerMemo.ViewTag)
 
-	auditMemo, err := gadget.Generate(api, circuit.Commitment)
+	for i := range circuit.OwnerMemoBody {
+		api.AssertIsEqual(circuit.OwnerMemoBody[i], ownerMemo.Ciphertext[i])
+	}
+
+	auditMemo, err := gadget.Generate(api, circuit.Commitment, circuit.SpentKey)
 	if err != nil {
 		return fmt.Errorf("failed to generate commitment: %w", err)
 	}
 
-	api.AssertIsEqual(circuit.AuditMemoHash, auditMemo)
+	api.AssertIsEqual(circuit.AuditMemoHash, auditMemo.Hash)
 
 	return nil
 }
@@ -59,13 +68,20 @@ func TestMemo_ToCircuit(t *testing.T) {
 
 	commitment, spentKey := builder.GenerateCommitment(12345)
 
-	_, ownerMemo, err := memo.Encrypt(*commitment, *spentKey)
+	ownerMemo, err := memo.Encrypt(*commitment, *spentKey)
 	require.NoError(t, err)
 
-	_, auditMemo, err := memo.Encrypt(*commitment, *spentKey)
+	auditMemo, err := memo.Encrypt(*commitment, *spentKey)
 	require.NoError(t, err)
 
-	circuit := MemoCircuit{}
+	circuit := MemoCircuit{
+		OwnerMemoBody: make([]frontend.Variable, len(ownerMemo.Ciphertext)),
+	}
+
+	ownerMemoBody := make([]frontend.Variable, len(ownerMemo.Ciphertext))
+	for i := range ownerMemo.Ciphertext {
+		ownerMemoBody[i] = ownerMemo.Ciphertext[i]
+	}
 
 	witness := MemoCircuit{
 		SecretKey: *secretKey,
@@ -80,11 +96,26 @@ func TestMemo_ToCircuit(t *testing.T) {
 			FreezeFlag:   commitment.FreezeFlag,
 			Blinding:     commitment.Blinding,
 		},
-		OwnerMemoHash: ownerMemo[len(ownerMemo)-1],
-		AuditMemoHash: auditMemo[len(auditMemo)-1],
+		SpentKey:         *spentKey,
+		OwnerMemoHash:    ownerMemo.Hash(),
+		OwnerMemoViewTag: ownerMemo.ViewTag,
+		OwnerMemoBody:    ownerMemoBody,
+		AuditMemoHash:    auditMemo.Hash(),
 	}
 
 	assert := test.NewAssert(t)
 
 	assert.ProverSucceeded(&circuit, &witness, test.WithCurves(ecc.BN254))
+
+	// A view tag that does not come from the shared key is rejected
+	witness.OwnerMemoViewTag = ownerMemo.Tag
+	assert.ProverFailed(&circuit, &witness, test.WithCurves(ecc.BN254))
+	witness.OwnerMemoViewTag = ownerMemo.ViewTag
+
+	// So is a memo hash over a different body with the same tag
+	swapped := *ownerMemo
+	swapped.Ciphertext = append([]fr.Element{}, ownerMemo.Ciphertext...)
+	swapped.Ciphertext[0] = fr.NewElement(1)
+	witness.OwnerMemoHash = swapped.Hash()
+	assert.ProverFailed(&circuit, &witness, test.WithCurves(ecc.BN254))
 }
@@ -0,0 +1,59 @@
+package circuits
+
+import (
+	"fmt"
+	"hide-pay/utils"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// MerkleUpdateCircuit proves that writing Leaf into the empty slot at Index
+// turns OldRoot into NewRoot, so a contract can append commitments without
+// hashing the tree itself.
+type MerkleUpdateCircuit struct {
+	Siblings []frontend.Variable `gnark:"siblings"`
+
+	Leaf    frontend.Variable `gnark:"leaf,public"`
+	Index   frontend.Variable `gnark:"index,public"`
+	OldRoot frontend.Variable `gnark:"oldRoot,public"`
+	NewRoot frontend.Variable `gnark:"newRoot,public"`
+}
+
+// NewMerkleUpdateCircuit sizes the circuit for paths of depth elements, the
+// leaf included, as MerkleProofGadget.
+func NewMerkleUpdateCircuit(depth int) *MerkleUpdateCircuit {
+	return &MerkleUpdateCircuit{
+		Siblings: make([]frontend.Variable, depth-1),
+	}
+}
+
+func (circuit *MerkleUpdateCircuit) Define(api frontend.API) error {
+	oldRoot, err := merkleRootWithLeaf(api, 0, circuit.Siblings, circuit.Index)
+	if err != nil {
+		return err
+	}
+
+	newRoot, err := merkleRootWithLeaf(api, circuit.Leaf, circuit.Siblings, circuit.Index)
+	if err != nil {
+		return err
+	}
+
+	api.AssertIsEqual(circuit.OldRoot, oldRoot)
+	api.AssertIsEqual(circuit.NewRoot, newRoot)
+
+	return nil
+}
+
+func merkleRootWithLeaf(api frontend.API, leaf frontend.Variable, siblings []frontend.Variable, index frontend.Variable) (frontend.Variable, error) {
+	hasher, err := utils.NewPoseidonHasher(api)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create poseidon hasher: %w", err)
+	}
+
+	merkleProof := MerkleProofGadget{
+		Path: append([]frontend.Variable{leaf}, siblings...),
+		Leaf: index,
+	}
+
+	return merkleProof.VerifyProof(api, hasher), nil
+}
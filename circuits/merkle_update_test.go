@@ -0,0 +1,49 @@
+package circuits_test
+
+import (
+	"hide-pay/builder"
+	"hide-pay/circuits"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerkleUpdate_Circuit(t *testing.T) {
+	depth := 6
+
+	leaves := []fr.Element{fr.NewElement(1), fr.NewElement(2), fr.NewElement(3)}
+
+	merkleTree := builder.NewMerkleTree(depth, poseidon2.NewMerkleDamgardHasher())
+	merkleTree.Build(leaves)
+
+	update := builder.MerkleUpdate{
+		MerkleProof: merkleTree.GetProof(len(leaves)),
+		Leaf:        fr.NewElement(4),
+	}
+
+	witness, err := update.ToWitness()
+	require.NoError(t, err)
+
+	// The new root is the root of the tree with the leaf appended
+	appended := builder.NewMerkleTree(depth, poseidon2.NewMerkleDamgardHasher())
+	appended.Build(append(leaves, update.Leaf))
+	require.Equal(t, appended.GetRoot(), witness.NewRoot)
+
+	assert := test.NewAssert(t)
+	circuit := circuits.NewMerkleUpdateCircuit(depth)
+
+	assert.ProverSucceeded(circuit, witness, test.WithCurves(ecc.BN254))
+
+	// An occupied slot cannot be overwritten
+	_, err = (&builder.MerkleUpdate{MerkleProof: merkleTree.GetProof(1), Leaf: fr.NewElement(4)}).ToWitness()
+	require.Error(t, err)
+
+	witness, err = update.ToWitness()
+	require.NoError(t, err)
+	witness.Index = 1
+	assert.ProverFailed(circuit, witness, test.WithCurves(ecc.BN254))
+}
@@ -0,0 +1,124 @@
+package circuits
+
+import (
+	"fmt"
+	"hide-pay/utils"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// KeyWrapDomain separates the content key wrap from the view tag. It is the
+// ASCII encoding of "wrap".
+const KeyWrapDomain = 0x77726170
+
+// MultiMemoGadget encrypts one opening for several readers. The payload is
+// encrypted once under ContentKey, and ContentKey is wrapped for every
+// receiver with the ECDH key of the shared ephemeral key. All wraps come from
+// the same ContentKey variable, which is what proves they agree.
+type MultiMemoGadget struct {
+	EphemeralSecretKey frontend.Variable      `gnark:"ephemeralSecretKey"`
+	ContentKey         frontend.Variable      `gnark:"contentKey"`
+	ReceiverPublicKeys [][2]frontend.Variable `gnark:"receiverPublicKeys"`
+}
+
+func NewMultiMemoGadget(receiverSize int) *MultiMemoGadget {
+	return &MultiMemoGadget{
+		ReceiverPublicKeys: make([][2]frontend.Variable, receiverSize),
+	}
+}
+
+type KeyWrapGadget struct {
+	ViewTag    frontend.Variable
+	WrappedKey frontend.Variable
+}
+
+type MultiMemoResultGadget struct {
+	EphemeralPublicKey [2]frontend.Variable
+	Wraps              []KeyWrapGadget
+	Ciphertext         []frontend.Variable
+	Tag                frontend.Variable
+	Hash               frontend.Variable
+}
+
+func (gadget *MultiMemoGadget) Generate(api frontend.API, output CommitmentGadget, spentKey frontend.Variable) (*MultiMemoResultGadget, error) {
+	wraps := make([]KeyWrapGadget, len(gadget.ReceiverPublicKeys))
+
+	for i := range gadget.ReceiverPublicKeys {
+		ecdh := ECDHGadget{
+			PublicKey: gadget.ReceiverPublicKeys[i],
+			SecretKey: gadget.EphemeralSecretKey,
+		}
+
+		sharedKey, err := ecdh.Compute(api)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute shared key %d: %w", i, err)
+		}
+
+		viewTag, err := computeViewTag(api, sharedKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute view tag %d: %w", i, err)
+		}
+
+		mask, err := computeKeyWrapMask(api, sharedKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute key wrap %d: %w", i, err)
+		}
+
+		wraps[i] = KeyWrapGadget{
+			ViewTag:    viewTag,
+			WrappedKey: api.Add(gadget.ContentKey, mask),
+		}
+	}
+
+	ephemeralPublicKey, err := computeEphemeralPublicKey(api, gadget.EphemeralSecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute ephemeral public key: %w", err)
+	}
+
+	// The content key fills the first key slot of the cipher
+	streamCipher := StreamCipherGadget{
+		Key: [2]frontend.Variable{gadget.ContentKey, 0},
+	}
+
+	ad := []frontend.Variable{
+		ephemeralPublicKey.X,
+		ephemeralPublicKey.Y,
+	}
+
+	ciphertext, err := streamCipher.Encrypt(api, ad, memoPlaintext(output, spentKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+
+	hasher, err := utils.NewPoseidonHasher(api)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create poseidon hasher: %w", err)
+	}
+
+	hasher.Write(ephemeralPublicKey.X, ephemeralPublicKey.Y)
+	for i := range wraps {
+		hasher.Write(wraps[i].ViewTag, wraps[i].WrappedKey)
+	}
+	hasher.Write(ciphertext...)
+
+	return &MultiMemoResultGadget{
+		EphemeralPublicKey: [2]frontend.Variable{ephemeralPublicKey.X, ephemeralPublicKey.Y},
+		Wraps:              wraps,
+		Ciphertext:         ciphertext[:len(ciphertext)-1],
+		Tag:                ciphertext[len(ciphertext)-1],
+		Hash:               hasher.Sum(),
+	}, nil
+}
+
+func computeKeyWrapMask(api frontend.API, sharedKey [2]frontend.Variable) (frontend.Variable, error) {
+	hasher, err := utils.NewPoseidonHasher(api)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create poseidon hasher: %w", err)
+	}
+
+	hasher.Write(KeyWrapDomain)
+	hasher.Write(sharedKey[0])
+	hasher.Write(sharedKey[1])
+
+	return hasher.Sum(), nil
+}
@@ -0,0 +1,128 @@
+package circuits_test
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+
+	"hide-pay/builder"
+	"hide-pay/circuits"
+	"hide-pay/utils"
+)
+
+type MultiMemoCircuit struct {
+	Memo       circuits.MultiMemoGadget
+	Commitment circuits.CommitmentGadget
+	SpentKey   frontend.Variable
+
+	WrappedKeys []frontend.Variable
+	MemoHash    frontend.Variable `gnark:",public"`
+}
+
+func (circuit *MultiMemoCircuit) Define(api frontend.API) error {
+	result, err := circuit.Memo.Generate(api, circuit.Commitment, circuit.SpentKey)
+	if err != nil {
+		return fmt.Errorf("failed to generate memo: %w", err)
+	}
+
+	for i := range circuit.WrappedKeys {
+		api.AssertIsEqual(circuit.WrappedKeys[i], result.Wraps[i].WrappedKey)
+	}
+
+	api.AssertIsEqual(circuit.MemoHash, result.Hash)
+
+	return nil
+}
+
+// SeparateMemosCircuit encrypts the same note once per reader, the layout
+// MultiMemoGadget replaces.
+type SeparateMemosCircuit struct {
+	Memos      []circuits.MemoGadget
+	Commitment circuits.CommitmentGadget
+	SpentKey   frontend.Variable
+	MemoHashes []frontend.Variable `gnark:",public"`
+}
+
+func (circuit *SeparateMemosCircuit) Define(api frontend.API) error {
+	for i := range circuit.Memos {
+		result, err := circuit.Memos[i].Generate(api, circuit.Commitment, circuit.SpentKey)
+		if err != nil {
+			return fmt.Errorf("failed to generate memo: %w", err)
+		}
+
+		api.AssertIsEqual(circuit.MemoHashes[i], result.Hash)
+	}
+
+	return nil
+}
+
+func TestMultiMemo_ToCircuit(t *testing.T) {
+	publicKeys := []twistededwardbn254.PointAffine{
+		utils.BuildPublicKey(*big.NewInt(11111)),
+		utils.BuildPublicKey(*big.NewInt(22222)),
+		utils.BuildPublicKey(*big.NewInt(33333)),
+	}
+
+	memo, err := builder.NewMultiMemo(*big.NewInt(44444), publicKeys)
+	require.NoError(t, err)
+
+	commitment, spentKey := builder.GenerateCommitment(12345)
+
+	envelope, err := memo.Encrypt(*commitment, *spentKey)
+	require.NoError(t, err)
+
+	wrappedKeys := make([]frontend.Variable, len(envelope.Wraps))
+	for i := range envelope.Wraps {
+		wrappedKeys[i] = envelope.Wraps[i].WrappedKey
+	}
+
+	circuit := MultiMemoCircuit{
+		Memo:        *circuits.NewMultiMemoGadget(len(publicKeys)),
+		WrappedKeys: make([]frontend.Variable, len(publicKeys)),
+	}
+
+	witness := MultiMemoCircuit{
+		Memo:        *memo.ToGadget(),
+		Commitment:  *commitment.ToGadget(),
+		SpentKey:    *spentKey,
+		WrappedKeys: wrappedKeys,
+		MemoHash:    envelope.Hash(),
+	}
+
+	assert := test.NewAssert(t)
+
+	assert.ProverSucceeded(&circuit, &witness, test.WithCurves(ecc.BN254))
+
+	// A wrap of another content key is rejected
+	witness.WrappedKeys = append([]frontend.Variable{}, wrappedKeys...)
+	witness.WrappedKeys[2] = 1
+	assert.ProverFailed(&circuit, &witness, test.WithCurves(ecc.BN254))
+}
+
+func TestMultiMemo_Constraints(t *testing.T) {
+	const readers = 3
+
+	multi, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &MultiMemoCircuit{
+		Memo:        *circuits.NewMultiMemoGadget(readers),
+		WrappedKeys: make([]frontend.Variable, readers),
+	})
+	require.NoError(t, err)
+
+	separate, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &SeparateMemosCircuit{
+		Memos:      make([]circuits.MemoGadget, readers),
+		MemoHashes: make([]frontend.Variable, readers),
+	})
+	require.NoError(t, err)
+
+	t.Logf("%d readers: multi-recipient %d constraints, separate memos %d constraints",
+		readers, multi.GetNbConstraints(), separate.GetNbConstraints())
+
+	require.Less(t, multi.GetNbConstraints(), separate.GetNbConstraints())
+}
@@ -0,0 +1,94 @@
+package circuits
+
+import (
+	"fmt"
+	"hide-pay/utils"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// spendNote proves that note is a leaf of the commitment tree and that its
+// nullifier key is the one it was sent to. It returns the nullifier and the
+// root of the tree.
+func spendNote(api frontend.API, note *NullifierGadget, merkleProof *MerkleProofGadget) (frontend.Variable, frontend.Variable, error) {
+	hasher, err := utils.NewPoseidonHasher(api)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create poseidon hasher: %w", err)
+	}
+
+	root := merkleProof.VerifyProof(api, hasher)
+
+	// The proven leaf must be the commitment being spent
+	commitment, err := note.CommitmentGadget.Compute(api)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compute input commitment: %w", err)
+	}
+	api.AssertIsEqual(merkleProof.Path[0], commitment)
+
+	// and the nullifier key must be the one it was sent to
+	if err := assertSpentAddress(api, note.SpentAddress, note.PrivateKey); err != nil {
+		return nil, nil, err
+	}
+
+	nullifier, err := note.Compute(api)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compute nullifier: %w", err)
+	}
+
+	return nullifier, root, nil
+}
+
+// createdNote holds the public values of a created note.
+type createdNote struct {
+	Commitment    frontend.Variable
+	OwnerMemoHash frontend.Variable
+	AuditMemoHash frontend.Variable
+}
+
+// createNote computes the commitment of note and the hashes of its owner and
+// audit memos, which both deliver spentKey. The audit memo must be encrypted
+// to the audit key the note commits to; callers bind that key to the one
+// auditors hold.
+func createNote(api frontend.API, note CommitmentGadget, spentKey frontend.Variable, ownerMemo MemoGadget, auditMemo MemoGadget) (*createdNote, error) {
+	commitment, err := note.Compute(api)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute commitment: %w", err)
+	}
+
+	api.AssertIsEqual(auditMemo.ReceiverPublicKey[0], note.AuditPubKey[0])
+	api.AssertIsEqual(auditMemo.ReceiverPublicKey[1], note.AuditPubKey[1])
+
+	// The receiver could not spend an output whose memo carries another key
+	if err := assertSpentAddress(api, note.SpentAddress, spentKey); err != nil {
+		return nil, err
+	}
+
+	owner, err := ownerMemo.Generate(api, note, spentKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate owner memo: %w", err)
+	}
+
+	audit, err := auditMemo.Generate(api, note, spentKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate audit memo: %w", err)
+	}
+
+	return &createdNote{
+		Commitment:    commitment,
+		OwnerMemoHash: owner.Hash,
+		AuditMemoHash: audit.Hash,
+	}, nil
+}
+
+// assertSpentAddress checks that spentAddress is the hash of spentKey.
+func assertSpentAddress(api frontend.API, spentAddress frontend.Variable, spentKey frontend.Variable) error {
+	hasher, err := utils.NewPoseidonHasher(api)
+	if err != nil {
+		return fmt.Errorf("failed to create poseidon hasher: %w", err)
+	}
+
+	hasher.Write(spentKey)
+	api.AssertIsEqual(spentAddress, hasher.Sum())
+
+	return nil
+}
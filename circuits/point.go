@@ -0,0 +1,67 @@
+package circuits
+
+import (
+	"fmt"
+	"math/big"
+
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	twistededwardcrypto "github.com/consensys/gnark-crypto/ecc/twistededwards"
+	"github.com/consensys/gnark/constraint/solver"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/native/twistededwards"
+)
+
+func init() {
+	solver.RegisterHint(cofactorClearHint)
+}
+
+// AssertIsValidPublicKey constrains point to lie on the curve, in the
+// prime-order subgroup, and not be the identity.
+//
+// Subgroup membership is shown by witnessing Q = (8^-1 mod order) * P and
+// asserting 8 * Q == P, since [8]E is exactly the prime-order subgroup.
+func AssertIsValidPublicKey(api frontend.API, point [2]frontend.Variable) error {
+	curve, err := twistededwards.NewEdCurve(api, twistededwardcrypto.BN254)
+	if err != nil {
+		return fmt.Errorf("failed to create twistededwards curve: %w", err)
+	}
+
+	p := twistededwards.Point{X: point[0], Y: point[1]}
+	curve.AssertIsOnCurve(p)
+
+	// (0, 1) is the identity and (0, -1) has order 2
+	api.AssertIsDifferent(p.X, 0)
+
+	res, err := api.Compiler().NewHint(cofactorClearHint, 2, p.X, p.Y)
+	if err != nil {
+		return fmt.Errorf("failed to compute cofactor hint: %w", err)
+	}
+
+	q := twistededwards.Point{X: res[0], Y: res[1]}
+	curve.AssertIsOnCurve(q)
+
+	q8 := curve.Double(curve.Double(curve.Double(q)))
+	api.AssertIsEqual(q8.X, p.X)
+	api.AssertIsEqual(q8.Y, p.Y)
+
+	return nil
+}
+
+func cofactorClearHint(_ *big.Int, inputs []*big.Int, outputs []*big.Int) error {
+	curve := twistededwardbn254.GetEdwardsCurve()
+
+	var point twistededwardbn254.PointAffine
+	point.X.SetBigInt(inputs[0])
+	point.Y.SetBigInt(inputs[1])
+
+	cofactor := curve.Cofactor.BigInt(new(big.Int))
+	inverse := new(big.Int).ModInverse(cofactor, &curve.Order)
+
+	var q twistededwardbn254.PointAffine
+	q.ScalarMultiplication(&point, inverse)
+
+	q.X.BigInt(outputs[0])
+	q.Y.BigInt(outputs[1])
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package circuits
+
+import (
+	"fmt"
+	"hide-pay/utils"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// ReceiptCircuit proves that the owner memo published with Commitment, as
+// identified by its ephemeral key and hash, encrypts an opening that pays
+// Amount of Asset to the receiver and carries a spent key matching the
+// commitment.
+type ReceiptCircuit struct {
+	Opening            CommitmentGadget  `gnark:"opening"`
+	SpentKey           frontend.Variable `gnark:"spentKey"`
+	EphemeralSecretKey frontend.Variable `gnark:"ephemeralSecretKey"`
+
+	Commitment frontend.Variable `gnark:"commitment,public"`
+	Asset      frontend.Variable `gnark:"asset,public"`
+	Amount     frontend.Variable `gnark:"amount,public"`
+
+	ReceiverOwnerPubKey [2]frontend.Variable `gnark:"receiverOwnerPubKey,public"`
+	ReceiverViewPubKey  [2]frontend.Variable `gnark:"receiverViewPubKey,public"`
+
+	EphemeralPublicKey [2]frontend.Variable `gnark:"ephemeralPublicKey,public"`
+	MemoHash           frontend.Variable    `gnark:"memoHash,public"`
+}
+
+func (circuit *ReceiptCircuit) Define(api frontend.API) error {
+	commitment, err := circuit.Opening.Compute(api)
+	if err != nil {
+		return fmt.Errorf("failed to compute commitment: %w", err)
+	}
+
+	api.AssertIsEqual(circuit.Commitment, commitment)
+	api.AssertIsEqual(circuit.Asset, circuit.Opening.Asset)
+	api.AssertIsEqual(circuit.Amount, circuit.Opening.Amount)
+	api.AssertIsEqual(circuit.ReceiverOwnerPubKey[0], circuit.Opening.OwnerPubKey[0])
+	api.AssertIsEqual(circuit.ReceiverOwnerPubKey[1], circuit.Opening.OwnerPubKey[1])
+	api.AssertIsEqual(circuit.ReceiverViewPubKey[0], circuit.Opening.ViewPubKey[0])
+	api.AssertIsEqual(circuit.ReceiverViewPubKey[1], circuit.Opening.ViewPubKey[1])
+
+	// Without the matching spent key the receiver could not spend the note
+	hasher, err := utils.NewPoseidonHasher(api)
+	if err != nil {
+		return fmt.Errorf("failed to create poseidon hasher: %w", err)
+	}
+
+	hasher.Write(circuit.SpentKey)
+	api.AssertIsEqual(circuit.Opening.SpentAddress, hasher.Sum())
+
+	memo := MemoGadget{
+		EphemeralSecretKey: circuit.EphemeralSecretKey,
+		ReceiverPublicKey:  circuit.ReceiverViewPubKey,
+	}
+
+	result, err := memo.Generate(api, circuit.Opening, circuit.SpentKey)
+	if err != nil {
+		return fmt.Errorf("failed to generate memo: %w", err)
+	}
+
+	api.AssertIsEqual(circuit.EphemeralPublicKey[0], result.EphemeralPublicKey[0])
+	api.AssertIsEqual(circuit.EphemeralPublicKey[1], result.EphemeralPublicKey[1])
+	api.AssertIsEqual(circuit.MemoHash, result.Hash)
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package circuits_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+
+	"hide-pay/builder"
+	"hide-pay/circuits"
+	"hide-pay/utils"
+)
+
+func TestReceipt_Circuit(t *testing.T) {
+	receiver := builder.ReceiverInfo{
+		OwnerPubKey: utils.BuildPublicKey(*big.NewInt(11111)),
+		ViewPubKey:  utils.BuildPublicKey(*big.NewInt(22222)),
+	}
+
+	commitment, spentKey := builder.GenerateCommitment(12345)
+	commitment.OwnerPubKey = receiver.OwnerPubKey
+	commitment.ViewPubKey = receiver.ViewPubKey
+
+	payment := builder.Payment{
+		Commitment:         *commitment,
+		SpentKey:           *spentKey,
+		EphemeralSecretKey: *big.NewInt(33333),
+		Receiver:           receiver,
+	}
+
+	witness, err := payment.ToWitness()
+	require.NoError(t, err)
+
+	assert := test.NewAssert(t)
+	circuit := circuits.ReceiptCircuit{}
+
+	assert.ProverSucceeded(&circuit, witness, test.WithCurves(ecc.BN254))
+
+	// A spent key that does not match SpentAddress
+	wrongSpentKey := *witness
+	wrongSpentKey.SpentKey = fr.NewElement(1)
+	assert.ProverFailed(&circuit, &wrongSpentKey, test.WithCurves(ecc.BN254))
+
+	// A note locked to someone else
+	commitment.OwnerPubKey = utils.BuildPublicKey(*big.NewInt(44444))
+	payment.Commitment = *commitment
+
+	witness, err = payment.ToWitness()
+	require.NoError(t, err)
+	assert.ProverFailed(&circuit, witness, test.WithCurves(ecc.BN254))
+}
+
+func TestReceipt_TransferOutput(t *testing.T) {
+	utxo, err := builder.GenerateUTXO(1, 10, 1, 2)
+	require.NoError(t, err)
+
+	result, err := utxo.BuildAndCheck()
+	require.NoError(t, err)
+
+	assert := test.NewAssert(t)
+	circuit := circuits.ReceiptCircuit{}
+
+	// The sender proves each output against the owner memo the transfer
+	// published, which is encrypted to the output's own viewing key
+	for i := range utxo.Commitment {
+		payment := builder.Payment{
+			Commitment:         utxo.Commitment[i],
+			SpentKey:           utxo.SpentKey[i],
+			EphemeralSecretKey: utxo.EphemeralReceiverSecretKey[i],
+			Receiver: builder.ReceiverInfo{
+				OwnerPubKey: utxo.Commitment[i].OwnerPubKey,
+				ViewPubKey:  utxo.Commitment[i].ViewPubKey,
+			},
+		}
+
+		witness, err := payment.ToWitness()
+		require.NoError(t, err)
+		require.Equal(t, result.Commitments[i].Commitment, witness.Commitment)
+		require.Equal(t, result.Commitments[i].OwnerMemo.Hash(), witness.MemoHash)
+
+		assert.ProverSucceeded(&circuit, witness, test.WithCurves(ecc.BN254))
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/test"
 	"github.com/stretchr/testify/assert"
@@ -55,3 +56,84 @@ func TestSchnorr_Circuit_Verification(t *testing.T) {
 	options := test.WithCurves(ecc.BN254)
 	assert.ProverSucceeded(circuit, &witness, options)
 }
+
+func TestSchnorr_Circuit_DeterministicSignature(t *testing.T) {
+	kp, err := builder.GenerateKeypair()
+	require.NoError(t, err)
+
+	messageHash := fr.NewElement(12345)
+	signature := kp.SignDeterministic(messageHash, fr.NewElement(67890))
+
+	result := builder.Verify(messageHash, signature, &kp.PublicKey)
+	assert.True(t, result)
+
+	circuit := NewSchnorrCircuit()
+	assert.NotNil(t, circuit)
+
+	assert := test.NewAssert(t)
+
+	witness := SchnorrCircuit{
+		SchnorrGadget: *circuits.NewSchnorrGadget(messageHash, signature.S, [2]frontend.Variable{signature.R.X, signature.R.Y}, [2]frontend.Variable{kp.PublicKey.X, kp.PublicKey.Y}),
+	}
+
+	options := test.WithCurves(ecc.BN254)
+	assert.ProverSucceeded(circuit, &witness, options)
+}
+
+func TestSchnorr_Circuit_MuSigSignature(t *testing.T) {
+	keypairs := make([]*builder.Keypair, 3)
+	publicKeys := make([]twistededwardbn254.PointAffine, len(keypairs))
+
+	for i := range keypairs {
+		kp, err := builder.GenerateKeypair()
+		require.NoError(t, err)
+
+		keypairs[i] = kp
+		publicKeys[i] = kp.PublicKey
+	}
+
+	key, err := builder.AggregatePublicKeys(publicKeys)
+	require.NoError(t, err)
+
+	messageHash := fr.NewElement(12345)
+
+	sessions := make([]*builder.MuSigSession, len(keypairs))
+	commitments := make([]fr.Element, len(keypairs))
+	for i := range keypairs {
+		sessions[i], err = builder.NewMuSigSession(keypairs[i], key, messageHash)
+		require.NoError(t, err)
+
+		commitments[i], err = sessions[i].Commit()
+		require.NoError(t, err)
+	}
+
+	nonces := make([]twistededwardbn254.PointAffine, len(keypairs))
+	for i := range sessions {
+		nonces[i], err = sessions[i].Reveal(commitments)
+		require.NoError(t, err)
+	}
+
+	partials := make([]fr.Element, len(keypairs))
+	for i := range sessions {
+		partials[i], err = sessions[i].PartialSign(nonces)
+		require.NoError(t, err)
+	}
+
+	signature, err := builder.AggregatePartialSignatures(nonces, partials)
+	require.NoError(t, err)
+
+	result := builder.Verify(messageHash, signature, &key.PublicKey)
+	assert.True(t, result)
+
+	circuit := NewSchnorrCircuit()
+	assert.NotNil(t, circuit)
+
+	assert := test.NewAssert(t)
+
+	witness := SchnorrCircuit{
+		SchnorrGadget: *circuits.NewSchnorrGadget(messageHash, signature.S, [2]frontend.Variable{signature.R.X, signature.R.Y}, [2]frontend.Variable{key.PublicKey.X, key.PublicKey.Y}),
+	}
+
+	options := test.WithCurves(ecc.BN254)
+	assert.ProverSucceeded(circuit, &witness, options)
+}
@@ -1,150 +1,177 @@
 package circuits
 
-// type UTXOGadget struct {
-// 	AllAsset []frontend.Variable `gnark:"allAsset"`
+import (
+	"fmt"
 
-// 	Nullifier []NullifierGadget `gnark:"nullifier"`
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/rangecheck"
+)
 
-// 	Commitment []CommitmentGadget `gnark:"commitment"`
+type UTXOGadget struct {
+	AllAsset []frontend.Variable `gnark:"allAsset"`
 
-// 	EphemeralReceiverSecretKey []frontend.Variable  `gnark:"ephemeralReceiverSecretKey"`
-// 	EphemeralAuditSecretKey    []frontend.Variable  `gnark:"ephemeralAuditSecretKey"`
-// 	ReceiverPublicKey          [2]frontend.Variable `gnark:"receiverPublicKey"`
-// 	AuditPublicKey             [2]frontend.Variable `gnark:"auditPublicKey"`
+	Nullifier []NullifierGadget `gnark:"nullifier"`
 
-// 	MerkleProofPath  []frontend.Variable `gnark:"merkleProofPath"`
-// 	MerkleProofIndex []frontend.Variable `gnark:"merkleProofIndex"`
-// }
+	Commitment []CommitmentGadget  `gnark:"commitment"`
+	SpentKey   []frontend.Variable `gnark:"spentKey"`
 
-// func NewUTXOGadget(allAssetSize int, depth int, nullifierSize int, commitmentSize int) *UTXOGadget {
+	// The owner memo of every output is encrypted to its own ViewPubKey, so
+	// one transfer can pay several receivers and return change
+	EphemeralReceiverSecretKey []frontend.Variable `gnark:"ephemeralReceiverSecretKey"`
+	EphemeralAuditSecretKey    []frontend.Variable `gnark:"ephemeralAuditSecretKey"`
 
-// 	return &UTXOGadget{
-// 		AllAsset: make([]frontend.Variable, allAssetSize),
+	// AuditPublicKey is public so the verifier can require the key of the
+	// auditors. Every output commits to it and its audit memo is encrypted
+	// to it.
+	AuditPublicKey [2]frontend.Variable `gnark:"auditPublicKey,public"`
 
-// 		Nullifier:        make([]NullifierGadget, nullifierSize),
-// 		MerkleProofPath:  make([]frontend.Variable, nullifierSize*depth),
-// 		MerkleProofIndex: make([]frontend.Variable, nullifierSize),
+	MerkleProofPath  []frontend.Variable `gnark:"merkleProofPath"`
+	MerkleProofIndex []frontend.Variable `gnark:"merkleProofIndex"`
+}
 
-// 		Commitment:                 make([]CommitmentGadget, commitmentSize),
-// 		EphemeralReceiverSecretKey: make([]frontend.Variable, commitmentSize),
-// 		EphemeralAuditSecretKey:    make([]frontend.Variable, commitmentSize),
-// 	}
-// }
+func NewUTXOGadget(allAssetSize int, depth int, nullifierSize int, commitmentSize int) *UTXOGadget {
+	return &UTXOGadget{
+		AllAsset: make([]frontend.Variable, allAssetSize),
 
-// func (gadget *UTXOGadget) BuildAndCheck(api frontend.API) (*UTXOResultGadget, error) {
-// 	nullifiers := make([]frontend.Variable, len(gadget.Nullifier))
-// 	commitments := make([]frontend.Variable, len(gadget.Commitment))
+		Nullifier:        make([]NullifierGadget, nullifierSize),
+		MerkleProofPath:  make([]frontend.Variable, nullifierSize*depth),
+		MerkleProofIndex: make([]frontend.Variable, nullifierSize),
 
-// 	// Check that the number of nullifiers, commitments, and ephemeral secret keys are the same
-// 	if len(gadget.Commitment) != len(gadget.EphemeralReceiverSecretKey) || len(gadget.Commitment) != len(gadget.EphemeralAuditSecretKey) {
-// 		return nil, fmt.Errorf("number of nullifiers, commitments, and ephemeral receiver and audit secret keys must be the same")
-// 	}
-
-// 	inputAmounts := make([]frontend.Variable, len(gadget.AllAsset))
-
-// 	for i := range gadget.AllAsset {
-// 		inputAmounts[i] = 0
-// 	}
-
-// 	merkleRoot := make([]frontend.Variable, len(gadget.Nullifier))
-
-// 	depth := len(gadget.MerkleProofPath) / len(gadget.Nullifier)
-
-// 	for i := range gadget.Nullifier {
-// 		gadgetNullifier := gadget.Nullifier[i]
-
-// 		rangerChecker := rangecheck.New(api)
-// 		rangerChecker.Check(gadgetNullifier.Amount, 253)
-
-// 		hasher, err := utils.NewPoseidonHasher(api)
-// 		if err != nil {
-// 			return nil, fmt.Errorf("failed to create poseidon hasher: %w", err)
-// 		}
-
-// 		// TODO: Check nullifier is in merkle tree
-// 		merkleProof := MerkleProofGadget{
-// 			Path: gadget.MerkleProofPath[i*depth : (i+1)*depth],
-// 			Leaf: gadget.MerkleProofIndex[i],
-// 		}
-
-// 		merkleRoot[i] = merkleProof.VerifyProof(api, hasher)
-
-// 		for j := range gadget.AllAsset {
-// 			diff := api.Sub(gadget.AllAsset[j], gadgetNullifier.Asset)
-// 			isZero := api.IsZero(diff)
-// 			inputAmounts[j] = api.Add(inputAmounts[j], api.Mul(gadgetNullifier.Amount, isZero))
-// 		}
-
-// 		nullifier, err := gadgetNullifier.Compute(api)
-// 		if err != nil {
-// 			return nil, fmt.Errorf("failed to compute nullifier: %w", err)
-// 		}
-// 		nullifiers[i] = nullifier
-// 	}
-
-// 	for i := range merkleRoot {
-// 		api.AssertIsEqual(merkleRoot[i], merkleRoot[0])
-// 	}
-
-// 	ownerMemoHashes := make([]frontend.Variable, len(gadget.EphemeralReceiverSecretKey))
-// 	auditMemoHashes := make([]frontend.Variable, len(gadget.EphemeralAuditSecretKey))
-
-// 	outputAmounts := make([]frontend.Variable, len(gadget.AllAsset))
-
-// 	for i := range gadget.AllAsset {
-// 		outputAmounts[i] = 0
-// 	}
-
-// 	for i := range gadget.Commitment {
-// 		gadgetCommitment := gadget.Commitment[i]
-
-// 		rangerChecker := rangecheck.New(api)
-// 		rangerChecker.Check(gadgetCommitment.Amount, 253)
-
-// 		for j := range gadget.AllAsset {
-// 			diff := api.Sub(gadget.AllAsset[j], gadgetCommitment.Asset)
-// 			isZero := api.IsZero(diff)
-// 			outputAmounts[j] = api.Add(outputAmounts[j], api.Mul(gadgetCommitment.Amount, isZero))
-// 		}
-
-// 		commitment, err := gadgetCommitment.Compute(api)
-// 		if err != nil {
-// 			return nil, fmt.Errorf("failed to compute commitment: %w", err)
-// 		}
-// 		commitments[i] = commitment
-
-// 		ownerMemoGadget := MemoGadget{
-// 			EphemeralSecretKey: gadget.EphemeralReceiverSecretKey[i],
-// 			ReceiverPublicKey:  gadget.ReceiverPublicKey,
-// 		}
-
-// 		ownerMemoHash, err := ownerMemoGadget.Generate(api, gadgetCommitment)
-// 		if err != nil {
-// 			return nil, fmt.Errorf("failed to generate owner memo: %w", err)
-// 		}
-// 		ownerMemoHashes[i] = ownerMemoHash
-
-// 		auditMemoGadget := MemoGadget{
-// 			EphemeralSecretKey: gadget.EphemeralAuditSecretKey[i],
-// 			ReceiverPublicKey:  gadget.AuditPublicKey,
-// 		}
-
-// 		auditMemoHash, err := auditMemoGadget.Generate(api, gadgetCommitment)
-// 		if err != nil {
-// 			return nil, fmt.Errorf("failed to generate audit memo: %w", err)
-// 		}
-// 		auditMemoHashes[i] = auditMemoHash
-// 	}
-
-// 	for i := range inputAmounts {
-// 		api.AssertIsEqual(inputAmounts[i], outputAmounts[i])
-// 	}
-
-// 	return &UTXOResultGadget{
-// 		Nullifiers:      nullifiers,
-// 		Commitments:     commitments,
-// 		OwnerMemoHashes: ownerMemoHashes,
-// 		AuditMemoHashes: auditMemoHashes,
-// 		MerkleRoot:      merkleRoot[0],
-// 	}, nil
-// }
+		Commitment:                 make([]CommitmentGadget, commitmentSize),
+		SpentKey:                   make([]frontend.Variable, commitmentSize),
+		EphemeralReceiverSecretKey: make([]frontend.Variable, commitmentSize),
+		EphemeralAuditSecretKey:    make([]frontend.Variable, commitmentSize),
+	}
+}
+
+func (gadget *UTXOGadget) BuildAndCheck(api frontend.API) (*UTXOResultGadget, error) {
+	nullifiers := make([]frontend.Variable, len(gadget.Nullifier))
+	commitments := make([]frontend.Variable, len(gadget.Commitment))
+
+	// Check that the number of commitments, spent keys and ephemeral secret keys are the same
+	if len(gadget.Commitment) != len(gadget.SpentKey) || len(gadget.Commitment) != len(gadget.EphemeralReceiverSecretKey) || len(gadget.Commitment) != len(gadget.EphemeralAuditSecretKey) {
+		return nil, fmt.Errorf("number of commitments, spent keys, and ephemeral receiver and audit secret keys must be the same")
+	}
+
+	if len(gadget.Nullifier) == 0 || len(gadget.MerkleProofPath)%len(gadget.Nullifier) != 0 {
+		return nil, fmt.Errorf("merkle proof path must hold one path of the same depth per nullifier")
+	}
+
+	inputAmounts := make([]frontend.Variable, len(gadget.AllAsset))
+
+	for i := range gadget.AllAsset {
+		inputAmounts[i] = 0
+	}
+
+	merkleRoot := make([]frontend.Variable, len(gadget.Nullifier))
+
+	depth := len(gadget.MerkleProofPath) / len(gadget.Nullifier)
+
+	// Each listed asset gets one balance equation below
+	for i := range gadget.AllAsset {
+		for j := i + 1; j < len(gadget.AllAsset); j++ {
+			api.AssertIsDifferent(gadget.AllAsset[i], gadget.AllAsset[j])
+		}
+	}
+
+	rangeChecker := rangecheck.New(api)
+
+	for i := range gadget.Nullifier {
+		gadgetNullifier := gadget.Nullifier[i]
+
+		rangeChecker.Check(gadgetNullifier.Amount, AmountBits)
+		assertListedAsset(api, gadget.AllAsset, gadgetNullifier.Asset)
+
+		// A frozen note can only be changed by the freeze circuit
+		api.AssertIsEqual(gadgetNullifier.FreezeFlag, 0)
+
+		merkleProof := MerkleProofGadget{
+			Path: gadget.MerkleProofPath[i*depth : (i+1)*depth],
+			Leaf: gadget.MerkleProofIndex[i],
+		}
+
+		nullifier, root, err := spendNote(api, &gadgetNullifier, &merkleProof)
+		if err != nil {
+			return nil, err
+		}
+		merkleRoot[i] = root
+
+		for j := range gadget.AllAsset {
+			diff := api.Sub(gadget.AllAsset[j], gadgetNullifier.Asset)
+			isZero := api.IsZero(diff)
+			inputAmounts[j] = api.Add(inputAmounts[j], api.Mul(gadgetNullifier.Amount, isZero))
+		}
+
+		nullifiers[i] = nullifier
+	}
+
+	for i := range merkleRoot {
+		api.AssertIsEqual(merkleRoot[i], merkleRoot[0])
+	}
+
+	ownerMemoHashes := make([]frontend.Variable, len(gadget.Commitment))
+	auditMemoHashes := make([]frontend.Variable, len(gadget.Commitment))
+
+	outputAmounts := make([]frontend.Variable, len(gadget.AllAsset))
+
+	for i := range gadget.AllAsset {
+		outputAmounts[i] = 0
+	}
+
+	for i := range gadget.Commitment {
+		gadgetCommitment := gadget.Commitment[i]
+
+		rangeChecker.Check(gadgetCommitment.Amount, AmountBits)
+		assertListedAsset(api, gadget.AllAsset, gadgetCommitment.Asset)
+
+		// and only the freeze circuit creates one
+		api.AssertIsEqual(gadgetCommitment.FreezeFlag, 0)
+
+		for j := range gadget.AllAsset {
+			diff := api.Sub(gadget.AllAsset[j], gadgetCommitment.Asset)
+			isZero := api.IsZero(diff)
+			outputAmounts[j] = api.Add(outputAmounts[j], api.Mul(gadgetCommitment.Amount, isZero))
+		}
+
+		created, err := createNote(api, gadgetCommitment, gadget.SpentKey[i],
+			MemoGadget{
+				EphemeralSecretKey: gadget.EphemeralReceiverSecretKey[i],
+				ReceiverPublicKey:  gadgetCommitment.ViewPubKey,
+			},
+			MemoGadget{
+				EphemeralSecretKey: gadget.EphemeralAuditSecretKey[i],
+				ReceiverPublicKey:  gadget.AuditPublicKey,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		commitments[i] = created.Commitment
+		ownerMemoHashes[i] = created.OwnerMemoHash
+		auditMemoHashes[i] = created.AuditMemoHash
+	}
+
+	for i := range inputAmounts {
+		api.AssertIsEqual(inputAmounts[i], outputAmounts[i])
+	}
+
+	return &UTXOResultGadget{
+		Nullifiers:      nullifiers,
+		Commitments:     commitments,
+		OwnerMemoHashes: ownerMemoHashes,
+		AuditMemoHashes: auditMemoHashes,
+		MerkleRoot:      merkleRoot[0],
+	}, nil
+}
+
+// assertListedAsset checks that asset is one of allAsset. A note of an
+// unlisted asset would be left out of every balance equation.
+func assertListedAsset(api frontend.API, allAsset []frontend.Variable, asset frontend.Variable) {
+	product := frontend.Variable(1)
+	for i := range allAsset {
+		product = api.Mul(product, api.Sub(asset, allAsset[i]))
+	}
+
+	api.AssertIsEqual(product, 0)
+}
@@ -1,89 +1,205 @@
 package circuits_test
 
-// func TestUTXO_ToGadget(t *testing.T) {
-// 	receiverSecretKey := big.NewInt(11111)
-// 	auditSecretKey := big.NewInt(22222)
-
-// 	receiverPublicKey := utils.BuildPublicKey(*receiverSecretKey)
-// 	auditPublicKey := utils.BuildPublicKey(*auditSecretKey)
-
-// 	nullifier1 := builder.Nullifier{
-// 		Commitment: builder.Commitment{
-// 			Asset:    fr.NewElement(1),
-// 			Amount:   fr.NewElement(2),
-// 			Blinding: fr.NewElement(3),
-// 		},
-// 	}
-
-// 	nullifier2 := builder.Nullifier{
-// 		Commitment: builder.Commitment{
-// 			Asset:    fr.NewElement(1),
-// 			Amount:   fr.NewElement(2),
-// 			Blinding: fr.NewElement(4),
-// 		},
-// 	}
-
-// 	depth := 10
-
-// 	merkleTree := builder.NewMerkleTree(depth, poseidon2.NewMerkleDamgardHasher())
-// 	elems := []fr.Element{
-// 		nullifier1.Commitment.Compute(),
-// 		nullifier2.Commitment.Compute(),
-// 	}
-// 	merkleTree.Build(elems)
-// 	root := merkleTree.GetRoot()
-
-// 	merkleProof1 := merkleTree.GetProof(0)
-// 	merkleProof2 := merkleTree.GetProof(1)
-
-// 	root1 := merkleProof1.Verify()
-// 	root2 := merkleProof2.Verify()
-
-// 	assert.Equal(t, root, root1)
-// 	assert.Equal(t, root, root2)
-
-// 	utxo := &builder.UTXO{
-// 		Nullifier: []builder.Nullifier{
-// 			nullifier1,
-// 			nullifier2,
-// 		},
-// 		MerkleProof: []builder.MerkleProof{
-// 			merkleProof1,
-// 			merkleProof2,
-// 		},
-// 		Commitment: []builder.Commitment{
-// 			{
-// 				Asset:    fr.NewElement(1),
-// 				Amount:   fr.NewElement(2),
-// 				Blinding: fr.NewElement(5),
-// 			},
-// 			{
-// 				Asset:    fr.NewElement(1),
-// 				Amount:   fr.NewElement(2),
-// 				Blinding: fr.NewElement(6),
-// 			},
-// 		},
-// 		EphemeralReceiverSecretKey: []big.Int{
-// 			*big.NewInt(1),
-// 			*big.NewInt(2),
-// 		},
-// 		EphemeralAuditSecretKey: []big.Int{
-// 			*big.NewInt(3),
-// 			*big.NewInt(4),
-// 		},
-// 		ReceiverPublicKey: receiverPublicKey,
-// 		AuditPublicKey:    auditPublicKey,
-// 	}
-
-// 	result, err := utxo.BuildAndCheck()
-// 	assert.NoError(t, err)
-
-// 	witness, err := builder.NewUTXOCircuitWitness(utxo, result)
-// 	assert.NoError(t, err)
-
-// 	utxoCircuit := circuits.NewUTXOCircuit(len(result.AllAsset), depth, len(utxo.Nullifier), len(utxo.Commitment))
-
-// 	assert := test.NewAssert(t)
-
-// 	assert.ProverSucceeded(utxoCircuit, witness, test.WithCurves(ecc.BN254))
-// }
+import (
+	"hide-pay/builder"
+	"hide-pay/circuits"
+	"hide-pay/utils"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUTXO_ToGadget(t *testing.T) {
+	depth := 10
+
+	utxo, err := builder.GenerateUTXO(1, depth, 2, 2)
+	require.NoError(t, err)
+
+	result, err := utxo.BuildAndCheck()
+	require.NoError(t, err)
+	require.NoError(t, result.PadAllAsset(2))
+
+	witness, err := builder.NewUTXOCircuitWitness(utxo, result)
+	require.NoError(t, err)
+
+	utxoCircuit := circuits.NewUTXOCircuit(len(result.AllAsset), depth, len(utxo.Nullifier), len(utxo.Commitment))
+
+	assert := test.NewAssert(t)
+
+	assert.ProverSucceeded(utxoCircuit, witness, test.WithCurves(ecc.BN254))
+
+	// Spending a note that is not the proven leaf
+	leaf, err := builder.NewUTXOCircuitWitness(utxo, result)
+	require.NoError(t, err)
+	leaf.UTXO.MerkleProofPath[0] = fr.NewElement(1)
+	assert.ProverFailed(utxoCircuit, leaf, test.WithCurves(ecc.BN254))
+
+	// Minting out of nothing
+	minted, err := builder.NewUTXOCircuitWitness(utxo, result)
+	require.NoError(t, err)
+	minted.UTXO.Commitment[0].Amount = 1000
+	assert.ProverFailed(utxoCircuit, minted, test.WithCurves(ecc.BN254))
+}
+
+func TestUTXO_UnlistedAsset(t *testing.T) {
+	depth := 10
+
+	utxo, err := builder.GenerateUTXO(1, depth, 2, 3)
+	require.NoError(t, err)
+
+	result, err := utxo.BuildAndCheck()
+	require.NoError(t, err)
+	require.NoError(t, result.PadAllAsset(2))
+
+	// The last output moves to an asset AllAsset does not list, and mints
+	// out of nothing in it
+	utxo.Commitment[1].Amount.Add(&utxo.Commitment[1].Amount, &utxo.Commitment[2].Amount)
+	utxo.Commitment[2].Asset = fr.NewElement(999)
+	utxo.Commitment[2].Amount = fr.NewElement(1000000)
+	forgeOutput(t, utxo, result, 1)
+	forgeOutput(t, utxo, result, 2)
+
+	witness, err := builder.NewUTXOCircuitWitness(utxo, result)
+	require.NoError(t, err)
+
+	utxoCircuit := circuits.NewUTXOCircuit(len(result.AllAsset), depth, len(utxo.Nullifier), len(utxo.Commitment))
+
+	assert := test.NewAssert(t)
+	assert.ProverFailed(utxoCircuit, witness, test.WithCurves(ecc.BN254))
+
+	// Listing the asset twice leaves out the other one
+	duplicated, err := builder.NewUTXOCircuitWitness(utxo, result)
+	require.NoError(t, err)
+	duplicated.UTXO.AllAsset[1] = duplicated.UTXO.AllAsset[0]
+	assert.ProverFailed(utxoCircuit, duplicated, test.WithCurves(ecc.BN254))
+}
+
+func TestUTXO_AuditKey(t *testing.T) {
+	depth := 10
+
+	utxo, err := builder.GenerateUTXO(1, depth, 1, 2)
+	require.NoError(t, err)
+
+	result, err := utxo.BuildAndCheck()
+	require.NoError(t, err)
+
+	// The sender encrypts the audit memos to a key of their own
+	utxo.AuditPublicKey = utils.BuildPublicKey(*big.NewInt(33333))
+	forgeOutput(t, utxo, result, 0)
+	forgeOutput(t, utxo, result, 1)
+
+	witness, err := builder.NewUTXOCircuitWitness(utxo, result)
+	require.NoError(t, err)
+
+	utxoCircuit := circuits.NewUTXOCircuit(len(result.AllAsset), depth, len(utxo.Nullifier), len(utxo.Commitment))
+
+	assert := test.NewAssert(t)
+	assert.ProverFailed(utxoCircuit, witness, test.WithCurves(ecc.BN254))
+}
+
+func TestUTXO_Frozen(t *testing.T) {
+	depth := 10
+
+	utxo, err := builder.GenerateUTXO(1, depth, 2, 2)
+	require.NoError(t, err)
+
+	result, err := utxo.BuildAndCheck()
+	require.NoError(t, err)
+
+	utxoCircuit := circuits.NewUTXOCircuit(len(result.AllAsset), depth, len(utxo.Nullifier), len(utxo.Commitment))
+
+	assert := test.NewAssert(t)
+
+	// The holder of a frozen note spends it anyway
+	frozen := *utxo
+	frozen.Nullifier = append([]builder.Nullifier{}, utxo.Nullifier...)
+	frozen.Nullifier[1].FreezeFlag = fr.NewElement(1)
+	frozenResult := *result
+	forgeInputs(t, &frozen, &frozenResult, depth)
+
+	witness, err := builder.NewUTXOCircuitWitness(&frozen, &frozenResult)
+	require.NoError(t, err)
+	assert.ProverFailed(utxoCircuit, witness, test.WithCurves(ecc.BN254))
+
+	// and a transfer cannot create a frozen note either
+	frozenOutput := *utxo
+	frozenOutput.Commitment = append([]builder.Commitment{}, utxo.Commitment...)
+	frozenOutput.Commitment[0].FreezeFlag = fr.NewElement(1)
+	frozenOutputResult := *result
+	frozenOutputResult.Commitments = append([]builder.UTXOCommitment{}, result.Commitments...)
+	forgeOutput(t, &frozenOutput, &frozenOutputResult, 0)
+
+	witness, err = builder.NewUTXOCircuitWitness(&frozenOutput, &frozenOutputResult)
+	require.NoError(t, err)
+	assert.ProverFailed(utxoCircuit, witness, test.WithCurves(ecc.BN254))
+}
+
+func TestUTXO_AmountWrapsAround(t *testing.T) {
+	depth := 10
+
+	utxo, err := builder.GenerateUTXO(1, depth, 2, 2)
+	require.NoError(t, err)
+
+	result, err := utxo.BuildAndCheck()
+	require.NoError(t, err)
+
+	// Two 253 bit outputs balance the 12 spent modulo the field
+	utxo.Commitment[0].Amount.SetBigInt(new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 253), big.NewInt(1)))
+	utxo.Commitment[1].Amount.Sub(new(fr.Element).SetUint64(12), &utxo.Commitment[0].Amount)
+	forgeOutput(t, utxo, result, 0)
+	forgeOutput(t, utxo, result, 1)
+
+	witness, err := builder.NewUTXOCircuitWitness(utxo, result)
+	require.NoError(t, err)
+
+	utxoCircuit := circuits.NewUTXOCircuit(len(result.AllAsset), depth, len(utxo.Nullifier), len(utxo.Commitment))
+
+	assert := test.NewAssert(t)
+	assert.ProverFailed(utxoCircuit, witness, test.WithCurves(ecc.BN254))
+}
+
+// forgeInputs puts the input notes of utxo, as changed, in a new tree and
+// recomputes the Merkle proofs, nullifiers and root, bypassing the checks of
+// BuildAndCheck.
+func forgeInputs(t *testing.T, utxo *builder.UTXO, result *builder.UTXOResult, depth int) {
+	leaves := make([]fr.Element, len(utxo.Nullifier))
+	for i := range utxo.Nullifier {
+		leaves[i] = utxo.Nullifier[i].Commitment.Compute()
+	}
+
+	tree := builder.NewMerkleTree(depth, poseidon2.NewMerkleDamgardHasher())
+	tree.Build(leaves)
+
+	utxo.MerkleProof = make([]builder.MerkleProof, len(leaves))
+	result.Nullifiers = make([]fr.Element, len(leaves))
+	for i := range leaves {
+		utxo.MerkleProof[i] = tree.GetProof(i)
+		result.Nullifiers[i] = utxo.Nullifier[i].Compute()
+	}
+	result.Root = tree.GetRoot()
+
+	require.Equal(t, result.Root, utxo.MerkleProof[0].Verify())
+}
+
+// forgeOutput recomputes the public values of output i after its note was
+// changed, bypassing the checks of BuildAndCheck.
+func forgeOutput(t *testing.T, utxo *builder.UTXO, result *builder.UTXOResult, i int) {
+	ownerMemo := builder.Memo{SecretKey: utxo.EphemeralReceiverSecretKey[i], PublicKey: utxo.Commitment[i].ViewPubKey}
+	owner, err := ownerMemo.Encrypt(utxo.Commitment[i], utxo.SpentKey[i])
+	require.NoError(t, err)
+
+	auditMemo := builder.Memo{SecretKey: utxo.EphemeralAuditSecretKey[i], PublicKey: utxo.AuditPublicKey}
+	audit, err := auditMemo.Encrypt(utxo.Commitment[i], utxo.SpentKey[i])
+	require.NoError(t, err)
+
+	result.Commitments[i] = builder.UTXOCommitment{
+		Commitment: utxo.Commitment[i].Compute(),
+		OwnerMemo:  *owner,
+		AuditMemo:  *audit,
+	}
+}
@@ -0,0 +1,49 @@
+package circuits
+
+import (
+	"github.com/consensys/gnark/frontend"
+)
+
+// WithdrawCircuit unshields a whole unfrozen note to a public recipient,
+// revealing its asset and amount.
+type WithdrawCircuit struct {
+	Note             NullifierGadget     `gnark:"note"`
+	MerkleProofPath  []frontend.Variable `gnark:"merkleProofPath"`
+	MerkleProofIndex frontend.Variable   `gnark:"merkleProofIndex"`
+
+	Recipient  frontend.Variable `gnark:"recipient,public"`
+	Asset      frontend.Variable `gnark:"asset,public"`
+	Amount     frontend.Variable `gnark:"amount,public"`
+	Nullifier  frontend.Variable `gnark:"nullifier,public"`
+	MerkleRoot frontend.Variable `gnark:"merkleRoot,public"`
+}
+
+func NewWithdrawCircuit(depth int) *WithdrawCircuit {
+	return &WithdrawCircuit{
+		MerkleProofPath: make([]frontend.Variable, depth),
+	}
+}
+
+func (circuit *WithdrawCircuit) Define(api frontend.API) error {
+	merkleProof := MerkleProofGadget{
+		Path: circuit.MerkleProofPath,
+		Leaf: circuit.MerkleProofIndex,
+	}
+
+	nullifier, root, err := spendNote(api, &circuit.Note, &merkleProof)
+	if err != nil {
+		return err
+	}
+
+	api.AssertIsEqual(circuit.Nullifier, nullifier)
+	api.AssertIsEqual(circuit.MerkleRoot, root)
+
+	api.AssertIsEqual(circuit.Note.Asset, circuit.Asset)
+	api.AssertIsEqual(circuit.Note.Amount, circuit.Amount)
+	api.AssertIsEqual(circuit.Note.FreezeFlag, 0)
+
+	// Nothing else reads the recipient, so bind it to the proof explicitly
+	api.Mul(circuit.Recipient, circuit.Recipient)
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package circuits_test
+
+import (
+	"hide-pay/builder"
+	"hide-pay/circuits"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+// spendableNote puts a generated note at index 1 of a small tree.
+func spendableNote(t *testing.T, seed int64, depth int) (builder.Nullifier, builder.MerkleProof) {
+	commitment, spentKey := builder.GenerateCommitment(seed)
+	other, _ := builder.GenerateCommitment(seed + 1)
+
+	merkleTree := builder.NewMerkleTree(depth, poseidon2.NewMerkleDamgardHasher())
+	merkleTree.Build([]fr.Element{other.Compute(), commitment.Compute()})
+
+	nullifier := builder.Nullifier{
+		Commitment:      *commitment,
+		SpentPrivateKey: *spentKey,
+	}
+
+	merkleProof := merkleTree.GetProof(1)
+	require.Equal(t, commitment.Compute(), merkleProof.Leaf())
+
+	return nullifier, merkleProof
+}
+
+func TestWithdraw_Circuit(t *testing.T) {
+	depth := 6
+
+	nullifier, merkleProof := spendableNote(t, 21, depth)
+
+	withdraw := builder.Withdraw{
+		Nullifier:   nullifier,
+		MerkleProof: merkleProof,
+		Recipient:   fr.NewElement(0xbeef),
+	}
+
+	witness, err := withdraw.ToWitness()
+	require.NoError(t, err)
+
+	assert := test.NewAssert(t)
+	circuit := circuits.NewWithdrawCircuit(depth)
+
+	assert.ProverSucceeded(circuit, witness, test.WithCurves(ecc.BN254))
+
+	// Claiming more than the note holds
+	witness, err = withdraw.ToWitness()
+	require.NoError(t, err)
+	witness.Amount = 1
+	assert.ProverFailed(circuit, witness, test.WithCurves(ecc.BN254))
+
+	// Spending with another nullifier key
+	witness, err = withdraw.ToWitness()
+	require.NoError(t, err)
+	witness.Note.PrivateKey = 1
+	assert.ProverFailed(circuit, witness, test.WithCurves(ecc.BN254))
+
+	// Frozen notes stay in the pool
+	withdraw.Nullifier.FreezeFlag = fr.NewElement(1)
+	_, err = withdraw.ToWitness()
+	require.Error(t, err)
+}
@@ -9,18 +9,24 @@ contract HidePay is CommitmentStorage, NullifierStorage {
         uint256 indexed index,
         bytes32 indexed commitment,
         bytes32[] ownerMemo,
-        bytes32[] auditMemo
+        bytes32[] auditMemo,
+        bytes32[] outgoingMemo
     );
 
     struct Transaction {
         bytes32[] nullifier;
         bytes32[] commitment;
-        bytes32[] ownerMemo;
-        bytes32[] auditMemo;
+        // one memo per commitment; an outgoing memo is encrypted under the
+        // sender's outgoing viewing key and is empty when the sender did not
+        // attach one
+        bytes32[][] ownerMemo;
+        bytes32[][] auditMemo;
+        bytes32[][] outgoingMemo;
     }
 
     error NullifierAlreadyUsed(bytes32 nullifier);
     error CommitmentAlreadyUsed(bytes32 commitment);
+    error MemoCountMismatch(uint256 transaction);
 
     function submitBlock(
         MerkleUpdater calldata merkleUpdater,
@@ -29,6 +35,15 @@ contract HidePay is CommitmentStorage, NullifierStorage {
         bytes[] calldata /* transactionsProofs */
     ) public {
         for (uint256 i = 0; i < transactions.length; i++) {
+            uint256 commitmentCount = transactions[i].commitment.length;
+            if (
+                transactions[i].ownerMemo.length != commitmentCount
+                    || transactions[i].auditMemo.length != commitmentCount
+                    || transactions[i].outgoingMemo.length != commitmentCount
+            ) {
+                revert MemoCountMismatch(i);
+            }
+
             for (uint256 j = 0; j < transactions[i].nullifier.length; j++) {
                 if (nullifiers[transactions[i].nullifier[j]]) {
                     revert NullifierAlreadyUsed(transactions[i].nullifier[j]);
@@ -45,7 +60,10 @@ contract HidePay is CommitmentStorage, NullifierStorage {
         updateMerkleTree(merkleUpdater, merkleUpdaterProof);
 
         for (uint256 i = 0; i < transactions.length; i++) {
-            // TODO: verify transactionsProofs
+            // TODO: verify transactionsProofs with the verifier exported by
+            // auditzero, taking the public inputs in the order given by
+            // `auditzero export-layout`; this needs the memo hashes computed
+            // on chain first
 
             for (uint256 j = 0; j < transactions[i].nullifier.length; j++) {
                 addNullifier(transactions[i].nullifier[j]);
@@ -54,12 +72,12 @@ contract HidePay is CommitmentStorage, NullifierStorage {
             for (uint256 j = 0; j < transactions[i].commitment.length; j++) {
                 addCommitment(transactions[i].commitment[j]);
 
-                // TODO: split ownerMemo and auditMemo into multiple events
                 emit CommitmentAdded(
                     lastCommitmentIndex,
                     transactions[i].commitment[j],
-                    transactions[i].ownerMemo,
-                    transactions[i].auditMemo
+                    transactions[i].ownerMemo[j],
+                    transactions[i].auditMemo[j],
+                    transactions[i].outgoingMemo[j]
                 );
             }
         }
@@ -0,0 +1,313 @@
+// Package daemon keeps the constraint systems and proving keys of a set of
+// circuits in memory and proves jobs against them on a bounded worker pool.
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hide-pay/prover"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/consensys/gnark/backend/witness"
+)
+
+var (
+	ErrUnknownCircuit = errors.New("unknown circuit")
+	ErrQueueFull      = errors.New("job queue is full")
+	ErrClosed         = errors.New("daemon is shutting down")
+)
+
+// Status is the stage a job is in.
+type Status string
+
+const (
+	StatusQueued  Status = "queued"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+// Config bounds the work a daemon takes on.
+type Config struct {
+	// Workers is the number of jobs proved at once. Each proof already uses
+	// every core, so more than one worker mostly trades latency for memory.
+	Workers int
+	// QueueSize is the number of jobs that may wait for a worker.
+	QueueSize int
+	// MaxFinished is the number of finished jobs kept for their results; the
+	// oldest are dropped first.
+	MaxFinished int
+}
+
+// DefaultConfig proves one job at a time.
+var DefaultConfig = Config{Workers: 1, QueueSize: 64, MaxFinished: 1024}
+
+// Result is a proof with its public inputs.
+type Result struct {
+	// Proof and PublicWitness are in gnark's binary encoding.
+	Proof         []byte                `json:"proof"`
+	PublicWitness []byte                `json:"publicWitness"`
+	Solidity      *prover.SolidityProof `json:"solidity"`
+}
+
+// Job is a snapshot of a proving job.
+type Job struct {
+	ID          string     `json:"id"`
+	Circuit     string     `json:"circuit"`
+	Status      Status     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	SubmittedAt time.Time  `json:"submittedAt"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+	// WaitMs is the time spent in the queue, ProveMs the time spent proving.
+	WaitMs  int64   `json:"waitMs"`
+	ProveMs int64   `json:"proveMs"`
+	Result  *Result `json:"result,omitempty"`
+}
+
+type job struct {
+	Job
+	witness witness.Witness
+}
+
+// Daemon proves jobs for the circuits it was started with.
+type Daemon struct {
+	config   Config
+	circuits map[string]*prover.Entry
+	queue    chan *job
+	workers  sync.WaitGroup
+
+	mu       sync.Mutex
+	jobs     map[string]*job
+	finished []string
+	closed   bool
+}
+
+// New starts the workers of a daemon serving entries, which are usually
+// loaded from a prover.Cache.
+func New(entries []*prover.Entry, config Config) (*Daemon, error) {
+	if config.Workers < 1 || config.QueueSize < 0 || config.MaxFinished < 1 {
+		return nil, fmt.Errorf("invalid config: %d workers, queue of %d, %d finished jobs", config.Workers, config.QueueSize, config.MaxFinished)
+	}
+
+	daemon := &Daemon{
+		config:   config,
+		circuits: make(map[string]*prover.Entry, len(entries)),
+		queue:    make(chan *job, config.QueueSize),
+		jobs:     make(map[string]*job),
+	}
+
+	for _, entry := range entries {
+		if _, ok := daemon.circuits[entry.Variant.Name]; ok {
+			return nil, fmt.Errorf("circuit %s is loaded twice", entry.Variant.Name)
+		}
+		daemon.circuits[entry.Variant.Name] = entry
+	}
+
+	daemon.workers.Add(config.Workers)
+	for range config.Workers {
+		go daemon.work()
+	}
+
+	return daemon, nil
+}
+
+// Circuits returns the loaded circuits sorted by name.
+func (daemon *Daemon) Circuits() []*prover.Entry {
+	entries := make([]*prover.Entry, 0, len(daemon.circuits))
+	for _, entry := range daemon.circuits {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Variant.Name < entries[j].Variant.Name
+	})
+
+	return entries
+}
+
+// Circuit returns the loaded circuit called name.
+func (daemon *Daemon) Circuit(name string) (*prover.Entry, error) {
+	entry, ok := daemon.circuits[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownCircuit, name)
+	}
+
+	return entry, nil
+}
+
+// Submit queues a proof of the full witness w for circuit. It fails with
+// ErrQueueFull instead of blocking when every queue slot is taken.
+func (daemon *Daemon) Submit(circuit string, w witness.Witness) (Job, error) {
+	entry, err := daemon.Circuit(circuit)
+	if err != nil {
+		return Job{}, err
+	}
+
+	if err := prover.CheckWitness(entry.CS, w); err != nil {
+		return Job{}, err
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	submitted := &job{
+		Job: Job{
+			ID:          id,
+			Circuit:     circuit,
+			Status:      StatusQueued,
+			SubmittedAt: time.Now(),
+		},
+		witness: w,
+	}
+
+	daemon.mu.Lock()
+	defer daemon.mu.Unlock()
+
+	// The queue is only closed under the lock, so the send below cannot panic
+	if daemon.closed {
+		return Job{}, ErrClosed
+	}
+
+	select {
+	case daemon.queue <- submitted:
+	default:
+		return Job{}, ErrQueueFull
+	}
+
+	daemon.jobs[id] = submitted
+
+	return submitted.Job, nil
+}
+
+// Job returns the current state of the job with the given id.
+func (daemon *Daemon) Job(id string) (Job, bool) {
+	daemon.mu.Lock()
+	defer daemon.mu.Unlock()
+
+	job, ok := daemon.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+
+	return job.Job, true
+}
+
+// Shutdown stops accepting jobs and waits for the queued and running ones to
+// finish, or for ctx to be done.
+func (daemon *Daemon) Shutdown(ctx context.Context) error {
+	daemon.mu.Lock()
+	if !daemon.closed {
+		daemon.closed = true
+		close(daemon.queue)
+	}
+	daemon.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		daemon.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (daemon *Daemon) work() {
+	defer daemon.workers.Done()
+
+	for job := range daemon.queue {
+		daemon.run(job)
+	}
+}
+
+func (daemon *Daemon) run(job *job) {
+	started := time.Now()
+
+	daemon.mu.Lock()
+	job.Status = StatusRunning
+	job.StartedAt = &started
+	job.WaitMs = started.Sub(job.SubmittedAt).Milliseconds()
+	daemon.mu.Unlock()
+
+	result, err := daemon.prove(job)
+
+	finished := time.Now()
+
+	daemon.mu.Lock()
+	defer daemon.mu.Unlock()
+
+	job.FinishedAt = &finished
+	job.ProveMs = finished.Sub(started).Milliseconds()
+	job.witness = nil
+
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = StatusDone
+		job.Result = result
+	}
+
+	daemon.finished = append(daemon.finished, job.ID)
+	for len(daemon.finished) > daemon.config.MaxFinished {
+		delete(daemon.jobs, daemon.finished[0])
+		daemon.finished = daemon.finished[1:]
+	}
+}
+
+func (daemon *Daemon) prove(job *job) (*Result, error) {
+	entry := daemon.circuits[job.Circuit]
+
+	proof, err := prover.Prove(entry.CS, entry.ProvingKey, job.witness)
+	if err != nil {
+		return nil, err
+	}
+
+	public, err := job.witness.Public()
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract public witness: %w", err)
+	}
+
+	solidity, err := prover.NewSolidityProof(proof, public)
+	if err != nil {
+		return nil, err
+	}
+
+	var proofBytes bytes.Buffer
+	if _, err := proof.WriteTo(&proofBytes); err != nil {
+		return nil, fmt.Errorf("failed to serialize proof: %w", err)
+	}
+
+	publicBytes, err := public.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize public witness: %w", err)
+	}
+
+	return &Result{
+		Proof:         proofBytes.Bytes(),
+		PublicWitness: publicBytes,
+		Solidity:      solidity,
+	}, nil
+}
+
+func newJobID() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", fmt.Errorf("failed to generate job id: %w", err)
+	}
+
+	return hex.EncodeToString(id[:]), nil
+}
@@ -0,0 +1,270 @@
+package daemon_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"hide-pay/builder"
+	"hide-pay/daemon"
+	"hide-pay/prover"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	transferVariant = prover.Variant{Name: "transfer-1x1", Kind: prover.KindTransfer, Shape: prover.Shape{Assets: 1, Depth: 4, Inputs: 1, Outputs: 1}}
+	updateVariant   = prover.Variant{Name: "merkle-update-4", Kind: prover.KindMerkleUpdate, Shape: prover.Shape{Depth: 4}}
+)
+
+var (
+	entriesOnce sync.Once
+	entries     []*prover.Entry
+)
+
+// loadEntries sets up the test circuits once; daemons only read the keys, so
+// every test shares them.
+func loadEntries(t *testing.T) []*prover.Entry {
+	entriesOnce.Do(func() {
+		cache, err := prover.OpenCache(t.TempDir())
+		require.NoError(t, err)
+
+		for _, variant := range []prover.Variant{transferVariant, updateVariant} {
+			entry, err := cache.Ensure(variant, prover.Groth16, nil)
+			require.NoError(t, err)
+			entries = append(entries, entry)
+		}
+	})
+	require.Len(t, entries, 2)
+
+	return entries
+}
+
+func newDaemon(t *testing.T, config daemon.Config) (*daemon.Daemon, *httptest.Server) {
+	d, err := daemon.New(loadEntries(t), config)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(d.Handler())
+	t.Cleanup(server.Close)
+	t.Cleanup(func() {
+		require.NoError(t, d.Shutdown(context.Background()))
+	})
+
+	return d, server
+}
+
+func post(t *testing.T, server *httptest.Server, request any) (int, []byte) {
+	body, err := json.Marshal(request)
+	require.NoError(t, err)
+
+	response, err := http.Post(server.URL+"/jobs", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(response.Body)
+	require.NoError(t, err)
+
+	return response.StatusCode, buf.Bytes()
+}
+
+func get(t *testing.T, server *httptest.Server, path string, value any) int {
+	response, err := http.Get(server.URL + path)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	require.NoError(t, json.NewDecoder(response.Body).Decode(value))
+
+	return response.StatusCode
+}
+
+func submit(t *testing.T, server *httptest.Server, request daemon.JobRequest) daemon.Job {
+	status, body := post(t, server, request)
+	require.Equal(t, http.StatusAccepted, status, string(body))
+
+	var job daemon.Job
+	require.NoError(t, json.Unmarshal(body, &job))
+
+	return job
+}
+
+func wait(t *testing.T, server *httptest.Server, id string) daemon.Job {
+	deadline := time.Now().Add(time.Minute)
+
+	for {
+		var job daemon.Job
+		require.Equal(t, http.StatusOK, get(t, server, "/jobs/"+id, &job))
+
+		if job.Status == daemon.StatusDone || job.Status == daemon.StatusFailed {
+			return job
+		}
+
+		require.True(t, time.Now().Before(deadline), "job %s is still %s", id, job.Status)
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func transferInput(t *testing.T, seed int64) json.RawMessage {
+	shape := transferVariant.Shape
+
+	utxo, err := builder.GenerateUTXO(seed, shape.Depth, shape.Inputs, shape.Outputs)
+	require.NoError(t, err)
+
+	input, err := prover.NewTransferInput(utxo)
+	require.NoError(t, err)
+
+	data, err := input.MarshalJSON()
+	require.NoError(t, err)
+
+	return data
+}
+
+func updateWitness(t *testing.T) []byte {
+	tree := builder.NewMerkleTree(updateVariant.Shape.Depth, poseidon2.NewMerkleDamgardHasher())
+	tree.Build([]fr.Element{fr.NewElement(1)})
+
+	update := builder.MerkleUpdate{MerkleProof: tree.GetProof(1), Leaf: fr.NewElement(2)}
+	assignment, err := update.ToWitness()
+	require.NoError(t, err)
+
+	w, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+
+	data, err := w.MarshalBinary()
+	require.NoError(t, err)
+
+	return data
+}
+
+func verify(t *testing.T, d *daemon.Daemon, job daemon.Job) {
+	require.Equal(t, daemon.StatusDone, job.Status, job.Error)
+	require.NotNil(t, job.Result)
+	require.NotNil(t, job.StartedAt)
+	require.NotNil(t, job.FinishedAt)
+
+	entry, err := d.Circuit(job.Circuit)
+	require.NoError(t, err)
+
+	proof := groth16.NewProof(ecc.BN254)
+	_, err = proof.ReadFrom(bytes.NewReader(job.Result.Proof))
+	require.NoError(t, err)
+
+	public, err := witness.New(ecc.BN254.ScalarField())
+	require.NoError(t, err)
+	require.NoError(t, public.UnmarshalBinary(job.Result.PublicWitness))
+
+	require.NoError(t, prover.Verify(proof, entry.VerifyingKey, public))
+	assert.Len(t, job.Result.Solidity.Input, prover.NbPublicInputs(entry.CS))
+}
+
+func TestDaemon_Prove(t *testing.T) {
+	d, server := newDaemon(t, daemon.Config{Workers: 2, QueueSize: 4, MaxFinished: 16})
+
+	var circuits []daemon.CircuitInfo
+	require.Equal(t, http.StatusOK, get(t, server, "/circuits", &circuits))
+	require.Len(t, circuits, 2)
+	assert.Equal(t, "merkle-update-4", circuits[0].Name)
+	assert.Equal(t, prover.Groth16, circuits[1].Backend)
+	assert.Equal(t, 7, circuits[1].NbPublic)
+
+	transfer := submit(t, server, daemon.JobRequest{Circuit: transferVariant.Name, Input: transferInput(t, 1)})
+	assert.Equal(t, daemon.StatusQueued, transfer.Status)
+
+	update := submit(t, server, daemon.JobRequest{Circuit: updateVariant.Name, Witness: updateWitness(t)})
+
+	verify(t, d, wait(t, server, transfer.ID))
+	verify(t, d, wait(t, server, update.ID))
+}
+
+func TestDaemon_Errors(t *testing.T) {
+	_, server := newDaemon(t, daemon.DefaultConfig)
+
+	// A header that claims a huge vector, and a witness cut short
+	oversized := updateWitness(t)
+	binary.BigEndian.PutUint32(oversized[8:12], 1<<30)
+	truncated := updateWitness(t)
+	truncated = truncated[:len(truncated)-1]
+
+	for _, test := range []struct {
+		name    string
+		request any
+		status  int
+		message string
+	}{
+		{"unknown circuit", daemon.JobRequest{Circuit: "transfer-9x9", Input: transferInput(t, 1)}, http.StatusNotFound, "unknown circuit"},
+		{"no input", daemon.JobRequest{Circuit: transferVariant.Name}, http.StatusBadRequest, "exactly one of input and witness"},
+		{"input and witness", daemon.JobRequest{Circuit: transferVariant.Name, Input: transferInput(t, 1), Witness: updateWitness(t)}, http.StatusBadRequest, "exactly one of input and witness"},
+		{"input for another kind", daemon.JobRequest{Circuit: updateVariant.Name, Input: transferInput(t, 1)}, http.StatusBadRequest, "input only describes transfers"},
+		{"witness of another circuit", daemon.JobRequest{Circuit: transferVariant.Name, Witness: updateWitness(t)}, http.StatusBadRequest, "constraint system expects"},
+		{"oversized witness", daemon.JobRequest{Circuit: updateVariant.Name, Witness: oversized}, http.StatusBadRequest, "vector of 1073741824 values"},
+		{"truncated witness", daemon.JobRequest{Circuit: updateVariant.Name, Witness: truncated}, http.StatusBadRequest, "invalid witness"},
+		{"short witness", daemon.JobRequest{Circuit: updateVariant.Name, Witness: []byte{0, 0}}, http.StatusBadRequest, "shorter than the header"},
+		{"invalid input", daemon.JobRequest{Circuit: transferVariant.Name, Input: json.RawMessage(`{"version":1}`)}, http.StatusBadRequest, "invalid transfer input"},
+		{"unknown field", map[string]string{"circuit": transferVariant.Name, "proof": "0x"}, http.StatusBadRequest, "unknown field"},
+	} {
+		status, body := post(t, server, test.request)
+		assert.Equal(t, test.status, status, test.name)
+		assert.Contains(t, string(body), test.message, test.name)
+	}
+
+	var response map[string]string
+	assert.Equal(t, http.StatusNotFound, get(t, server, "/jobs/missing", &response))
+}
+
+func TestDaemon_Shutdown(t *testing.T) {
+	d, server := newDaemon(t, daemon.Config{Workers: 1, QueueSize: 4, MaxFinished: 16})
+
+	first := submit(t, server, daemon.JobRequest{Circuit: updateVariant.Name, Witness: updateWitness(t)})
+	second := submit(t, server, daemon.JobRequest{Circuit: updateVariant.Name, Witness: updateWitness(t)})
+
+	// Shutdown finishes the accepted jobs before it returns
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	require.NoError(t, d.Shutdown(ctx))
+
+	for _, id := range []string{first.ID, second.ID} {
+		job, ok := d.Job(id)
+		require.True(t, ok)
+		assert.Equal(t, daemon.StatusDone, job.Status, job.Error)
+	}
+
+	status, body := post(t, server, daemon.JobRequest{Circuit: updateVariant.Name, Witness: updateWitness(t)})
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Contains(t, string(body), "shutting down")
+
+	var response map[string]string
+	assert.Equal(t, http.StatusServiceUnavailable, get(t, server, "/healthz", &response))
+}
+
+func TestDaemon_MaxFinished(t *testing.T) {
+	d, server := newDaemon(t, daemon.Config{Workers: 1, QueueSize: 4, MaxFinished: 1})
+
+	first := submit(t, server, daemon.JobRequest{Circuit: updateVariant.Name, Witness: updateWitness(t)})
+	wait(t, server, first.ID)
+
+	second := submit(t, server, daemon.JobRequest{Circuit: updateVariant.Name, Witness: updateWitness(t)})
+	wait(t, server, second.ID)
+
+	_, ok := d.Job(first.ID)
+	assert.False(t, ok)
+
+	_, ok = d.Job(second.ID)
+	assert.True(t, ok)
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	_, err := daemon.New(nil, daemon.Config{Workers: 0, QueueSize: 1, MaxFinished: 1})
+	assert.Error(t, err)
+}
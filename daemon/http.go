@@ -0,0 +1,220 @@
+package daemon
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hide-pay/prover"
+	"net/http"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/witness"
+)
+
+// maxRequestSize bounds a job request; a witness of the largest registered
+// circuit is well under a megabyte.
+const maxRequestSize = 16 << 20
+
+// JobRequest asks for a proof of Circuit. Exactly one of Input, a transfer
+// input as prover.TransferInput encodes it, and Witness, a full witness in
+// gnark's binary encoding, is set.
+type JobRequest struct {
+	Circuit string          `json:"circuit"`
+	Input   json.RawMessage `json:"input,omitempty"`
+	Witness []byte          `json:"witness,omitempty"`
+}
+
+// CircuitInfo describes a loaded circuit.
+type CircuitInfo struct {
+	Name          string         `json:"name"`
+	Kind          prover.Kind    `json:"kind"`
+	Shape         prover.Shape   `json:"shape"`
+	Backend       prover.Backend `json:"backend"`
+	Hash          string         `json:"hash"`
+	NbConstraints int            `json:"nbConstraints"`
+	NbPublic      int            `json:"nbPublic"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Handler serves the daemon API:
+//
+//	GET  /circuits   the loaded circuits
+//	POST /jobs       queue a JobRequest, answers 202 with the Job
+//	GET  /jobs/{id}  the Job, with its Result once done
+//	GET  /healthz    200 while jobs are accepted
+func (daemon *Daemon) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /circuits", daemon.handleCircuits)
+	mux.HandleFunc("POST /jobs", daemon.handleSubmit)
+	mux.HandleFunc("GET /jobs/{id}", daemon.handleJob)
+	mux.HandleFunc("GET /healthz", daemon.handleHealth)
+
+	return mux
+}
+
+func (daemon *Daemon) handleCircuits(w http.ResponseWriter, r *http.Request) {
+	entries := daemon.Circuits()
+
+	circuits := make([]CircuitInfo, len(entries))
+	for i, entry := range entries {
+		circuits[i] = CircuitInfo{
+			Name:          entry.Variant.Name,
+			Kind:          entry.Variant.Kind,
+			Shape:         entry.Variant.Shape,
+			Backend:       entry.Backend,
+			Hash:          entry.Hash,
+			NbConstraints: entry.CS.GetNbConstraints(),
+			NbPublic:      prover.NbPublicInputs(entry.CS),
+		}
+	}
+
+	writeJSON(w, http.StatusOK, circuits)
+}
+
+func (daemon *Daemon) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var request JobRequest
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid job request: %w", err))
+		return
+	}
+
+	full, err := daemon.witness(&request)
+	if err != nil {
+		if errors.Is(err, ErrUnknownCircuit) {
+			writeError(w, http.StatusNotFound, err)
+		} else {
+			writeError(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	job, err := daemon.Submit(request.Circuit, full)
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusAccepted, job)
+	case errors.Is(err, ErrQueueFull), errors.Is(err, ErrClosed):
+		writeError(w, http.StatusServiceUnavailable, err)
+	default:
+		writeError(w, http.StatusBadRequest, err)
+	}
+}
+
+// witness builds the full witness of a request. Transfer inputs are checked
+// natively here, so a bad transfer is refused before it takes a queue slot.
+func (daemon *Daemon) witness(request *JobRequest) (witness.Witness, error) {
+	entry, err := daemon.Circuit(request.Circuit)
+	if err != nil {
+		return nil, err
+	}
+
+	if (len(request.Input) == 0) == (len(request.Witness) == 0) {
+		return nil, fmt.Errorf("exactly one of input and witness is required")
+	}
+
+	if len(request.Witness) > 0 {
+		if err := checkWitnessHeader(request.Witness, prover.NbPublicInputs(entry.CS), entry.CS.GetNbSecretVariables()); err != nil {
+			return nil, err
+		}
+
+		full, err := witness.New(ecc.BN254.ScalarField())
+		if err != nil {
+			return nil, err
+		}
+
+		if err := full.UnmarshalBinary(request.Witness); err != nil {
+			return nil, fmt.Errorf("invalid witness: %w", err)
+		}
+
+		return full, nil
+	}
+
+	if entry.Variant.Kind != prover.KindTransfer {
+		return nil, fmt.Errorf("%s is a %s circuit, input only describes transfers", entry.Variant.Name, entry.Variant.Kind)
+	}
+
+	input, err := prover.LoadTransferInput(request.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	if shape := input.Shape(entry.Variant.Shape.Assets); shape != entry.Variant.Shape {
+		return nil, fmt.Errorf("input has shape %s, %s expects %s", shape, entry.Variant.Name, entry.Variant.Shape)
+	}
+
+	return prover.NewWitness(entry.Variant.Shape, input.ToUTXO())
+}
+
+// witnessHeaderSize is the size of the counts gnark writes before the values
+// of a binary witness: public, secret and vector length, 4 bytes each.
+const witnessHeaderSize = 12
+
+// checkWitnessHeader compares the counts a binary witness declares with the
+// ones of the constraint system, so that UnmarshalBinary never sizes its
+// vector from an unchecked length.
+func checkWitnessHeader(data []byte, nbPublic int, nbSecret int) error {
+	if len(data) < witnessHeaderSize {
+		return fmt.Errorf("invalid witness: %d bytes is shorter than the header", len(data))
+	}
+
+	public := binary.BigEndian.Uint32(data[0:4])
+	secret := binary.BigEndian.Uint32(data[4:8])
+	length := binary.BigEndian.Uint32(data[8:12])
+
+	if uint64(public) != uint64(nbPublic) || uint64(secret) != uint64(nbSecret) {
+		return fmt.Errorf("witness declares %d public and %d secret values, the constraint system expects %d and %d", public, secret, nbPublic, nbSecret)
+	}
+
+	if uint64(length) != uint64(nbPublic)+uint64(nbSecret) {
+		return fmt.Errorf("invalid witness: vector of %d values for %d public and %d secret", length, public, secret)
+	}
+
+	if want := witnessHeaderSize + (nbPublic+nbSecret)*fr.Bytes; len(data) != want {
+		return fmt.Errorf("invalid witness: %d bytes, expected %d", len(data), want)
+	}
+
+	return nil
+}
+
+func (daemon *Daemon) handleJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := daemon.Job(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown job %q", r.PathValue("id")))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (daemon *Daemon) handleHealth(w http.ResponseWriter, r *http.Request) {
+	daemon.mu.Lock()
+	closed := daemon.closed
+	daemon.mu.Unlock()
+
+	if closed {
+		writeError(w, http.StatusServiceUnavailable, ErrClosed)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	// The status is sent, so a failed write can only be dropped
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,231 @@
+package prover
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	gnarkbackend "github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/backend/plonk"
+	plonkbn254 "github.com/consensys/gnark/backend/plonk/bn254"
+	"github.com/consensys/gnark/backend/solidity"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	csbn254 "github.com/consensys/gnark/constraint/bn254"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	gnarkio "github.com/consensys/gnark/io"
+)
+
+// Backend is a proof system. Groth16 proves R1CS and needs a setup per
+// circuit; PLONK proves sparse R1CS from a universal KZG SRS.
+type Backend string
+
+const (
+	Groth16 Backend = "groth16"
+	Plonk   Backend = "plonk"
+)
+
+// ProvingKey is a BN254 Groth16 or PLONK proving key.
+type ProvingKey interface {
+	io.WriterTo
+	io.ReaderFrom
+	gnarkio.WriterRawTo
+	gnarkio.UnsafeReaderFrom
+}
+
+// VerifyingKey is a BN254 Groth16 or PLONK verifying key.
+type VerifyingKey interface {
+	io.WriterTo
+	io.ReaderFrom
+	solidity.VerifyingKey
+}
+
+// Proof is a BN254 Groth16 or PLONK proof.
+type Proof interface {
+	io.WriterTo
+	io.ReaderFrom
+}
+
+func ParseBackend(name string) (Backend, error) {
+	switch backend := Backend(name); backend {
+	case Groth16, Plonk:
+		return backend, nil
+	default:
+		return "", fmt.Errorf("unknown backend %q, expected %s or %s", name, Groth16, Plonk)
+	}
+}
+
+// BackendOf returns the backend that proves cs.
+func BackendOf(cs constraint.ConstraintSystem) (Backend, error) {
+	// gnark uses one type for both kinds of system and tells them apart by Type
+	concrete, ok := cs.(*csbn254.R1CS)
+	if !ok {
+		return "", fmt.Errorf("unsupported constraint system %T", cs)
+	}
+
+	switch concrete.Type {
+	case constraint.SystemR1CS:
+		return Groth16, nil
+	case constraint.SystemSparseR1CS:
+		return Plonk, nil
+	default:
+		return "", fmt.Errorf("unsupported constraint system type %d", concrete.Type)
+	}
+}
+
+// Compile builds the constraint system of circuit over BN254: R1CS for
+// Groth16 and sparse R1CS for PLONK.
+func (backend Backend) Compile(circuit frontend.Circuit) (constraint.ConstraintSystem, error) {
+	var builder frontend.NewBuilder
+
+	switch backend {
+	case Groth16:
+		builder = r1cs.NewBuilder
+	case Plonk:
+		builder = scs.NewBuilder
+	default:
+		return nil, fmt.Errorf("unknown backend %q", backend)
+	}
+
+	cs, err := frontend.Compile(ecc.BN254.ScalarField(), builder, circuit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile: %w", err)
+	}
+
+	return cs, nil
+}
+
+func (backend Backend) newCS() constraint.ConstraintSystem {
+	if backend == Plonk {
+		return plonk.NewCS(ecc.BN254)
+	}
+
+	return groth16.NewCS(ecc.BN254)
+}
+
+func (backend Backend) newProvingKey() ProvingKey {
+	if backend == Plonk {
+		return plonk.NewProvingKey(ecc.BN254)
+	}
+
+	return groth16.NewProvingKey(ecc.BN254)
+}
+
+func (backend Backend) newVerifyingKey() VerifyingKey {
+	if backend == Plonk {
+		return plonk.NewVerifyingKey(ecc.BN254)
+	}
+
+	return groth16.NewVerifyingKey(ecc.BN254)
+}
+
+func (backend Backend) newProof() Proof {
+	if backend == Plonk {
+		return plonk.NewProof(ecc.BN254)
+	}
+
+	return groth16.NewProof(ecc.BN254)
+}
+
+// Setup creates the keys of cs with the backend it was compiled for. PLONK
+// needs srs, Groth16 ignores it.
+func Setup(cs constraint.ConstraintSystem, srs *SRS) (ProvingKey, VerifyingKey, error) {
+	backend, err := BackendOf(cs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if backend == Groth16 {
+		pk, vk, err := groth16.Setup(cs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to run setup: %w", err)
+		}
+
+		return pk, vk, nil
+	}
+
+	if srs == nil {
+		return nil, nil, fmt.Errorf("plonk setup needs an SRS")
+	}
+
+	if err := srs.check(cs); err != nil {
+		return nil, nil, err
+	}
+
+	pk, vk, err := plonk.Setup(cs, srs.Canonical, srs.Lagrange)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to run setup: %w", err)
+	}
+
+	return pk, vk, nil
+}
+
+// Prove proves w with the backend cs was compiled for. The key must come from
+// the same backend. Proofs target the exported Solidity verifier, so Groth16
+// commitments are hashed to the field with Keccak.
+func Prove(cs constraint.ConstraintSystem, pk ProvingKey, w witness.Witness) (Proof, error) {
+	if err := CheckProvingKey(cs, pk); err != nil {
+		return nil, err
+	}
+
+	var proof Proof
+	var err error
+
+	switch pk := pk.(type) {
+	case *groth16bn254.ProvingKey:
+		proof, err = groth16.Prove(cs, pk, w, solidity.WithProverTargetSolidityVerifier(gnarkbackend.GROTH16))
+	case *plonkbn254.ProvingKey:
+		proof, err = plonk.Prove(cs, pk, w, solidity.WithProverTargetSolidityVerifier(gnarkbackend.PLONK))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to prove: %w", err)
+	}
+
+	return proof, nil
+}
+
+// Verify checks proof against vk and the public witness.
+func Verify(proof Proof, vk VerifyingKey, public witness.Witness) error {
+	switch vk := vk.(type) {
+	case *groth16bn254.VerifyingKey:
+		concrete, ok := proof.(*groth16bn254.Proof)
+		if !ok {
+			return fmt.Errorf("%w: %T is not a groth16 proof", ErrKeyMismatch, proof)
+		}
+		return groth16.Verify(concrete, vk, public, solidity.WithVerifierTargetSolidityVerifier(gnarkbackend.GROTH16))
+	case *plonkbn254.VerifyingKey:
+		concrete, ok := proof.(*plonkbn254.Proof)
+		if !ok {
+			return fmt.Errorf("%w: %T is not a plonk proof", ErrKeyMismatch, proof)
+		}
+		return plonk.Verify(concrete, vk, public, solidity.WithVerifierTargetSolidityVerifier(gnarkbackend.PLONK))
+	default:
+		return fmt.Errorf("unsupported verifying key %T", vk)
+	}
+}
+
+// BackendOfKey returns the backend of a verifying key.
+func BackendOfKey(vk VerifyingKey) (Backend, error) {
+	switch vk.(type) {
+	case *groth16bn254.VerifyingKey:
+		return Groth16, nil
+	case *plonkbn254.VerifyingKey:
+		return Plonk, nil
+	default:
+		return "", fmt.Errorf("unsupported verifying key %T", vk)
+	}
+}
+
+// NbPublicInputs returns the number of public inputs of cs. An R1CS counts
+// its constant one wire as a public variable, a sparse R1CS has no such wire.
+func NbPublicInputs(cs constraint.ConstraintSystem) int {
+	if backend, err := BackendOf(cs); err == nil && backend == Plonk {
+		return cs.GetNbPublicVariables()
+	}
+
+	return cs.GetNbPublicVariables() - 1
+}
@@ -0,0 +1,117 @@
+package prover_test
+
+import (
+	"hide-pay/builder"
+	"hide-pay/prover"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	kzgbn254 "github.com/consensys/gnark-crypto/ecc/bn254/kzg"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// merkleUpdateWitness returns the witness of appending a leaf to a tree of
+// depth 4, a circuit small enough for a quick PLONK setup.
+func merkleUpdateWitness(t *testing.T) (prover.Variant, witness.Witness) {
+	variant := prover.Variant{Name: "merkle-update-4", Kind: prover.KindMerkleUpdate, Shape: prover.Shape{Depth: 4}}
+
+	leaves := []fr.Element{fr.NewElement(1), fr.NewElement(2)}
+	tree := builder.NewMerkleTree(variant.Shape.Depth, poseidon2.NewMerkleDamgardHasher())
+	tree.Build(leaves)
+
+	update := builder.MerkleUpdate{MerkleProof: tree.GetProof(len(leaves)), Leaf: fr.NewElement(3)}
+	assignment, err := update.ToWitness()
+	require.NoError(t, err)
+
+	w, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+
+	return variant, w
+}
+
+func TestPlonk_ProveFromSRSFile(t *testing.T) {
+	variant, w := merkleUpdateWitness(t)
+
+	cs, err := variant.Compile(prover.Plonk)
+	require.NoError(t, err)
+
+	backend, err := prover.BackendOf(cs)
+	require.NoError(t, err)
+	require.Equal(t, prover.Plonk, backend)
+
+	// A ceremony SRS is larger than one circuit needs
+	sizeCanonical, _ := plonk.SRSSize(cs)
+	srs, err := kzgbn254.NewSRS(uint64(2*sizeCanonical), big.NewInt(42))
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "srs.dat")
+	require.NoError(t, prover.WriteFile(path, srs))
+
+	loaded, err := prover.ReadSRS(path, cs)
+	require.NoError(t, err)
+
+	pk, vk, err := prover.Setup(cs, loaded)
+	require.NoError(t, err)
+	require.NoError(t, prover.CheckProvingKey(cs, pk))
+	require.NoError(t, prover.CheckVerifyingKey(cs, vk))
+
+	require.NoError(t, prover.CheckWitness(cs, w))
+
+	proof, err := prover.Prove(cs, pk, w)
+	require.NoError(t, err)
+
+	public, err := w.Public()
+	require.NoError(t, err)
+	require.NoError(t, prover.CheckPublicWitness(vk, public))
+	require.NoError(t, prover.Verify(proof, vk, public))
+
+	// A Groth16 proof is not checked against a PLONK key
+	groth16CS, err := variant.Compile(prover.Groth16)
+	require.NoError(t, err)
+	groth16PK, groth16VK, err := prover.Setup(groth16CS, nil)
+	require.NoError(t, err)
+	groth16Proof, err := prover.Prove(groth16CS, groth16PK, w)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, prover.Verify(groth16Proof, vk, public), prover.ErrKeyMismatch)
+	require.NoError(t, prover.Verify(groth16Proof, groth16VK, public))
+
+	_, err = prover.Prove(cs, groth16PK, w)
+	require.ErrorIs(t, err, prover.ErrKeyMismatch)
+}
+
+func TestReadSRS_TooSmall(t *testing.T) {
+	variant, _ := merkleUpdateWitness(t)
+
+	cs, err := variant.Compile(prover.Plonk)
+	require.NoError(t, err)
+
+	srs, err := kzgbn254.NewSRS(16, big.NewInt(42))
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "srs.dat")
+	require.NoError(t, prover.WriteFile(path, srs))
+
+	_, err = prover.ReadSRS(path, cs)
+	assert.ErrorContains(t, err, "constraint system needs")
+
+	_, _, err = prover.Setup(cs, nil)
+	assert.Error(t, err)
+}
+
+func TestParseBackend(t *testing.T) {
+	backend, err := prover.ParseBackend("plonk")
+	require.NoError(t, err)
+	assert.Equal(t, prover.Plonk, backend)
+
+	_, err = prover.ParseBackend("stark")
+	assert.Error(t, err)
+}
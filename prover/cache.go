@@ -0,0 +1,339 @@
+package prover
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/consensys/gnark/constraint"
+)
+
+// ErrNotCached reports a variant that has no keys in the cache yet.
+var ErrNotCached = errors.New("not in the cache")
+
+const (
+	cacheCSFile       = "cs.dat"
+	cachePKFile       = "pk.dat"
+	cacheVKFile       = "vk.dat"
+	cacheManifestFile = "manifest.json"
+	cacheVariantsDir  = "variants"
+)
+
+// Cache stores constraint systems and their keys content-addressed by the
+// SHA-256 of the serialized constraint system:
+//
+//	<dir>/<cs hash>/cs.dat, pk.dat, vk.dat, manifest.json
+//	<dir>/variants/<backend>/<name>.json
+//
+// Groth16 and PLONK compile a variant to different constraint systems, so
+// their entries never collide.
+//
+// The manifest records the hashes of the key files, so a key copied from
+// another circuit or setup is refused. The variant records name the hash a
+// variant last compiled to, which lets Load skip compiling.
+type Cache struct {
+	dir string
+}
+
+// Entry is a cached constraint system with its keys.
+type Entry struct {
+	Variant      Variant
+	Backend      Backend
+	Hash         string
+	CS           constraint.ConstraintSystem
+	ProvingKey   ProvingKey
+	VerifyingKey VerifyingKey
+}
+
+type cacheManifest struct {
+	Backend       Backend `json:"backend"`
+	CSHash        string  `json:"csHash"`
+	PKHash        string  `json:"pkHash"`
+	VKHash        string  `json:"vkHash"`
+	NbConstraints int     `json:"nbConstraints"`
+	NbPublic      int     `json:"nbPublic"`
+}
+
+type variantRecord struct {
+	Name   string `json:"name"`
+	Kind   Kind   `json:"kind"`
+	Shape  Shape  `json:"shape"`
+	CSHash string `json:"csHash"`
+}
+
+func OpenCache(dir string) (*Cache, error) {
+	if err := os.MkdirAll(filepath.Join(dir, cacheVariantsDir, string(Groth16)), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, cacheVariantsDir, string(Plonk)), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache: %w", err)
+	}
+
+	return &Cache{dir: dir}, nil
+}
+
+// Dir returns the directory holding the files of the constraint system with
+// the given hash.
+func (cache *Cache) Dir(hash string) string {
+	return filepath.Join(cache.dir, hash)
+}
+
+// HashConstraintSystem returns the hex SHA-256 of the serialized cs.
+func HashConstraintSystem(cs constraint.ConstraintSystem) (string, error) {
+	hasher := sha256.New()
+	if _, err := cs.WriteTo(hasher); err != nil {
+		return "", fmt.Errorf("failed to serialize constraint system: %w", err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// Ensure compiles variant for backend and returns its keys, running the
+// setup only when the resulting constraint system has none in the cache.
+// srs is only asked for when a PLONK setup runs.
+func (cache *Cache) Ensure(variant Variant, backend Backend, srs SRSSource) (*Entry, error) {
+	cs, err := variant.Compile(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := HashConstraintSystem(cs)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := cache.Dir(hash)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache entry: %w", err)
+	}
+
+	entry := &Entry{Variant: variant, Backend: backend, Hash: hash, CS: cs}
+
+	if _, err := os.Stat(filepath.Join(dir, cacheManifestFile)); err == nil {
+		if err := cache.loadKeys(entry); err != nil {
+			return nil, err
+		}
+	} else if errors.Is(err, os.ErrNotExist) {
+		if err := cache.setup(entry, srs); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+
+	if err := cache.writeRecord(entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// Load returns the cached keys of variant for backend without compiling it.
+// It fails with ErrNotCached when Ensure has not run for the variant, or ran
+// with other parameters.
+func (cache *Cache) Load(variant Variant, backend Backend) (*Entry, error) {
+	var record variantRecord
+	if err := readJSON(cache.recordPath(variant.Name, backend), &record); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%s for %s is %w", variant.Name, backend, ErrNotCached)
+		}
+		return nil, err
+	}
+
+	if record.Kind != variant.Kind || record.Shape != variant.Shape {
+		return nil, fmt.Errorf("%s was cached as %s %s, not %s: %w", variant.Name, record.Kind, record.Shape, variant, ErrNotCached)
+	}
+
+	csPath := filepath.Join(cache.Dir(record.CSHash), cacheCSFile)
+
+	cs, err := ReadConstraintSystem(backend, csPath)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := HashConstraintSystem(cs)
+	if err != nil {
+		return nil, err
+	}
+
+	if hash != record.CSHash {
+		return nil, fmt.Errorf("%s hashes to %s, expected %s", csPath, hash, record.CSHash)
+	}
+
+	entry := &Entry{Variant: variant, Backend: backend, Hash: hash, CS: cs}
+	if err := cache.loadKeys(entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (cache *Cache) setup(entry *Entry, srs SRSSource) error {
+	dir := cache.Dir(entry.Hash)
+
+	var loaded *SRS
+	if entry.Backend == Plonk {
+		if srs == nil {
+			return fmt.Errorf("plonk setup of %s needs an SRS", entry.Variant.Name)
+		}
+
+		var err error
+		if loaded, err = srs(entry.CS); err != nil {
+			return err
+		}
+	}
+
+	pk, vk, err := Setup(entry.CS, loaded)
+	if err != nil {
+		return fmt.Errorf("failed to set up %s: %w", entry.Variant.Name, err)
+	}
+
+	if err := WriteFile(filepath.Join(dir, cacheCSFile), entry.CS); err != nil {
+		return err
+	}
+
+	if err := WriteProvingKey(filepath.Join(dir, cachePKFile), pk); err != nil {
+		return err
+	}
+
+	if err := WriteFile(filepath.Join(dir, cacheVKFile), vk); err != nil {
+		return err
+	}
+
+	pkHash, err := hashFile(filepath.Join(dir, cachePKFile))
+	if err != nil {
+		return err
+	}
+
+	vkHash, err := hashFile(filepath.Join(dir, cacheVKFile))
+	if err != nil {
+		return err
+	}
+
+	// The manifest goes last: an entry without one is an interrupted setup
+	manifest := cacheManifest{
+		Backend:       entry.Backend,
+		CSHash:        entry.Hash,
+		PKHash:        pkHash,
+		VKHash:        vkHash,
+		NbConstraints: entry.CS.GetNbConstraints(),
+		NbPublic:      NbPublicInputs(entry.CS),
+	}
+	if err := writeJSON(filepath.Join(dir, cacheManifestFile), manifest); err != nil {
+		return err
+	}
+
+	entry.ProvingKey = pk
+	entry.VerifyingKey = vk
+
+	return nil
+}
+
+func (cache *Cache) loadKeys(entry *Entry) error {
+	dir := cache.Dir(entry.Hash)
+
+	var manifest cacheManifest
+	if err := readJSON(filepath.Join(dir, cacheManifestFile), &manifest); err != nil {
+		return err
+	}
+
+	if manifest.CSHash != entry.Hash || manifest.Backend != entry.Backend {
+		return fmt.Errorf("%w: manifest in %s is for %s constraint system %s", ErrKeyMismatch, dir, manifest.Backend, manifest.CSHash)
+	}
+
+	for _, file := range []struct {
+		name string
+		hash string
+	}{
+		{cachePKFile, manifest.PKHash},
+		{cacheVKFile, manifest.VKHash},
+	} {
+		hash, err := hashFile(filepath.Join(dir, file.name))
+		if err != nil {
+			return err
+		}
+
+		if hash != file.hash {
+			return fmt.Errorf("%w: %s in %s hashes to %s, manifest expects %s", ErrKeyMismatch, file.name, dir, hash, file.hash)
+		}
+	}
+
+	pk, err := ReadProvingKey(entry.Backend, filepath.Join(dir, cachePKFile))
+	if err != nil {
+		return err
+	}
+
+	vk, err := ReadVerifyingKey(entry.Backend, filepath.Join(dir, cacheVKFile))
+	if err != nil {
+		return err
+	}
+
+	if err := CheckProvingKey(entry.CS, pk); err != nil {
+		return err
+	}
+
+	if err := CheckVerifyingKey(entry.CS, vk); err != nil {
+		return err
+	}
+
+	entry.ProvingKey = pk
+	entry.VerifyingKey = vk
+
+	return nil
+}
+
+func (cache *Cache) recordPath(name string, backend Backend) string {
+	return filepath.Join(cache.dir, cacheVariantsDir, string(backend), name+".json")
+}
+
+func (cache *Cache) writeRecord(entry *Entry) error {
+	return writeJSON(cache.recordPath(entry.Variant.Name, entry.Backend), variantRecord{
+		Name:   entry.Variant.Name,
+		Kind:   entry.Variant.Kind,
+		Shape:  entry.Variant.Shape,
+		CSHash: entry.Hash,
+	})
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func readJSON(path string, value any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func writeJSON(path string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
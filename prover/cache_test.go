@@ -0,0 +1,101 @@
+package prover_test
+
+import (
+	"hide-pay/prover"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	cache, err := prover.OpenCache(t.TempDir())
+	require.NoError(t, err)
+
+	update := prover.Variant{Name: "merkle-update-4", Kind: prover.KindMerkleUpdate, Shape: prover.Shape{Depth: 4}}
+	withdraw := prover.Variant{Name: "withdraw-4", Kind: prover.KindWithdraw, Shape: prover.Shape{Depth: 4}}
+
+	_, err = cache.Load(update, prover.Groth16)
+	require.ErrorIs(t, err, prover.ErrNotCached)
+
+	entry, err := cache.Ensure(update, prover.Groth16, nil)
+	require.NoError(t, err)
+
+	hash, err := prover.HashConstraintSystem(entry.CS)
+	require.NoError(t, err)
+	assert.Equal(t, hash, entry.Hash)
+
+	vk, err := os.ReadFile(filepath.Join(cache.Dir(entry.Hash), "vk.dat"))
+	require.NoError(t, err)
+
+	// A second run compiles to the same constraint system and reuses its keys
+	again, err := cache.Ensure(update, prover.Groth16, nil)
+	require.NoError(t, err)
+	assert.Equal(t, entry.Hash, again.Hash)
+
+	vkAgain, err := os.ReadFile(filepath.Join(cache.Dir(entry.Hash), "vk.dat"))
+	require.NoError(t, err)
+	assert.Equal(t, vk, vkAgain)
+
+	loaded, err := cache.Load(update, prover.Groth16)
+	require.NoError(t, err)
+	assert.Equal(t, entry.Hash, loaded.Hash)
+
+	// Other parameters under the same name are not served from the cache
+	deeper := update
+	deeper.Shape.Depth = 5
+	_, err = cache.Load(deeper, prover.Groth16)
+	require.ErrorIs(t, err, prover.ErrNotCached)
+
+	other, err := cache.Ensure(withdraw, prover.Groth16, nil)
+	require.NoError(t, err)
+	require.NotEqual(t, entry.Hash, other.Hash)
+
+	// Keys of one circuit are refused for another
+	require.ErrorIs(t, prover.CheckProvingKey(entry.CS, other.ProvingKey), prover.ErrKeyMismatch)
+	require.ErrorIs(t, prover.CheckVerifyingKey(entry.CS, other.VerifyingKey), prover.ErrKeyMismatch)
+
+	otherVK, err := os.ReadFile(filepath.Join(cache.Dir(other.Hash), "vk.dat"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(cache.Dir(entry.Hash), "vk.dat"), otherVK, 0o644))
+
+	_, err = cache.Load(update, prover.Groth16)
+	require.ErrorIs(t, err, prover.ErrKeyMismatch)
+
+	_, err = cache.Ensure(update, prover.Groth16, nil)
+	require.ErrorIs(t, err, prover.ErrKeyMismatch)
+}
+
+func TestCache_Plonk(t *testing.T) {
+	cache, err := prover.OpenCache(t.TempDir())
+	require.NoError(t, err)
+
+	update := prover.Variant{Name: "merkle-update-4", Kind: prover.KindMerkleUpdate, Shape: prover.Shape{Depth: 4}}
+
+	_, err = cache.Ensure(update, prover.Plonk, nil)
+	require.Error(t, err)
+
+	entry, err := cache.Ensure(update, prover.Plonk, prover.NewUnsafeSRS)
+	require.NoError(t, err)
+	assert.Equal(t, prover.Plonk, entry.Backend)
+
+	// The sparse R1CS has its own entry, next to the Groth16 one
+	groth16, err := cache.Ensure(update, prover.Groth16, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, entry.Hash, groth16.Hash)
+
+	loaded, err := cache.Load(update, prover.Plonk)
+	require.NoError(t, err)
+	assert.Equal(t, entry.Hash, loaded.Hash)
+
+	// A cached entry needs no SRS
+	again, err := cache.Ensure(update, prover.Plonk, nil)
+	require.NoError(t, err)
+	assert.Equal(t, entry.Hash, again.Hash)
+
+	// Keys of one backend are refused for the other
+	require.ErrorIs(t, prover.CheckProvingKey(entry.CS, groth16.ProvingKey), prover.ErrKeyMismatch)
+	require.ErrorIs(t, prover.CheckVerifyingKey(groth16.CS, entry.VerifyingKey), prover.ErrKeyMismatch)
+}
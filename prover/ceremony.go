@@ -0,0 +1,189 @@
+package prover
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark/backend/groth16/bn254/mpcsetup"
+	"github.com/consensys/gnark/constraint"
+	csbn254 "github.com/consensys/gnark/constraint/bn254"
+)
+
+var (
+	// ErrNoContributions reports a ceremony sealed without any contribution,
+	// whose toxic waste would only come from the public beacon.
+	ErrNoContributions = errors.New("ceremony has no contributions")
+	// ErrInvalidContribution reports a contribution that does not build on
+	// the previous one.
+	ErrInvalidContribution = errors.New("invalid contribution")
+)
+
+// ReadPhase1 loads the sealed output of a Groth16 phase-1 ceremony and cuts it
+// down to the domain of cs. Powers of τ are valid at any smaller size, so one
+// phase 1 serves every circuit up to its size.
+func ReadPhase1(path string, cs constraint.ConstraintSystem) (*mpcsetup.SrsCommons, error) {
+	var commons mpcsetup.SrsCommons
+	if err := ReadFile(path, &commons); err != nil {
+		return nil, err
+	}
+
+	// The sealed keys take their domain from the size of the phase-1 output,
+	// so it must match the domain of cs exactly
+	n := int(fft.NewDomain(uint64(cs.GetNbConstraints())).Cardinality)
+	if len(commons.G2.Tau) < n {
+		return nil, fmt.Errorf("phase 1 %s has domain size %d, constraint system needs %d", path, len(commons.G2.Tau), n)
+	}
+
+	commons.G1.Tau = commons.G1.Tau[:2*n-1]
+	commons.G1.AlphaTau = commons.G1.AlphaTau[:n]
+	commons.G1.BetaTau = commons.G1.BetaTau[:n]
+	commons.G2.Tau = commons.G2.Tau[:n]
+
+	return &commons, nil
+}
+
+// ReadContribution reads a phase-2 contribution, or the initial parameters
+// written from Ceremony.Initial.
+func ReadContribution(path string) (*mpcsetup.Phase2, error) {
+	contribution := new(mpcsetup.Phase2)
+	if err := ReadFile(path, contribution); err != nil {
+		return nil, err
+	}
+
+	return contribution, nil
+}
+
+// ContributionHash is the hash a contributor publishes for their
+// contribution. The next contribution is bound to it, so a transcript of
+// hashes pins down the whole chain.
+func ContributionHash(contribution *mpcsetup.Phase2) (string, error) {
+	hasher := sha256.New()
+	if _, err := contribution.WriteTo(hasher); err != nil {
+		return "", fmt.Errorf("failed to hash contribution: %w", err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// Ceremony is the Groth16 phase 2 of one constraint system on top of a
+// phase-1 output. A coordinator initializes it, contributors each run
+// Contribute on the latest parameters in turn, and anyone holding the chain
+// of contributions can verify and seal it into the final keys.
+type Ceremony struct {
+	cs      *csbn254.R1CS
+	commons *mpcsetup.SrsCommons
+	// initial and evaluations are what phase 2 starts from; they only depend
+	// on cs and commons and are expensive, so they are computed once
+	initial     *mpcsetup.Phase2
+	evaluations mpcsetup.Phase2Evaluations
+}
+
+// NewCeremony prepares the phase 2 of cs, which must be compiled for Groth16.
+// commons is usually loaded with ReadPhase1.
+func NewCeremony(cs constraint.ConstraintSystem, commons *mpcsetup.SrsCommons) (*Ceremony, error) {
+	r1cs, ok := cs.(*csbn254.R1CS)
+	if !ok {
+		return nil, fmt.Errorf("unsupported constraint system %T", cs)
+	}
+
+	if backend, err := BackendOf(cs); err != nil {
+		return nil, err
+	} else if backend != Groth16 {
+		return nil, fmt.Errorf("phase 2 only applies to %s, constraint system is compiled for %s", Groth16, backend)
+	}
+
+	if n := int(fft.NewDomain(uint64(cs.GetNbConstraints())).Cardinality); len(commons.G1.AlphaTau) != n {
+		return nil, fmt.Errorf("phase 1 has domain size %d, constraint system needs %d", len(commons.G1.AlphaTau), n)
+	}
+
+	ceremony := &Ceremony{cs: r1cs, commons: commons, initial: new(mpcsetup.Phase2)}
+	ceremony.evaluations = ceremony.initial.Initialize(r1cs, commons)
+
+	return ceremony, nil
+}
+
+// Initial returns the parameters the first contributor starts from. They
+// are deterministic, which is how Verify checks the first contribution.
+func (ceremony *Ceremony) Initial() (*mpcsetup.Phase2, error) {
+	return clone(ceremony.initial)
+}
+
+// Contribute returns next, the contribution made on top of previous with
+// fresh randomness. The randomness is dropped when it returns.
+func Contribute(previous *mpcsetup.Phase2) (*mpcsetup.Phase2, error) {
+	next, err := clone(previous)
+	if err != nil {
+		return nil, err
+	}
+
+	next.Contribute()
+
+	return next, nil
+}
+
+// Verify checks that each contribution correctly builds on the previous one,
+// the first on the initial parameters.
+func (ceremony *Ceremony) Verify(contributions ...*mpcsetup.Phase2) error {
+	if len(contributions) == 0 {
+		return ErrNoContributions
+	}
+
+	previous := ceremony.initial
+	for i, next := range contributions {
+		if err := previous.Verify(next); err != nil {
+			return fmt.Errorf("%w %d: %v", ErrInvalidContribution, i+1, err)
+		}
+		previous = next
+	}
+
+	return nil
+}
+
+// Seal verifies the contributions, applies a last contribution derived from
+// beacon, a public random value only known after the last contribution, and
+// returns the final keys. Anyone can reproduce them from the same inputs.
+func (ceremony *Ceremony) Seal(beacon []byte, contributions ...*mpcsetup.Phase2) (ProvingKey, VerifyingKey, error) {
+	if len(beacon) == 0 {
+		return nil, nil, fmt.Errorf("ceremony beacon is empty")
+	}
+
+	if err := ceremony.Verify(contributions...); err != nil {
+		return nil, nil, err
+	}
+
+	// Sealing updates the last contribution in place, so it gets a copy
+	last, err := clone(contributions[len(contributions)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pk, vk := last.Seal(ceremony.commons, &ceremony.evaluations, beacon)
+
+	if err := CheckProvingKey(ceremony.cs, pk); err != nil {
+		return nil, nil, err
+	}
+
+	if err := CheckVerifyingKey(ceremony.cs, vk); err != nil {
+		return nil, nil, err
+	}
+
+	return pk, vk, nil
+}
+
+func clone(contribution *mpcsetup.Phase2) (*mpcsetup.Phase2, error) {
+	var buf bytes.Buffer
+	if _, err := contribution.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("failed to serialize contribution: %w", err)
+	}
+
+	copied := new(mpcsetup.Phase2)
+	if _, err := copied.ReadFrom(&buf); err != nil {
+		return nil, fmt.Errorf("failed to deserialize contribution: %w", err)
+	}
+
+	return copied, nil
+}
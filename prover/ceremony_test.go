@@ -0,0 +1,167 @@
+package prover_test
+
+import (
+	"bytes"
+	"fmt"
+	"hide-pay/prover"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark/backend/groth16/bn254/mpcsetup"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/std/rangecheck"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// squareCircuit is small enough for a quick ceremony, and its range check
+// adds a commitment whose key the ceremony also randomizes.
+type squareCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (circuit *squareCircuit) Define(api frontend.API) error {
+	rangecheck.New(api).Check(circuit.X, 16)
+	api.AssertIsEqual(api.Mul(circuit.X, circuit.X), circuit.Y)
+
+	return nil
+}
+
+// writePhase1 simulates a phase-1 ceremony of two contributors for a domain
+// twice as large as cs needs, and writes its sealed output to a file.
+func writePhase1(t *testing.T, cs constraint.ConstraintSystem) string {
+	n := 2 * fft.NewDomain(uint64(cs.GetNbConstraints())).Cardinality
+
+	first := mpcsetup.NewPhase1(n)
+	first.Contribute()
+
+	var second mpcsetup.Phase1
+	require.NoError(t, roundTrip(first, &second))
+	second.Contribute()
+
+	commons, err := mpcsetup.VerifyPhase1(n, []byte("phase 1 beacon"), first, &second)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "phase1.dat")
+	require.NoError(t, prover.WriteFile(path, &commons))
+
+	return path
+}
+
+// roundTrip hands a contribution to the next contributor through a file.
+func roundTrip(from io.WriterTo, to io.ReaderFrom) error {
+	var buf bytes.Buffer
+	if _, err := from.WriteTo(&buf); err != nil {
+		return err
+	}
+
+	_, err := to.ReadFrom(&buf)
+	return err
+}
+
+func TestCeremony(t *testing.T) {
+	cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &squareCircuit{})
+	require.NoError(t, err)
+	require.Len(t, cs.GetCommitments().CommitmentIndexes(), 1)
+
+	commons, err := prover.ReadPhase1(writePhase1(t, cs), cs)
+	require.NoError(t, err)
+
+	ceremony, err := prover.NewCeremony(cs, commons)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	previous := filepath.Join(dir, "phase2-0.dat")
+	initial, err := ceremony.Initial()
+	require.NoError(t, err)
+	require.NoError(t, prover.WriteFile(previous, initial))
+
+	// Each contributor reads the latest parameters and publishes their own
+	const nbContributors = 3
+	var (
+		contributions []*mpcsetup.Phase2
+		hashes        []string
+	)
+	for i := range nbContributors {
+		current, err := prover.ReadContribution(previous)
+		require.NoError(t, err)
+
+		next, err := prover.Contribute(current)
+		require.NoError(t, err)
+
+		previous = filepath.Join(dir, fmt.Sprintf("phase2-%d.dat", i+1))
+		require.NoError(t, prover.WriteFile(previous, next))
+
+		hash, err := prover.ContributionHash(next)
+		require.NoError(t, err)
+		hashes = append(hashes, hash)
+
+		contribution, err := prover.ReadContribution(previous)
+		require.NoError(t, err)
+		contributions = append(contributions, contribution)
+	}
+	assert.NotEqual(t, hashes[0], hashes[1])
+
+	require.NoError(t, ceremony.Verify(contributions...))
+
+	// A chain missing a link or out of order is refused
+	assert.ErrorContains(t, ceremony.Verify(contributions[0], contributions[2]), "invalid contribution 2")
+	assert.ErrorContains(t, ceremony.Verify(contributions[1], contributions[0], contributions[2]), "invalid contribution 1")
+	assert.ErrorIs(t, ceremony.Verify(contributions[2]), prover.ErrInvalidContribution)
+	assert.ErrorIs(t, ceremony.Verify(), prover.ErrNoContributions)
+
+	pk, vk, err := ceremony.Seal([]byte("beacon"), contributions...)
+	require.NoError(t, err)
+
+	// Sealing leaves the contributions intact and is reproducible
+	require.NoError(t, ceremony.Verify(contributions...))
+	_, again, err := ceremony.Seal([]byte("beacon"), contributions...)
+	require.NoError(t, err)
+	assert.Equal(t, vk, again)
+
+	_, _, err = ceremony.Seal([]byte("beacon"))
+	assert.ErrorIs(t, err, prover.ErrNoContributions)
+
+	w, err := frontend.NewWitness(&squareCircuit{X: 300, Y: 90000}, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+
+	proof, err := prover.Prove(cs, pk, w)
+	require.NoError(t, err)
+
+	public, err := w.Public()
+	require.NoError(t, err)
+	require.NoError(t, prover.Verify(proof, vk, public))
+}
+
+func TestReadPhase1_TooSmall(t *testing.T) {
+	variant, _ := merkleUpdateWitness(t)
+
+	cs, err := variant.Compile(prover.Groth16)
+	require.NoError(t, err)
+
+	commons, err := mpcsetup.VerifyPhase1(16, []byte("beacon"), contribute(mpcsetup.NewPhase1(16)))
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "phase1.dat")
+	require.NoError(t, prover.WriteFile(path, &commons))
+
+	_, err = prover.ReadPhase1(path, cs)
+	assert.ErrorContains(t, err, "constraint system needs")
+
+	// Phase 2 is specific to Groth16
+	plonkCS, err := variant.Compile(prover.Plonk)
+	require.NoError(t, err)
+	_, err = prover.NewCeremony(plonkCS, &commons)
+	assert.Error(t, err)
+}
+
+func contribute(phase1 *mpcsetup.Phase1) *mpcsetup.Phase1 {
+	phase1.Contribute()
+	return phase1
+}
@@ -0,0 +1,172 @@
+package prover
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	csbn254 "github.com/consensys/gnark/constraint/bn254"
+	"github.com/consensys/gnark/constraint/solver"
+	"github.com/consensys/gnark/frontend"
+	gnarkprofile "github.com/consensys/gnark/profile"
+	"github.com/google/pprof/profile"
+)
+
+// Frame is a Go source location in the stack that added a constraint.
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+func (frame Frame) String() string {
+	return fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line)
+}
+
+// inGnark tells frames of the gnark frontend and standard gadgets from the
+// circuit code calling them.
+func (frame Frame) inGnark() bool {
+	return strings.HasPrefix(frame.Function, "github.com/consensys/")
+}
+
+// DebugCS is a constraint system with the Go stack that added each of its
+// constraints, so a constraint the witness does not satisfy can be traced
+// back to the gadget that asserts it.
+type DebugCS struct {
+	constraint.ConstraintSystem
+	// stacks holds one stack per constraint, innermost frame first
+	stacks [][]Frame
+}
+
+// The gnark profiler records every constraint compiled while a session is
+// open, from any goroutine, so debug compiles do not overlap.
+var profileMu sync.Mutex
+
+// CompileDebug compiles circuit like Backend.Compile and records the stack
+// of each constraint. It is several times slower and only meant for
+// checking witnesses; a compile running concurrently in the same process
+// would mix its constraints into the stacks.
+func CompileDebug(circuit frontend.Circuit, backend Backend) (*DebugCS, error) {
+	profileMu.Lock()
+	defer profileMu.Unlock()
+
+	dir, err := os.MkdirTemp("", "auditzero-profile")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "constraints.pprof")
+
+	session := gnarkprofile.Start(gnarkprofile.WithPath(path))
+	cs, err := backend.Compile(circuit)
+	session.Stop()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	recorded, err := profile.Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse constraint profile: %w", err)
+	}
+
+	// Samples are recorded in the order constraints are added, so sample i
+	// is constraint i
+	if len(recorded.Sample) != cs.GetNbConstraints() {
+		return nil, fmt.Errorf("profile recorded %d constraints, constraint system has %d", len(recorded.Sample), cs.GetNbConstraints())
+	}
+
+	stacks := make([][]Frame, len(recorded.Sample))
+	for i, sample := range recorded.Sample {
+		for _, location := range sample.Location {
+			for _, line := range location.Line {
+				stacks[i] = append(stacks[i], Frame{Function: line.Function.SystemName, File: line.Function.Filename, Line: int(line.Line)})
+			}
+		}
+	}
+
+	return &DebugCS{ConstraintSystem: cs, stacks: stacks}, nil
+}
+
+// UnsatisfiedError is a constraint the witness does not satisfy, with the
+// stack that added it.
+type UnsatisfiedError struct {
+	Constraint int
+	Err        error
+	// Stack starts at the innermost frame and ends at the Define method of
+	// the circuit.
+	Stack []Frame
+}
+
+func (e *UnsatisfiedError) Error() string {
+	if frame, ok := e.Location(); ok {
+		return fmt.Sprintf("constraint #%d is not satisfied at %s: %v", e.Constraint, frame, e.Err)
+	}
+
+	return fmt.Sprintf("constraint #%d is not satisfied: %v", e.Constraint, e.Err)
+}
+
+func (e *UnsatisfiedError) Unwrap() error {
+	return e.Err
+}
+
+// Location returns the innermost frame outside gnark: the line of the
+// gadget that asserted the constraint, rather than the gnark code building
+// it.
+func (e *UnsatisfiedError) Location() (Frame, bool) {
+	for _, frame := range e.Stack {
+		if !frame.inGnark() {
+			return frame, true
+		}
+	}
+
+	return Frame{}, false
+}
+
+// CircuitStack returns the frames of Stack outside gnark, innermost first.
+func (e *UnsatisfiedError) CircuitStack() []Frame {
+	var frames []Frame
+	for _, frame := range e.Stack {
+		if !frame.inGnark() {
+			frames = append(frames, frame)
+		}
+	}
+
+	return frames
+}
+
+// Check solves cs with w. A constraint w does not satisfy is reported as an
+// *UnsatisfiedError; the solver stops at the first one it reaches.
+func (cs *DebugCS) Check(w witness.Witness, opts ...solver.Option) error {
+	if err := CheckWitness(cs.ConstraintSystem, w); err != nil {
+		return err
+	}
+
+	_, err := cs.Solve(w, opts...)
+	if err == nil {
+		return nil
+	}
+
+	var unsatisfied *csbn254.UnsatisfiedConstraintError
+	if !errors.As(err, &unsatisfied) {
+		return fmt.Errorf("failed to solve: %w", err)
+	}
+
+	result := &UnsatisfiedError{Constraint: unsatisfied.CID, Err: unsatisfied.Err}
+	if unsatisfied.CID >= 0 && unsatisfied.CID < len(cs.stacks) {
+		result.Stack = cs.stacks[unsatisfied.CID]
+	}
+
+	return result
+}
@@ -0,0 +1,113 @@
+package prover_test
+
+import (
+	"hide-pay/builder"
+	"hide-pay/prover"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugCS_Check(t *testing.T) {
+	for _, backend := range []prover.Backend{prover.Groth16, prover.Plonk} {
+		cs, err := prover.CompileDebug(&squareCircuit{}, backend)
+		require.NoError(t, err)
+
+		w, err := frontend.NewWitness(&squareCircuit{X: 3, Y: 9}, ecc.BN254.ScalarField())
+		require.NoError(t, err)
+		require.NoError(t, cs.Check(w), backend)
+
+		w, err = frontend.NewWitness(&squareCircuit{X: 3, Y: 10}, ecc.BN254.ScalarField())
+		require.NoError(t, err)
+
+		var unsatisfied *prover.UnsatisfiedError
+		require.ErrorAs(t, cs.Check(w), &unsatisfied, backend)
+
+		location, ok := unsatisfied.Location()
+		require.True(t, ok, backend)
+		assert.Equal(t, "hide-pay/prover_test.(*squareCircuit).Define", location.Function, backend)
+		assert.Equal(t, "ceremony_test.go", filepath.Base(location.File), backend)
+		assert.Contains(t, unsatisfied.Error(), "ceremony_test.go", backend)
+	}
+}
+
+func TestDebugCS_Check_Transfer(t *testing.T) {
+	shape := prover.Shape{Assets: 1, Depth: 4, Inputs: 1, Outputs: 2}
+
+	cs, err := prover.CompileDebug(shape.Circuit(), prover.Groth16)
+	require.NoError(t, err)
+
+	utxo, err := builder.GenerateUTXO(1, shape.Depth, shape.Inputs, shape.Outputs)
+	require.NoError(t, err)
+
+	result, err := utxo.BuildAndCheck()
+	require.NoError(t, err)
+
+	assignment, err := builder.NewUTXOCircuitWitness(utxo, result)
+	require.NoError(t, err)
+
+	// The note is spent with a key it was not sent to
+	assignment.UTXO.Nullifier[0].PrivateKey = 12345
+
+	w, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	require.NoError(t, err)
+
+	var unsatisfied *prover.UnsatisfiedError
+	require.ErrorAs(t, cs.Check(w), &unsatisfied)
+
+	location, ok := unsatisfied.Location()
+	require.True(t, ok)
+	assert.Equal(t, "note.go", filepath.Base(location.File))
+
+	// The stack goes out to the Define method of the circuit
+	stack := unsatisfied.CircuitStack()
+	assert.True(t, strings.HasSuffix(stack[len(stack)-1].Function, ".Define"), stack[len(stack)-1].Function)
+}
+
+func TestTraceTransfer(t *testing.T) {
+	utxo, err := builder.GenerateUTXO(1, 4, 2, 2)
+	require.NoError(t, err)
+
+	trace, err := prover.TraceTransfer(utxo)
+	require.NoError(t, err)
+	require.Len(t, trace, 2*3+2*7)
+	assert.Equal(t, "inputs[0].commitment", trace[0].Name)
+	assert.Equal(t, "outputs[1].auditMemo.hash", trace[len(trace)-1].Name)
+	assert.Nil(t, prover.FirstDivergence(trace))
+
+	// The circuit reduces an ephemeral key past the field modulus, the
+	// builder does not
+	modulus := fr.Modulus()
+	utxo.EphemeralAuditSecretKey[1].Add(&utxo.EphemeralAuditSecretKey[1], modulus)
+
+	trace, err = prover.TraceTransfer(utxo)
+	require.NoError(t, err)
+
+	divergence := prover.FirstDivergence(trace)
+	require.NotNil(t, divergence)
+	assert.Equal(t, "outputs[1].auditMemo.viewTag", divergence.Name)
+}
+
+func TestTraceTransfer_Unsatisfied(t *testing.T) {
+	utxo, err := builder.GenerateUTXO(1, 4, 1, 1)
+	require.NoError(t, err)
+
+	// The gadgets reject a viewing key off the curve before any value is
+	// compared
+	utxo.Commitment[0].ViewPubKey.X.Add(&utxo.Commitment[0].ViewPubKey.X, new(fr.Element).SetOne())
+
+	_, err = prover.TraceTransfer(utxo)
+
+	var unsatisfied *prover.UnsatisfiedError
+	require.ErrorAs(t, err, &unsatisfied)
+
+	location, ok := unsatisfied.Location()
+	require.True(t, ok)
+	assert.Equal(t, "hide-pay/circuits.AssertIsValidPublicKey", location.Function)
+}
@@ -0,0 +1,131 @@
+package prover
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	plonkbn254 "github.com/consensys/gnark/backend/plonk/bn254"
+)
+
+type g1JSON [2]string
+
+type g2JSON [2][2]string
+
+// verifyingKeyJSON lists the points of a BN254 Groth16 verifying key as
+// decimal coordinates, G2 coordinates as [A0, A1].
+type verifyingKeyJSON struct {
+	Protocol      string   `json:"protocol"`
+	Curve         string   `json:"curve"`
+	NbPublic      int      `json:"nbPublic"`
+	NbCommitments int      `json:"nbCommitments"`
+	Alpha         g1JSON   `json:"alpha"`
+	Beta          g2JSON   `json:"beta"`
+	Gamma         g2JSON   `json:"gamma"`
+	Delta         g2JSON   `json:"delta"`
+	K             []g1JSON `json:"k"`
+}
+
+// plonkVerifyingKeyJSON lists the commitments of a BN254 PLONK verifying key
+// in the same encoding, with the field elements of the domain as decimals.
+type plonkVerifyingKeyJSON struct {
+	Protocol                    string    `json:"protocol"`
+	Curve                       string    `json:"curve"`
+	NbPublic                    int       `json:"nbPublic"`
+	Size                        uint64    `json:"size"`
+	Generator                   string    `json:"generator"`
+	CosetShift                  string    `json:"cosetShift"`
+	S                           [3]g1JSON `json:"s"`
+	Ql                          g1JSON    `json:"ql"`
+	Qr                          g1JSON    `json:"qr"`
+	Qm                          g1JSON    `json:"qm"`
+	Qo                          g1JSON    `json:"qo"`
+	Qk                          g1JSON    `json:"qk"`
+	Qcp                         []g1JSON  `json:"qcp"`
+	CommitmentConstraintIndexes []uint64  `json:"commitmentConstraintIndexes"`
+	KzgG1                       g1JSON    `json:"kzgG1"`
+	KzgG2                       [2]g2JSON `json:"kzgG2"`
+}
+
+// ExportVerifyingKeyJSON writes vk in a form other tooling can consume without
+// gnark's binary encoding.
+func ExportVerifyingKeyJSON(vk VerifyingKey, w io.Writer) error {
+	var export any
+
+	switch concrete := vk.(type) {
+	case *groth16bn254.VerifyingKey:
+		export = exportGroth16(concrete)
+	case *plonkbn254.VerifyingKey:
+		export = exportPlonk(concrete)
+	default:
+		return fmt.Errorf("unsupported verifying key %T", vk)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(export)
+}
+
+func exportGroth16(concrete *groth16bn254.VerifyingKey) verifyingKeyJSON {
+	export := verifyingKeyJSON{
+		Protocol:      "groth16",
+		Curve:         "bn254",
+		NbPublic:      NbPublic(concrete),
+		NbCommitments: len(concrete.CommitmentKeys),
+		Alpha:         encodeG1(&concrete.G1.Alpha),
+		Beta:          encodeG2(&concrete.G2.Beta),
+		Gamma:         encodeG2(&concrete.G2.Gamma),
+		Delta:         encodeG2(&concrete.G2.Delta),
+		K:             make([]g1JSON, len(concrete.G1.K)),
+	}
+
+	for i := range concrete.G1.K {
+		export.K[i] = encodeG1(&concrete.G1.K[i])
+	}
+
+	return export
+}
+
+func exportPlonk(concrete *plonkbn254.VerifyingKey) plonkVerifyingKeyJSON {
+	export := plonkVerifyingKeyJSON{
+		Protocol:                    "plonk",
+		Curve:                       "bn254",
+		NbPublic:                    NbPublic(concrete),
+		Size:                        concrete.Size,
+		Generator:                   concrete.Generator.String(),
+		CosetShift:                  concrete.CosetShift.String(),
+		Ql:                          encodeG1(&concrete.Ql),
+		Qr:                          encodeG1(&concrete.Qr),
+		Qm:                          encodeG1(&concrete.Qm),
+		Qo:                          encodeG1(&concrete.Qo),
+		Qk:                          encodeG1(&concrete.Qk),
+		Qcp:                         make([]g1JSON, len(concrete.Qcp)),
+		CommitmentConstraintIndexes: append([]uint64{}, concrete.CommitmentConstraintIndexes...),
+		KzgG1:                       encodeG1(&concrete.Kzg.G1),
+		KzgG2:                       [2]g2JSON{encodeG2(&concrete.Kzg.G2[0]), encodeG2(&concrete.Kzg.G2[1])},
+	}
+
+	for i := range concrete.S {
+		export.S[i] = encodeG1(&concrete.S[i])
+	}
+
+	for i := range concrete.Qcp {
+		export.Qcp[i] = encodeG1(&concrete.Qcp[i])
+	}
+
+	return export
+}
+
+func encodeG1(point *bn254.G1Affine) g1JSON {
+	return g1JSON{point.X.String(), point.Y.String()}
+}
+
+func encodeG2(point *bn254.G2Affine) g2JSON {
+	return g2JSON{
+		{point.X.A0.String(), point.X.A1.String()},
+		{point.Y.A0.String(), point.Y.A1.String()},
+	}
+}
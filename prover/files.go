@@ -0,0 +1,124 @@
+package prover
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+)
+
+// WriteFile writes object to path, or to stdout when path is "-".
+func WriteFile(path string, object io.WriterTo) error {
+	if path == "-" {
+		_, err := object.WriteTo(os.Stdout)
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := object.WriteTo(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return file.Close()
+}
+
+// ReadFile fills object from path and fails on a short or trailing read.
+func ReadFile(path string, object io.ReaderFrom) error {
+	return readFile(path, object.ReadFrom)
+}
+
+func readFile(path string, readFrom func(io.Reader) (int64, error)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	n, err := readFrom(file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if n != info.Size() {
+		return fmt.Errorf("failed to read %s: %d trailing bytes", path, info.Size()-n)
+	}
+
+	return nil
+}
+
+// ReadConstraintSystem reads a constraint system compiled for backend.
+func ReadConstraintSystem(backend Backend, path string) (constraint.ConstraintSystem, error) {
+	cs := backend.newCS()
+	if err := ReadFile(path, cs); err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
+
+// WriteProvingKey writes pk uncompressed, which is much faster to load.
+func WriteProvingKey(path string, pk ProvingKey) error {
+	return WriteFile(path, writerFunc(pk.WriteRawTo))
+}
+
+// ReadProvingKey reads a key written by WriteProvingKey. Points are not
+// checked, so pk must come from a trusted setup.
+func ReadProvingKey(backend Backend, path string) (ProvingKey, error) {
+	pk := backend.newProvingKey()
+	if err := readFile(path, pk.UnsafeReadFrom); err != nil {
+		return nil, err
+	}
+
+	return pk, nil
+}
+
+func ReadVerifyingKey(backend Backend, path string) (VerifyingKey, error) {
+	vk := backend.newVerifyingKey()
+	if err := ReadFile(path, vk); err != nil {
+		return nil, err
+	}
+
+	return vk, nil
+}
+
+func ReadProof(backend Backend, path string) (Proof, error) {
+	proof := backend.newProof()
+	if err := ReadFile(path, proof); err != nil {
+		return nil, err
+	}
+
+	return proof, nil
+}
+
+// ReadWitness reads a full or public witness in gnark's binary encoding.
+func ReadWitness(path string) (witness.Witness, error) {
+	w, err := witness.New(ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ReadFile(path, w); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+type writerFunc func(io.Writer) (int64, error)
+
+func (f writerFunc) WriteTo(w io.Writer) (int64, error) {
+	return f(w)
+}
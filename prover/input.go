@@ -0,0 +1,311 @@
+package prover
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hide-pay/builder"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+)
+
+// TransferInputVersion is the only transfer input layout understood by this
+// package.
+const TransferInputVersion = 2
+
+// TransferInput is everything needed to prove a transfer, in a form a wallet
+// can hand to a remote prover. It maps onto builder.UTXO.
+type TransferInput struct {
+	Version int
+
+	Inputs  []InputNote
+	Outputs []OutputNote
+
+	AuditPublicKey twistededwardbn254.PointAffine
+}
+
+// InputNote is a spent note with its nullifier key and membership proof.
+// MerklePath starts with the note commitment, as builder.MerkleProof.Path.
+type InputNote struct {
+	Note            builder.Commitment
+	SpentPrivateKey fr.Element
+	MerkleIndex     uint64
+	MerklePath      []fr.Element
+}
+
+// OutputNote is a created note with the spent key delivered in its memos and
+// the ephemeral keys of the owner and audit memos.
+type OutputNote struct {
+	Note                       builder.Commitment
+	SpentKey                   fr.Element
+	EphemeralReceiverSecretKey big.Int
+	EphemeralAuditSecretKey    big.Int
+}
+
+// FieldError locates a problem in a transfer input, e.g.
+// inputs[1].merkleProof.path[3].
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// InputError lists every field error found while loading a transfer input.
+type InputError struct {
+	Errors []*FieldError
+}
+
+func (e *InputError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i := range e.Errors {
+		messages[i] = e.Errors[i].Error()
+	}
+
+	return "invalid transfer input: " + strings.Join(messages, "; ")
+}
+
+type fieldErrors []*FieldError
+
+func (errs *fieldErrors) add(field string, err error) {
+	*errs = append(*errs, &FieldError{Field: field, Err: err})
+}
+
+func (errs fieldErrors) err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &InputError{Errors: errs}
+}
+
+var (
+	errRequired         = errors.New("is required")
+	errNotCanonical     = errors.New("is not a canonical field element")
+	errScalarRange      = errors.New("must be in [1, curve order)")
+	errSpentKey         = errors.New("does not hash to the note spent address")
+	errAuditKey         = errors.New("is not the audit public key of the transfer")
+	errMerkleLeaf       = errors.New("is not the note commitment")
+	errTruncated        = errors.New("unexpected end of data")
+	errUnsupportedVer   = fmt.Errorf("unsupported version, expected %d", TransferInputVersion)
+	errMerkleIndex      = errors.New("does not fit the tree depth")
+	errMerkleDepth      = errors.New("must have the same length for every input")
+	errMerkleTooShallow = errors.New("must hold the leaf and at least one sibling")
+)
+
+// NewTransferInput describes utxo as a transfer input.
+func NewTransferInput(utxo *builder.UTXO) (*TransferInput, error) {
+	if len(utxo.Nullifier) != len(utxo.MerkleProof) {
+		return nil, fmt.Errorf("number of nullifiers and merkle proofs must be the same")
+	}
+
+	if len(utxo.Commitment) != len(utxo.SpentKey) || len(utxo.Commitment) != len(utxo.EphemeralReceiverSecretKey) || len(utxo.Commitment) != len(utxo.EphemeralAuditSecretKey) {
+		return nil, fmt.Errorf("number of commitments, spent keys, and ephemeral receiver and audit secret keys must be the same")
+	}
+
+	input := &TransferInput{
+		Version:        TransferInputVersion,
+		Inputs:         make([]InputNote, len(utxo.Nullifier)),
+		Outputs:        make([]OutputNote, len(utxo.Commitment)),
+		AuditPublicKey: utxo.AuditPublicKey,
+	}
+
+	for i := range utxo.Nullifier {
+		input.Inputs[i] = InputNote{
+			Note:            utxo.Nullifier[i].Commitment,
+			SpentPrivateKey: utxo.Nullifier[i].SpentPrivateKey,
+			MerkleIndex:     uint64(utxo.MerkleProof[i].Index()),
+			MerklePath:      utxo.MerkleProof[i].Path(),
+		}
+	}
+
+	for i := range utxo.Commitment {
+		input.Outputs[i] = OutputNote{
+			Note:                       utxo.Commitment[i],
+			SpentKey:                   utxo.SpentKey[i],
+			EphemeralReceiverSecretKey: utxo.EphemeralReceiverSecretKey[i],
+			EphemeralAuditSecretKey:    utxo.EphemeralAuditSecretKey[i],
+		}
+	}
+
+	return input, nil
+}
+
+// Validate checks every field that can be checked on its own: versions, keys,
+// scalar ranges, spent keys, audit keys and Merkle paths. Balances are left to
+// builder.UTXO.BuildAndCheck. The error is an *InputError.
+func (input *TransferInput) Validate() error {
+	var errs fieldErrors
+
+	if input.Version != TransferInputVersion {
+		errs.add("version", errUnsupportedVer)
+	}
+
+	if len(input.Inputs) == 0 {
+		errs.add("inputs", errors.New("at least one input is required"))
+	}
+
+	if len(input.Outputs) == 0 {
+		errs.add("outputs", errors.New("at least one output is required"))
+	}
+
+	for i := range input.Inputs {
+		note := &input.Inputs[i]
+		field := fmt.Sprintf("inputs[%d]", i)
+
+		validateNote(&errs, field+".note", &note.Note)
+
+		if !builder.CheckSpentAddress(note.Note.SpentAddress, note.SpentPrivateKey) {
+			errs.add(field+".spentPrivateKey", errSpentKey)
+		}
+
+		switch {
+		case len(note.MerklePath) < 2:
+			errs.add(field+".merkleProof.path", errMerkleTooShallow)
+		case len(note.MerklePath) != len(input.Inputs[0].MerklePath):
+			errs.add(field+".merkleProof.path", errMerkleDepth)
+		default:
+			if len(note.MerklePath)-1 < 64 && note.MerkleIndex >= 1<<(len(note.MerklePath)-1) {
+				errs.add(field+".merkleProof.index", errMerkleIndex)
+			}
+
+			if note.MerklePath[0] != note.Note.Compute() {
+				errs.add(field+".merkleProof.path[0]", errMerkleLeaf)
+			}
+		}
+	}
+
+	for i := range input.Outputs {
+		note := &input.Outputs[i]
+		field := fmt.Sprintf("outputs[%d]", i)
+
+		validateNote(&errs, field+".note", &note.Note)
+
+		if !builder.CheckSpentAddress(note.Note.SpentAddress, note.SpentKey) {
+			errs.add(field+".spentKey", errSpentKey)
+		}
+
+		if note.Note.AuditPubKey != input.AuditPublicKey {
+			errs.add(field+".note.auditPubKey", errAuditKey)
+		}
+
+		validateScalar(&errs, field+".ephemeralReceiverSecretKey", &note.EphemeralReceiverSecretKey)
+		validateScalar(&errs, field+".ephemeralAuditSecretKey", &note.EphemeralAuditSecretKey)
+	}
+
+	validatePoint(&errs, "auditPublicKey", &input.AuditPublicKey)
+
+	return errs.err()
+}
+
+func validateNote(errs *fieldErrors, field string, note *builder.Commitment) {
+	validatePoint(errs, field+".ownerPubKey", &note.OwnerPubKey)
+	validatePoint(errs, field+".viewPubKey", &note.ViewPubKey)
+	validatePoint(errs, field+".auditPubKey", &note.AuditPubKey)
+}
+
+func validatePoint(errs *fieldErrors, field string, point *twistededwardbn254.PointAffine) {
+	if err := builder.ValidatePublicKey(point); err != nil {
+		errs.add(field, err)
+	}
+}
+
+func validateScalar(errs *fieldErrors, field string, scalar *big.Int) {
+	order := twistededwardbn254.GetEdwardsCurve().Order
+	if scalar.Sign() <= 0 || scalar.Cmp(&order) >= 0 {
+		errs.add(field, errScalarRange)
+	}
+}
+
+// Shape returns the shape this input proves with, for a circuit compiled for
+// the given number of assets.
+func (input *TransferInput) Shape(assets int) Shape {
+	shape := Shape{
+		Assets:  assets,
+		Inputs:  len(input.Inputs),
+		Outputs: len(input.Outputs),
+	}
+
+	if len(input.Inputs) > 0 {
+		shape.Depth = len(input.Inputs[0].MerklePath)
+	}
+
+	return shape
+}
+
+// ToUTXO converts a validated input to the builder representation.
+func (input *TransferInput) ToUTXO() *builder.UTXO {
+	utxo := &builder.UTXO{
+		AuditPublicKey: input.AuditPublicKey,
+	}
+
+	for i := range input.Inputs {
+		note := &input.Inputs[i]
+
+		utxo.Nullifier = append(utxo.Nullifier, builder.Nullifier{
+			Commitment:      note.Note,
+			SpentPrivateKey: note.SpentPrivateKey,
+		})
+		utxo.MerkleProof = append(utxo.MerkleProof, builder.NewMerkleProof(note.MerklePath, int(note.MerkleIndex)))
+	}
+
+	for i := range input.Outputs {
+		note := &input.Outputs[i]
+
+		utxo.Commitment = append(utxo.Commitment, note.Note)
+		utxo.SpentKey = append(utxo.SpentKey, note.SpentKey)
+		utxo.EphemeralReceiverSecretKey = append(utxo.EphemeralReceiverSecretKey, note.EphemeralReceiverSecretKey)
+		utxo.EphemeralAuditSecretKey = append(utxo.EphemeralAuditSecretKey, note.EphemeralAuditSecretKey)
+	}
+
+	return utxo
+}
+
+// LoadTransferInput decodes a JSON or binary transfer input, telling them
+// apart by the binary magic, and validates it.
+func LoadTransferInput(data []byte) (*TransferInput, error) {
+	input := &TransferInput{}
+
+	if bytes.HasPrefix(data, transferInputMagic[:]) {
+		if err := input.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := input.UnmarshalJSON(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	return input, nil
+}
+
+func ReadTransferInput(path string) (*TransferInput, error) {
+	var data []byte
+	var err error
+
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadTransferInput(data)
+}
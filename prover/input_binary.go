@@ -0,0 +1,274 @@
+package prover
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hide-pay/builder"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+)
+
+// The binary layout is the magic, a version byte, the Merkle path length as a
+// byte, the input and output counts as big-endian uint16, then:
+//
+//	per input:  note, spent private key, index (uint64), path
+//	per output: note, spent key, ephemeral receiver and audit secret keys
+//	receiver public key, audit public key
+//
+// Notes are their eight fields in declaration order, points are X then Y, and
+// every element or scalar is 32 big-endian bytes.
+var transferInputMagic = [4]byte{'A', 'Z', 'T', 'I'}
+
+const transferInputHeaderSize = len(transferInputMagic) + 1 + 1 + 2 + 2
+
+func (input *TransferInput) MarshalBinary() ([]byte, error) {
+	if len(input.Inputs) == 0 {
+		return nil, fmt.Errorf("transfer input has no inputs")
+	}
+
+	depth := len(input.Inputs[0].MerklePath)
+	if depth > 0xff {
+		return nil, fmt.Errorf("merkle path length %d does not fit the binary format", depth)
+	}
+
+	if len(input.Inputs) > 0xffff || len(input.Outputs) > 0xffff {
+		return nil, fmt.Errorf("%d inputs and %d outputs do not fit the binary format", len(input.Inputs), len(input.Outputs))
+	}
+
+	data := make([]byte, 0, transferInputHeaderSize)
+	data = append(data, transferInputMagic[:]...)
+	data = append(data, byte(input.Version), byte(depth))
+	data = binary.BigEndian.AppendUint16(data, uint16(len(input.Inputs)))
+	data = binary.BigEndian.AppendUint16(data, uint16(len(input.Outputs)))
+
+	for i := range input.Inputs {
+		note := &input.Inputs[i]
+
+		if len(note.MerklePath) != depth {
+			return nil, fmt.Errorf("inputs[%d].merkleProof.path: %w", i, errMerkleDepth)
+		}
+
+		data = appendNote(data, &note.Note)
+		data = appendElement(data, &note.SpentPrivateKey)
+		data = binary.BigEndian.AppendUint64(data, note.MerkleIndex)
+		for j := range note.MerklePath {
+			data = appendElement(data, &note.MerklePath[j])
+		}
+	}
+
+	for i := range input.Outputs {
+		note := &input.Outputs[i]
+
+		data = appendNote(data, &note.Note)
+		data = appendElement(data, &note.SpentKey)
+
+		var err error
+		if data, err = appendScalar(data, &note.EphemeralReceiverSecretKey); err != nil {
+			return nil, fmt.Errorf("outputs[%d].ephemeralReceiverSecretKey: %w", i, err)
+		}
+		if data, err = appendScalar(data, &note.EphemeralAuditSecretKey); err != nil {
+			return nil, fmt.Errorf("outputs[%d].ephemeralAuditSecretKey: %w", i, err)
+		}
+	}
+
+	data = appendPoint(data, &input.AuditPublicKey)
+
+	return data, nil
+}
+
+// UnmarshalBinary decodes every field and reports all malformed ones in an
+// *InputError. It does not run Validate.
+func (input *TransferInput) UnmarshalBinary(data []byte) error {
+	if len(data) < transferInputHeaderSize || [4]byte(data[:4]) != transferInputMagic {
+		return fmt.Errorf("not a binary transfer input")
+	}
+
+	var errs fieldErrors
+
+	r := &binaryReader{data: data, offset: len(transferInputMagic), errs: &errs}
+
+	decoded := TransferInput{
+		Version: int(r.uint8("version")),
+	}
+
+	if decoded.Version != TransferInputVersion {
+		errs.add("version", errUnsupportedVer)
+		return errs.err()
+	}
+
+	depth := int(r.uint8("depth"))
+	decoded.Inputs = make([]InputNote, r.uint16("inputs"))
+	decoded.Outputs = make([]OutputNote, r.uint16("outputs"))
+
+	for i := range decoded.Inputs {
+		if r.truncated {
+			break
+		}
+
+		note := &decoded.Inputs[i]
+		field := fmt.Sprintf("inputs[%d]", i)
+
+		note.Note = r.note(field + ".note")
+		note.SpentPrivateKey = r.element(field + ".spentPrivateKey")
+		note.MerkleIndex = r.uint64(field + ".merkleProof.index")
+		note.MerklePath = make([]fr.Element, depth)
+		for j := range note.MerklePath {
+			note.MerklePath[j] = r.element(fmt.Sprintf("%s.merkleProof.path[%d]", field, j))
+		}
+	}
+
+	for i := range decoded.Outputs {
+		note := &decoded.Outputs[i]
+		field := fmt.Sprintf("outputs[%d]", i)
+
+		note.Note = r.note(field + ".note")
+		note.SpentKey = r.element(field + ".spentKey")
+		note.EphemeralReceiverSecretKey = r.scalar(field + ".ephemeralReceiverSecretKey")
+		note.EphemeralAuditSecretKey = r.scalar(field + ".ephemeralAuditSecretKey")
+	}
+
+	decoded.AuditPublicKey = r.point("auditPublicKey")
+
+	if !r.truncated && r.offset != len(data) {
+		errs.add("data", fmt.Errorf("%d trailing bytes", len(data)-r.offset))
+	}
+
+	if err := errs.err(); err != nil {
+		return err
+	}
+
+	*input = decoded
+
+	return nil
+}
+
+func appendElement(data []byte, element *fr.Element) []byte {
+	bytes := element.Bytes()
+	return append(data, bytes[:]...)
+}
+
+func appendPoint(data []byte, point *twistededwardbn254.PointAffine) []byte {
+	data = appendElement(data, &point.X)
+	return appendElement(data, &point.Y)
+}
+
+func appendScalar(data []byte, scalar *big.Int) ([]byte, error) {
+	if scalar.Sign() < 0 || scalar.BitLen() > 256 {
+		return nil, fmt.Errorf("does not fit 32 bytes")
+	}
+
+	var bytes [32]byte
+	scalar.FillBytes(bytes[:])
+
+	return append(data, bytes[:]...), nil
+}
+
+func appendNote(data []byte, note *builder.Commitment) []byte {
+	data = appendElement(data, &note.Asset)
+	data = appendElement(data, &note.Amount)
+	data = appendPoint(data, &note.OwnerPubKey)
+	data = appendElement(data, &note.SpentAddress)
+	data = appendPoint(data, &note.ViewPubKey)
+	data = appendPoint(data, &note.AuditPubKey)
+	data = appendElement(data, &note.FreezeFlag)
+	return appendElement(data, &note.Blinding)
+}
+
+// binaryReader reads fixed-size fields, recording a field error for the first
+// field that runs past the end of the data and ignoring every later read.
+type binaryReader struct {
+	data      []byte
+	offset    int
+	errs      *fieldErrors
+	truncated bool
+}
+
+func (r *binaryReader) next(field string, size int) []byte {
+	if r.truncated {
+		return nil
+	}
+
+	if len(r.data)-r.offset < size {
+		r.errs.add(field, errTruncated)
+		r.truncated = true
+		return nil
+	}
+
+	b := r.data[r.offset : r.offset+size]
+	r.offset += size
+
+	return b
+}
+
+func (r *binaryReader) uint8(field string) uint8 {
+	b := r.next(field, 1)
+	if b == nil {
+		return 0
+	}
+
+	return b[0]
+}
+
+func (r *binaryReader) uint16(field string) uint16 {
+	b := r.next(field, 2)
+	if b == nil {
+		return 0
+	}
+
+	return binary.BigEndian.Uint16(b)
+}
+
+func (r *binaryReader) uint64(field string) uint64 {
+	b := r.next(field, 8)
+	if b == nil {
+		return 0
+	}
+
+	return binary.BigEndian.Uint64(b)
+}
+
+func (r *binaryReader) element(field string) fr.Element {
+	b := r.next(field, fr.Bytes)
+	if b == nil {
+		return fr.Element{}
+	}
+
+	var element fr.Element
+	if err := element.SetBytesCanonical(b); err != nil {
+		r.errs.add(field, errNotCanonical)
+	}
+
+	return element
+}
+
+func (r *binaryReader) scalar(field string) big.Int {
+	var scalar big.Int
+
+	if b := r.next(field, 32); b != nil {
+		scalar.SetBytes(b)
+	}
+
+	return scalar
+}
+
+func (r *binaryReader) point(field string) twistededwardbn254.PointAffine {
+	return twistededwardbn254.PointAffine{
+		X: r.element(field + ".x"),
+		Y: r.element(field + ".y"),
+	}
+}
+
+func (r *binaryReader) note(field string) builder.Commitment {
+	return builder.Commitment{
+		Asset:        r.element(field + ".asset"),
+		Amount:       r.element(field + ".amount"),
+		OwnerPubKey:  r.point(field + ".ownerPubKey"),
+		SpentAddress: r.element(field + ".spentAddress"),
+		ViewPubKey:   r.point(field + ".viewPubKey"),
+		AuditPubKey:  r.point(field + ".auditPubKey"),
+		FreezeFlag:   r.element(field + ".freezeFlag"),
+		Blinding:     r.element(field + ".blinding"),
+	}
+}
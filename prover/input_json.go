@@ -0,0 +1,232 @@
+package prover
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hide-pay/builder"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+)
+
+type pointJSON struct {
+	X string `json:"x"`
+	Y string `json:"y"`
+}
+
+type noteJSON struct {
+	Asset        string    `json:"asset"`
+	Amount       string    `json:"amount"`
+	OwnerPubKey  pointJSON `json:"ownerPubKey"`
+	SpentAddress string    `json:"spentAddress"`
+	ViewPubKey   pointJSON `json:"viewPubKey"`
+	AuditPubKey  pointJSON `json:"auditPubKey"`
+	FreezeFlag   string    `json:"freezeFlag"`
+	Blinding     string    `json:"blinding"`
+}
+
+type merkleProofJSON struct {
+	Index uint64   `json:"index"`
+	Path  []string `json:"path"`
+}
+
+type inputNoteJSON struct {
+	Note            noteJSON        `json:"note"`
+	SpentPrivateKey string          `json:"spentPrivateKey"`
+	MerkleProof     merkleProofJSON `json:"merkleProof"`
+}
+
+type outputNoteJSON struct {
+	Note                       noteJSON `json:"note"`
+	SpentKey                   string   `json:"spentKey"`
+	EphemeralReceiverSecretKey string   `json:"ephemeralReceiverSecretKey"`
+	EphemeralAuditSecretKey    string   `json:"ephemeralAuditSecretKey"`
+}
+
+// transferInputJSON encodes field elements and scalars as 0x-prefixed hex;
+// decimal strings are accepted as well.
+type transferInputJSON struct {
+	Version        int              `json:"version"`
+	Inputs         []inputNoteJSON  `json:"inputs"`
+	Outputs        []outputNoteJSON `json:"outputs"`
+	AuditPublicKey pointJSON        `json:"auditPublicKey"`
+}
+
+func (input *TransferInput) MarshalJSON() ([]byte, error) {
+	raw := transferInputJSON{
+		Version:        input.Version,
+		Inputs:         make([]inputNoteJSON, len(input.Inputs)),
+		Outputs:        make([]outputNoteJSON, len(input.Outputs)),
+		AuditPublicKey: encodePointJSON(&input.AuditPublicKey),
+	}
+
+	for i := range input.Inputs {
+		note := &input.Inputs[i]
+
+		path := make([]string, len(note.MerklePath))
+		for j := range note.MerklePath {
+			path[j] = encodeElementJSON(&note.MerklePath[j])
+		}
+
+		raw.Inputs[i] = inputNoteJSON{
+			Note:            encodeNoteJSON(&note.Note),
+			SpentPrivateKey: encodeElementJSON(&note.SpentPrivateKey),
+			MerkleProof: merkleProofJSON{
+				Index: note.MerkleIndex,
+				Path:  path,
+			},
+		}
+	}
+
+	for i := range input.Outputs {
+		note := &input.Outputs[i]
+
+		raw.Outputs[i] = outputNoteJSON{
+			Note:                       encodeNoteJSON(&note.Note),
+			SpentKey:                   encodeElementJSON(&note.SpentKey),
+			EphemeralReceiverSecretKey: "0x" + note.EphemeralReceiverSecretKey.Text(16),
+			EphemeralAuditSecretKey:    "0x" + note.EphemeralAuditSecretKey.Text(16),
+		}
+	}
+
+	return json.Marshal(raw)
+}
+
+// UnmarshalJSON decodes every field and reports all malformed ones in an
+// *InputError. It does not run Validate.
+func (input *TransferInput) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var raw transferInputJSON
+	if err := decoder.Decode(&raw); err != nil {
+		return fmt.Errorf("invalid transfer input JSON: %w", err)
+	}
+
+	var errs fieldErrors
+
+	decoded := TransferInput{
+		Version:        raw.Version,
+		Inputs:         make([]InputNote, len(raw.Inputs)),
+		Outputs:        make([]OutputNote, len(raw.Outputs)),
+		AuditPublicKey: decodePointJSON(&errs, "auditPublicKey", raw.AuditPublicKey),
+	}
+
+	for i := range raw.Inputs {
+		note := &raw.Inputs[i]
+		field := fmt.Sprintf("inputs[%d]", i)
+
+		path := make([]fr.Element, len(note.MerkleProof.Path))
+		for j := range note.MerkleProof.Path {
+			path[j] = decodeElementJSON(&errs, fmt.Sprintf("%s.merkleProof.path[%d]", field, j), note.MerkleProof.Path[j])
+		}
+
+		decoded.Inputs[i] = InputNote{
+			Note:            decodeNoteJSON(&errs, field+".note", &note.Note),
+			SpentPrivateKey: decodeElementJSON(&errs, field+".spentPrivateKey", note.SpentPrivateKey),
+			MerkleIndex:     note.MerkleProof.Index,
+			MerklePath:      path,
+		}
+	}
+
+	for i := range raw.Outputs {
+		note := &raw.Outputs[i]
+		field := fmt.Sprintf("outputs[%d]", i)
+
+		decoded.Outputs[i] = OutputNote{
+			Note:                       decodeNoteJSON(&errs, field+".note", &note.Note),
+			SpentKey:                   decodeElementJSON(&errs, field+".spentKey", note.SpentKey),
+			EphemeralReceiverSecretKey: decodeScalarJSON(&errs, field+".ephemeralReceiverSecretKey", note.EphemeralReceiverSecretKey),
+			EphemeralAuditSecretKey:    decodeScalarJSON(&errs, field+".ephemeralAuditSecretKey", note.EphemeralAuditSecretKey),
+		}
+	}
+
+	if err := errs.err(); err != nil {
+		return err
+	}
+
+	*input = decoded
+
+	return nil
+}
+
+func encodeElementJSON(element *fr.Element) string {
+	return "0x" + element.Text(16)
+}
+
+func encodePointJSON(point *twistededwardbn254.PointAffine) pointJSON {
+	return pointJSON{X: encodeElementJSON(&point.X), Y: encodeElementJSON(&point.Y)}
+}
+
+func encodeNoteJSON(note *builder.Commitment) noteJSON {
+	return noteJSON{
+		Asset:        encodeElementJSON(&note.Asset),
+		Amount:       encodeElementJSON(&note.Amount),
+		OwnerPubKey:  encodePointJSON(&note.OwnerPubKey),
+		SpentAddress: encodeElementJSON(&note.SpentAddress),
+		ViewPubKey:   encodePointJSON(&note.ViewPubKey),
+		AuditPubKey:  encodePointJSON(&note.AuditPubKey),
+		FreezeFlag:   encodeElementJSON(&note.FreezeFlag),
+		Blinding:     encodeElementJSON(&note.Blinding),
+	}
+}
+
+func decodeElementJSON(errs *fieldErrors, field string, s string) fr.Element {
+	if s == "" {
+		errs.add(field, errRequired)
+		return fr.Element{}
+	}
+
+	value, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		errs.add(field, fmt.Errorf("%q is not a decimal or 0x-prefixed hex number", s))
+		return fr.Element{}
+	}
+
+	if value.Sign() < 0 || value.Cmp(fr.Modulus()) >= 0 {
+		errs.add(field, errNotCanonical)
+		return fr.Element{}
+	}
+
+	var element fr.Element
+	element.SetBigInt(value)
+
+	return element
+}
+
+func decodeScalarJSON(errs *fieldErrors, field string, s string) big.Int {
+	if s == "" {
+		errs.add(field, errRequired)
+		return big.Int{}
+	}
+
+	value, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		errs.add(field, fmt.Errorf("%q is not a decimal or 0x-prefixed hex number", s))
+		return big.Int{}
+	}
+
+	return *value
+}
+
+func decodePointJSON(errs *fieldErrors, field string, raw pointJSON) twistededwardbn254.PointAffine {
+	return twistededwardbn254.PointAffine{
+		X: decodeElementJSON(errs, field+".x", raw.X),
+		Y: decodeElementJSON(errs, field+".y", raw.Y),
+	}
+}
+
+func decodeNoteJSON(errs *fieldErrors, field string, raw *noteJSON) builder.Commitment {
+	return builder.Commitment{
+		Asset:        decodeElementJSON(errs, field+".asset", raw.Asset),
+		Amount:       decodeElementJSON(errs, field+".amount", raw.Amount),
+		OwnerPubKey:  decodePointJSON(errs, field+".ownerPubKey", raw.OwnerPubKey),
+		SpentAddress: decodeElementJSON(errs, field+".spentAddress", raw.SpentAddress),
+		ViewPubKey:   decodePointJSON(errs, field+".viewPubKey", raw.ViewPubKey),
+		AuditPubKey:  decodePointJSON(errs, field+".auditPubKey", raw.AuditPubKey),
+		FreezeFlag:   decodeElementJSON(errs, field+".freezeFlag", raw.FreezeFlag),
+		Blinding:     decodeElementJSON(errs, field+".blinding", raw.Blinding),
+	}
+}
@@ -0,0 +1,174 @@
+package prover_test
+
+import (
+	"encoding/json"
+	"errors"
+	"hide-pay/builder"
+	"hide-pay/prover"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTransferInput(t *testing.T) *prover.TransferInput {
+	utxo, err := builder.GenerateUTXO(1, 6, 2, 3)
+	require.NoError(t, err)
+
+	input, err := prover.NewTransferInput(utxo)
+	require.NoError(t, err)
+
+	return input
+}
+
+func fieldsOf(t *testing.T, err error) []string {
+	var inputErr *prover.InputError
+	require.True(t, errors.As(err, &inputErr), "expected an InputError, got %v", err)
+
+	fields := make([]string, len(inputErr.Errors))
+	for i := range inputErr.Errors {
+		fields[i] = inputErr.Errors[i].Field
+	}
+
+	return fields
+}
+
+func TestTransferInput_JSONRoundTrip(t *testing.T) {
+	input := newTransferInput(t)
+
+	data, err := json.Marshal(input)
+	require.NoError(t, err)
+
+	loaded, err := prover.LoadTransferInput(data)
+	require.NoError(t, err)
+
+	again, err := json.Marshal(loaded)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(data), string(again))
+
+	expected, err := input.ToUTXO().BuildAndCheck()
+	require.NoError(t, err)
+	actual, err := loaded.ToUTXO().BuildAndCheck()
+	require.NoError(t, err)
+
+	assert.Equal(t, expected.Root, actual.Root)
+	assert.Equal(t, expected.Nullifiers, actual.Nullifiers)
+	assert.Equal(t, expected.Commitments, actual.Commitments)
+}
+
+func TestTransferInput_BinaryRoundTrip(t *testing.T) {
+	input := newTransferInput(t)
+
+	data, err := input.MarshalBinary()
+	require.NoError(t, err)
+
+	loaded, err := prover.LoadTransferInput(data)
+	require.NoError(t, err)
+
+	again, err := loaded.MarshalBinary()
+	require.NoError(t, err)
+	assert.Equal(t, data, again)
+
+	shape := loaded.Shape(1)
+	assert.Equal(t, prover.Shape{Assets: 1, Depth: 6, Inputs: 2, Outputs: 3}, shape)
+
+	_, err = prover.NewWitness(shape, loaded.ToUTXO())
+	require.NoError(t, err)
+}
+
+func TestTransferInput_JSONFieldErrors(t *testing.T) {
+	input := newTransferInput(t)
+
+	data, err := json.Marshal(input)
+	require.NoError(t, err)
+
+	var raw map[string]any
+	require.NoError(t, json.Unmarshal(data, &raw))
+
+	inputs := raw["inputs"].([]any)
+	first := inputs[0].(map[string]any)
+	first["spentPrivateKey"] = fr.Modulus().String()
+
+	second := inputs[1].(map[string]any)
+	path := second["merkleProof"].(map[string]any)["path"].([]any)
+	path[3] = "not a number"
+
+	data, err = json.Marshal(raw)
+	require.NoError(t, err)
+
+	_, err = prover.LoadTransferInput(data)
+	require.Error(t, err)
+	assert.ElementsMatch(t, []string{"inputs[0].spentPrivateKey", "inputs[1].merkleProof.path[3]"}, fieldsOf(t, err))
+	assert.Contains(t, err.Error(), "is not a canonical field element")
+
+	// Unknown fields are rejected rather than ignored
+	raw["change"] = "0x1"
+	data, err = json.Marshal(raw)
+	require.NoError(t, err)
+
+	_, err = prover.LoadTransferInput(data)
+	require.ErrorContains(t, err, "unknown field")
+}
+
+func TestTransferInput_Validate(t *testing.T) {
+	input := newTransferInput(t)
+	require.NoError(t, input.Validate())
+
+	input.Version = 3
+	input.Inputs[1].MerklePath[0] = fr.NewElement(1)
+	input.Inputs[0].MerkleIndex = 1 << 5
+	input.Outputs[2].SpentKey = fr.NewElement(7)
+	input.Outputs[0].EphemeralAuditSecretKey.SetInt64(0)
+	input.AuditPublicKey.Y.SetOne()
+
+	assert.ElementsMatch(t, []string{
+		"version",
+		"inputs[1].merkleProof.path[0]",
+		"inputs[0].merkleProof.index",
+		"outputs[2].spentKey",
+		"outputs[0].ephemeralAuditSecretKey",
+		"auditPublicKey",
+		// Outputs no longer commit to the audit key
+		"outputs[0].note.auditPubKey",
+		"outputs[1].note.auditPubKey",
+		"outputs[2].note.auditPubKey",
+	}, fieldsOf(t, input.Validate()))
+
+	input = newTransferInput(t)
+	input.Inputs[1].MerklePath = input.Inputs[1].MerklePath[:4]
+
+	assert.Equal(t, []string{"inputs[1].merkleProof.path"}, fieldsOf(t, input.Validate()))
+
+	input = newTransferInput(t)
+	input.Outputs[1].Note.AuditPubKey = input.Outputs[1].Note.ViewPubKey
+
+	assert.Equal(t, []string{"outputs[1].note.auditPubKey"}, fieldsOf(t, input.Validate()))
+}
+
+func TestTransferInput_BinaryFieldErrors(t *testing.T) {
+	input := newTransferInput(t)
+
+	data, err := input.MarshalBinary()
+	require.NoError(t, err)
+
+	_, err = prover.LoadTransferInput(data[:len(data)-8])
+	assert.Equal(t, []string{"auditPublicKey.y"}, fieldsOf(t, err))
+	assert.ErrorContains(t, err, "unexpected end of data")
+
+	_, err = prover.LoadTransferInput(append(append([]byte{}, data...), 0))
+	assert.Equal(t, []string{"data"}, fieldsOf(t, err))
+
+	// The amount of the first input note sits after the header and its asset
+	tampered := append([]byte{}, data...)
+	copy(tampered[10+32:10+64], fr.Modulus().FillBytes(make([]byte, 32)))
+
+	_, err = prover.LoadTransferInput(tampered)
+	assert.Equal(t, []string{"inputs[0].note.amount"}, fieldsOf(t, err))
+
+	tampered = append([]byte{}, data...)
+	tampered[4] = 3
+
+	_, err = prover.LoadTransferInput(tampered)
+	assert.Equal(t, []string{"version"}, fieldsOf(t, err))
+}
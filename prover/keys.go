@@ -0,0 +1,115 @@
+package prover
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	plonkbn254 "github.com/consensys/gnark/backend/plonk/bn254"
+	"github.com/consensys/gnark/constraint"
+)
+
+// ErrKeyMismatch reports a key that was not set up for the constraint system
+// it is used with.
+var ErrKeyMismatch = errors.New("key does not match the constraint system")
+
+// CheckProvingKey fails with ErrKeyMismatch when pk cannot have been set up
+// for cs. Keys of circuits with the same wire and constraint counts are not
+// told apart; the cache also checks file hashes for that.
+func CheckProvingKey(cs constraint.ConstraintSystem, pk ProvingKey) error {
+	backend, err := BackendOf(cs)
+	if err != nil {
+		return err
+	}
+
+	nbCommitments := len(cs.GetCommitments().CommitmentIndexes())
+
+	switch key := pk.(type) {
+	case *groth16bn254.ProvingKey:
+		if backend != Groth16 {
+			return fmt.Errorf("%w: groth16 proving key for a %s constraint system", ErrKeyMismatch, backend)
+		}
+
+		nbWires := cs.GetNbPublicVariables() + cs.GetNbSecretVariables() + cs.GetNbInternalVariables()
+		if len(key.InfinityA) != nbWires {
+			return fmt.Errorf("%w: proving key has %d wires, constraint system has %d", ErrKeyMismatch, len(key.InfinityA), nbWires)
+		}
+
+		domain := fft.NewDomain(uint64(cs.GetNbConstraints()))
+		if key.Domain.Cardinality != domain.Cardinality {
+			return fmt.Errorf("%w: proving key domain has size %d, constraint system needs %d", ErrKeyMismatch, key.Domain.Cardinality, domain.Cardinality)
+		}
+
+		if len(key.CommitmentKeys) != nbCommitments {
+			return fmt.Errorf("%w: proving key has %d commitment keys, constraint system has %d commitments", ErrKeyMismatch, len(key.CommitmentKeys), nbCommitments)
+		}
+
+		return nil
+	case *plonkbn254.ProvingKey:
+		if backend != Plonk {
+			return fmt.Errorf("%w: plonk proving key for a %s constraint system", ErrKeyMismatch, backend)
+		}
+
+		return checkPlonkVerifyingKey(cs, key.Vk)
+	default:
+		return fmt.Errorf("%w: unsupported proving key %T", ErrKeyMismatch, pk)
+	}
+}
+
+// CheckVerifyingKey fails with ErrKeyMismatch when vk cannot have been set up
+// for cs.
+func CheckVerifyingKey(cs constraint.ConstraintSystem, vk VerifyingKey) error {
+	backend, err := BackendOf(cs)
+	if err != nil {
+		return err
+	}
+
+	switch key := vk.(type) {
+	case *groth16bn254.VerifyingKey:
+		if backend != Groth16 {
+			return fmt.Errorf("%w: groth16 verifying key for a %s constraint system", ErrKeyMismatch, backend)
+		}
+
+		if nbPublic := NbPublicInputs(cs); NbPublic(vk) != nbPublic {
+			return fmt.Errorf("%w: verifying key has %d public inputs, constraint system has %d", ErrKeyMismatch, NbPublic(vk), nbPublic)
+		}
+
+		if nbCommitments := len(cs.GetCommitments().CommitmentIndexes()); len(key.CommitmentKeys) != nbCommitments {
+			return fmt.Errorf("%w: verifying key has %d commitment keys, constraint system has %d commitments", ErrKeyMismatch, len(key.CommitmentKeys), nbCommitments)
+		}
+
+		return nil
+	case *plonkbn254.VerifyingKey:
+		if backend != Plonk {
+			return fmt.Errorf("%w: plonk verifying key for a %s constraint system", ErrKeyMismatch, backend)
+		}
+
+		return checkPlonkVerifyingKey(cs, key)
+	default:
+		return fmt.Errorf("%w: unsupported verifying key %T", ErrKeyMismatch, vk)
+	}
+}
+
+func checkPlonkVerifyingKey(cs constraint.ConstraintSystem, vk *plonkbn254.VerifyingKey) error {
+	if vk == nil {
+		return fmt.Errorf("%w: proving key has no verifying key", ErrKeyMismatch)
+	}
+
+	// Public inputs take the first rows of a PLONK trace
+	nbPublic := NbPublicInputs(cs)
+	if int(vk.NbPublicVariables) != nbPublic {
+		return fmt.Errorf("%w: verifying key has %d public inputs, constraint system has %d", ErrKeyMismatch, vk.NbPublicVariables, nbPublic)
+	}
+
+	if size := ecc.NextPowerOfTwo(uint64(cs.GetNbConstraints() + nbPublic)); vk.Size != size {
+		return fmt.Errorf("%w: verifying key domain has size %d, constraint system needs %d", ErrKeyMismatch, vk.Size, size)
+	}
+
+	if nbCommitments := len(cs.GetCommitments().CommitmentIndexes()); len(vk.CommitmentConstraintIndexes) != nbCommitments {
+		return fmt.Errorf("%w: verifying key has %d commitments, constraint system has %d", ErrKeyMismatch, len(vk.CommitmentConstraintIndexes), nbCommitments)
+	}
+
+	return nil
+}
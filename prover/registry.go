@@ -0,0 +1,161 @@
+package prover
+
+import (
+	"fmt"
+	"hide-pay/circuits"
+	"sort"
+
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+)
+
+// Kind is the statement a circuit variant proves.
+type Kind string
+
+const (
+	KindTransfer     Kind = "transfer"
+	KindDeposit      Kind = "deposit"
+	KindWithdraw     Kind = "withdraw"
+	KindFreeze       Kind = "freeze"
+	KindMerkleUpdate Kind = "merkle-update"
+)
+
+// DefaultDepth is the depth of the on-chain commitment tree, leaf included.
+const DefaultDepth = 34
+
+// Variant is a named circuit with the parameters it is compiled for. Only the
+// Shape fields a kind uses are set: transfers use all of them, deposits none,
+// and the other kinds only Depth.
+type Variant struct {
+	Name  string
+	Kind  Kind
+	Shape Shape
+}
+
+var registry = map[string]Variant{}
+
+func init() {
+	for _, variant := range []Variant{
+		{Name: "transfer-4x16", Kind: KindTransfer, Shape: Shape{Assets: 1, Depth: DefaultDepth, Inputs: 4, Outputs: 16}},
+		{Name: "transfer-16x16", Kind: KindTransfer, Shape: Shape{Assets: 1, Depth: DefaultDepth, Inputs: 16, Outputs: 16}},
+		{Name: "deposit", Kind: KindDeposit},
+		{Name: "withdraw", Kind: KindWithdraw, Shape: Shape{Depth: DefaultDepth}},
+		{Name: "freeze", Kind: KindFreeze, Shape: Shape{Depth: DefaultDepth}},
+		{Name: "merkle-update", Kind: KindMerkleUpdate, Shape: Shape{Depth: DefaultDepth}},
+	} {
+		if err := RegisterVariant(variant); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterVariant adds a variant to the registry. Names are unique.
+func RegisterVariant(variant Variant) error {
+	if variant.Name == "" {
+		return fmt.Errorf("variant name is required")
+	}
+
+	if _, ok := registry[variant.Name]; ok {
+		return fmt.Errorf("variant %q is already registered", variant.Name)
+	}
+
+	if err := variant.Validate(); err != nil {
+		return fmt.Errorf("invalid variant %q: %w", variant.Name, err)
+	}
+
+	registry[variant.Name] = variant
+
+	return nil
+}
+
+// LookupVariant returns the registered variant called name.
+func LookupVariant(name string) (Variant, error) {
+	variant, ok := registry[name]
+	if !ok {
+		return Variant{}, fmt.Errorf("unknown circuit variant %q", name)
+	}
+
+	return variant, nil
+}
+
+// Variants returns the registered variants sorted by name.
+func Variants() []Variant {
+	variants := make([]Variant, 0, len(registry))
+	for _, variant := range registry {
+		variants = append(variants, variant)
+	}
+
+	sort.Slice(variants, func(i, j int) bool {
+		return variants[i].Name < variants[j].Name
+	})
+
+	return variants
+}
+
+func (variant Variant) Validate() error {
+	switch variant.Kind {
+	case KindTransfer:
+		return variant.Shape.Validate()
+	case KindDeposit:
+		if variant.Shape != (Shape{}) {
+			return fmt.Errorf("deposit takes no parameters, got %s", variant.Shape)
+		}
+		return nil
+	case KindWithdraw, KindFreeze, KindMerkleUpdate:
+		if variant.Shape != (Shape{Depth: variant.Shape.Depth}) {
+			return fmt.Errorf("%s only takes a depth, got %s", variant.Kind, variant.Shape)
+		}
+		if variant.Shape.Depth < 2 {
+			return fmt.Errorf("depth must be at least 2, got %d", variant.Shape.Depth)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown circuit kind %q", variant.Kind)
+	}
+}
+
+func (variant Variant) String() string {
+	switch variant.Kind {
+	case KindTransfer:
+		return fmt.Sprintf("%s (%s %s)", variant.Name, variant.Kind, variant.Shape)
+	case KindDeposit:
+		return fmt.Sprintf("%s (%s)", variant.Name, variant.Kind)
+	default:
+		return fmt.Sprintf("%s (%s depth=%d)", variant.Name, variant.Kind, variant.Shape.Depth)
+	}
+}
+
+// Circuit returns the empty circuit of the variant.
+func (variant Variant) Circuit() (frontend.Circuit, error) {
+	if err := variant.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch variant.Kind {
+	case KindTransfer:
+		return variant.Shape.Circuit(), nil
+	case KindDeposit:
+		return &circuits.DepositCircuit{}, nil
+	case KindWithdraw:
+		return circuits.NewWithdrawCircuit(variant.Shape.Depth), nil
+	case KindFreeze:
+		return circuits.NewFreezeCircuit(variant.Shape.Depth), nil
+	default:
+		return circuits.NewMerkleUpdateCircuit(variant.Shape.Depth), nil
+	}
+}
+
+// Compile builds the constraint system of the variant for backend.
+func (variant Variant) Compile(backend Backend) (constraint.ConstraintSystem, error) {
+	circuit, err := variant.Circuit()
+	if err != nil {
+		return nil, err
+	}
+
+	cs, err := backend.Compile(circuit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile %s: %w", variant.Name, err)
+	}
+
+	return cs, nil
+}
@@ -0,0 +1,49 @@
+package prover_test
+
+import (
+	"hide-pay/prover"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	names := []string{}
+	for _, variant := range prover.Variants() {
+		names = append(names, variant.Name)
+	}
+	assert.Equal(t, []string{"deposit", "freeze", "merkle-update", "transfer-16x16", "transfer-4x16", "withdraw"}, names)
+
+	variant, err := prover.LookupVariant("transfer-4x16")
+	require.NoError(t, err)
+	assert.Equal(t, prover.Shape{Assets: 1, Depth: prover.DefaultDepth, Inputs: 4, Outputs: 16}, variant.Shape)
+
+	_, err = prover.LookupVariant("transfer-1x1")
+	require.ErrorContains(t, err, "unknown circuit variant")
+
+	require.ErrorContains(t, prover.RegisterVariant(variant), "already registered")
+	require.Error(t, prover.RegisterVariant(prover.Variant{Name: "withdraw-shallow", Kind: prover.KindWithdraw, Shape: prover.Shape{Depth: 1}}))
+	require.Error(t, prover.RegisterVariant(prover.Variant{Name: "deposit-wide", Kind: prover.KindDeposit, Shape: prover.Shape{Outputs: 2}}))
+	require.Error(t, prover.RegisterVariant(prover.Variant{Name: "swap", Kind: "swap"}))
+}
+
+func TestVariant_Compile(t *testing.T) {
+	for _, variant := range []prover.Variant{
+		{Name: "transfer", Kind: prover.KindTransfer, Shape: prover.Shape{Assets: 1, Depth: 4, Inputs: 1, Outputs: 1}},
+		{Name: "deposit", Kind: prover.KindDeposit},
+		{Name: "withdraw", Kind: prover.KindWithdraw, Shape: prover.Shape{Depth: 4}},
+		{Name: "freeze", Kind: prover.KindFreeze, Shape: prover.Shape{Depth: 4}},
+		{Name: "merkle-update", Kind: prover.KindMerkleUpdate, Shape: prover.Shape{Depth: 4}},
+	} {
+		for _, backend := range []prover.Backend{prover.Groth16, prover.Plonk} {
+			cs, err := variant.Compile(backend)
+			require.NoError(t, err, variant.Name)
+			assert.Positive(t, cs.GetNbConstraints(), variant.Name)
+
+			compiledFor, err := prover.BackendOf(cs)
+			require.NoError(t, err)
+			assert.Equal(t, backend, compiledFor, variant.Name)
+		}
+	}
+}
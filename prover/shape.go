@@ -0,0 +1,55 @@
+package prover
+
+import (
+	"fmt"
+	"hide-pay/circuits"
+
+	"github.com/consensys/gnark/constraint"
+)
+
+// Shape fixes the sizes a transfer circuit is compiled for. Proving keys are
+// only valid for the shape they were set up with.
+type Shape struct {
+	Assets  int `json:"assets,omitempty"`
+	Depth   int `json:"depth,omitempty"`
+	Inputs  int `json:"inputs,omitempty"`
+	Outputs int `json:"outputs,omitempty"`
+}
+
+func (shape Shape) Validate() error {
+	if shape.Assets < 1 {
+		return fmt.Errorf("assets must be at least 1, got %d", shape.Assets)
+	}
+
+	if shape.Depth < 2 {
+		return fmt.Errorf("depth must be at least 2, got %d", shape.Depth)
+	}
+
+	if shape.Inputs < 1 {
+		return fmt.Errorf("inputs must be at least 1, got %d", shape.Inputs)
+	}
+
+	if shape.Outputs < 1 {
+		return fmt.Errorf("outputs must be at least 1, got %d", shape.Outputs)
+	}
+
+	return nil
+}
+
+func (shape Shape) String() string {
+	return fmt.Sprintf("assets=%d depth=%d inputs=%d outputs=%d", shape.Assets, shape.Depth, shape.Inputs, shape.Outputs)
+}
+
+// Circuit returns the empty transfer circuit of this shape.
+func (shape Shape) Circuit() *circuits.UTXOCircuit {
+	return circuits.NewUTXOCircuit(shape.Assets, shape.Depth, shape.Inputs, shape.Outputs)
+}
+
+// Compile builds the constraint system of the transfer circuit for backend.
+func Compile(shape Shape, backend Backend) (constraint.ConstraintSystem, error) {
+	if err := shape.Validate(); err != nil {
+		return nil, err
+	}
+
+	return backend.Compile(shape.Circuit())
+}
@@ -0,0 +1,234 @@
+package prover
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	plonkbn254 "github.com/consensys/gnark/backend/plonk/bn254"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"golang.org/x/crypto/sha3"
+)
+
+const wordSize = 32
+
+// ExportSolidity writes the Solidity verifier contract of vk. A Groth16
+// verifier hashes commitments with Keccak, which Prove and Verify also use.
+func ExportSolidity(vk VerifyingKey, w io.Writer) error {
+	if err := vk.ExportSolidity(w); err != nil {
+		return fmt.Errorf("failed to export solidity verifier: %w", err)
+	}
+
+	return nil
+}
+
+// PublicLayout lists the public inputs of a constraint system in the order
+// its verifier takes them.
+type PublicLayout struct {
+	NbPublic int           `json:"nbPublic"`
+	Fields   []PublicField `json:"fields"`
+}
+
+// PublicField is a run of public inputs, e.g. all nullifiers of a transfer.
+type PublicField struct {
+	Name   string `json:"name"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+}
+
+// NewPublicLayout reads the public inputs of cs from the names gnark keeps
+// for them. Elements of a slice or array form one field named after the
+// circuit field, without the path of the gadgets holding it. Both the R1CS of
+// Groth16 and the sparse R1CS of PLONK are supported.
+func NewPublicLayout(cs constraint.ConstraintSystem) (*PublicLayout, error) {
+	backend, err := BackendOf(cs)
+	if err != nil {
+		return nil, err
+	}
+
+	// csbn254.R1CS and csbn254.SparseR1CS alias one unexported type, so an
+	// assertion on either accepts both. Read the names through the Resolver
+	// every constraint.ConstraintSystem implements instead, public wires first
+	names := make([]string, cs.GetNbPublicVariables())
+	for i := range names {
+		names[i] = cs.VariableToString(i)
+	}
+
+	// An R1CS names its constant one wire first, a sparse R1CS has no such
+	// wire and names the public inputs only
+	if backend == Groth16 {
+		if len(names) == 0 {
+			return nil, fmt.Errorf("constraint system does not name its constant wire")
+		}
+		names = names[1:]
+	}
+
+	nbPublic := NbPublicInputs(cs)
+	if len(names) != nbPublic {
+		return nil, fmt.Errorf("constraint system names %d public inputs, expected %d", len(names), nbPublic)
+	}
+
+	layout := &PublicLayout{NbPublic: nbPublic}
+	for i, name := range names {
+		name = publicFieldName(name)
+
+		if last := len(layout.Fields) - 1; last >= 0 && layout.Fields[last].Name == name {
+			layout.Fields[last].Length++
+			continue
+		}
+
+		layout.Fields = append(layout.Fields, PublicField{Name: name, Offset: i, Length: 1})
+	}
+
+	return layout, nil
+}
+
+// publicFieldName turns a gnark name such as Result_nullifiers_3 into
+// nullifiers.
+func publicFieldName(name string) string {
+	parts := strings.Split(name, "_")
+	for len(parts) > 1 {
+		if _, err := strconv.Atoi(parts[len(parts)-1]); err != nil {
+			break
+		}
+		parts = parts[:len(parts)-1]
+	}
+
+	return parts[len(parts)-1]
+}
+
+// SolidityProof is a proof encoded for the verifier ExportSolidity writes.
+// Proof holds the arguments before the public inputs, which is what HidePay
+// takes per transaction since it rebuilds the inputs from the transaction;
+// Calldata is the complete call to the verifier.
+type SolidityProof struct {
+	Backend  Backend  `json:"backend"`
+	Proof    string   `json:"proof"`
+	Input    []string `json:"input"`
+	Calldata string   `json:"calldata"`
+}
+
+// NewSolidityProof encodes proof and its public witness as calldata of
+//
+//	verifyProof(uint256[8] proof, [uint256[2n] commitments, uint256[2] commitmentPok,] uint256[N] input)
+//
+// for Groth16, where the bracketed arguments are only present for circuits
+// with commitments, and of
+//
+//	Verify(bytes proof, uint256[] public_inputs)
+//
+// for PLONK.
+func NewSolidityProof(proof Proof, public witness.Witness) (*SolidityProof, error) {
+	vector, ok := public.Vector().(fr.Vector)
+	if !ok {
+		return nil, fmt.Errorf("unsupported witness %T", public.Vector())
+	}
+
+	input := make([][]byte, len(vector))
+	for i := range vector {
+		word := vector[i].Bytes()
+		input[i] = word[:]
+	}
+
+	var backend Backend
+	var proofBytes, calldata []byte
+
+	switch concrete := proof.(type) {
+	case *groth16bn254.Proof:
+		backend = Groth16
+		proofBytes, calldata = encodeGroth16(concrete, input)
+	case *plonkbn254.Proof:
+		backend = Plonk
+		proofBytes, calldata = encodePlonk(concrete, input)
+	default:
+		return nil, fmt.Errorf("unsupported proof %T", proof)
+	}
+
+	result := &SolidityProof{
+		Backend:  backend,
+		Proof:    "0x" + hex.EncodeToString(proofBytes),
+		Input:    make([]string, len(input)),
+		Calldata: "0x" + hex.EncodeToString(calldata),
+	}
+	for i := range input {
+		result.Input[i] = "0x" + hex.EncodeToString(input[i])
+	}
+
+	return result, nil
+}
+
+// encodeGroth16 lays the points out as EIP-197 expects them, with the
+// imaginary part of G2 coordinates first. All arguments are static arrays,
+// so their ABI encoding is the words back to back.
+func encodeGroth16(proof *groth16bn254.Proof, input [][]byte) ([]byte, []byte) {
+	var args []byte
+
+	ar, bs, krs := proof.Ar.RawBytes(), proof.Bs.RawBytes(), proof.Krs.RawBytes()
+	args = append(args, ar[:]...)
+	args = append(args, bs[:]...)
+	args = append(args, krs[:]...)
+
+	signature := "verifyProof(uint256[8],"
+	if len(proof.Commitments) > 0 {
+		for i := range proof.Commitments {
+			commitment := proof.Commitments[i].RawBytes()
+			args = append(args, commitment[:]...)
+		}
+
+		pok := proof.CommitmentPok.RawBytes()
+		args = append(args, pok[:]...)
+
+		signature += fmt.Sprintf("uint256[%d],uint256[2],", 2*len(proof.Commitments))
+	}
+	signature += fmt.Sprintf("uint256[%d])", len(input))
+
+	proofBytes := append([]byte{}, args...)
+
+	for _, word := range input {
+		args = append(args, word...)
+	}
+
+	return proofBytes, append(selector(signature), args...)
+}
+
+// encodePlonk ABI encodes the dynamic bytes and uint256[] arguments of the
+// PLONK verifier: two offsets, then each argument prefixed by its length.
+func encodePlonk(proof *plonkbn254.Proof, input [][]byte) ([]byte, []byte) {
+	proofBytes := proof.MarshalSolidity()
+	padded := (len(proofBytes) + wordSize - 1) / wordSize * wordSize
+
+	calldata := selector("Verify(bytes,uint256[])")
+	calldata = append(calldata, uint256Word(2*wordSize)...)
+	calldata = append(calldata, uint256Word(uint64(3*wordSize+padded))...)
+
+	calldata = append(calldata, uint256Word(uint64(len(proofBytes)))...)
+	calldata = append(calldata, proofBytes...)
+	calldata = append(calldata, make([]byte, padded-len(proofBytes))...)
+
+	calldata = append(calldata, uint256Word(uint64(len(input)))...)
+	for _, word := range input {
+		calldata = append(calldata, word...)
+	}
+
+	return proofBytes, calldata
+}
+
+func selector(signature string) []byte {
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write([]byte(signature))
+
+	return hasher.Sum(nil)[:4]
+}
+
+func uint256Word(value uint64) []byte {
+	word := make([]byte, wordSize)
+	binary.BigEndian.PutUint64(word[wordSize-8:], value)
+
+	return word
+}
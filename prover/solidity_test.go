@@ -0,0 +1,187 @@
+package prover_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"hide-pay/builder"
+	"hide-pay/prover"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
+)
+
+func decodeHex(t *testing.T, value string) []byte {
+	data, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+	require.NoError(t, err)
+
+	return data
+}
+
+func selector(signature string) []byte {
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write([]byte(signature))
+
+	return hasher.Sum(nil)[:4]
+}
+
+func TestNewPublicLayout(t *testing.T) {
+	shape := prover.Shape{Assets: 1, Depth: 4, Inputs: 2, Outputs: 3}
+
+	expected := []prover.PublicField{
+		{Name: "auditPublicKey", Offset: 0, Length: 2},
+		{Name: "nullifiers", Offset: 2, Length: 2},
+		{Name: "commitments", Offset: 4, Length: 3},
+		{Name: "ownerMemoHashes", Offset: 7, Length: 3},
+		{Name: "auditMemoHashes", Offset: 10, Length: 3},
+		{Name: "merkleRoot", Offset: 13, Length: 1},
+	}
+
+	for _, backend := range []prover.Backend{prover.Groth16, prover.Plonk} {
+		cs, err := prover.Compile(shape, backend)
+		require.NoError(t, err)
+
+		// The names survive the constraint system file
+		path := filepath.Join(t.TempDir(), "cs.dat")
+		require.NoError(t, prover.WriteFile(path, cs))
+		cs, err = prover.ReadConstraintSystem(backend, path)
+		require.NoError(t, err)
+
+		layout, err := prover.NewPublicLayout(cs)
+		require.NoError(t, err)
+		assert.Equal(t, 14, layout.NbPublic, backend)
+		assert.Equal(t, expected, layout.Fields, backend)
+	}
+
+	// A sparse R1CS has no constant wire, so its first input is at offset 0
+	// as well
+	variant, err := prover.LookupVariant("withdraw")
+	require.NoError(t, err)
+
+	for _, backend := range []prover.Backend{prover.Groth16, prover.Plonk} {
+		cs, err := variant.Compile(backend)
+		require.NoError(t, err)
+
+		layout, err := prover.NewPublicLayout(cs)
+		require.NoError(t, err)
+		assert.Equal(t, 5, layout.NbPublic, backend)
+		assert.Equal(t, []prover.PublicField{
+			{Name: "recipient", Offset: 0, Length: 1},
+			{Name: "asset", Offset: 1, Length: 1},
+			{Name: "amount", Offset: 2, Length: 1},
+			{Name: "nullifier", Offset: 3, Length: 1},
+			{Name: "merkleRoot", Offset: 4, Length: 1},
+		}, layout.Fields, backend)
+	}
+}
+
+func TestNewSolidityProof_Groth16(t *testing.T) {
+	shape := prover.Shape{Assets: 1, Depth: 4, Inputs: 1, Outputs: 1}
+
+	cs, err := prover.Compile(shape, prover.Groth16)
+	require.NoError(t, err)
+
+	pk, vk, err := prover.Setup(cs, nil)
+	require.NoError(t, err)
+
+	utxo, err := builder.GenerateUTXO(1, shape.Depth, shape.Inputs, shape.Outputs)
+	require.NoError(t, err)
+	w, err := prover.NewWitness(shape, utxo)
+	require.NoError(t, err)
+
+	proof, err := prover.Prove(cs, pk, w)
+	require.NoError(t, err)
+
+	public, err := w.Public()
+	require.NoError(t, err)
+
+	// Proofs made for the Solidity verifier still verify natively
+	require.NoError(t, prover.Verify(proof, vk, public))
+
+	encoded, err := prover.NewSolidityProof(proof, public)
+	require.NoError(t, err)
+	assert.Equal(t, prover.Groth16, encoded.Backend)
+
+	// The range checks of the transfer circuit add one commitment
+	concrete := proof.(*groth16bn254.Proof)
+	require.Len(t, concrete.Commitments, 1)
+
+	calldata := decodeHex(t, encoded.Calldata)
+	require.Len(t, calldata, 4+32*(8+2+2+7))
+	assert.Equal(t, selector("verifyProof(uint256[8],uint256[2],uint256[2],uint256[7])"), calldata[:4])
+
+	ar, bs := concrete.Ar.RawBytes(), concrete.Bs.RawBytes()
+	assert.Equal(t, ar[:], calldata[4:68])
+	// G2 coordinates are in EIP-197 order, imaginary part first
+	xA1 := concrete.Bs.X.A1.Bytes()
+	assert.Equal(t, xA1[:], calldata[68:100])
+	assert.Equal(t, bs[:], calldata[68:196])
+
+	proofBytes := decodeHex(t, encoded.Proof)
+	assert.Equal(t, calldata[4:4+32*12], proofBytes)
+
+	vector := public.Vector().(fr.Vector)
+	require.Len(t, encoded.Input, len(vector))
+	for i := range vector {
+		word := vector[i].Bytes()
+		assert.Equal(t, word[:], decodeHex(t, encoded.Input[i]))
+		assert.Equal(t, word[:], calldata[4+32*(12+i):4+32*(13+i)])
+	}
+
+	var contract bytes.Buffer
+	require.NoError(t, prover.ExportSolidity(vk, &contract))
+	assert.Contains(t, contract.String(), "function verifyProof(")
+}
+
+func TestNewSolidityProof_Plonk(t *testing.T) {
+	variant, w := merkleUpdateWitness(t)
+
+	cs, err := variant.Compile(prover.Plonk)
+	require.NoError(t, err)
+
+	srs, err := prover.NewUnsafeSRS(cs)
+	require.NoError(t, err)
+
+	pk, vk, err := prover.Setup(cs, srs)
+	require.NoError(t, err)
+
+	proof, err := prover.Prove(cs, pk, w)
+	require.NoError(t, err)
+
+	public, err := w.Public()
+	require.NoError(t, err)
+
+	encoded, err := prover.NewSolidityProof(proof, public)
+	require.NoError(t, err)
+	assert.Equal(t, prover.Plonk, encoded.Backend)
+
+	proofBytes := decodeHex(t, encoded.Proof)
+	padded := (len(proofBytes) + 31) / 32 * 32
+
+	calldata := decodeHex(t, encoded.Calldata)
+	require.Len(t, calldata, 4+32*3+padded+32*(1+4))
+	assert.Equal(t, selector("Verify(bytes,uint256[])"), calldata[:4])
+
+	word := func(value byte) []byte {
+		word := make([]byte, 32)
+		word[31] = value
+		return word
+	}
+
+	// Offsets of both dynamic arguments, then the proof and the inputs, each
+	// prefixed by its length
+	assert.Equal(t, word(0x40), calldata[4:36])
+	assert.Equal(t, uint64(0x60+padded), new(fr.Element).SetBytes(calldata[36:68]).Uint64())
+	assert.Equal(t, uint64(len(proofBytes)), new(fr.Element).SetBytes(calldata[68:100]).Uint64())
+	assert.Equal(t, proofBytes, calldata[100:100+len(proofBytes)])
+	assert.Equal(t, word(4), calldata[100+padded:132+padded])
+
+	var contract bytes.Buffer
+	require.NoError(t, prover.ExportSolidity(vk, &contract))
+	assert.Contains(t, contract.String(), "function Verify(")
+}
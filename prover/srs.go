@@ -0,0 +1,96 @@
+package prover
+
+import (
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	kzgbn254 "github.com/consensys/gnark-crypto/ecc/bn254/kzg"
+	"github.com/consensys/gnark-crypto/kzg"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/test/unsafekzg"
+)
+
+// SRS is a KZG structured reference string sized for one constraint system,
+// in canonical and Lagrange form as plonk.Setup takes it.
+type SRS struct {
+	Canonical kzg.SRS
+	Lagrange  kzg.SRS
+}
+
+// SRSSource provides an SRS for a constraint system, so callers only pay for
+// loading or generating one when a PLONK setup actually runs.
+type SRSSource func(cs constraint.ConstraintSystem) (*SRS, error)
+
+// SRSFile is the source reading path with ReadSRS.
+func SRSFile(path string) SRSSource {
+	return func(cs constraint.ConstraintSystem) (*SRS, error) {
+		return ReadSRS(path, cs)
+	}
+}
+
+// NewUnsafeSRS generates an SRS from a known toxic value. Proofs made with it
+// can be forged; it is for tests and cost comparisons only.
+func NewUnsafeSRS(cs constraint.ConstraintSystem) (*SRS, error) {
+	canonical, lagrange, err := unsafekzg.NewSRS(cs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate unsafe SRS: %w", err)
+	}
+
+	return &SRS{Canonical: canonical, Lagrange: lagrange}, nil
+}
+
+// ReadSRS loads a canonical BN254 KZG SRS, e.g. the output of a powers of tau
+// ceremony, and cuts it down to the size cs needs.
+func ReadSRS(path string, cs constraint.ConstraintSystem) (*SRS, error) {
+	srs := kzg.NewSRS(ecc.BN254)
+	if err := ReadFile(path, srs); err != nil {
+		return nil, err
+	}
+
+	concrete, ok := srs.(*kzgbn254.SRS)
+	if !ok {
+		return nil, fmt.Errorf("unsupported SRS %T", srs)
+	}
+
+	sizeCanonical, sizeLagrange := plonk.SRSSize(cs)
+	if len(concrete.Pk.G1) < sizeCanonical {
+		return nil, fmt.Errorf("SRS %s has %d points, constraint system needs %d", path, len(concrete.Pk.G1), sizeCanonical)
+	}
+
+	lagrange, err := kzgbn254.ToLagrangeG1(concrete.Pk.G1[:sizeLagrange])
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert SRS to Lagrange form: %w", err)
+	}
+
+	return &SRS{
+		Canonical: &kzgbn254.SRS{
+			Pk: kzgbn254.ProvingKey{G1: concrete.Pk.G1[:sizeCanonical]},
+			Vk: concrete.Vk,
+		},
+		Lagrange: &kzgbn254.SRS{
+			Pk: kzgbn254.ProvingKey{G1: lagrange},
+			Vk: concrete.Vk,
+		},
+	}, nil
+}
+
+func (srs *SRS) check(cs constraint.ConstraintSystem) error {
+	sizeCanonical, sizeLagrange := plonk.SRSSize(cs)
+
+	canonical, ok := srs.Canonical.(*kzgbn254.SRS)
+	if !ok {
+		return fmt.Errorf("unsupported SRS %T", srs.Canonical)
+	}
+
+	lagrange, ok := srs.Lagrange.(*kzgbn254.SRS)
+	if !ok {
+		return fmt.Errorf("unsupported SRS %T", srs.Lagrange)
+	}
+
+	if len(canonical.Pk.G1) < sizeCanonical || len(lagrange.Pk.G1) != sizeLagrange {
+		return fmt.Errorf("SRS has %d canonical and %d Lagrange points, constraint system needs %d and %d", len(canonical.Pk.G1), len(lagrange.Pk.G1), sizeCanonical, sizeLagrange)
+	}
+
+	return nil
+}
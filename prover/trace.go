@@ -0,0 +1,249 @@
+package prover
+
+import (
+	"fmt"
+	"hide-pay/builder"
+	"hide-pay/circuits"
+	"hide-pay/utils"
+	"math/big"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/constraint/solver"
+	"github.com/consensys/gnark/frontend"
+)
+
+// Intermediate is a value of a transfer computed both by the circuit gadgets
+// and by the native builder.
+type Intermediate struct {
+	// Name locates the value like a transfer input field, e.g.
+	// outputs[1].ownerMemo.viewTag.
+	Name    string     `json:"name"`
+	Circuit fr.Element `json:"circuit"`
+	Native  fr.Element `json:"native"`
+}
+
+func (value *Intermediate) Diverges() bool {
+	return value.Circuit != value.Native
+}
+
+// FirstDivergence returns the first intermediate on which the circuit and the
+// builder disagree, or nil.
+func FirstDivergence(trace []Intermediate) *Intermediate {
+	for i := range trace {
+		if trace[i].Diverges() {
+			return &trace[i]
+		}
+	}
+
+	return nil
+}
+
+// TraceTransfer computes the intermediates of utxo with the transfer gadgets
+// and with the native builder, in the order the circuit computes them: for
+// each input its commitment, Merkle root and nullifier, then for each output
+// its commitment and both memos. The gadgets run in a circuit without the
+// final assertions of the transfer, so their values are known even when the
+// transfer witness does not solve.
+func TraceTransfer(utxo *builder.UTXO) ([]Intermediate, error) {
+	names := traceNames(len(utxo.Nullifier), len(utxo.Commitment))
+
+	var (
+		native    []fr.Element
+		nativeErr error
+		wg        sync.WaitGroup
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		native, nativeErr = traceNative(utxo)
+	}()
+
+	circuit, err := traceCircuit(utxo, len(names))
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	if nativeErr != nil {
+		return nil, nativeErr
+	}
+
+	trace := make([]Intermediate, len(names))
+	for i := range names {
+		trace[i] = Intermediate{Name: names[i], Circuit: circuit[i], Native: native[i]}
+	}
+
+	return trace, nil
+}
+
+func traceNames(nbInputs int, nbOutputs int) []string {
+	var names []string
+	for i := range nbInputs {
+		for _, value := range []string{"commitment", "merkleRoot", "nullifier"} {
+			names = append(names, fmt.Sprintf("inputs[%d].%s", i, value))
+		}
+	}
+
+	for i := range nbOutputs {
+		names = append(names, fmt.Sprintf("outputs[%d].commitment", i))
+		for _, memo := range []string{"ownerMemo", "auditMemo"} {
+			for _, value := range []string{"viewTag", "tag", "hash"} {
+				names = append(names, fmt.Sprintf("outputs[%d].%s.%s", i, memo, value))
+			}
+		}
+	}
+
+	return names
+}
+
+func traceNative(utxo *builder.UTXO) ([]fr.Element, error) {
+	if len(utxo.Nullifier) != len(utxo.MerkleProof) {
+		return nil, fmt.Errorf("number of nullifiers and merkle proofs must be the same")
+	}
+
+	var values []fr.Element
+	for i := range utxo.Nullifier {
+		values = append(values, utxo.Nullifier[i].Commitment.Compute(), utxo.MerkleProof[i].Verify(), utxo.Nullifier[i].Compute())
+	}
+
+	for i := range utxo.Commitment {
+		values = append(values, utxo.Commitment[i].Compute())
+
+		for _, memo := range []builder.Memo{
+			{SecretKey: utxo.EphemeralReceiverSecretKey[i], PublicKey: utxo.Commitment[i].ViewPubKey},
+			{SecretKey: utxo.EphemeralAuditSecretKey[i], PublicKey: utxo.AuditPublicKey},
+		} {
+			envelope, err := memo.Encrypt(utxo.Commitment[i], utxo.SpentKey[i])
+			if err != nil {
+				return nil, fmt.Errorf("failed to encrypt memo of output %d: %w", i, err)
+			}
+
+			values = append(values, envelope.ViewTag, envelope.Tag, envelope.Hash())
+		}
+	}
+
+	return values, nil
+}
+
+// traceProbe runs the gadgets of the transfer circuit on a transfer and
+// hands each intermediate to captureHint.
+type traceProbe struct {
+	UTXO circuits.UTXOGadget
+}
+
+func (probe *traceProbe) Define(api frontend.API) error {
+	hasher, err := utils.NewPoseidonHasher(api)
+	if err != nil {
+		return fmt.Errorf("failed to create poseidon hasher: %w", err)
+	}
+
+	gadget := &probe.UTXO
+	depth := len(gadget.MerkleProofPath) / len(gadget.Nullifier)
+
+	var values []frontend.Variable
+	for i := range gadget.Nullifier {
+		commitment, err := gadget.Nullifier[i].CommitmentGadget.Compute(api)
+		if err != nil {
+			return fmt.Errorf("failed to compute input commitment: %w", err)
+		}
+
+		merkleProof := circuits.MerkleProofGadget{
+			Path: gadget.MerkleProofPath[i*depth : (i+1)*depth],
+			Leaf: gadget.MerkleProofIndex[i],
+		}
+		hasher.Reset()
+		root := merkleProof.VerifyProof(api, hasher)
+
+		nullifier, err := gadget.Nullifier[i].Compute(api)
+		if err != nil {
+			return fmt.Errorf("failed to compute nullifier: %w", err)
+		}
+
+		values = append(values, commitment, root, nullifier)
+	}
+
+	for i := range gadget.Commitment {
+		commitment, err := gadget.Commitment[i].Compute(api)
+		if err != nil {
+			return fmt.Errorf("failed to compute commitment: %w", err)
+		}
+		values = append(values, commitment)
+
+		for _, memo := range []circuits.MemoGadget{
+			{EphemeralSecretKey: gadget.EphemeralReceiverSecretKey[i], ReceiverPublicKey: gadget.Commitment[i].ViewPubKey},
+			{EphemeralSecretKey: gadget.EphemeralAuditSecretKey[i], ReceiverPublicKey: gadget.AuditPublicKey},
+		} {
+			result, err := memo.Generate(api, gadget.Commitment[i], gadget.SpentKey[i])
+			if err != nil {
+				return fmt.Errorf("failed to generate memo: %w", err)
+			}
+			values = append(values, result.ViewTag, result.Tag, result.Hash)
+		}
+	}
+
+	// The index goes along with each value since hints are not solved in
+	// the order they are created
+	for i := range values {
+		if _, err := api.Compiler().NewHint(captureHint, 1, i, values[i]); err != nil {
+			return fmt.Errorf("failed to capture intermediate %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// captureHint passes its value through. TraceTransfer overrides it to read
+// the values.
+func captureHint(_ *big.Int, inputs []*big.Int, outputs []*big.Int) error {
+	outputs[0].Set(inputs[1])
+	return nil
+}
+
+func traceCircuit(utxo *builder.UTXO, nbValues int) ([]fr.Element, error) {
+	if len(utxo.Nullifier) == 0 || len(utxo.MerkleProof) == 0 {
+		return nil, fmt.Errorf("transfer has no inputs")
+	}
+
+	gadget, err := utxo.ToGadget(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build assignment: %w", err)
+	}
+
+	depth := len(utxo.MerkleProof[0].Path())
+	empty := circuits.NewUTXOGadget(0, depth, len(utxo.Nullifier), len(utxo.Commitment))
+
+	cs, err := CompileDebug(&traceProbe{UTXO: *empty}, Groth16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile trace circuit: %w", err)
+	}
+
+	w, err := frontend.NewWitness(&traceProbe{UTXO: *gadget}, ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace witness: %w", err)
+	}
+
+	var mu sync.Mutex
+	values := make([]fr.Element, nbValues)
+	capture := func(field *big.Int, inputs []*big.Int, outputs []*big.Int) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		index := inputs[0].Int64()
+		if index < 0 || index >= int64(nbValues) {
+			return fmt.Errorf("unexpected intermediate %d", index)
+		}
+		values[index].SetBigInt(inputs[1])
+
+		return captureHint(field, inputs, outputs)
+	}
+
+	// A gadget can fail on its own, e.g. on a key off the curve; the
+	// location says which one
+	if err := cs.Check(w, solver.OverrideHint(solver.GetHintID(captureHint), capture)); err != nil {
+		return nil, fmt.Errorf("trace circuit does not solve: %w", err)
+	}
+
+	return values, nil
+}
@@ -0,0 +1,102 @@
+package prover
+
+import (
+	"fmt"
+	"hide-pay/builder"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	plonkbn254 "github.com/consensys/gnark/backend/plonk/bn254"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+)
+
+// NewWitness checks utxo natively and assigns it to a circuit of this shape.
+func NewWitness(shape Shape, utxo *builder.UTXO) (witness.Witness, error) {
+	if err := shape.Validate(); err != nil {
+		return nil, err
+	}
+
+	if len(utxo.Nullifier) != shape.Inputs || len(utxo.Commitment) != shape.Outputs {
+		return nil, fmt.Errorf("transfer has %d inputs and %d outputs, shape expects %d and %d", len(utxo.Nullifier), len(utxo.Commitment), shape.Inputs, shape.Outputs)
+	}
+
+	result, err := utxo.BuildAndCheck()
+	if err != nil {
+		return nil, fmt.Errorf("invalid transfer: %w", err)
+	}
+
+	if err := result.PadAllAsset(shape.Assets); err != nil {
+		return nil, err
+	}
+
+	assignment, err := builder.NewUTXOCircuitWitness(utxo, result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build assignment: %w", err)
+	}
+
+	if len(assignment.UTXO.MerkleProofPath) != shape.Inputs*shape.Depth {
+		return nil, fmt.Errorf("merkle proofs have depth %d, shape expects %d", len(assignment.UTXO.MerkleProofPath)/shape.Inputs, shape.Depth)
+	}
+
+	w, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("failed to build witness: %w", err)
+	}
+
+	return w, nil
+}
+
+// CheckWitness fails when w is not a full witness for cs.
+func CheckWitness(cs constraint.ConstraintSystem, w witness.Witness) error {
+	nbPublic := NbPublicInputs(cs)
+	nbSecret := cs.GetNbSecretVariables()
+
+	public, err := w.Public()
+	if err != nil {
+		return fmt.Errorf("invalid witness: %w", err)
+	}
+
+	nbElements, nbPublicElements := WitnessSize(w), WitnessSize(public)
+
+	if nbPublicElements != nbPublic || nbElements != nbPublic+nbSecret {
+		return fmt.Errorf("witness has %d public and %d secret values, constraint system expects %d and %d", nbPublicElements, nbElements-nbPublicElements, nbPublic, nbSecret)
+	}
+
+	return nil
+}
+
+// CheckPublicWitness fails when w does not have the public inputs vk expects.
+func CheckPublicWitness(vk VerifyingKey, w witness.Witness) error {
+	if nbPublic := WitnessSize(w); nbPublic != NbPublic(vk) {
+		return fmt.Errorf("public witness has %d values, verifying key expects %d", nbPublic, NbPublic(vk))
+	}
+
+	return nil
+}
+
+// NbPublic returns the number of public inputs of vk. For Groth16,
+// NbPublicWitness also counts the wires of the commitments used by range
+// checks.
+func NbPublic(vk VerifyingKey) int {
+	switch concrete := vk.(type) {
+	case *groth16bn254.VerifyingKey:
+		return concrete.NbPublicWitness() - len(concrete.CommitmentKeys)
+	case *plonkbn254.VerifyingKey:
+		return int(concrete.NbPublicVariables)
+	default:
+		return -1
+	}
+}
+
+// WitnessSize returns the number of values in w, or -1 for another field.
+func WitnessSize(w witness.Witness) int {
+	vector, ok := w.Vector().(fr.Vector)
+	if !ok {
+		return -1
+	}
+
+	return len(vector)
+}
@@ -1,4 +1,6 @@
 #!/bin/bash
 
-go build -o target/build ./bins/gnark-build || exit 1
-./target/build
\ No newline at end of file
+mkdir -p target
+go build -o target/auditzero ./bins/auditzero || exit 1
+./target/auditzero compile -cs target/cs.dat "$@" || exit 1
+./target/auditzero setup -cs target/cs.dat -pk target/pk.dat -vk target/vk.dat
@@ -1,4 +1,10 @@
 #!/bin/bash
 
-go build -o target/prove ./bins/gnark-prove || exit 1
-./target/prove
+if [ -z "$1" ]; then
+    echo "usage: $0 <witness file>" >&2
+    exit 2
+fi
+
+go build -o target/auditzero ./bins/auditzero || exit 1
+./target/auditzero prove -cs target/cs.dat -pk target/pk.dat -witness "$1" -proof target/proof.dat -public target/public.dat || exit 1
+./target/auditzero verify -vk target/vk.dat -proof target/proof.dat -public target/public.dat
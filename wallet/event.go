@@ -0,0 +1,247 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hide-pay/builder"
+	"io"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// CommitmentAdded mirrors the contract event of the same name. The memos are
+// the ones of this commitment alone, a transfer with several outputs emits one
+// event per output. OutgoingMemo is empty when the sender did not attach one.
+type CommitmentAdded struct {
+	Index        uint64
+	Commitment   fr.Element
+	OwnerMemo    [][32]byte
+	AuditMemo    [][32]byte
+	OutgoingMemo [][32]byte
+}
+
+// Event is one ledger update in chain order. Exactly one of CommitmentAdded
+// and Nullifier is set; nullifiers are taken from submitted transactions since
+// the contract does not emit them.
+type Event struct {
+	CommitmentAdded *CommitmentAdded
+	Nullifier       *fr.Element
+}
+
+// EventSource yields events in chain order and returns io.EOF once drained.
+type EventSource interface {
+	Next() (*Event, error)
+}
+
+type channelSource struct {
+	events <-chan Event
+}
+
+// NewChannelSource reads events from a channel until it is closed.
+func NewChannelSource(events <-chan Event) EventSource {
+	return &channelSource{events: events}
+}
+
+func (source *channelSource) Next() (*Event, error) {
+	event, ok := <-source.events
+	if !ok {
+		return nil, io.EOF
+	}
+
+	return &event, nil
+}
+
+type fileSource struct {
+	decoder *json.Decoder
+}
+
+// NewFileSource reads a stream of JSON encoded events, typically one per line.
+func NewFileSource(reader io.Reader) EventSource {
+	return &fileSource{decoder: json.NewDecoder(reader)}
+}
+
+func (source *fileSource) Next() (*Event, error) {
+	var event Event
+	if err := source.decoder.Decode(&event); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	return &event, nil
+}
+
+// TransferEvents returns the CommitmentAdded events the contract emits for
+// the outputs of a transfer, with indices counting up from index.
+func TransferEvents(index uint64, result *builder.UTXOResult) []Event {
+	events := make([]Event, len(result.Commitments))
+	for i, output := range result.Commitments {
+		added := &CommitmentAdded{
+			Index:      index + uint64(i),
+			Commitment: output.Commitment,
+			OwnerMemo:  output.OwnerMemo.MarshalWords(),
+			AuditMemo:  output.AuditMemo.MarshalWords(),
+		}
+		if output.OutgoingMemo != nil {
+			added.OutgoingMemo = output.OutgoingMemo.MarshalWords()
+		}
+
+		events[i] = Event{CommitmentAdded: added}
+	}
+
+	return events
+}
+
+type eventJSON struct {
+	Type         string   `json:"type"`
+	Index        uint64   `json:"index,omitempty"`
+	Commitment   string   `json:"commitment,omitempty"`
+	OwnerMemo    []string `json:"ownerMemo,omitempty"`
+	AuditMemo    []string `json:"auditMemo,omitempty"`
+	OutgoingMemo []string `json:"outgoingMemo,omitempty"`
+	Nullifier    string   `json:"nullifier,omitempty"`
+}
+
+const (
+	eventTypeCommitmentAdded = "commitmentAdded"
+	eventTypeNullifier       = "nullifier"
+)
+
+// MarshalJSON encodes every bytes32 value as 0x-prefixed hex.
+func (event Event) MarshalJSON() ([]byte, error) {
+	switch {
+	case event.CommitmentAdded != nil && event.Nullifier == nil:
+		added := event.CommitmentAdded
+
+		return json.Marshal(eventJSON{
+			Type:         eventTypeCommitmentAdded,
+			Index:        added.Index,
+			Commitment:   encodeWord(added.Commitment.Bytes()),
+			OwnerMemo:    encodeWords(added.OwnerMemo),
+			AuditMemo:    encodeWords(added.AuditMemo),
+			OutgoingMemo: encodeWords(added.OutgoingMemo),
+		})
+	case event.Nullifier != nil && event.CommitmentAdded == nil:
+		return json.Marshal(eventJSON{
+			Type:      eventTypeNullifier,
+			Nullifier: encodeWord(event.Nullifier.Bytes()),
+		})
+	default:
+		return nil, fmt.Errorf("event must set exactly one of CommitmentAdded and Nullifier")
+	}
+}
+
+func (event *Event) UnmarshalJSON(data []byte) error {
+	var raw eventJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	switch raw.Type {
+	case eventTypeCommitmentAdded:
+		commitment, err := decodeElement(raw.Commitment)
+		if err != nil {
+			return fmt.Errorf("invalid commitment: %w", err)
+		}
+
+		ownerMemo, err := decodeWords(raw.OwnerMemo)
+		if err != nil {
+			return fmt.Errorf("invalid owner memo: %w", err)
+		}
+
+		auditMemo, err := decodeWords(raw.AuditMemo)
+		if err != nil {
+			return fmt.Errorf("invalid audit memo: %w", err)
+		}
+
+		outgoingMemo, err := decodeWords(raw.OutgoingMemo)
+		if err != nil {
+			return fmt.Errorf("invalid outgoing memo: %w", err)
+		}
+
+		*event = Event{
+			CommitmentAdded: &CommitmentAdded{
+				Index:        raw.Index,
+				Commitment:   commitment,
+				OwnerMemo:    ownerMemo,
+				AuditMemo:    auditMemo,
+				OutgoingMemo: outgoingMemo,
+			},
+		}
+	case eventTypeNullifier:
+		nullifier, err := decodeElement(raw.Nullifier)
+		if err != nil {
+			return fmt.Errorf("invalid nullifier: %w", err)
+		}
+
+		*event = Event{Nullifier: &nullifier}
+	default:
+		return fmt.Errorf("unknown event type %q", raw.Type)
+	}
+
+	return nil
+}
+
+func encodeWord(word [32]byte) string {
+	return "0x" + hex.EncodeToString(word[:])
+}
+
+func encodeWords(words [][32]byte) []string {
+	encoded := make([]string, len(words))
+	for i := range words {
+		encoded[i] = encodeWord(words[i])
+	}
+
+	return encoded
+}
+
+func decodeWord(s string) ([32]byte, error) {
+	var word [32]byte
+
+	if !strings.HasPrefix(s, "0x") {
+		return word, fmt.Errorf("missing 0x prefix")
+	}
+
+	data, err := hex.DecodeString(s[2:])
+	if err != nil {
+		return word, err
+	}
+
+	if len(data) != len(word) {
+		return word, fmt.Errorf("expected %d bytes, got %d", len(word), len(data))
+	}
+
+	copy(word[:], data)
+
+	return word, nil
+}
+
+func decodeWords(encoded []string) ([][32]byte, error) {
+	words := make([][32]byte, len(encoded))
+	for i := range encoded {
+		word, err := decodeWord(encoded[i])
+		if err != nil {
+			return nil, fmt.Errorf("word %d: %w", i, err)
+		}
+		words[i] = word
+	}
+
+	return words, nil
+}
+
+func decodeElement(s string) (fr.Element, error) {
+	word, err := decodeWord(s)
+	if err != nil {
+		return fr.Element{}, err
+	}
+
+	var element fr.Element
+	if err := element.SetBytesCanonical(word[:]); err != nil {
+		return fr.Element{}, err
+	}
+
+	return element, nil
+}
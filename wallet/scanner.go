@@ -0,0 +1,286 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"hide-pay/builder"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+)
+
+// Note is an output recovered from an owner memo.
+type Note struct {
+	Index      uint64
+	Commitment fr.Element
+	Opening    builder.Commitment
+	SpentKey   fr.Element
+	Nullifier  fr.Element
+	Spent      bool
+}
+
+// SentNote is an output recovered from an outgoing memo: a note this wallet
+// created for Receiver.
+type SentNote struct {
+	Index      uint64
+	Commitment fr.Element
+	Opening    builder.Commitment
+	Receiver   twistededwardbn254.PointAffine
+}
+
+// ScanStats counts how the scanned commitments were handled.
+type ScanStats struct {
+	Commitments int
+	Matched     int
+	// Rejected memos passed the view tag but did not decrypt or did not open
+	// the commitment they were published with.
+	Rejected int
+	// Sent counts the outgoing memos opened with the outgoing viewing key.
+	Sent int
+}
+
+// Scanner trial-decrypts owner memos with a viewing key and tracks the notes
+// it finds, their spends and the resulting per-asset balances. With an
+// outgoing viewing key it also rebuilds the history of sent notes.
+type Scanner struct {
+	viewSecretKey      big.Int
+	outgoingViewingKey *fr.Element
+
+	notes       []*Note
+	commitments map[fr.Element]*Note
+	nullifiers  map[fr.Element]*Note
+	// early holds nullifiers seen before the note they spend
+	early    map[fr.Element]bool
+	balances map[fr.Element]*big.Int
+
+	sent map[fr.Element]*SentNote
+
+	stats ScanStats
+}
+
+func NewScanner(viewSecretKey big.Int) *Scanner {
+	return &Scanner{
+		viewSecretKey: viewSecretKey,
+		commitments:   make(map[fr.Element]*Note),
+		nullifiers:    make(map[fr.Element]*Note),
+		early:         make(map[fr.Element]bool),
+		balances:      make(map[fr.Element]*big.Int),
+		sent:          make(map[fr.Element]*SentNote),
+	}
+}
+
+// SetOutgoingViewingKey enables recovery of the notes sent with
+// outgoingViewingKey. It applies to the events processed afterwards.
+func (scanner *Scanner) SetOutgoingViewingKey(outgoingViewingKey fr.Element) {
+	scanner.outgoingViewingKey = &outgoingViewingKey
+}
+
+// Scan processes every event of source until it is drained.
+func (scanner *Scanner) Scan(source EventSource) error {
+	for {
+		event, err := source.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := scanner.Process(event); err != nil {
+			return err
+		}
+	}
+}
+
+func (scanner *Scanner) Process(event *Event) error {
+	switch {
+	case event.CommitmentAdded != nil:
+		scanner.processCommitment(event.CommitmentAdded)
+	case event.Nullifier != nil:
+		scanner.processNullifier(*event.Nullifier)
+	default:
+		return fmt.Errorf("empty event")
+	}
+
+	return nil
+}
+
+func (scanner *Scanner) processCommitment(added *CommitmentAdded) {
+	scanner.stats.Commitments++
+
+	scanner.processOwnerMemo(added)
+
+	if scanner.outgoingViewingKey != nil && len(added.OutgoingMemo) > 0 {
+		scanner.processOutgoingMemo(added)
+	}
+}
+
+func (scanner *Scanner) processOwnerMemo(added *CommitmentAdded) {
+	if _, ok := scanner.commitments[added.Commitment]; ok {
+		return
+	}
+
+	// Memos that are malformed or addressed to someone else are skipped
+	var envelope builder.EncryptedMemo
+	if err := envelope.UnmarshalWords(added.OwnerMemo); err != nil {
+		return
+	}
+
+	sharedKey, matched, err := envelope.CheckViewTag(scanner.viewSecretKey)
+	if err != nil || !matched {
+		return
+	}
+
+	scanner.stats.Matched++
+
+	opening, spentKey, err := envelope.DecryptWithSharedKey(sharedKey)
+	if err != nil || opening.Compute() != added.Commitment {
+		scanner.stats.Rejected++
+		return
+	}
+
+	nullifier := builder.Nullifier{
+		Commitment:      *opening,
+		SpentPrivateKey: *spentKey,
+	}
+
+	note := &Note{
+		Index:      added.Index,
+		Commitment: added.Commitment,
+		Opening:    *opening,
+		SpentKey:   *spentKey,
+		Nullifier:  nullifier.Compute(),
+	}
+
+	scanner.notes = append(scanner.notes, note)
+	scanner.commitments[note.Commitment] = note
+	scanner.nullifiers[note.Nullifier] = note
+
+	balance := scanner.balance(note.Opening.Asset)
+	balance.Add(balance, note.Opening.Amount.BigInt(new(big.Int)))
+
+	if scanner.early[note.Nullifier] {
+		delete(scanner.early, note.Nullifier)
+		scanner.markSpent(note)
+	}
+}
+
+func (scanner *Scanner) processOutgoingMemo(added *CommitmentAdded) {
+	if _, ok := scanner.sent[added.Commitment]; ok {
+		return
+	}
+
+	// Outgoing memos of other senders fail authentication and are skipped
+	var envelope builder.EncryptedMemo
+	if err := envelope.UnmarshalWords(added.OwnerMemo); err != nil {
+		return
+	}
+
+	var outgoing builder.EncryptedOutgoingMemo
+	if err := outgoing.UnmarshalWords(added.OutgoingMemo); err != nil {
+		return
+	}
+
+	opening, _, receiver, err := outgoing.RecoverNote(*scanner.outgoingViewingKey, added.Commitment, &envelope)
+	if err != nil || opening.Compute() != added.Commitment {
+		return
+	}
+
+	scanner.stats.Sent++
+
+	scanner.sent[added.Commitment] = &SentNote{
+		Index:      added.Index,
+		Commitment: added.Commitment,
+		Opening:    *opening,
+		Receiver:   *receiver,
+	}
+}
+
+func (scanner *Scanner) processNullifier(nullifier fr.Element) {
+	note, ok := scanner.nullifiers[nullifier]
+	if !ok {
+		scanner.early[nullifier] = true
+		return
+	}
+
+	scanner.markSpent(note)
+}
+
+func (scanner *Scanner) markSpent(note *Note) {
+	if note.Spent {
+		return
+	}
+
+	note.Spent = true
+
+	balance := scanner.balance(note.Opening.Asset)
+	balance.Sub(balance, note.Opening.Amount.BigInt(new(big.Int)))
+}
+
+func (scanner *Scanner) balance(asset fr.Element) *big.Int {
+	balance, ok := scanner.balances[asset]
+	if !ok {
+		balance = new(big.Int)
+		scanner.balances[asset] = balance
+	}
+
+	return balance
+}
+
+// Balance returns the sum of unspent amounts of asset.
+func (scanner *Scanner) Balance(asset fr.Element) *big.Int {
+	balance, ok := scanner.balances[asset]
+	if !ok {
+		return new(big.Int)
+	}
+
+	return new(big.Int).Set(balance)
+}
+
+// Balances returns a copy of every per-asset balance seen so far.
+func (scanner *Scanner) Balances() map[fr.Element]*big.Int {
+	balances := make(map[fr.Element]*big.Int, len(scanner.balances))
+	for asset, balance := range scanner.balances {
+		balances[asset] = new(big.Int).Set(balance)
+	}
+
+	return balances
+}
+
+// UnspentNotes returns the notes not yet spent, ordered by ledger index.
+func (scanner *Scanner) UnspentNotes() []Note {
+	notes := make([]Note, 0, len(scanner.notes))
+	for _, note := range scanner.notes {
+		if !note.Spent {
+			notes = append(notes, *note)
+		}
+	}
+
+	sort.Slice(notes, func(i, j int) bool {
+		return notes[i].Index < notes[j].Index
+	})
+
+	return notes
+}
+
+// SentNotes returns the notes recovered from outgoing memos, ordered by ledger
+// index.
+func (scanner *Scanner) SentNotes() []SentNote {
+	notes := make([]SentNote, 0, len(scanner.sent))
+	for _, note := range scanner.sent {
+		notes = append(notes, *note)
+	}
+
+	sort.Slice(notes, func(i, j int) bool {
+		return notes[i].Index < notes[j].Index
+	})
+
+	return notes
+}
+
+func (scanner *Scanner) Stats() ScanStats {
+	return scanner.stats
+}
@@ -0,0 +1,268 @@
+package wallet_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"hide-pay/builder"
+	"hide-pay/utils"
+	"hide-pay/wallet"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	twistededwardbn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ledgerNote struct {
+	event     wallet.Event
+	nullifier fr.Element
+}
+
+// newLedgerNote builds a CommitmentAdded event whose owner memo is encrypted
+// to viewPublicKey.
+func newLedgerNote(t *testing.T, index uint64, asset uint64, amount uint64, viewPublicKey twistededwardbn254.PointAffine) ledgerNote {
+	commitment, spentKey := builder.GenerateCommitment(int64(index) + 1)
+	commitment.Asset = fr.NewElement(asset)
+	commitment.Amount = fr.NewElement(amount)
+
+	memo := &builder.Memo{
+		SecretKey: *big.NewInt(int64(index) + 1000),
+		PublicKey: viewPublicKey,
+	}
+
+	envelope, err := memo.Encrypt(*commitment, *spentKey)
+	require.NoError(t, err)
+
+	nullifier := builder.Nullifier{
+		Commitment:      *commitment,
+		SpentPrivateKey: *spentKey,
+	}
+
+	return ledgerNote{
+		event: wallet.Event{
+			CommitmentAdded: &wallet.CommitmentAdded{
+				Index:      index,
+				Commitment: commitment.Compute(),
+				OwnerMemo:  envelope.MarshalWords(),
+				AuditMemo:  envelope.MarshalWords(),
+			},
+		},
+		nullifier: nullifier.Compute(),
+	}
+}
+
+func newLedger(t *testing.T, viewPublicKey twistededwardbn254.PointAffine) []wallet.Event {
+	foreignPublicKey := utils.BuildPublicKey(*big.NewInt(99999))
+
+	mine0 := newLedgerNote(t, 0, 1, 100, viewPublicKey)
+	foreign := newLedgerNote(t, 1, 1, 500, foreignPublicKey)
+	mine2 := newLedgerNote(t, 2, 1, 30, viewPublicKey)
+	mine3 := newLedgerNote(t, 3, 2, 7, viewPublicKey)
+
+	return []wallet.Event{
+		mine0.event,
+		foreign.event,
+		mine2.event,
+		{Nullifier: &mine0.nullifier},
+		{Nullifier: &foreign.nullifier},
+		mine3.event,
+	}
+}
+
+func assertLedgerScanned(t *testing.T, scanner *wallet.Scanner) {
+	assert.Equal(t, big.NewInt(30), scanner.Balance(fr.NewElement(1)))
+	assert.Equal(t, big.NewInt(7), scanner.Balance(fr.NewElement(2)))
+	assert.Equal(t, big.NewInt(0), scanner.Balance(fr.NewElement(3)))
+
+	unspent := scanner.UnspentNotes()
+	require.Len(t, unspent, 2)
+	assert.Equal(t, uint64(2), unspent[0].Index)
+	assert.Equal(t, uint64(3), unspent[1].Index)
+	assert.Equal(t, unspent[0].Commitment, unspent[0].Opening.Compute())
+
+	assert.Equal(t, wallet.ScanStats{Commitments: 4, Matched: 3}, scanner.Stats())
+}
+
+func TestScanner_ChannelSource(t *testing.T) {
+	viewSecretKey := big.NewInt(22222)
+	events := newLedger(t, utils.BuildPublicKey(*viewSecretKey))
+
+	ch := make(chan wallet.Event, len(events))
+	for _, event := range events {
+		ch <- event
+	}
+	close(ch)
+
+	scanner := wallet.NewScanner(*viewSecretKey)
+	require.NoError(t, scanner.Scan(wallet.NewChannelSource(ch)))
+
+	assertLedgerScanned(t, scanner)
+}
+
+func TestScanner_FileSource(t *testing.T) {
+	viewSecretKey := big.NewInt(22222)
+	events := newLedger(t, utils.BuildPublicKey(*viewSecretKey))
+
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	for _, event := range events {
+		require.NoError(t, encoder.Encode(event))
+	}
+
+	scanner := wallet.NewScanner(*viewSecretKey)
+	require.NoError(t, scanner.Scan(wallet.NewFileSource(buf)))
+
+	assertLedgerScanned(t, scanner)
+
+	scanner = wallet.NewScanner(*viewSecretKey)
+	assert.Error(t, scanner.Scan(wallet.NewFileSource(bytes.NewBufferString(`{"type":"unknown"}`))))
+}
+
+func TestScanner_RejectsWrongCommitment(t *testing.T) {
+	viewSecretKey := big.NewInt(22222)
+	note := newLedgerNote(t, 0, 1, 100, utils.BuildPublicKey(*viewSecretKey))
+
+	// A valid memo published next to a commitment it does not open
+	note.event.CommitmentAdded.Commitment.SetOne()
+
+	scanner := wallet.NewScanner(*viewSecretKey)
+	require.NoError(t, scanner.Process(&note.event))
+
+	assert.Empty(t, scanner.UnspentNotes())
+	assert.Equal(t, big.NewInt(0), scanner.Balance(fr.NewElement(1)))
+	assert.Equal(t, 1, scanner.Stats().Rejected)
+}
+
+func TestScanner_NullifierBeforeNote(t *testing.T) {
+	viewSecretKey := big.NewInt(22222)
+	note := newLedgerNote(t, 0, 1, 100, utils.BuildPublicKey(*viewSecretKey))
+
+	scanner := wallet.NewScanner(*viewSecretKey)
+	require.NoError(t, scanner.Process(&wallet.Event{Nullifier: &note.nullifier}))
+	require.NoError(t, scanner.Process(&note.event))
+
+	// Replayed events do not double count
+	require.NoError(t, scanner.Process(&note.event))
+	require.NoError(t, scanner.Process(&wallet.Event{Nullifier: &note.nullifier}))
+
+	assert.Empty(t, scanner.UnspentNotes())
+	assert.Equal(t, big.NewInt(0), scanner.Balance(fr.NewElement(1)))
+}
+
+func TestScanner_SentNotes(t *testing.T) {
+	senderViewSecretKey := big.NewInt(33333)
+	receiverPublicKey := utils.BuildPublicKey(*big.NewInt(22222))
+
+	outgoingViewingKey, err := builder.NewOutgoingViewingKey()
+	require.NoError(t, err)
+
+	events := make([]wallet.Event, 0, 3)
+	for index := uint64(0); index < 3; index++ {
+		commitment, spentKey := builder.GenerateCommitment(int64(index) + 1)
+		commitment.Asset = fr.NewElement(1)
+		commitment.Amount = fr.NewElement(10 * (index + 1))
+
+		memo := &builder.Memo{
+			SecretKey: *big.NewInt(int64(index) + 1000),
+			PublicKey: receiverPublicKey,
+		}
+
+		envelope, err := memo.Encrypt(*commitment, *spentKey)
+		require.NoError(t, err)
+
+		added := &wallet.CommitmentAdded{
+			Index:      index,
+			Commitment: commitment.Compute(),
+			OwnerMemo:  envelope.MarshalWords(),
+			AuditMemo:  envelope.MarshalWords(),
+		}
+
+		// The second output was sent by someone else
+		key := outgoingViewingKey
+		if index == 1 {
+			key = fr.NewElement(42)
+		}
+
+		outgoing, err := memo.EncryptOutgoing(key, added.Commitment)
+		require.NoError(t, err)
+		added.OutgoingMemo = outgoing.MarshalWords()
+
+		events = append(events, wallet.Event{CommitmentAdded: added})
+	}
+
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	for _, event := range events {
+		require.NoError(t, encoder.Encode(event))
+	}
+
+	scanner := wallet.NewScanner(*senderViewSecretKey)
+	scanner.SetOutgoingViewingKey(outgoingViewingKey)
+	require.NoError(t, scanner.Scan(wallet.NewFileSource(buf)))
+
+	// Replayed events do not duplicate history
+	require.NoError(t, scanner.Process(&events[0]))
+
+	sent := scanner.SentNotes()
+	require.Len(t, sent, 2)
+	assert.Equal(t, uint64(0), sent[0].Index)
+	assert.Equal(t, uint64(2), sent[1].Index)
+	assert.Equal(t, fr.NewElement(30), sent[1].Opening.Amount)
+	assert.Equal(t, sent[1].Commitment, sent[1].Opening.Compute())
+	assert.Equal(t, receiverPublicKey, sent[1].Receiver)
+
+	// Sent notes are not owned by the sender
+	assert.Empty(t, scanner.UnspentNotes())
+	assert.Equal(t, 2, scanner.Stats().Sent)
+
+	// Without the key the history stays hidden
+	scanner = wallet.NewScanner(*senderViewSecretKey)
+	for i := range events {
+		require.NoError(t, scanner.Process(&events[i]))
+	}
+	assert.Empty(t, scanner.SentNotes())
+}
+
+func TestScanner_TwoOutputTransfer(t *testing.T) {
+	viewSecretKey := big.NewInt(22222)
+
+	outgoingViewingKey, err := builder.NewOutgoingViewingKey()
+	require.NoError(t, err)
+
+	utxo, err := builder.GenerateUTXO(1, 10, 2, 2)
+	require.NoError(t, err)
+	for i := range utxo.Commitment {
+		utxo.Commitment[i].ViewPubKey = utils.BuildPublicKey(*viewSecretKey)
+	}
+	utxo.OutgoingViewingKey = &outgoingViewingKey
+
+	result, err := utxo.BuildAndCheck()
+	require.NoError(t, err)
+
+	// One event per output, each with the memos of that output only
+	events := wallet.TransferEvents(5, result)
+	require.Len(t, events, 2)
+
+	scanner := wallet.NewScanner(*viewSecretKey)
+	scanner.SetOutgoingViewingKey(outgoingViewingKey)
+	for i := range events {
+		require.NoError(t, scanner.Process(&events[i]))
+	}
+
+	unspent := scanner.UnspentNotes()
+	require.Len(t, unspent, 2)
+	for i := range unspent {
+		assert.Equal(t, uint64(5+i), unspent[i].Index)
+		assert.Equal(t, utxo.Commitment[i], unspent[i].Opening)
+		assert.Equal(t, utxo.SpentKey[i], unspent[i].SpentKey)
+	}
+	assert.Equal(t, big.NewInt(12), scanner.Balance(utxo.Commitment[0].Asset))
+
+	sent := scanner.SentNotes()
+	require.Len(t, sent, 2)
+	assert.Equal(t, utxo.Commitment[1], sent[1].Opening)
+
+	assert.Equal(t, wallet.ScanStats{Commitments: 2, Matched: 2, Sent: 2}, scanner.Stats())
+}